@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// refreshHashesFlag makes annotate record the current hash of snippets whose "_hash" is stale.
+var refreshHashesFlag bool
+
+// annotateCmd updates annotations in place.
+var annotateCmd = &cobra.Command{
+	Use:   "annotate [file]",
+	Short: "Tag snippets interactively, or update snippet annotations in place",
+	Long: `Annotate writes the tags of your snippets.
+
+With --interactive (-i), it opens a file in the terminal: move to the first line
+of a snippet and press space, move to its last line and press space again, then
+type its categories like --categories, completed with tab from the categories
+tagged in --dir. Press w to write the tags around the lines marked, q to quit
+without writing.
+
+With --refresh-hashes, every snippet whose content no longer matches its "_hash"
+gets the hash of its current content, once whatever the hash guards (documentation,
+prompts) has been reviewed:
+
+# >: {"foundation": ["messages"], "_hash": "3f2a9c81d04e"}
+
+lint and extract warn about such snippets (BRIO014) until then. Only snippets
+that already have a "_hash" are touched; add "_hash": "" to start tracking one.
+Usage example:
+brio annotate -i src/legacy/billing.py
+brio annotate --refresh-hashes --dir ./src
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if interactiveFlag {
+			if len(args) != 1 || refreshHashesFlag {
+				fatalf("--interactive takes the file to annotate, and no other option")
+			}
+			tagged, err := annotateInteractively(cmd.Context(), args[0], os.Stdin, os.Stdout)
+			if err != nil {
+				fatalf("Error annotating %s: %v", args[0], err)
+			}
+			if tagged > 0 {
+				fmt.Printf("Updated %s (%s)\n", displayPath(args[0]), snippetCount(tagged))
+			}
+			return
+		}
+		if len(args) > 0 {
+			fatalf("A file can only be given with --interactive")
+		}
+		if !refreshHashesFlag {
+			fatalf("Nothing to do: pass --interactive or --refresh-hashes")
+		}
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+
+		stale := make(map[string][]snippet)
+		for _, filePath := range files {
+			if cmd.Context().Err() != nil {
+				break
+			}
+			report.Files++
+			snips, _, err := scanFile(filePath)
+			if err != nil {
+				report.addError(err)
+				continue
+			}
+			for _, s := range snips {
+				if s.Attrs["hash"] != nil && !strings.EqualFold(s.Attr("hash"), s.Hash()) {
+					stale[filePath] = append(stale[filePath], s)
+				}
+			}
+		}
+		// Refreshing only part of the hashes would leave the others stale without a word.
+		checkCanceled(cmd.Context())
+
+		for _, filePath := range sortedKeys(stale) {
+			original, err := os.ReadFile(filePath)
+			if err != nil {
+				fatalf("Error reading %s: %v", filePath, err)
+			}
+			updated, refreshed := refreshHashes(string(original), stale[filePath])
+			if refreshed == 0 {
+				continue
+			}
+			if err := os.WriteFile(filePath, []byte(updated), 0644); err != nil {
+				fatalf("Error writing %s: %v", filePath, err)
+			}
+			fmt.Printf("Updated %s (%s)\n", displayPath(filePath), snippetCount(refreshed))
+		}
+	},
+}
+
+// init registers annotateCmd and its flags.
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+
+	annotateCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	annotateCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	annotateCmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false,
+		"Open the file given in the terminal to mark snippets and pick their categories")
+	annotateCmd.Flags().BoolVar(&refreshHashesFlag, "refresh-hashes", false,
+		`Set the "_hash" of snippets whose content changed to the hash of their current content`)
+}
+
+// refreshHashes returns src with the "_hash" of the start tags of snips, which were found in src,
+// replaced by the hash of their content, and how many were. Snippets in a section of their file
+// (e.g. a notebook cell), whose line numbers are relative to it, are skipped with a warning.
+func refreshHashes(src string, snips []snippet) (string, int) {
+	lines := strings.SplitAfter(src, "\n")
+	refreshed := 0
+	for _, s := range snips {
+		where := brio.SectionPath(displayPath(s.File), s.Section)
+		if s.Section != "" {
+			log.Printf("Warning: can't refresh the _hash of %s:%d, in a section of its file; update it to %q by hand",
+				where, s.StartLine, s.Hash())
+			continue
+		}
+		if refreshHash(lines, s) {
+			refreshed++
+		} else {
+			log.Printf("Warning: can't find the _hash of %s:%d in its start tag", where, s.StartLine)
+		}
+	}
+	return strings.Join(lines, ""), refreshed
+}
+
+// refreshHash replaces the recorded "_hash" of s in its start tag by the hash of its content, and
+// reports whether it found it. The tag may span several lines, ending on the start line of s: the
+// nearest "_hash" at or above it is in the tag.
+func refreshHash(lines []string, s snippet) bool {
+	recorded := s.Attr("hash")
+	for i := min(s.StartLine, len(lines)) - 1; i >= 0; i-- {
+		key := strings.Index(lines[i], "_hash")
+		if key < 0 {
+			continue
+		}
+		// An empty hash can't be searched for: replace the first empty string after the key.
+		value := strings.Index(lines[i][key:], `"`+recorded+`"`) + 1
+		if recorded != "" {
+			value = strings.Index(lines[i][key:], recorded)
+		}
+		if value <= 0 {
+			return false
+		}
+		value += key
+		lines[i] = lines[i][:value] + s.Hash() + lines[i][value+len(recorded):]
+		return true
+	}
+	return false
+}
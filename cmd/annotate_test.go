@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshHashes(t *testing.T) {
+	src := `# >: {"tests": [], "_hash": "abc123"}
+def send():
+    pass
+# <: {"tests": []}
+
+# start: {
+#   "tests": [],
+#   "_hash": ""
+# }
+def receive():
+    pass
+# end: {"tests": []}
+
+# >: {"tests": []}
+def untracked():
+    pass
+# <: {"tests": []}
+`
+	path := filepath.Join(t.TempDir(), "app.py")
+	assert.Nil(t, os.WriteFile(path, []byte(src), 0644))
+	snips, _, err := scanFile(path)
+	assert.Nil(t, err)
+	assert.Len(t, snips, 3)
+
+	updated, refreshed := refreshHashes(src, snips[:2])
+	assert.Equal(t, 2, refreshed)
+	assert.Nil(t, os.WriteFile(path, []byte(updated), 0644))
+	snips, _, err = scanFile(path)
+	assert.Nil(t, err)
+	for _, s := range snips[:2] {
+		assert.Equal(t, s.Hash(), s.Attr("hash"))
+	}
+	assert.Contains(t, updated, `"_hash": "`+snips[1].Hash()+`"`)
+	assert.Equal(t, src[len(src)-70:], updated[len(updated)-70:], "the untracked snippet is left alone")
+
+	// Snippets of a notebook cell are skipped.
+	cell := snips[0]
+	cell.Section = "cell 2"
+	_, refreshed = refreshHashes(updated, []snippet{cell})
+	assert.Equal(t, 0, refreshed)
+}
@@ -0,0 +1,493 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/spf13/cobra"
+)
+
+// fencePattern matches the line opening or closing a fenced code block, capturing its indentation,
+// fence and info string.
+var fencePattern = regexp.MustCompile("^(\\s*)(`{3,}|~{3,})(.*)$")
+
+// labelLinePattern matches the line number ending a label, e.g. ":12" or ":12-30".
+var labelLinePattern = regexp.MustCompile(`:(\d+)(?:-\d+)?$`)
+
+// labelHashPattern matches the hash of the content of a snippet ending a label, e.g.
+// "cart-total@3f2a9c81d04e".
+var labelHashPattern = regexp.MustCompile(`^(.*?)\s*@([0-9a-fA-F]{6,64})$`)
+
+// forceFlag makes apply and update overwrite snippets that changed since they were extracted;
+// requireHashFlag makes them refuse to write snippets without a hash to check; mergeFlag names
+// what was extracted, to merge the changes of both sides into.
+var (
+	forceFlag       bool
+	requireHashFlag bool
+	mergeFlag       string
+)
+
+// applyCmd writes edited snippets back into their source files.
+var applyCmd = &cobra.Command{
+	Use:   "apply [response file]",
+	Short: "Write the snippets of a response document back between their tags",
+	Long: `Apply reads a document holding new versions of tagged snippets, such as the answer
+of a model to a prompt made with extract, and writes each one back between the
+start and end tags of its snippet, closing the loop extract → model → apply.
+
+Every fenced code block of the document preceded by a label on its own line
+replaces a snippet: the _id of the snippet, or the path of its file as extract
+prints it, followed by the number of a line of the snippet when the file has
+several (e.g. "src/models.py:40:"). Labels may be wrapped in ` + "`backticks`" + `, **bold**
+or a heading. The tags themselves are kept, and the new lines are indented like the
+lines they replace. Blocks without a label are left alone. What extract added to
+the snippets is taken out: the imports of --with-imports, and, where the file
+indents with tabs, the spaces tabs_to_spaces or --tabs-to-spaces expanded them to
+(pass the same --tabs-to-spaces to apply).
+
+A label may end with the hash of the snippet when it was extracted, as extract
+templates print it with {{ .Hash }} (e.g. "cart-total@3f2a9c81d04e"). Snippets
+that changed since are conflicts, left alone: --merge merges both changes given
+the document extracted, and --force overwrites them. With --require-hash, blocks
+without a hash are refused too. apply exits with status 1 when a block is left out.
+
+The document is read from the file given, or from the standard input.
+Usage example:
+brio extract -c billing --template labeled.tmpl | llm "Add type hints" | brio apply --dir ./src
+brio apply response.md --dir ./src -c billing
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var response []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			response, err = io.ReadAll(os.Stdin)
+		} else {
+			response, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			fatalf("Error reading the response: %v", err)
+		}
+		overrideTabWidth(cmd)
+		blocks := parseResponse(string(response))
+		if len(blocks) == 0 {
+			fatalf("No labeled code block found in the response")
+		}
+		bases := make(map[string][]string)
+		if mergeFlag != "" {
+			extracted, err := os.ReadFile(mergeFlag)
+			if err != nil {
+				fatalf("Error reading %s: %v", mergeFlag, err)
+			}
+			for _, b := range parseResponse(string(extracted)) {
+				bases[b.label+"@"+b.hash] = b.content
+			}
+		}
+
+		snips, scanned := scanTagged(cmd.Context(), brio.ParseCategories(categoriesArg))
+
+		edits := make(map[string][]snippetEdit)
+		skipped := 0
+		for _, b := range blocks {
+			s, err := matchBlock(b, snips)
+			if err != nil {
+				log.Printf("Warning: skipping the block of line %d: %v", b.line, err)
+				skipped++
+				continue
+			}
+			edit := snippetEdit{snippet: s, content: b.content, hash: b.hash, base: bases[b.label+"@"+b.hash]}
+			edits[s.File] = append(edits[s.File], edit)
+		}
+
+		for _, filePath := range sortedKeys(edits) {
+			skipped += len(edits[filePath]) - writeEdits(filePath, edits[filePath], scanned[filePath])
+		}
+		if skipped > 0 {
+			exit(1)
+		}
+	},
+}
+
+// init registers applyCmd and its flags.
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan for the snippets")
+	applyCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	applyCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories of the snippets the blocks may replace, e.g. 'messages:foundation'")
+	addGuardFlags(applyCmd, "The document extracted, labeled like the response, to merge changes made since into")
+	addTabWidthFlag(applyCmd, "Tab width the snippets were extracted with, to indent them with tabs again where the file does")
+}
+
+// addTabWidthFlag registers --tabs-to-spaces on cmd, with usage.
+func addTabWidthFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().IntVar(&tabsToSpacesFlag, "tabs-to-spaces", 0, usage)
+	cmd.Flags().Lookup("tabs-to-spaces").NoOptDefVal = defaultTabWidth
+}
+
+// overrideTabWidth applies --tabs-to-spaces, if set, over the tabs_to_spaces setting of the config file.
+func overrideTabWidth(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("tabs-to-spaces") {
+		return
+	}
+	if tabsToSpacesFlag < 0 {
+		fatalf("--tabs-to-spaces must be 0 or more, got %d", tabsToSpacesFlag)
+	}
+	cfg.TabsToSpaces = tabsToSpacesFlag
+}
+
+// addGuardFlags registers the flags guarding the snippets of cmd from being overwritten.
+func addGuardFlags(cmd *cobra.Command, mergeUsage string) {
+	cmd.Flags().BoolVar(&forceFlag, "force", false, "Overwrite snippets that changed since they were extracted")
+	cmd.Flags().BoolVar(&requireHashFlag, "require-hash", false,
+		"Refuse to overwrite snippets without the hash of their content when they were extracted")
+	cmd.Flags().StringVar(&mergeFlag, "merge", "", mergeUsage)
+}
+
+// scanTagged returns the tagged snippets of the files of --dir matching catMap, along with every
+// snippet of each file. It exits if the files can't be listed or ctx is canceled: a snippet missing
+// from the results could be the one to replace.
+func scanTagged(ctx context.Context, catMap map[string][]string) ([]snippet, map[string][]snippet) {
+	files, err := collectFiles(ctx, dirFlag, filePattern)
+	if err != nil && ctx.Err() == nil {
+		fatalf("Error collecting files: %v", err)
+	}
+	var snips []snippet
+	scanned := make(map[string][]snippet)
+	for _, filePath := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Files++
+		found, _, err := scanFile(filePath)
+		if err != nil {
+			report.addError(err)
+			continue
+		}
+		scanned[filePath] = found
+		for _, s := range found {
+			if s.Matches(catMap) {
+				snips = append(snips, s)
+			}
+		}
+	}
+	checkCanceled(ctx)
+	return snips, scanned
+}
+
+// writeEdits applies edits to filePath, whose snippets are snips (see applyEdits), and returns how
+// many were. It exits if the file can't be read or written.
+func writeEdits(filePath string, edits []snippetEdit, snips []snippet) int {
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		fatalf("Error reading %s: %v", filePath, err)
+	}
+	updated, applied := applyEdits(string(original), edits, snips)
+	if applied == 0 || updated == string(original) {
+		return applied
+	}
+	if err := os.WriteFile(filePath, []byte(updated), 0644); err != nil {
+		fatalf("Error writing %s: %v", filePath, err)
+	}
+	fmt.Printf("Updated %s (%s)\n", displayPath(filePath), snippetCount(applied))
+	return applied
+}
+
+// responseBlock is a labeled code block of a response document.
+type responseBlock struct {
+	label   string
+	hash    string // of the snippet when it was extracted, if the label ends with it
+	line    int    // of the opening fence, from 1
+	content []string
+}
+
+// parseResponse returns the code blocks of doc whose opening fence follows a label, the last line
+// that isn't blank before it, with its decorations (heading marks, bold, backticks, a trailing
+// colon) removed.
+func parseResponse(doc string) []responseBlock {
+	lines := strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n")
+	var blocks []responseBlock
+	label := ""
+	for i := 0; i < len(lines); i++ {
+		m := fencePattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			if strings.TrimSpace(lines[i]) != "" {
+				label = cleanLabel(lines[i])
+			}
+			continue
+		}
+		b := responseBlock{label: label, line: i + 1, content: []string{}}
+		if m := labelHashPattern.FindStringSubmatch(label); m != nil {
+			b.label, b.hash = m[1], m[2]
+		}
+		fence := m[2]
+		for i++; i < len(lines); i++ {
+			if c := fencePattern.FindStringSubmatch(lines[i]); c != nil && c[2][0] == fence[0] &&
+				len(c[2]) >= len(fence) && strings.TrimSpace(c[3]) == "" {
+				break
+			}
+			b.content = append(b.content, strings.TrimPrefix(lines[i], m[1]))
+		}
+		if b.label != "" {
+			blocks = append(blocks, b)
+		}
+		label = ""
+	}
+	return blocks
+}
+
+// cleanLabel returns the text of a label line without its decorations.
+func cleanLabel(line string) string {
+	label := strings.TrimSpace(line)
+	label = strings.TrimSpace(strings.TrimLeft(label, "#"))
+	label = strings.TrimSuffix(label, ":")
+	label = strings.Trim(label, "*`")
+	return strings.TrimSpace(strings.TrimSuffix(label, ":"))
+}
+
+// matchBlock returns the snippet of snips b replaces: the one whose _id is its label, or else the
+// only one in the file of its label, or the innermost one holding the line that ends it.
+func matchBlock(b responseBlock, snips []snippet) (snippet, error) {
+	for _, s := range snips {
+		if id := s.Attr("id"); id != "" && id == b.label {
+			return s, nil
+		}
+	}
+
+	path, line := b.label, 0
+	if m := labelLinePattern.FindStringSubmatchIndex(path); m != nil {
+		line, _ = strconv.Atoi(path[m[2]:m[3]])
+		path = path[:m[0]]
+	}
+	var found []snippet
+	for _, s := range snips {
+		if !samePath(brio.SectionPath(displayPath(s.File), s.Section), path) {
+			continue
+		}
+		if line > 0 && (line < s.StartLine || line > s.EndLine) {
+			continue
+		}
+		found = append(found, s)
+	}
+	if len(found) == 0 {
+		return snippet{}, fmt.Errorf("no snippet matches %q", b.label)
+	}
+	if line == 0 && len(found) > 1 {
+		return snippet{}, fmt.Errorf("%s has %s; add the line of the one to replace to the label, e.g. %q",
+			path, snippetCount(len(found)), fmt.Sprintf("%s:%d", path, found[0].StartLine))
+	}
+	// Nested snippets all hold the line; the innermost starts last.
+	sort.SliceStable(found, func(i, j int) bool { return found[i].StartLine > found[j].StartLine })
+	return found[0], nil
+}
+
+// samePath reports whether a and b name the same file, as written in labels.
+func samePath(a, b string) bool {
+	return a == b || displayPath(a) == displayPath(b)
+}
+
+// snippetEdit is the new content of a snippet.
+type snippetEdit struct {
+	snippet snippet
+	content []string
+	// hash is the hash of the content of the snippet the edit was made from, if known, and base
+	// that content, if known.
+	hash string
+	base []string
+}
+
+// applyEdits returns src with the lines between the start and end tags of each edit replaced by
+// its content, reindented like them, and how many were. snips are all the snippets of src: edits of
+// snippets holding the tags of others, in a section of their file (e.g. a notebook cell), or
+// overlapping another edit are skipped with a warning.
+func applyEdits(src string, edits []snippetEdit, snips []snippet) (string, int) {
+	lines := strings.SplitAfter(src, "\n")
+	newline := "\n"
+	if strings.HasSuffix(lines[0], "\r\n") {
+		newline = "\r\n"
+	}
+
+	// Apply from the bottom up, so that the lines of the edits left stay where they were.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].snippet.StartLine > edits[j].snippet.StartLine })
+	applied := 0
+	above := len(lines) + 1 // start line of the last edit applied
+	for _, e := range edits {
+		s := e.snippet
+		where := fmt.Sprintf("%s:%d", brio.SectionPath(displayPath(s.File), s.Section), s.StartLine)
+		switch {
+		case s.Section != "":
+			log.Printf("Warning: can't apply %s, in a section of its file", where)
+			continue
+		case s.EndLine >= above:
+			log.Printf("Warning: can't apply %s, which overlaps another block", where)
+			continue
+		case s.EndLine > len(lines):
+			log.Printf("Warning: can't apply %s, past the end of the file", where)
+			continue
+		case enclosesTags(s, snips):
+			log.Printf("Warning: can't apply %s, which holds other snippets; apply them instead", where)
+			continue
+		}
+
+		old := lines[s.StartLine : s.EndLine-1]
+		content, err := guardEdit(unprocess(e, old), old)
+		if err != nil {
+			log.Printf("Warning: can't apply %s: %v", where, err)
+			continue
+		}
+		indent := commonIndent(old)
+		if len(old) == 0 {
+			indent = commonIndent(lines[s.StartLine-1 : s.StartLine])
+		}
+		var replacement []string
+		for _, line := range strings.Split(dedent(strings.Join(content, "\n")), "\n") {
+			if line != "" {
+				line = indent + line
+			}
+			replacement = append(replacement, line+newline)
+		}
+		if len(content) == 0 {
+			replacement = nil
+		}
+		lines = append(lines[:s.StartLine], append(replacement, lines[s.EndLine-1:]...)...)
+		above = s.StartLine
+		applied++
+	}
+	return strings.Join(lines, ""), applied
+}
+
+// unprocess returns e without what extract added to the content of its snippet, whose lines in its
+// file are region: the imports --with-imports heads it with, and the spaces tabs were expanded to
+// where the file indents with tabs.
+func unprocess(e snippetEdit, region []string) snippetEdit {
+	strip := func(content []string) []string {
+		if content == nil {
+			return nil
+		}
+		return retab(brio.WithoutImports(e.snippet, content), region, cfg.TabsToSpaces)
+	}
+	e.content, e.base = strip(e.content), strip(e.base)
+	return e
+}
+
+// retab returns content with the spaces indenting its lines turned back into tabs width columns
+// wide, when region indents with tabs and content with spaces only.
+func retab(content, region []string, width int) []string {
+	if width <= 0 {
+		return content
+	}
+	tabbed := false
+	for _, line := range region {
+		if strings.HasPrefix(line, "\t") {
+			tabbed = true
+		}
+	}
+	if !tabbed {
+		return content
+	}
+	for _, line := range content {
+		if indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]; strings.Contains(indent, "\t") {
+			return content
+		}
+	}
+	results := make([]string, len(content))
+	for i, line := range content {
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		results[i] = strings.Repeat("\t", spaces/width) + line[spaces-spaces%width:]
+	}
+	return results
+}
+
+// enclosesTags reports whether outer holds the tags of one of snips: as its lines are replaced,
+// they would be lost.
+func enclosesTags(outer snippet, snips []snippet) bool {
+	for _, s := range snips {
+		if s.Section == "" && s.StartLine > outer.StartLine && s.EndLine < outer.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+// commonIndent returns the leading whitespace common to the lines that aren't blank.
+func commonIndent(lines []string) string {
+	prefix, first := "", true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// guardEdit returns the content e writes over region, the lines of its snippet: the content of e if
+// the snippet didn't change since e was made from it, or with --force, or else both changes merged
+// if the base of e is known. A snippet that changed otherwise is a conflict.
+func guardEdit(e snippetEdit, region []string) ([]string, error) {
+	if e.hash == "" {
+		if requireHashFlag && !forceFlag {
+			return nil, errors.New("no hash tells whether it changed since it was extracted; give it, or pass --force")
+		}
+		return e.content, nil
+	}
+	current := e.snippet.Hash()
+	if strings.EqualFold(current, e.hash) || forceFlag {
+		return e.content, nil
+	}
+	if e.base == nil {
+		return nil, fmt.Errorf("conflict: it changed since it was extracted (hash %s, now %s); "+
+			"pass --merge with what was extracted to merge the changes, or --force to overwrite them", e.hash, current)
+	}
+	if base := (snippet{Content: e.base}).Hash(); !strings.EqualFold(base, e.hash) {
+		return nil, fmt.Errorf("conflict: the content to merge into has hash %s, not %s", base, e.hash)
+	}
+	merged, ok := mergeLines(e.base, region, e.content)
+	if !ok {
+		return nil, fmt.Errorf("conflict: it changed since it was extracted (hash %s, now %s), "+
+			"and the changes overlap; merge them by hand, or pass --force to overwrite them", e.hash, current)
+	}
+	return merged, nil
+}
+
+// mergeLines returns the changes from base to theirs applied to ours, all compared without the
+// indentation common to their lines, and reports whether they all applied exactly.
+func mergeLines(base, ours, theirs []string) ([]string, bool) {
+	dmp := diffmatchpatch.New()
+	// Only apply changes to text that is where and what it was.
+	dmp.MatchThreshold = 0
+	text := func(lines []string) string {
+		trimmed := make([]string, len(lines))
+		for i, line := range lines {
+			trimmed[i] = strings.TrimRight(line, "\r\n")
+		}
+		return dedent(strings.Join(trimmed, "\n")) + "\n"
+	}
+	patches := dmp.PatchMake(text(base), text(theirs))
+	merged, applied := dmp.PatchApply(patches, text(ours))
+	for _, ok := range applied {
+		if !ok {
+			return nil, false
+		}
+	}
+	return strings.Split(strings.TrimSuffix(merged, "\n"), "\n"), true
+}
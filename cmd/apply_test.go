@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponse(t *testing.T) {
+	doc := "Here are the updated snippets.\n\n" +
+		"**`models.py:4`**\n```python\ndef total(self):\n    return sum(self.items)\n```\n\n" +
+		"### billing-client\n\n````\n```nested```\n````\n\n" +
+		"```\nunlabeled\n```\n"
+	blocks := parseResponse(doc)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "models.py:4", blocks[0].label)
+	assert.Equal(t, 4, blocks[0].line)
+	assert.Equal(t, []string{"def total(self):", "    return sum(self.items)"}, blocks[0].content)
+	assert.Equal(t, "billing-client", blocks[1].label)
+	assert.Equal(t, []string{"```nested```"}, blocks[1].content)
+}
+
+func TestApplyEdits(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "models.py")
+	src := "class Cart:\n" +
+		"    # >: {\"billing\": [\"cart\"], \"_id\": \"cart-total\"}\n" +
+		"    def total(self):\n" +
+		"        return 0\n" +
+		"    # <: {\"billing\": []}\n" +
+		"\n" +
+		"# >: {\"billing\": [\"tax\"]}\n" +
+		"RATE = 0.2\n" +
+		"# <: {\"billing\": []}\n"
+	require.NoError(t, os.WriteFile(filePath, []byte(src), 0644))
+	snips, _, err := scanFile(filePath)
+	require.NoError(t, err)
+
+	blocks := parseResponse("cart-total:\n```python\ndef total(self):\n    return sum(self.items)\n```\n" +
+		filePath + ":8:\n```python\nRATE = 0.25\n```\n")
+	var edits []snippetEdit
+	for _, b := range blocks {
+		s, err := matchBlock(b, snips)
+		require.NoError(t, err)
+		edits = append(edits, snippetEdit{snippet: s, content: b.content})
+	}
+	updated, applied := applyEdits(src, edits, snips)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, "class Cart:\n"+
+		"    # >: {\"billing\": [\"cart\"], \"_id\": \"cart-total\"}\n"+
+		"    def total(self):\n"+
+		"        return sum(self.items)\n"+
+		"    # <: {\"billing\": []}\n"+
+		"\n"+
+		"# >: {\"billing\": [\"tax\"]}\n"+
+		"RATE = 0.25\n"+
+		"# <: {\"billing\": []}\n", updated, "tags are kept and the indentation of the lines replaced restored")
+
+	_, err = matchBlock(responseBlock{label: filePath}, snips)
+	assert.ErrorContains(t, err, "has 2 snippets")
+	_, err = matchBlock(responseBlock{label: "missing"}, snips)
+	assert.EqualError(t, err, `no snippet matches "missing"`)
+}
+
+func TestApplyEditsUnprocess(t *testing.T) {
+	defer func(width int) { cfg.TabsToSpaces = width }(cfg.TabsToSpaces)
+	cfg.TabsToSpaces = 4
+	filePath := filepath.Join(t.TempDir(), "cart.py")
+	src := "import os\n\nclass Cart:\n\t# >: {\"billing\": []}\n\tdef total(self):\n\t\treturn 0\n\t# <: {\"billing\": []}\n"
+	require.NoError(t, os.WriteFile(filePath, []byte(src), 0644))
+	snips, _, err := scanFile(filePath)
+	require.NoError(t, err)
+	extracted, err := brio.Chain{brio.WithImports(), brio.NormalizeWhitespace(4, false)}.Apply(snips)
+	require.NoError(t, err)
+	require.Equal(t, []string{"# imports of cart.py", "import os", "# ...", "    def total(self):", "        return 0"}, extracted[0].Content)
+
+	edit := snippetEdit{snippet: snips[0], hash: extracted[0].Hash(),
+		content: []string{"# imports of cart.py", "import os", "# ...", "def total(self):", "    return os.getpid()"}}
+	updated, applied := applyEdits(src, []snippetEdit{edit}, snips)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, "import os\n\nclass Cart:\n\t# >: {\"billing\": []}\n\tdef total(self):\n\t\treturn os.getpid()\n\t# <: {\"billing\": []}\n", updated,
+		"the imports are left out and the file's tabs kept")
+}
+
+func TestApplyEditsSkipsEnclosingSnippets(t *testing.T) {
+	cfg.Regions = true
+	defer func() { cfg = defaultConfig() }()
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "a.py")
+	src := "# region outer\n# region inner\nx = 1\n# endregion\n# endregion\n"
+	require.NoError(t, os.WriteFile(filePath, []byte(src), 0644))
+	snips, _, err := scanFile(filePath)
+	require.NoError(t, err)
+
+	s, err := matchBlock(responseBlock{label: filePath + ":3"}, snips)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.StartLine, "the innermost snippet holding the line")
+
+	var outer snippet
+	for _, s := range snips {
+		if s.StartLine == 1 {
+			outer = s
+		}
+	}
+	updated, applied := applyEdits(src, []snippetEdit{{snippet: outer, content: []string{"y = 2"}}}, snips)
+	assert.Equal(t, 0, applied)
+	assert.Equal(t, src, updated)
+}
+
+func TestGuardEdit(t *testing.T) {
+	defer func() { forceFlag, requireHashFlag = false, false }()
+	base := []string{"def total(items):", "    result = 0", "    for i in items:", "        result += i", "    return result"}
+	s := snippet{Content: base}
+	theirs := []string{"def total(items: list[int]) -> int:", "    result = 0", "    for i in items:", "        result += i", "    return result"}
+
+	content, err := guardEdit(snippetEdit{snippet: s, content: theirs, hash: s.Hash()}, base)
+	assert.NoError(t, err)
+	assert.Equal(t, theirs, content)
+
+	requireHashFlag = true
+	_, err = guardEdit(snippetEdit{snippet: s, content: theirs}, base)
+	assert.ErrorContains(t, err, "no hash")
+	requireHashFlag = false
+
+	// The snippet changed since: its last line, which the edit didn't touch.
+	ours := []string{"    def total(items):\n", "        result = 0\n", "        for i in items:\n", "            result += i\n", "        return round(result)\n"}
+	changed := snippet{Content: ours}
+	_, err = guardEdit(snippetEdit{snippet: changed, content: theirs, hash: s.Hash()}, ours)
+	assert.ErrorContains(t, err, "conflict: it changed since it was extracted")
+
+	content, err = guardEdit(snippetEdit{snippet: changed, content: theirs, hash: s.Hash(), base: base}, ours)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"def total(items: list[int]) -> int:", "    result = 0", "    for i in items:",
+		"        result += i", "    return round(result)"}, content)
+
+	// Both changed the first line.
+	ours[0] = "    def total(values):\n"
+	changed = snippet{Content: ours}
+	_, err = guardEdit(snippetEdit{snippet: changed, content: theirs, hash: s.Hash(), base: base}, ours)
+	assert.ErrorContains(t, err, "the changes overlap")
+
+	forceFlag = true
+	content, err = guardEdit(snippetEdit{snippet: changed, content: theirs, hash: s.Hash()}, ours)
+	assert.NoError(t, err)
+	assert.Equal(t, theirs, content)
+}
+
+func TestParseResponseHash(t *testing.T) {
+	blocks := parseResponse("`cart-total@3F2A9C81D04E`:\n```\npass\n```\n")
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "cart-total", blocks[0].label)
+	assert.Equal(t, "3F2A9C81D04E", blocks[0].hash)
+}
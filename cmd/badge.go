@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// badgeMetric selects what the badge shows: "count" or "coverage".
+// badgeLabel overrides the text on the left side of the badge.
+// badgeOutput is the file the SVG is written to; empty means stdout.
+var (
+	badgeMetric string
+	badgeLabel  string
+	badgeOutput string
+)
+
+// badgeCmd renders a shields.io-style SVG badge for embedding in a README.
+var badgeCmd = &cobra.Command{
+	Use:   "badge",
+	Short: "Generate an SVG badge with the snippet count or annotation coverage",
+	Long: `Badge writes a small SVG badge that repositories can embed in their README.
+
+Metrics:
+  count     number of tagged snippets
+  coverage  percentage of source lines (in supported files) that lie inside a snippet
+
+Usage example:
+brio badge --metric coverage --output badge.svg
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		catMap := brio.ParseCategories(categoriesArg)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		stats := computeStats(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		var label, value, color string
+		switch badgeMetric {
+		case "count":
+			label, value, color = "snippets", fmt.Sprint(stats.Snippets), "#007ec6"
+		case "coverage":
+			coverage := stats.coverage()
+			label, value, color = "brio coverage", fmt.Sprintf("%.0f%%", coverage), coverageColor(coverage)
+		default:
+			fatalf("Unknown badge metric %q, expected count or coverage", badgeMetric)
+		}
+		if badgeLabel != "" {
+			label = badgeLabel
+		}
+
+		svg := renderBadge(label, value, color)
+		if badgeOutput == "" {
+			fmt.Print(svg)
+			return
+		}
+		if err := os.WriteFile(badgeOutput, []byte(svg), 0644); err != nil {
+			fatalf("Error writing badge: %v", err)
+		}
+	},
+}
+
+// init registers badgeCmd and its flags.
+func init() {
+	rootCmd.AddCommand(badgeCmd)
+
+	badgeCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	badgeCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	badgeCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Only count snippets in these categories, e.g. 'messages:foundation,tests'")
+	badgeCmd.Flags().StringVar(&badgeMetric, "metric", "count", "Metric to show: count or coverage")
+	badgeCmd.Flags().StringVar(&badgeLabel, "label", "", "Text on the left side of the badge")
+	badgeCmd.Flags().StringVarP(&badgeOutput, "output", "o", "", "File to write the SVG to (default: stdout)")
+}
+
+// annotationStats summarizes how much of a codebase is annotated.
+type annotationStats struct {
+	Files       int // supported files scanned
+	TaggedFiles int // files with at least one matching snippet
+	Snippets    int // matching snippets
+	Lines       int // lines in the scanned files
+	TaggedLines int // lines inside matching snippets
+}
+
+// computeStats scans files and counts the snippets matching catMap and the lines they cover.
+// It stops early when ctx is canceled.
+func computeStats(ctx context.Context, files []string, catMap map[string][]string) annotationStats {
+	var stats annotationStats
+
+	for _, filePath := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Files++
+		snips, _, err := scanFile(filePath)
+		if err != nil {
+			report.addError(err)
+			continue
+		}
+		content, err := brio.ReadFile(filePath)
+		if err != nil {
+			report.addError(&brio.FileError{File: filePath, Kind: brio.FailureUnreadable, Err: err})
+			continue
+		}
+
+		stats.Files++
+		stats.Lines += countLines(content)
+
+		// Snippets can nest (e.g. regions), so count each covered line once. Line numbers are
+		// relative to the section (e.g. notebook cell) of their snippet.
+		type sectionLine struct {
+			section string
+			line    int
+		}
+		covered := make(map[sectionLine]bool)
+		for _, s := range snips {
+			if !s.Matches(catMap) {
+				continue
+			}
+			stats.Snippets++
+			for line := s.StartLine; line <= s.EndLine; line++ {
+				covered[sectionLine{s.Section, line}] = true
+			}
+		}
+		if len(covered) > 0 {
+			stats.TaggedFiles++
+		}
+		stats.TaggedLines += len(covered)
+	}
+
+	return stats
+}
+
+// coverage returns the percentage of scanned lines covered by snippets.
+func (s annotationStats) coverage() float64 {
+	if s.Lines == 0 {
+		return 0
+	}
+	return 100 * float64(s.TaggedLines) / float64(s.Lines)
+}
+
+// countLines returns the number of lines in content, counting a last line without a newline.
+func countLines(content []byte) int {
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// coverageColor picks the badge color for a coverage percentage.
+func coverageColor(coverage float64) string {
+	switch {
+	case coverage >= 80:
+		return "#4c1"
+	case coverage >= 50:
+		return "#dfb317"
+	default:
+		return "#e05d44"
+	}
+}
+
+// renderBadge renders a flat badge in the style of shields.io. Text widths are estimated from
+// the character count since the font isn't available for measuring.
+func renderBadge(label, value, color string) string {
+	labelWidth := textWidth(label)
+	valueWidth := textWidth(value)
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+  <title>%[4]s: %[5]s</title>
+  <linearGradient id="s" x2="0" y2="100%%">
+    <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+    <stop offset="1" stop-opacity=".1"/>
+  </linearGradient>
+  <clipPath id="r">
+    <rect width="%[1]d" height="20" rx="3" fill="#fff"/>
+  </clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="14">%[4]s</text>
+    <text x="%[8]d" y="14">%[5]s</text>
+  </g>
+</svg>
+`, width, labelWidth, valueWidth, label, value, color, labelWidth/2, labelWidth+valueWidth/2)
+}
+
+// textWidth estimates the rendered width of s in 11px Verdana, plus padding.
+func textWidth(s string) int {
+	return len([]rune(s))*7 + 10
+}
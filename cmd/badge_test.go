@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStats(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `import os
+
+# >: {"foundation": ["messages"]}
+class Message(TenantModel):
+    pass
+# <: {"foundation": ["messages"]}
+
+# >: {"tests": ["messages"]}
+def test_message():
+    assert True
+# <: {"tests": ["messages"]}
+`
+	tagged := filepath.Join(tempDir, "tagged.py")
+	untagged := filepath.Join(tempDir, "untagged.py")
+	assert.Nil(t, os.WriteFile(tagged, []byte(fileContent), 0644))
+	assert.Nil(t, os.WriteFile(untagged, []byte("a = 1\nb = 2\nc = 3\nd = 4\ne = 5"), 0644))
+
+	stats := computeStats(context.Background(), []string{tagged, untagged}, map[string][]string{})
+	assert.Equal(t, annotationStats{Files: 2, TaggedFiles: 1, Snippets: 2, Lines: 16, TaggedLines: 8}, stats)
+	assert.Equal(t, 50.0, stats.coverage())
+
+	stats = computeStats(context.Background(), []string{tagged, untagged}, brio.ParseCategories("tests"))
+	assert.Equal(t, 1, stats.Snippets)
+	assert.Equal(t, 4, stats.TaggedLines)
+}
+
+func TestRenderBadge(t *testing.T) {
+	svg := renderBadge("brio coverage", "85%", coverageColor(85))
+	assert.Contains(t, svg, `aria-label="brio coverage: 85%"`)
+	assert.Contains(t, svg, `fill="#4c1"`)
+	assert.Contains(t, svg, `<text x="50" y="14">brio coverage</text>`)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// baselineFlag names the file of known lint problems; updateBaselineFlag makes lint rewrite it.
+var (
+	baselineFlag       string
+	updateBaselineFlag bool
+)
+
+// baseline lists the problems lint knew about when it was last updated, which it doesn't fail on.
+type baseline struct {
+	Issues []baselineIssue `json:"issues"`
+}
+
+// baselineIssue is a known problem. Line numbers are recorded for readers but not matched, so
+// that editing a file above a problem doesn't make it new.
+type baselineIssue struct {
+	File    string `json:"file"` // relative to --dir, with forward slashes
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// key identifies the problem regardless of its line.
+func (b baselineIssue) key() string {
+	return b.File + "\x00" + b.Code + "\x00" + b.Message
+}
+
+// newBaseline returns the baseline of issues found below dir, sorted for stable diffs.
+func newBaseline(dir string, issues []issue) baseline {
+	b := baseline{Issues: make([]baselineIssue, 0, len(issues))}
+	for _, i := range issues {
+		b.Issues = append(b.Issues, baselineIssue{File: baselinePath(dir, i), Line: i.Line, Code: i.Code, Message: i.Message})
+	}
+	sort.SliceStable(b.Issues, func(i, j int) bool {
+		x, y := b.Issues[i], b.Issues[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		return x.Line < y.Line
+	})
+	return b
+}
+
+// baselinePath returns the path baselines name the file of i by: relative to dir, with forward slashes.
+func baselinePath(dir string, i issue) string {
+	path := i.File
+	if rel, err := filepath.Rel(dir, i.File); err == nil {
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+	if i.Section != "" {
+		path += "#" + i.Section
+	}
+	return path
+}
+
+// readBaseline reads the baseline at path.
+func readBaseline(path string) (baseline, error) {
+	var b baseline
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("invalid baseline %s: %v", path, err)
+	}
+	return b, nil
+}
+
+// write writes the baseline to path.
+func (b baseline) write(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// filter returns the issues found below dir that the baseline doesn't know about, and how many of
+// the problems it knows about are gone. Each known problem excuses a single issue: a second copy
+// of it is new.
+func (b baseline) filter(dir string, issues []issue) (fresh []issue, fixed int) {
+	known := make(map[string]int, len(b.Issues))
+	for _, k := range b.Issues {
+		known[k.key()]++
+	}
+	for _, i := range issues {
+		k := baselineIssue{File: baselinePath(dir, i), Code: i.Code, Message: i.Message}.key()
+		if known[k] > 0 {
+			known[k]--
+			continue
+		}
+		fresh = append(fresh, i)
+	}
+	for _, n := range known {
+		fixed += n
+	}
+	return fresh, fixed
+}
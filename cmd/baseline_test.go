@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+	dir := filepath.Join("src", "app")
+	invalid := issue{File: filepath.Join(dir, "old.py"), Line: 12, Code: brio.CodeInvalidTag, Message: "invalid tag"}
+	unclosed := issue{File: filepath.Join(dir, "lib", "nb.ipynb"), Section: "cell 2", Line: 3, Code: brio.CodeUnmatchedTag, Message: "start tag is never closed"}
+	expired := issue{File: filepath.Join(dir, "old.py"), Line: 4, Code: brio.CodeExpired, Message: "snippet expired on 2024-01-01"}
+
+	b := newBaseline(dir, []issue{invalid, unclosed, expired, invalid})
+	assert.Equal(t, []baselineIssue{
+		{File: "lib/nb.ipynb#cell 2", Line: 3, Code: brio.CodeUnmatchedTag, Message: "start tag is never closed"},
+		{File: "old.py", Line: 4, Code: brio.CodeExpired, Message: "snippet expired on 2024-01-01"},
+		{File: "old.py", Line: 12, Code: brio.CodeInvalidTag, Message: "invalid tag"},
+		{File: "old.py", Line: 12, Code: brio.CodeInvalidTag, Message: "invalid tag"},
+	}, b.Issues)
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	assert.Nil(t, b.write(path))
+	read, err := readBaseline(path)
+	assert.Nil(t, err)
+	assert.Equal(t, b, read)
+
+	// Moving a known problem keeps it known; a third copy of one is new, and fixing one counts.
+	moved := invalid
+	moved.Line = 20
+	added := issue{File: filepath.Join(dir, "new.py"), Line: 1, Code: brio.CodeInvalidTag, Message: "invalid tag"}
+	fresh, fixed := read.filter(dir, []issue{moved, unclosed, invalid, invalid, added})
+	assert.Equal(t, []issue{invalid, added}, fresh)
+	assert.Equal(t, 1, fixed)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// benchRuns is how many times bench runs every phase.
+// benchFormat selects the output of bench: "text" or "json".
+var (
+	benchRuns   int
+	benchFormat string
+)
+
+// Phases of a run timed by bench.
+const (
+	phaseCollect = "collect" // walking the directory for files a plugin handles
+	phaseParse   = "parse"   // scanning the files for snippets matching the categories
+	phaseRender  = "render"  // rendering the snippets as Markdown
+)
+
+// benchCmd times the phases of an extraction.
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Time file collection, parsing and rendering over a directory",
+	Long: `Bench runs an extraction over --dir several times and reports how long each
+phase took: collecting the files, parsing them for snippets, and rendering the
+snippets as Markdown, along with the memory allocated per run. Nothing is printed
+but the timings.
+
+Combine it with --profile-cpu and --profile-mem to find out where the time goes.
+Usage example:
+brio bench --dir . --runs 5 --profile-cpu cpu.pprof
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if benchRuns < 1 {
+			fatalf("--runs must be at least 1, got %d", benchRuns)
+		}
+		opts := cfg.options()
+		opts.Dir, opts.Pattern = dirFlag, filePattern
+		opts.Categories = brio.ParseCategories(categoriesArg)
+
+		result, err := runBench(cmd.Context(), opts, benchRuns)
+		checkCanceled(cmd.Context())
+		if err != nil {
+			fatalf("Error collecting files: %v", err)
+		}
+
+		switch benchFormat {
+		case "text":
+			result.writeText(os.Stdout)
+		case "json":
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				fatalf("Error encoding the results: %v", err)
+			}
+			fmt.Println(string(data))
+		default:
+			fatalf("Unknown format %q, expected text or json", benchFormat)
+		}
+	},
+}
+
+// init registers benchCmd and its flags.
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	benchCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	benchCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to extract, e.g. 'messages:foundation,tests' (default: all)")
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 3, "How many times to run every phase")
+	benchCmd.Flags().StringVar(&benchFormat, "format", "text", "Output format: text or json")
+}
+
+// benchResult holds the timings of every phase over the runs of a benchmark.
+type benchResult struct {
+	Files    int          `json:"files"`
+	Failed   int          `json:"failed"`
+	Snippets int          `json:"snippets"`
+	Runs     int          `json:"runs"`
+	Phases   []benchPhase `json:"phases"`
+}
+
+// benchPhase is the timing of a phase over all runs.
+type benchPhase struct {
+	Name string `json:"name"`
+	// Min, Mean and Max are in milliseconds.
+	Min  float64 `json:"min_ms"`
+	Mean float64 `json:"mean_ms"`
+	Max  float64 `json:"max_ms"`
+	// Alloc is the memory allocated per run, in bytes.
+	Alloc uint64 `json:"alloc_bytes"`
+
+	durations []time.Duration
+	allocated uint64
+}
+
+// runBench runs the phases of an extraction with opts runs times. It stops when ctx is done.
+func runBench(ctx context.Context, opts brio.Options, runs int) (*benchResult, error) {
+	extractor := brio.New(opts)
+	phases := []*benchPhase{{Name: phaseCollect}, {Name: phaseParse}, {Name: phaseRender}}
+	result := &benchResult{Runs: runs}
+
+	// measure runs fn and records how long it took and how much it allocated in phase.
+	measure := func(phase *benchPhase, fn func()) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		fn()
+		phase.durations = append(phase.durations, time.Since(start))
+		runtime.ReadMemStats(&after)
+		phase.allocated += after.TotalAlloc - before.TotalAlloc
+	}
+
+	for run := 0; run < runs && ctx.Err() == nil; run++ {
+		var files []string
+		var err error
+		measure(phases[0], func() { files, err = extractor.Files(ctx) })
+		if err != nil {
+			return nil, err
+		}
+
+		var snips []snippet
+		measure(phases[1], func() {
+			var scanErr error
+			snips, _, scanErr = extractor.ExtractFiles(ctx, files)
+			result.Failed = failedFiles(scanErr)
+		})
+		measure(phases[2], func() { _ = brio.RenderMarkdown(snips) })
+
+		result.Files, result.Snippets = len(files), len(snips)
+	}
+
+	for _, phase := range phases {
+		phase.summarize()
+		result.Phases = append(result.Phases, *phase)
+	}
+	return result, nil
+}
+
+// failedFiles returns how many files err reports as not scanned.
+func failedFiles(err error) int {
+	if err == nil {
+		return 0
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return len(joined.Unwrap())
+	}
+	return 1
+}
+
+// summarize computes the statistics of the recorded runs of p.
+func (p *benchPhase) summarize() {
+	if len(p.durations) == 0 {
+		return
+	}
+	fastest, slowest, total := p.durations[0], p.durations[0], time.Duration(0)
+	for _, d := range p.durations {
+		fastest, slowest = min(fastest, d), max(slowest, d)
+		total += d
+	}
+	p.Min = milliseconds(fastest)
+	p.Max = milliseconds(slowest)
+	p.Mean = milliseconds(total / time.Duration(len(p.durations)))
+	p.Alloc = p.allocated / uint64(len(p.durations))
+}
+
+// milliseconds returns d in milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeText prints the results as a table.
+func (r *benchResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%d files (%d failed), %d snippets, %d runs\n\n", r.Files, r.Failed, r.Snippets, r.Runs)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "phase\tmin\tmean\tmax\talloc/run")
+	for _, p := range r.Phases {
+		fmt.Fprintf(tw, "%s\t%.1fms\t%.1fms\t%.1fms\t%.1fMB\n", p.Name, p.Min, p.Mean, p.Max, float64(p.Alloc)/(1<<20))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBench(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "# >: {\"tests\": [\"bench\"]}\npass\n# <: {\"tests\": [\"bench\"]}\n"
+	for _, name := range []string{"a.py", "b.py"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	result, err := runBench(context.Background(), brio.Options{Dir: tempDir}, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Files)
+	assert.Equal(t, 2, result.Snippets)
+	assert.Equal(t, 0, result.Failed)
+	assert.Len(t, result.Phases, 3)
+	for _, phase := range result.Phases {
+		assert.Len(t, phase.durations, 3)
+		assert.LessOrEqual(t, phase.Min, phase.Mean)
+		assert.LessOrEqual(t, phase.Mean, phase.Max)
+	}
+
+	var out bytes.Buffer
+	result.writeText(&out)
+	assert.Contains(t, out.String(), "2 files (0 failed), 2 snippets, 3 runs")
+	assert.Contains(t, out.String(), "collect")
+
+	_, err = runBench(context.Background(), brio.Options{Dir: filepath.Join(tempDir, "missing")}, 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// maxTokensPerCategoryFlag caps the estimated tokens of the snippets of each category given, over the
+// max_tokens_per_category setting of the config file.
+var maxTokensPerCategoryFlag map[string]int
+
+// categoryLimits returns the token caps of the categories: those of the config file, overridden
+// category by category by flags.
+func categoryLimits(configured, flags map[string]int) (map[string]int, error) {
+	limits := make(map[string]int, len(configured)+len(flags))
+	for category, limit := range configured {
+		limits[category] = limit
+	}
+	for category, limit := range flags {
+		if limit <= 0 {
+			return nil, fmt.Errorf("%s: the limit must be more than 0, got %d", category, limit)
+		}
+		limits[category] = limit
+	}
+	return limits, nil
+}
+
+// limitCategories trims snips to the token limits of their categories, each on its own, so that one
+// large category can't crowd out the others. Snippets are taken by "_priority", highest first, then
+// in order; one is kept if it fits the limit of every category it is tagged with, and smaller ones
+// may still fit after one that doesn't. The tokens of a snippet are those of tokens, measured in the
+// format the snippets are output in. The snippets kept stay in order; it also returns how many were
+// omitted.
+func limitCategories(snips []snippet, limits map[string]int, tokens func(snippet) int) ([]snippet, int) {
+	if len(limits) == 0 {
+		return snips, 0
+	}
+	order := make([]int, len(snips))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return snips[order[a]].Priority() > snips[order[b]].Priority()
+	})
+
+	used := make(map[string]int)
+	keep := make([]bool, len(snips))
+	omitted := 0
+	for _, i := range order {
+		s := snips[i]
+		size := tokens(s)
+		fits := true
+		for category := range s.Categories {
+			if limit, ok := limits[category]; ok && used[category]+size > limit {
+				fits = false
+			}
+		}
+		if !fits {
+			omitted++
+			continue
+		}
+		for category := range s.Categories {
+			used[category] += size
+		}
+		keep[i] = true
+	}
+
+	kept := make([]snippet, 0, len(snips)-omitted)
+	for i, s := range snips {
+		if keep[i] {
+			kept = append(kept, s)
+		}
+	}
+	return kept, omitted
+}
+
+// markdownTokens estimates the tokens of s rendered as brio.RenderMarkdown does.
+func markdownTokens(s snippet) int {
+	return estimateTokens(brio.RenderMarkdown([]snippet{s}))
+}
+
+// rpcTokens estimates the tokens of s in the JSON of rpc and run.
+func rpcTokens(s snippet) int {
+	data, err := json.Marshal(newRPCSnippet(s))
+	if err != nil {
+		return markdownTokens(s)
+	}
+	return estimateTokens(string(data))
+}
+
+// extractTokens estimates the tokens of s as extract outputs it: rendered with --template, or else
+// in the largest of the formats of --format, the Markdown in the preset of --style or with
+// --anchors.
+func extractTokens(s snippet) int {
+	one := []snippet{s}
+	var out strings.Builder
+	if outputTemplate != nil {
+		if err := writeTemplate(&out, outputTemplate, one); err == nil {
+			return estimateTokens(out.String())
+		}
+	}
+	tokens := 0
+	for _, format := range outputFormats {
+		out.Reset()
+		if format == formatJSON {
+			_ = writeJSONSnippets(&out, one)
+		} else {
+			out.WriteString(plainMarkdown(one))
+		}
+		tokens = max(tokens, estimateTokens(out.String()))
+	}
+	return tokens
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitCategories(t *testing.T) {
+	lines := func(n int) []string { return strings.Split(strings.Repeat("x = 1\n", n-1)+"x = 1", "\n") }
+	snips := []snippet{
+		{File: "a.py", StartLine: 1, Categories: map[string][]string{"foundation": {}}, Content: lines(40)},
+		{File: "b.py", StartLine: 1, Categories: map[string][]string{"foundation": {}}, Content: lines(40),
+			Attrs: map[string][]string{"priority": {"2"}}},
+		{File: "c.py", StartLine: 1, Categories: map[string][]string{"foundation": {}}, Content: lines(2)},
+		{File: "d.py", StartLine: 1, Categories: map[string][]string{"tests": {}}, Content: lines(40)},
+		{File: "e.py", StartLine: 1, Categories: map[string][]string{"foundation": {}, "tests": {}}, Content: lines(2)},
+	}
+	limit := markdownTokens(snips[1]) + markdownTokens(snips[2])
+
+	kept, omitted := limitCategories(snips, map[string]int{"foundation": limit}, markdownTokens)
+	// b.py goes first for its priority, a.py no longer fits but c.py, smaller, does; tests have no
+	// limit.
+	var files []string
+	for _, s := range kept {
+		files = append(files, s.File)
+	}
+	assert.Equal(t, []string{"b.py", "c.py", "d.py"}, files)
+	assert.Equal(t, 2, omitted)
+
+	kept, omitted = limitCategories(snips, nil, markdownTokens)
+	assert.Len(t, kept, len(snips))
+	assert.Zero(t, omitted)
+}
+
+func TestCategoryLimits(t *testing.T) {
+	limits, err := categoryLimits(map[string]int{"foundation": 4000, "tests": 1000}, map[string]int{"tests": 2000})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"foundation": 4000, "tests": 2000}, limits)
+
+	_, err = categoryLimits(nil, map[string]int{"tests": 0})
+	assert.ErrorContains(t, err, "tests: the limit must be more than 0")
+}
+
+func TestExtractTokens(t *testing.T) {
+	defer func() { styleFlag, outputFormats = "", []string{formatMarkdown} }()
+	s := snippet{File: "a.py", StartLine: 1, EndLine: 3, Categories: map[string][]string{"foundation": {"billing"}},
+		Content: []string{"x = 1"}}
+	assert.Equal(t, markdownTokens(s), extractTokens(s))
+
+	styleFlag = styleDetailed
+	assert.Greater(t, extractTokens(s), markdownTokens(s), "the metadata of --style counts")
+	styleFlag = ""
+
+	outputFormats = []string{formatMarkdown, formatJSON}
+	var doc strings.Builder
+	require.NoError(t, writeJSONSnippets(&doc, []snippet{s}))
+	assert.Equal(t, estimateTokens(doc.String()), extractTokens(s), "the largest format counts")
+}
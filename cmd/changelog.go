@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// changelogFormat selects the output of changelog: "markdown" or "json".
+var changelogFormat string
+
+// changelogCmd lists the changes of each snippet from the git history of its lines.
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "List when each snippet last changed and by whom, from git history",
+	Long: `Changelog blames the lines of each snippet matching --categories and lists the
+commits that last changed them, newest first, with their date, author and summary,
+along with the "_version" of the snippet if it declares one. Lines not committed
+yet are counted apart.
+
+Snippets in a section of their file, such as a notebook cell, get the changes of
+their whole file. --dir must be in a git repository.
+Usage example:
+brio changelog --categories security --dir ./src --format json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		catMap := brio.ParseCategories(categoriesArg)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		history, err := brio.OpenHistory(dirFlag, "", now())
+		if err != nil {
+			fatalf("Error reading the git history: %v", err)
+		}
+		entries := buildChangelog(snips, history)
+
+		switch changelogFormat {
+		case "markdown":
+			fmt.Print(renderChangelog(entries))
+		case "json":
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				fatalf("Error encoding the changelog: %v", err)
+			}
+			fmt.Println(string(data))
+		default:
+			fatalf("Unknown changelog format %q, expected markdown or json", changelogFormat)
+		}
+	},
+}
+
+// init registers changelogCmd and its flags.
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	changelogCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	changelogCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to include, e.g. 'messages:foundation,tests' (default: all)")
+	changelogCmd.Flags().StringVar(&changelogFormat, "format", "markdown", "Output format: markdown or json")
+}
+
+// changelogEntry is the history of a snippet.
+type changelogEntry struct {
+	File       string              `json:"file"`
+	Section    string              `json:"section,omitempty"`
+	StartLine  int                 `json:"start_line"`
+	EndLine    int                 `json:"end_line"`
+	Categories map[string][]string `json:"categories"`
+	Version    string              `json:"version,omitempty"`
+	// Uncommitted counts the lines changed since the last commit.
+	Uncommitted int `json:"uncommitted_lines"`
+	// Changes are the commits that last changed the other lines, newest first.
+	Changes []snippetChange `json:"changes"`
+}
+
+// snippetChange is a commit that last changed lines of a snippet.
+type snippetChange struct {
+	brio.Origin
+	Lines int `json:"lines"`
+}
+
+// buildChangelog returns the history of each of snips. Snippets whose history can't be read are
+// left out with a warning.
+func buildChangelog(snips []snippet, history *brio.History) []changelogEntry {
+	entries := []changelogEntry{}
+	for _, s := range snips {
+		origins, err := history.Origins(s)
+		if err != nil {
+			log.Printf("Warning: leaving out %s:%d, whose history can't be read: %v",
+				brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, err)
+			continue
+		}
+
+		entry := changelogEntry{
+			File:       displayPath(s.File),
+			Section:    s.Section,
+			StartLine:  s.StartLine,
+			EndLine:    s.EndLine,
+			Categories: s.Categories,
+			Version:    s.Attr("version"),
+			Changes:    []snippetChange{},
+		}
+		lines := make(map[string]int)
+		for _, o := range origins {
+			if o == nil {
+				entry.Uncommitted++
+				continue
+			}
+			if lines[o.Hash] == 0 {
+				entry.Changes = append(entry.Changes, snippetChange{Origin: *o})
+			}
+			lines[o.Hash]++
+		}
+		for i := range entry.Changes {
+			entry.Changes[i].Lines = lines[entry.Changes[i].Hash]
+		}
+		sort.SliceStable(entry.Changes, func(i, j int) bool {
+			return entry.Changes[i].Date.After(entry.Changes[j].Date)
+		})
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// renderChangelog renders entries as Markdown: a section per snippet listing its changes.
+func renderChangelog(entries []changelogEntry) string {
+	var out strings.Builder
+	out.WriteString("# Changelog\n")
+	for _, e := range entries {
+		fmt.Fprintf(&out, "\n## %s:%d-%d\n\n", brio.SectionPath(e.File, e.Section), e.StartLine, e.EndLine)
+
+		if len(e.Categories) > 0 {
+			fmt.Fprintf(&out, "Categories: %s  \n", joinCategories(e.Categories))
+		}
+		if e.Version != "" {
+			fmt.Fprintf(&out, "Version: %s  \n", e.Version)
+		}
+		switch {
+		case e.Uncommitted > 0:
+			out.WriteString("Last changed: not committed yet  \n")
+		case len(e.Changes) > 0:
+			last := e.Changes[0]
+			fmt.Fprintf(&out, "Last changed: %s by %s  \n", last.Date.Format(time.DateOnly), last.Author)
+		}
+		out.WriteString("\n")
+
+		if e.Uncommitted > 0 {
+			fmt.Fprintf(&out, "- not committed yet (%s)\n", lineCount(e.Uncommitted))
+		}
+		for _, c := range e.Changes {
+			fmt.Fprintf(&out, "- %s `%.7s` %s: %s (%s)\n",
+				c.Date.Format(time.DateOnly), c.Hash, c.Author, c.Summary, lineCount(c.Lines))
+		}
+	}
+	return out.String()
+}
+
+// lineCount returns "1 line" or "n lines".
+func lineCount(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
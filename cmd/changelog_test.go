@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderChangelog(t *testing.T) {
+	date := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	entries := []changelogEntry{
+		{
+			File: "auth/login.py", StartLine: 12, EndLine: 40,
+			Categories: map[string][]string{"security": {"auth"}, "tests": {}},
+			Version:    "1.2",
+			Changes: []snippetChange{
+				{Origin: brio.Origin{Hash: "a1b2c3d4e5f6", Author: "Jane Doe <jane@example.com>", Date: date, Summary: "Check token expiry"}, Lines: 5},
+				{Origin: brio.Origin{Hash: "0f9e8d7c6b5a", Author: "Sam Roe <sam@example.com>", Date: date.AddDate(-1, 0, 0), Summary: "Add login"}, Lines: 1},
+			},
+		},
+		{File: "auth/token.py", StartLine: 3, EndLine: 9, Uncommitted: 2},
+	}
+	assert.Equal(t, "# Changelog\n"+
+		"\n## auth/login.py:12-40\n\n"+
+		"Categories: security: auth; tests  \n"+
+		"Version: 1.2  \n"+
+		"Last changed: 2026-03-04 by Jane Doe <jane@example.com>  \n\n"+
+		"- 2026-03-04 `a1b2c3d` Jane Doe <jane@example.com>: Check token expiry (5 lines)\n"+
+		"- 2025-03-04 `0f9e8d7` Sam Roe <sam@example.com>: Add login (1 line)\n"+
+		"\n## auth/token.py:3-9\n\n"+
+		"Last changed: not committed yet  \n\n"+
+		"- not committed yet (2 lines)\n", renderChangelog(entries))
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// checkRefsCmd checks that the references to snippets, from other snippets and from documents,
+// resolve.
+var checkRefsCmd = &cobra.Command{
+	Use:   "check-refs [markdown files...]",
+	Short: "Check that snippet ids are unique and references to snippets resolve",
+	Long: `Check-refs reports the references to snippets that are broken:
+
+- ids declared by two snippets with "_id" (BRIO013)
+- "_depends_on" entries naming an id no snippet declares (BRIO011)
+- blocks of the Markdown files given that brio inject would fill with nothing,
+  as no snippet matches their categories, or that are never closed (BRIO015,
+  BRIO001)
+
+Each problem is printed as file:line: message. The command exits with status 1
+if any problem was found.
+Usage example:
+brio check-refs README.md docs/*.md --dir ./src
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, map[string][]string{})
+		// References to the snippets of the files left out would look broken.
+		checkCanceled(cmd.Context())
+
+		issues := snippetRefIssues(snips)
+		for _, docPath := range args {
+			doc, err := os.ReadFile(docPath)
+			if err != nil {
+				fatalf("Error reading %s: %v", docPath, err)
+			}
+			issues = append(issues, docRefIssues(docPath, string(doc), snips)...)
+		}
+
+		for _, i := range issues {
+			fmt.Println(i)
+		}
+		if len(issues) > 0 {
+			printReport()
+			exit(1)
+		}
+	},
+}
+
+// init registers checkRefsCmd and its flags.
+func init() {
+	rootCmd.AddCommand(checkRefsCmd)
+
+	checkRefsCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan for snippets")
+	checkRefsCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+}
+
+// snippetRefIssues returns the ids of snips declared twice and their "_depends_on" entries naming
+// ids none declares. Dependency cycles, which deps reports, resolve and are left out.
+func snippetRefIssues(snips []snippet) []issue {
+	var issues []issue
+	for _, i := range buildDepGraph(snips).issues {
+		if i.Code == brio.CodeDuplicateID || i.Code == brio.CodeDanglingDependency {
+			issues = append(issues, i)
+		}
+	}
+	return issues
+}
+
+// docRefIssues returns the inject blocks of doc, read from docPath, that no snippet of snips
+// matches or that aren't closed.
+func docRefIssues(docPath, doc string, snips []snippet) []issue {
+	var issues []issue
+	lines := strings.Split(doc, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := injectStartPattern.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if m == nil {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !injectEndPattern.MatchString(strings.TrimRight(lines[end], "\r")) {
+			end++
+		}
+		if end == len(lines) {
+			issues = append(issues, issue{File: docPath, Line: i + 1, Code: brio.CodeUnmatchedTag,
+				Message: fmt.Sprintf("<!-- brio:%s --> has no closing <!-- /brio -->", m[1])})
+			break
+		}
+
+		catMap := brio.ParseCategories(m[1])
+		matched := false
+		for _, s := range snips {
+			if s.Matches(catMap) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			issues = append(issues, issue{File: docPath, Line: i + 1, Code: brio.CodeDanglingInjection,
+				Message: fmt.Sprintf("no snippet matches <!-- brio:%s -->", m[1])})
+		}
+		i = end
+	}
+	return issues
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnippetRefIssues(t *testing.T) {
+	snips := []snippet{
+		{File: "a.py", StartLine: 1, Attrs: map[string][]string{"id": {"auth"}, "depends_on": {"db", "cache"}}},
+		{File: "b.py", StartLine: 4, Attrs: map[string][]string{"id": {"db"}, "depends_on": {"auth"}}},
+		{File: "c.py", StartLine: 9, Attrs: map[string][]string{"id": {"db"}}},
+	}
+	issues := snippetRefIssues(snips)
+	assert.Equal(t, []issue{
+		{File: "c.py", Line: 9, Code: brio.CodeDuplicateID, Message: `snippet id "db" is already declared at b.py:4`},
+		{File: "a.py", Line: 1, Code: brio.CodeDanglingDependency, Message: `_depends_on names unknown snippet id "cache"`},
+	}, issues, "the auth <-> db cycle resolves")
+}
+
+func TestDocRefIssues(t *testing.T) {
+	snips := []snippet{{File: "a.py", Categories: map[string][]string{"foundation": {"messages"}}}}
+	doc := "# Guide\n" +
+		"<!-- brio:messages:foundation -->\n" +
+		"old\n" +
+		"<!-- /brio -->\n" +
+		"<!-- brio:billing:foundation -->\n" +
+		"<!-- /brio -->\n" +
+		"<!-- brio:messages -->\n"
+	assert.Equal(t, []issue{
+		{File: "guide.md", Line: 5, Code: brio.CodeDanglingInjection, Message: "no snippet matches <!-- brio:billing:foundation -->"},
+		{File: "guide.md", Line: 7, Code: brio.CodeUnmatchedTag, Message: "<!-- brio:messages --> has no closing <!-- /brio -->"},
+	}, docRefIssues("guide.md", doc, snips))
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboardFlag makes extract copy the snippets to the clipboard instead of printing them.
+var clipboardFlag bool
+
+// clipboardCommand is a command that copies its standard input to the clipboard.
+type clipboardCommand struct {
+	name string
+	args []string
+	// env, when set, is an environment variable the command needs, e.g. the Wayland display.
+	env string
+}
+
+// clipboardCommands are the commands copyToClipboard tries, in order: the first one installed is used.
+var clipboardCommands = []clipboardCommand{
+	{name: "pbcopy"},
+	{name: "wl-copy", env: "WAYLAND_DISPLAY"},
+	{name: "xclip", args: []string{"-selection", "clipboard"}},
+	{name: "xsel", args: []string{"--clipboard", "--input"}},
+	{name: "clip.exe"},
+}
+
+// errNoClipboard is returned by copyToClipboard when none of clipboardCommands is available.
+var errNoClipboard = errors.New("no clipboard tool found, install one of pbcopy, wl-copy, xclip or xsel")
+
+// copyToClipboard replaces the content of the system clipboard with text.
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands {
+		if c.env != "" && os.Getenv(c.env) == "" {
+			continue
+		}
+		path, err := exec.LookPath(c.name)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, c.args...)
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+	return errNoClipboard
+}
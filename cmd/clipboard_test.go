@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	saved := clipboardCommands
+	defer func() { clipboardCommands = saved }()
+
+	// A command that saves what it's given stands for the clipboard, after ones that are skipped.
+	clipboard := filepath.Join(t.TempDir(), "clipboard")
+	t.Setenv("BRIO_TEST_DISPLAY", "")
+	clipboardCommands = []clipboardCommand{
+		{name: "brio-no-such-clipboard"},
+		{name: "sh", args: []string{"-c", "exit 1"}, env: "BRIO_TEST_DISPLAY"},
+		{name: "sh", args: []string{"-c", "cat > " + clipboard}},
+	}
+	assert.Nil(t, copyToClipboard("# Snippets\n"))
+	content, err := os.ReadFile(clipboard)
+	assert.Nil(t, err)
+	assert.Equal(t, "# Snippets\n", string(content))
+
+	clipboardCommands = clipboardCommands[:2]
+	assert.Equal(t, errNoClipboard, copyToClipboard("# Snippets\n"))
+}
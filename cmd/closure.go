@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// closureDepthFlag makes extract follow the functions and types snippets use to their definitions,
+// this many levels deep; closureTokensFlag bounds the tokens these definitions add.
+var (
+	closureDepthFlag  int
+	closureTokensFlag int
+)
+
+// definitionsOf returns the definitions snips use, found among the files of --dir as set by
+// --closure-depth, for as many as fit in --closure-tokens. Each definition starts with a comment
+// naming it. It fails if the files of --dir can't be listed or read.
+func definitionsOf(ctx context.Context, snips []snippet) ([]snippet, error) {
+	// Definitions are looked up in every file, not only those matching --files.
+	files, err := collectFiles(ctx, dirFlag, "*")
+	if err != nil {
+		return nil, fmt.Errorf("collecting files: %w", err)
+	}
+	defs, err := brio.New(cfg.options()).Definitions(ctx, files)
+	if err != nil {
+		return nil, fmt.Errorf("finding definitions: %w", err)
+	}
+
+	var results []snippet
+	tokens := 0
+	closure := defs.Closure(snips, closureDepthFlag)
+	for i, def := range closure {
+		name := def.Attr("definition")
+		comment := brio.LineComment(def.Plugin.GetCommentStyle(), fmt.Sprintf("definition of %s, used above", name))
+		def.Content = append([]string{comment}, def.Content...)
+		size := estimateTokens(brio.RenderMarkdown([]snippet{def}))
+		if closureTokensFlag > 0 && tokens+size > closureTokensFlag {
+			log.Printf("Warning: leaving out %d of %d definitions, over --closure-tokens %d",
+				len(closure)-i, len(closure), closureTokensFlag)
+			break
+		}
+		tokens += size
+		results = append(results, def)
+	}
+	return results, nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionsOf(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `# >: {"foundation": ["billing"]}
+def total(x):
+    return double(x)
+# <: {"foundation": ["billing"]}
+
+def double(x):
+    return twice(x)
+
+def twice(x):
+    return x * 2
+`
+	filePath := filepath.Join(tempDir, "billing.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	savedDir := dirFlag
+	dirFlag, closureDepthFlag = tempDir, 1
+	defer func() { dirFlag, closureDepthFlag, closureTokensFlag = savedDir, 0, 0 }()
+
+	snips := extractSnippets(context.Background(), []string{filePath}, nil)
+	require.Len(t, snips, 1)
+	defs, err := definitionsOf(context.Background(), snips)
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	assert.Equal(t, []string{"# definition of double, used above", "def double(x):", "    return twice(x)"}, defs[0].Content)
+	assert.Equal(t, 6, defs[0].StartLine)
+
+	closureDepthFlag = 2
+	defs, err = definitionsOf(context.Background(), snips)
+	require.NoError(t, err)
+	assert.Len(t, defs, 2)
+	closureTokensFlag = 40
+	defs, err = definitionsOf(context.Background(), snips)
+	require.NoError(t, err)
+	assert.Len(t, defs, 1, "the nearest definition fits")
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// codeOwnersLocations lists where GitHub looks for a CODEOWNERS file, relative to the repository root.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwners maps repository paths to their owners according to a CODEOWNERS file.
+type codeOwners struct {
+	root  string
+	rules []ownerRule
+}
+
+// ownerRule is one CODEOWNERS line: a path pattern and the owners it assigns.
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// loadCodeOwners finds the CODEOWNERS file of the repository containing dir, searching dir and its parents
+// up to the repository root. It returns nil if there is none.
+func loadCodeOwners(dir string) (*codeOwners, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for _, location := range codeOwnersLocations {
+			f, err := os.Open(filepath.Join(current, location))
+			if err != nil {
+				continue
+			}
+			owners, err := parseCodeOwners(current, f)
+			_ = f.Close()
+			return owners, err
+		}
+
+		// Don't look past the repository root.
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return nil, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, nil
+		}
+		current = parent
+	}
+}
+
+// loadRootCodeOwners returns the CODEOWNERS file at the root of a repository that isn't checked out
+// on disk, such as one cloned by --repo. It returns nil if there is none.
+func loadRootCodeOwners(root string) (*codeOwners, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range codeOwnersLocations {
+		content, err := brio.ReadFile(filepath.Join(root, location))
+		if err != nil {
+			continue
+		}
+		return parseCodeOwners(abs, bytes.NewReader(content))
+	}
+	return nil, nil
+}
+
+// parseCodeOwners reads CODEOWNERS rules for the repository rooted at root.
+func parseCodeOwners(root string, r io.Reader) (*codeOwners, error) {
+	c := &codeOwners{root: root}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		c.rules = append(c.rules, ownerRule{
+			pattern: codeOwnersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+
+	return c, scanner.Err()
+}
+
+// codeOwnersPattern converts a gitignore-style CODEOWNERS pattern into a regular expression
+// matching slash-separated paths relative to the repository root.
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	// Patterns with a leading or inner slash are anchored at the root; others match at any depth.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	// A pattern naming a directory owns everything below it, but "docs/*" only covers direct children.
+	if !strings.HasSuffix(pattern, "/*") {
+		expr.WriteString("(?:/.*)?")
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
+
+// ownersOf returns the owners of path; the last matching rule wins, as on GitHub.
+func (c *codeOwners) ownersOf(path string) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(c.root, abs)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].pattern.MatchString(rel) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// assignOwners fills the "owner" attribute of snippets without an explicit "_owner" from CODEOWNERS.
+func assignOwners(snips []snippet, owners *codeOwners) {
+	if owners == nil {
+		return
+	}
+	for i := range snips {
+		if len(snips[i].Attrs["owner"]) > 0 {
+			continue
+		}
+		if fileOwners := owners.ownersOf(snips[i].File); len(fileOwners) > 0 {
+			if snips[i].Attrs == nil {
+				snips[i].Attrs = make(map[string][]string)
+			}
+			snips[i].Attrs["owner"] = fileOwners
+		}
+	}
+}
+
+// filterByOwner keeps the snippets owned by one of the comma-separated owners in ownerArg.
+func filterByOwner(snips []snippet, ownerArg string) []snippet {
+	var wanted []string
+	for _, owner := range strings.Split(ownerArg, ",") {
+		if owner = strings.TrimSpace(owner); owner != "" {
+			wanted = append(wanted, owner)
+		}
+	}
+	if len(wanted) == 0 {
+		return snips
+	}
+
+	var results []snippet
+	for _, s := range snips {
+		if ownedBy(s.Attrs["owner"], wanted) {
+			results = append(results, s)
+		}
+	}
+	return results
+}
+
+// ownedBy reports whether any of owners is one of wanted. The leading "@" is optional, and a team
+// may be given without its organization, so "team-payments" matches "@acme/team-payments".
+func ownedBy(owners, wanted []string) bool {
+	for _, owner := range owners {
+		owner = strings.TrimPrefix(owner, "@")
+		for _, w := range wanted {
+			w = strings.TrimPrefix(w, "@")
+			if owner == w || strings.HasSuffix(owner, "/"+w) {
+				return true
+			}
+		}
+	}
+	return false
+}
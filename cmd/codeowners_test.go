@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOwnersOf(t *testing.T) {
+	root := t.TempDir()
+	owners, err := parseCodeOwners(root, strings.NewReader(`# Default owners
+*                  @acme/platform
+*.ts               @acme/frontend
+/services/billing/ @acme/team-payments alice@example.com
+docs/*             @acme/docs
+`))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"main.py", []string{"@acme/platform"}},
+		{"web/app.ts", []string{"@acme/frontend"}},
+		{"services/billing/invoice.py", []string{"@acme/team-payments", "alice@example.com"}},
+		{"services/billing/api/client.ts", []string{"@acme/team-payments", "alice@example.com"}},
+		{"other/services/billing/x.py", []string{"@acme/platform"}},
+		{"docs/intro.py", []string{"@acme/docs"}},
+		{"docs/api/intro.py", []string{"@acme/platform"}},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, owners.ownersOf(filepath.Join(root, tc.path)), "Path: %s", tc.path)
+	}
+}
+
+func TestFilterByOwner(t *testing.T) {
+	tempDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(tempDir, ".github"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, ".github", "CODEOWNERS"),
+		[]byte("*.py @acme/team-payments\n"), 0644))
+
+	fileContent := `# >: {"foundation": ["billing"]}
+class Invoice:
+    pass
+# <: {"foundation": ["billing"]}
+
+# >: {"foundation": ["messages"], "_owner": "@acme/team-messaging"}
+class Message:
+    pass
+# <: {"foundation": ["messages"]}`
+
+	filePath := filepath.Join(tempDir, "models.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	owners, err := loadCodeOwners(tempDir)
+	assert.Nil(t, err)
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assignOwners(snips, owners)
+	assert.Equal(t, []string{"@acme/team-payments"}, snips[0].Attrs["owner"])
+	assert.Equal(t, []string{"@acme/team-messaging"}, snips[1].Attrs["owner"])
+
+	filtered := filterByOwner(snips, "team-payments")
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, 1, filtered[0].StartLine)
+
+	assert.Len(t, filterByOwner(snips, "@acme/team-messaging,team-payments"), 2)
+	assert.Len(t, filterByOwner(snips, "team-search"), 0)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// Values of --color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorFlag selects when extract colorizes its output: auto, always or never.
+// themeFlag overrides the theme setting of the config file.
+// colorOutput is whether the snippets printed by the running command are colorized, and in which
+// theme; nil means plain Markdown.
+var (
+	colorFlag   string
+	themeFlag   string
+	colorOutput *theme
+)
+
+// theme holds the SGR parameters (e.g. "1;36" for bold cyan) of each part of a colorized snippet.
+type theme struct {
+	path     string // the path of the snippet's file
+	lines    string // its line numbers
+	category string // the names of its categories
+	domain   string // their domains
+	fence    string // the ``` lines around its content
+	// links makes headings links to the sources of the snippets, for files on disk.
+	links bool
+}
+
+// themes are the built-in themes, by name; "dark" is the default.
+var themes = map[string]theme{
+	"dark":  {path: "1;36", lines: "33", category: "1;35", domain: "35", fence: "2"},
+	"light": {path: "1;34", lines: "31", category: "1;32", domain: "32", fence: "90"},
+}
+
+// defaultTheme is the theme used when none is configured.
+const defaultTheme = "dark"
+
+// resolveColor returns the theme snippets printed to out are colorized in, or nil if they aren't:
+// with --color auto, they are when out is a terminal and NO_COLOR isn't set.
+func resolveColor(mode, themeName string, out *os.File) (*theme, error) {
+	t, ok := themes[themeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q, expected one of %s", themeName, strings.Join(sortedKeys(themes), ", "))
+	}
+	switch mode {
+	case colorAlways:
+		return &t, nil
+	case colorNever:
+		return nil, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return nil, nil
+		}
+		if info, err := out.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return nil, nil
+		}
+		return &t, nil
+	default:
+		return nil, fmt.Errorf("--color must be %q, %q or %q, got %q", colorAuto, colorAlways, colorNever, mode)
+	}
+}
+
+// renderSnippets renders snips as Markdown, colorized as set by colorOutput.
+func renderSnippets(snips []snippet) string {
+	if colorOutput == nil {
+		return plainMarkdown(snips)
+	}
+	return colorOutput.render(snips)
+}
+
+// render renders snips like brio.RenderMarkdown, colorized, with the line numbers and the
+// categories of each snippet added to its heading for skimming, and its path linked to its source
+// if t.links is set.
+func (t theme) render(snips []snippet) string {
+	wd, wdErr := os.Getwd()
+	var out strings.Builder
+	for _, s := range snips {
+		path := s.File
+		if wdErr == nil {
+			if rel, err := filepath.Rel(wd, s.File); err == nil {
+				path = rel
+			}
+		}
+
+		heading := paint(t.path, brio.SectionPath(path, s.Section)) +
+			paint(t.lines, fmt.Sprintf(":%d-%d", s.StartLine, s.EndLine))
+		if t.links {
+			heading = hyperlink(sourceURI(s), heading)
+		}
+		out.WriteString(heading)
+		for _, label := range t.labels(s.Categories) {
+			out.WriteString("  " + label)
+		}
+		out.WriteString("\n")
+		out.WriteString(paint(t.fence, "```"+s.Language()) + "\n")
+		for _, line := range s.Content {
+			out.WriteString(line + "\n")
+		}
+		out.WriteString(paint(t.fence, "```") + "\n\n")
+	}
+	return out.String()
+}
+
+// labels returns the colorized labels of categories, e.g. "foundation: messages, alerts", sorted.
+func (t theme) labels(categories map[string][]string) []string {
+	labels := make([]string, 0, len(categories))
+	for _, name := range sortedKeys(categories) {
+		label := paint(t.category, name)
+		if domains := categories[name]; len(domains) > 0 {
+			label += ": " + paint(t.domain, strings.Join(domains, ", "))
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// paint wraps s in the SGR escape sequences of params.
+func paint(params, s string) string {
+	return "\x1b[" + params + "m" + s + "\x1b[0m"
+}
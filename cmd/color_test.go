@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveColor(t *testing.T) {
+	// A regular file is not a terminal.
+	out, err := os.Create(filepath.Join(t.TempDir(), "out.md"))
+	assert.Nil(t, err)
+	defer out.Close()
+
+	th, err := resolveColor(colorAlways, "light", out)
+	assert.Nil(t, err)
+	assert.Equal(t, themes["light"], *th)
+
+	th, err = resolveColor(colorAuto, "dark", out)
+	assert.Nil(t, err)
+	assert.Nil(t, th)
+
+	th, err = resolveColor(colorNever, "dark", out)
+	assert.Nil(t, err)
+	assert.Nil(t, th)
+
+	// NO_COLOR only matters to auto.
+	t.Setenv("NO_COLOR", "1")
+	th, err = resolveColor(colorAlways, "dark", out)
+	assert.Nil(t, err)
+	assert.NotNil(t, th)
+
+	_, err = resolveColor("sometimes", "dark", out)
+	assert.EqualError(t, err, `--color must be "auto", "always" or "never", got "sometimes"`)
+	_, err = resolveColor(colorAlways, "neon", out)
+	assert.EqualError(t, err, `unknown theme "neon", expected one of dark, light`)
+}
+
+func TestRenderSnippetsColor(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	snips := []snippet{{
+		File:       filepath.Join(wd, "app.py"),
+		StartLine:  3,
+		EndLine:    6,
+		Categories: map[string][]string{"tests": {}, "foundation": {"messages", "alerts"}},
+		Content:    []string{"def send():", "    pass"},
+		Plugin:     python,
+	}}
+
+	saved := colorOutput
+	defer func() { colorOutput = saved }()
+
+	colorOutput = nil
+	assert.Equal(t, brio.RenderMarkdown(snips), renderSnippets(snips))
+
+	dark := themes["dark"]
+	colorOutput = &dark
+	assert.Equal(t, "\x1b[1;36mapp.py\x1b[0m\x1b[33m:3-6\x1b[0m"+
+		"  \x1b[1;35mfoundation\x1b[0m: \x1b[35mmessages, alerts\x1b[0m  \x1b[1;35mtests\x1b[0m\n"+
+		"\x1b[2m```python\x1b[0m\n"+
+		"def send():\n    pass\n"+
+		"\x1b[2m```\x1b[0m\n\n", renderSnippets(snips))
+}
+
+func TestRenderSnippetsLinks(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	snips := []snippet{{File: filepath.Join(wd, "app.py"), StartLine: 3, EndLine: 6, Plugin: python}}
+
+	saved := colorOutput
+	defer func() { colorOutput = saved }()
+	dark := themes["dark"]
+	dark.links = true
+	colorOutput = &dark
+	assert.Contains(t, renderSnippets(snips), "\x1b]8;;vscode://file"+filepath.ToSlash(wd)+"/app.py:3\x1b\\"+
+		"\x1b[1;36mapp.py\x1b[0m\x1b[33m:3-6\x1b[0m\x1b]8;;\x1b\\\n")
+}
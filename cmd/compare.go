@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// refAFlag and refBFlag name the git refs compare reads the snippets of; compareFormat selects how
+// the lines of modified snippets are shown: "unified" or "side-by-side".
+var (
+	refAFlag      string
+	refBFlag      string
+	compareFormat string
+)
+
+// Formats of the differences of modified snippets.
+const (
+	formatUnified    = "unified"
+	formatSideBySide = "side-by-side"
+)
+
+// compareCmd reports how the snippets of two git refs differ.
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Report how the matching snippets differ between two git refs",
+	Long: `Compare extracts the snippets of --dir as they are at two refs of its git
+repository (branches, tags, commits, or revisions such as HEAD~3), without
+checking either out, and prints a Markdown report of the snippets added, removed
+and modified from --ref-a to --ref-b, e.g. to write a review prompt about what a
+feature branch changes.
+
+Snippets declaring "_id" are matched by it; the others by file and categories,
+in order. The lines of modified snippets are shown as a unified diff, or side by
+side with --format side-by-side.
+Usage example:
+brio compare --ref-a main --ref-b feature/x -c foundation
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if refAFlag == "" || refBFlag == "" {
+			fatalf("Both --ref-a and --ref-b are required")
+		}
+		if compareFormat != formatUnified && compareFormat != formatSideBySide {
+			fatalf("Unknown format %q, expected %s or %s", compareFormat, formatUnified, formatSideBySide)
+		}
+		catMap := brio.ParseCategories(categoriesArg)
+
+		a := refSnippets(cmd.Context(), refAFlag, catMap)
+		b := refSnippets(cmd.Context(), refBFlag, catMap)
+		checkCanceled(cmd.Context())
+
+		fmt.Print(renderComparison(compareSnippets(a, b), refAFlag, refBFlag, compareFormat))
+		printReport()
+	},
+}
+
+// init registers compareCmd and its flags.
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&refAFlag, "ref-a", "", "Git ref to compare from, e.g. main")
+	compareCmd.Flags().StringVar(&refBFlag, "ref-b", "", "Git ref to compare to, e.g. feature/x")
+	compareCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory of the repository to scan")
+	compareCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	compareCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to include, e.g. 'messages:foundation,tests' (default: all)")
+	compareCmd.Flags().StringVar(&compareFormat, "format", formatUnified,
+		"How modified snippets are shown: unified or side-by-side")
+}
+
+// refSnippets returns the snippets of --dir at ref that match catMap, their files named relative to
+// --dir so that those of two refs can be matched.
+func refSnippets(ctx context.Context, ref string, catMap map[string][]string) []snippet {
+	root, err := brio.OpenRef(dirFlag, ref)
+	if err != nil {
+		fatalf("Error reading %s: %v", ref, err)
+	}
+	files, err := collectFiles(ctx, root, filePattern)
+	if err != nil && ctx.Err() == nil {
+		fatalf("Error collecting files of %s: %v", ref, err)
+	}
+	snips := extractSnippets(ctx, files, catMap)
+	for i, s := range snips {
+		if rel, err := filepath.Rel(root, s.File); err == nil {
+			snips[i].File = rel
+		}
+	}
+	return snips
+}
+
+// snippetPair pairs a snippet of the first ref with the matching one of the second; a is nil for
+// an added snippet and b for a removed one.
+type snippetPair struct {
+	a, b *snippet
+}
+
+// comparison is the result of comparing the snippets of two refs.
+type comparison struct {
+	changes   []snippetPair // added, removed and modified snippets, by file
+	unchanged int
+}
+
+// compareSnippets matches the snippets of a and b by snippetKeys and returns those that differ.
+func compareSnippets(a, b []snippet) comparison {
+	keysA, keysB := snippetKeys(a), snippetKeys(b)
+	byKey := make(map[string]int, len(a))
+	for i, key := range keysA {
+		byKey[key] = i
+	}
+
+	var c comparison
+	matched := make([]bool, len(a))
+	for j, key := range keysB {
+		i, ok := byKey[key]
+		if !ok {
+			c.changes = append(c.changes, snippetPair{b: &b[j]})
+			continue
+		}
+		matched[i] = true
+		if slices.Equal(a[i].Content, b[j].Content) {
+			c.unchanged++
+			continue
+		}
+		c.changes = append(c.changes, snippetPair{a: &a[i], b: &b[j]})
+	}
+	for i := range a {
+		if !matched[i] {
+			c.changes = append(c.changes, snippetPair{a: &a[i]})
+		}
+	}
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		return c.changes[i].snippet().File < c.changes[j].snippet().File
+	})
+	return c
+}
+
+// snippet returns the snippet of the second ref of the change, or that of the first if it was removed.
+func (c snippetPair) snippet() *snippet {
+	if c.b != nil {
+		return c.b
+	}
+	return c.a
+}
+
+// snippetKeys returns the keys snippets are matched across refs by: their file and "_id", or for
+// those without one, their file and categories, numbered in order.
+func snippetKeys(snips []snippet) []string {
+	keys := make([]string, len(snips))
+	seen := make(map[string]int)
+	for i, s := range snips {
+		if id := s.Attr("id"); id != "" {
+			keys[i] = s.File + "#" + id
+			continue
+		}
+		key := s.File + " " + strings.Join(sortedKeys(s.Categories), ",")
+		seen[key]++
+		keys[i] = fmt.Sprintf("%s %d", key, seen[key])
+	}
+	return keys
+}
+
+// renderComparison renders c as Markdown, the lines of modified snippets in format.
+func renderComparison(c comparison, refA, refB, format string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# Snippets changed from %s to %s\n\n", refA, refB)
+	if len(c.changes) == 0 {
+		fmt.Fprintf(&out, "No snippet differs (%s unchanged).\n", snippetCount(c.unchanged))
+		return out.String()
+	}
+
+	counts := map[string]int{}
+	for _, change := range c.changes {
+		counts[change.status()]++
+	}
+	fmt.Fprintf(&out, "%d modified, %d added, %d removed, %d unchanged.\n\n",
+		counts["Modified"], counts["Added"], counts["Removed"], c.unchanged)
+
+	for _, change := range c.changes {
+		s := change.snippet()
+		fmt.Fprintf(&out, "## %s: %s (%s)\n\n", change.status(), brio.SectionPath(s.File, s.Section), change.lines())
+		switch {
+		case change.a == nil || change.b == nil:
+			fmt.Fprintf(&out, "```%s\n%s\n```\n\n", s.Language(), strings.Join(s.Content, "\n"))
+		case format == formatSideBySide:
+			fmt.Fprintf(&out, "```\n%s```\n\n", sideBySide(lineDiff(change.a.Content, change.b.Content), refA, refB))
+		default:
+			fmt.Fprintf(&out, "```diff\n--- %s\n+++ %s\n", refA, refB)
+			for _, op := range lineDiff(change.a.Content, change.b.Content) {
+				fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+			}
+			out.WriteString("```\n\n")
+		}
+	}
+	return out.String()
+}
+
+// status names the kind of change: "Added", "Removed" or "Modified".
+func (c snippetPair) status() string {
+	switch {
+	case c.a == nil:
+		return "Added"
+	case c.b == nil:
+		return "Removed"
+	default:
+		return "Modified"
+	}
+}
+
+// lines describes the lines of the snippets of the change, e.g. "lines 3-9 → 3-12".
+func (c snippetPair) lines() string {
+	span := func(s *snippet) string { return fmt.Sprintf("%d-%d", s.StartLine, s.EndLine) }
+	switch {
+	case c.a == nil:
+		return "lines " + span(c.b)
+	case c.b == nil:
+		return "lines " + span(c.a)
+	default:
+		return fmt.Sprintf("lines %s → %s", span(c.a), span(c.b))
+	}
+}
+
+// diffOp is a line of a line diff: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// lineDiff returns the shortest edit from a to b, line by line, from their longest common subsequence.
+func lineDiff(a, b []string) []diffOp {
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// sideBySide lays ops out in two columns, as diff -y does: the lines of refA on the left and those
+// of refB on the right, with "|" between lines changed, "<" by removed ones and ">" by added ones.
+func sideBySide(ops []diffOp, refA, refB string) string {
+	type row struct {
+		left, right string
+		mark        byte
+	}
+	var rows []row
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			rows = append(rows, row{ops[k].line, ops[k].line, ' '})
+			k++
+			continue
+		}
+		// Pair the lines of a run of removals and additions.
+		var removed, added []string
+		for ; k < len(ops) && ops[k].kind != ' '; k++ {
+			if ops[k].kind == '-' {
+				removed = append(removed, ops[k].line)
+			} else {
+				added = append(added, ops[k].line)
+			}
+		}
+		for n := 0; n < max(len(removed), len(added)); n++ {
+			switch {
+			case n >= len(added):
+				rows = append(rows, row{removed[n], "", '<'})
+			case n >= len(removed):
+				rows = append(rows, row{"", added[n], '>'})
+			default:
+				rows = append(rows, row{removed[n], added[n], '|'})
+			}
+		}
+	}
+
+	width := len(refA)
+	for i, r := range rows {
+		rows[i].left = strings.ReplaceAll(r.left, "\t", "    ")
+		rows[i].right = strings.ReplaceAll(r.right, "\t", "    ")
+		width = max(width, len(rows[i].left))
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%-*s   %s\n", width, refA, refB)
+	for _, r := range rows {
+		out.WriteString(strings.TrimRight(fmt.Sprintf("%-*s %c %s", width, r.left, r.mark, r.right), " ") + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareSnippets(t *testing.T) {
+	a := []snippet{
+		{File: "app.py", StartLine: 1, EndLine: 3, Categories: map[string][]string{"foundation": nil}, Content: []string{"def f():", "    return 1"}},
+		{File: "app.py", StartLine: 5, EndLine: 7, Categories: map[string][]string{"foundation": nil}, Content: []string{"def g():", "    pass"}},
+		{File: "old.py", StartLine: 1, EndLine: 2, Attrs: map[string][]string{"id": {"legacy"}}, Content: []string{"x = 1"}},
+	}
+	b := []snippet{
+		{File: "app.py", StartLine: 3, EndLine: 6, Categories: map[string][]string{"foundation": nil}, Content: []string{"def f():", "    log()", "    return 2"}},
+		{File: "app.py", StartLine: 8, EndLine: 10, Categories: map[string][]string{"foundation": nil}, Content: []string{"def g():", "    pass"}},
+		{File: "new.py", StartLine: 1, EndLine: 2, Content: []string{"y = 2"}},
+	}
+
+	c := compareSnippets(a, b)
+	assert.Equal(t, 1, c.unchanged)
+	assert.Len(t, c.changes, 3)
+	var statuses []string
+	for _, change := range c.changes {
+		statuses = append(statuses, change.status()+" "+change.snippet().File)
+	}
+	assert.Equal(t, []string{"Modified app.py", "Added new.py", "Removed old.py"}, statuses)
+
+	assert.Equal(t, "# Snippets changed from main to feature\n\n"+
+		"1 modified, 1 added, 1 removed, 1 unchanged.\n\n"+
+		"## Modified: app.py (lines 1-3 → 3-6)\n\n"+
+		"```diff\n--- main\n+++ feature\n def f():\n-    return 1\n+    log()\n+    return 2\n```\n\n"+
+		"## Added: new.py (lines 1-2)\n\n```\ny = 2\n```\n\n"+
+		"## Removed: old.py (lines 1-2)\n\n```\nx = 1\n```\n\n",
+		renderComparison(c, "main", "feature", formatUnified))
+
+	assert.Equal(t, "# Snippets changed from main to main\n\nNo snippet differs (3 snippets unchanged).\n",
+		renderComparison(compareSnippets(a, a), "main", "main", formatUnified))
+}
+
+func TestSideBySide(t *testing.T) {
+	ops := lineDiff([]string{"def f():", "\treturn 1", "# end"}, []string{"def f():", "\treturn 2", "\tlog()", "# end"})
+	assert.Equal(t, "main           feature\n"+
+		"def f():       def f():\n"+
+		"    return 1 |     return 2\n"+
+		"             >     log()\n"+
+		"# end          # end\n",
+		sideBySide(ops, "main", "feature"))
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the scan behind the completion of --categories, so that pressing tab
+// in a huge tree completes what was found so far instead of hanging the shell.
+const completionTimeout = 3 * time.Second
+
+// registerCompletions completes --categories, in every command that has it, with the categories
+// and domains actually tagged in the files of --dir.
+func registerCompletions() {
+	for _, c := range rootCmd.Commands() {
+		if c.Flags().Lookup("categories") != nil {
+			_ = c.RegisterFlagCompletionFunc("categories", completeCategories)
+		}
+	}
+}
+
+// completeCategories completes the value of --categories from the snippets of --dir.
+func completeCategories(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Hooks don't run for completions: load .brio.yaml and the plugins as a command would.
+	if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	opts := cfg.options()
+	opts.Dir, opts.Pattern = dirFlag, filePattern
+	// Whatever was found before a failure or the timeout is still worth completing.
+	snips, _, _ := brio.New(opts).Extract(ctx)
+	return categoryCompletions(countTags(snips), toComplete), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// tagCounts counts the snippets tagged with each category and domain.
+type tagCounts struct {
+	categories map[string]int
+	domains    map[string]int
+	// byDomain counts the snippets of each category, by domain.
+	byDomain map[string]map[string]int
+}
+
+// countTags returns the tagCounts of snips.
+func countTags(snips []snippet) tagCounts {
+	counts := tagCounts{categories: make(map[string]int), domains: make(map[string]int), byDomain: make(map[string]map[string]int)}
+	for _, s := range snips {
+		seen := make(map[string]bool)
+		for category, domains := range s.Categories {
+			counts.categories[category]++
+			if counts.byDomain[category] == nil {
+				counts.byDomain[category] = make(map[string]int)
+			}
+			for _, domain := range domains {
+				counts.byDomain[category][domain]++
+				if !seen[domain] {
+					seen[domain] = true
+					counts.domains[domain]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// categoryCompletions returns the completions of toComplete, a --categories value such as
+// "messages:foundation,te", from counts. The last comma-separated part is completed, with the
+// categories of its domain (given, or inherited from an earlier part) that aren't already listed,
+// and with "domain:" for each domain. Each completion is described by the number of snippets
+// tagged with it.
+func categoryCompletions(counts tagCounts, toComplete string) []string {
+	done, current := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		done, current = toComplete[:i+1], toComplete[i+1:]
+	}
+	listed := brio.ParseCategories(strings.TrimSuffix(done, ","))
+
+	// The domain of the last part is the one given before its colon, or else inherited.
+	domain := ""
+	for _, part := range strings.Split(done, ",") {
+		if d, _, ok := strings.Cut(part, ":"); ok {
+			domain = strings.TrimSpace(d)
+		}
+	}
+	if d, _, ok := strings.Cut(current, ":"); ok {
+		domain = strings.TrimSpace(d)
+		done += d + ":"
+	}
+
+	var completions []string
+	for _, category := range sortedKeys(counts.categories) {
+		if _, ok := listed[category]; ok {
+			continue
+		}
+		n := counts.categories[category]
+		if domain != "" {
+			n = counts.byDomain[category][domain]
+		}
+		if n > 0 {
+			completions = append(completions, fmt.Sprintf("%s%s\t%s", done, category, snippetCount(n)))
+		}
+	}
+	if !strings.Contains(current, ":") {
+		for _, d := range sortedKeys(counts.domains) {
+			completions = append(completions, fmt.Sprintf("%s%s:\tdomain, %s", done, d, snippetCount(counts.domains[d])))
+		}
+	}
+
+	// Not every shell filters completions by what was typed.
+	var matching []string
+	for _, c := range completions {
+		if strings.HasPrefix(c, toComplete) {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
+
+// snippetCount describes n snippets.
+func snippetCount(n int) string {
+	if n == 1 {
+		return "1 snippet"
+	}
+	return fmt.Sprintf("%d snippets", n)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryCompletions(t *testing.T) {
+	counts := countTags([]snippet{
+		{Categories: map[string][]string{"foundation": {"messages", "alerts"}, "tests": {"messages"}}},
+		{Categories: map[string][]string{"foundation": {"messages"}}},
+		{Categories: map[string][]string{"docs": {}}},
+	})
+
+	tests := []struct {
+		toComplete string
+		want       []string
+	}{
+		{"", []string{
+			"docs\t1 snippet", "foundation\t2 snippets", "tests\t1 snippet",
+			"alerts:\tdomain, 1 snippet", "messages:\tdomain, 2 snippets",
+		}},
+		{"fo", []string{"foundation\t2 snippets"}},
+		{"me", []string{"messages:\tdomain, 2 snippets"}},
+		{"alerts:", []string{"alerts:foundation\t1 snippet"}},
+		{"messages:t", []string{"messages:tests\t1 snippet"}},
+		{"messages:foundation,", []string{
+			"messages:foundation,tests\t1 snippet",
+			"messages:foundation,alerts:\tdomain, 1 snippet", "messages:foundation,messages:\tdomain, 2 snippets",
+		}},
+		{"docs,alerts:", []string{"docs,alerts:foundation\t1 snippet"}},
+		{"unknown:", nil},
+		{"x", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, categoryCompletions(counts, tt.toComplete), "%q", tt.toComplete)
+	}
+}
@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the config file brio looks for in the working directory
+// when --config is not given.
+const defaultConfigFile = ".brio.yaml"
+
+// Marker styles accepted for snippet start/end tags.
+const (
+	markersArrows   = brio.MarkersArrows   // # >: {...} / # <: {...}
+	markersKeywords = brio.MarkersKeywords // # start: {...} / # end: {...}
+	markersBoth     = brio.MarkersBoth
+)
+
+// defaultPluginDir is where brio looks for WASM plugin modules when plugin_dir is not set. Native
+// plugins run unsandboxed, so they are never loaded from it: a checkout could ship its own.
+const defaultPluginDir = ".brio/plugins"
+
+// Tag payload formats.
+const (
+	payloadJSON = brio.PayloadJSON // # >: {"foundation": ["messages"]}
+	payloadYAML = brio.PayloadYAML // # >: foundation: [messages]
+)
+
+// config holds the settings read from a .brio.yaml file.
+type config struct {
+	// Markers selects which start/end tag syntax is recognized:
+	// "arrows", "keywords" or "both" (the default).
+	Markers string `yaml:"markers"`
+	// Regions turns IDE region markers (#region, // MARK:) into snippets.
+	Regions bool `yaml:"regions"`
+	// CustomMarkers are the start and end markers of other snippet conventions to extract too
+	// (see customMarkerConfig).
+	CustomMarkers []customMarkerConfig `yaml:"custom_markers"`
+	// VirtualSnippets declares snippets of files that can't carry tags (see virtualSnippetConfig).
+	VirtualSnippets []virtualSnippetConfig `yaml:"virtual_snippets"`
+	// PathDefaults give default categories and domains to the snippets of directories (see
+	// pathDefaultConfig).
+	PathDefaults []pathDefaultConfig `yaml:"path_defaults"`
+	// IncludeComments keeps the lines of multi-line comments without tags in snippets.
+	IncludeComments bool `yaml:"include_comments"`
+	// Payload selects the tag payload syntax: "json" (the default) or "yaml".
+	Payload string `yaml:"payload"`
+	// Languages declares additional languages (see languageConfig).
+	Languages []languageConfig `yaml:"languages"`
+	// Fallback guesses the comment prefix of files no plugin handles from their tags.
+	Fallback bool `yaml:"fallback"`
+	// Extensions maps additional extensions (".pyx") to the language of the plugin handling them
+	// ("python").
+	Extensions map[string]string `yaml:"extensions"`
+	// Pin maps extensions (".h") and file names ("Jenkinsfile") to the name of the plugin
+	// that must handle them, settling conflicts between plugins.
+	Pin map[string]string `yaml:"pin"`
+	// PluginDir is the directory plugin modules (*.wasm) are loaded from, defaultPluginDir when
+	// unset. Go plugins (*.so) are only loaded from --plugin-dir (see pluginDirs).
+	PluginDir string `yaml:"plugin_dir"`
+	// Jobs is how many files are read and parsed at once; 0 (the default) means one per CPU.
+	Jobs int `yaml:"jobs"`
+	// Ignore names more directories to skip (e.g. "generated", "*.egg-info"), on top of
+	// brio.DefaultIgnores.
+	Ignore []string `yaml:"ignore"`
+	// NoDefaultIgnores scans the directories of brio.DefaultIgnores too.
+	NoDefaultIgnores bool `yaml:"no_default_ignores"`
+	// Theme is the colors of snippets printed to a terminal: "dark" (the default) or "light".
+	Theme string `yaml:"theme"`
+	// TabsToSpaces expands the tabs of snippet content to this many columns; 0 (the default)
+	// leaves them.
+	TabsToSpaces int `yaml:"tabs_to_spaces"`
+	// TrimTrailingWhitespace strips the whitespace ending the lines of snippet content.
+	TrimTrailingWhitespace bool `yaml:"trim_trailing_whitespace"`
+	// Transforms are commands the snippets printed by extract are piped through, in order, with
+	// --transforms only (see transformConfig).
+	Transforms []transformConfig `yaml:"transforms"`
+	// MaxTokensPerCategory caps the estimated tokens of the snippets extract prints, and rpc and run
+	// return, for each category given, trimming the lowest "_priority" snippets of a category over its
+	// cap.
+	MaxTokensPerCategory map[string]int `yaml:"max_tokens_per_category"`
+
+	// dir is the directory of the config file, which the paths of path_defaults are relative to.
+	dir string
+	// nativePluginDir is the directory Go plugins are loaded from, set by --plugin-dir only.
+	nativePluginDir string
+}
+
+// customMarkerConfig is a pair of regular expressions matching the start and end markers of an
+// existing snippet convention, e.g. `--8<-- \[start:(?P<name>[\w.-]+)\]` for mkdocs snippets (see
+// brio.CustomMarker).
+type customMarkerConfig struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// marker compiles the patterns of m.
+func (m customMarkerConfig) marker() (brio.CustomMarker, error) {
+	start, err := regexp.Compile(m.Start)
+	if err != nil {
+		return brio.CustomMarker{}, fmt.Errorf("start: %w", err)
+	}
+	end, err := regexp.Compile(m.End)
+	if err != nil {
+		return brio.CustomMarker{}, fmt.Errorf("end: %w", err)
+	}
+	marker := brio.CustomMarker{Start: start, End: end}
+	return marker, marker.Validate()
+}
+
+// virtualSnippetConfig declares a snippet of File without tagging it: the definition named Symbol,
+// or the Lines "40-120", with the Categories (and "_owner"-like attributes) a tag would give it (see
+// brio.VirtualSnippet).
+type virtualSnippetConfig struct {
+	File       string              `yaml:"file"`
+	Symbol     string              `yaml:"symbol"`
+	Lines      string              `yaml:"lines"`
+	Categories map[string][]string `yaml:"categories"`
+}
+
+// snippet returns the brio.VirtualSnippet v declares.
+func (v virtualSnippetConfig) snippet() (brio.VirtualSnippet, error) {
+	if v.File == "" {
+		return brio.VirtualSnippet{}, errors.New("a file is required")
+	}
+	if (v.Symbol == "") == (v.Lines == "") {
+		return brio.VirtualSnippet{}, fmt.Errorf("%s: either a symbol or lines is required", v.File)
+	}
+	snippet := brio.VirtualSnippet{File: v.File, Symbol: v.Symbol, Tags: v.Categories}
+	if v.Lines != "" {
+		ranges, err := parseRanges(v.File + ":" + v.Lines)
+		if err != nil {
+			return brio.VirtualSnippet{}, err
+		}
+		if len(ranges) != 1 {
+			return brio.VirtualSnippet{}, fmt.Errorf("%s: lines must be a single start-end range", v.File)
+		}
+		snippet.StartLine, snippet.EndLine = ranges[0].start, ranges[0].end
+	}
+	return snippet, nil
+}
+
+// pathDefaultConfig gives the snippets of the files below Path, relative to the directory of the
+// config file, the Categories (and "_owner"-like
+// attributes) and the Domains their tags leave out (see brio.PathDefault):
+//
+//	path_defaults:
+//	  - path: services/messages
+//	    domains: [messages]
+type pathDefaultConfig struct {
+	Path       string              `yaml:"path"`
+	Categories map[string][]string `yaml:"categories"`
+	Domains    []string            `yaml:"domains"`
+}
+
+// pathDefault returns the brio.PathDefault d declares.
+func (d pathDefaultConfig) pathDefault() (brio.PathDefault, error) {
+	if d.Path == "" {
+		return brio.PathDefault{}, errors.New("a path is required")
+	}
+	if len(d.Categories) == 0 && len(d.Domains) == 0 {
+		return brio.PathDefault{}, fmt.Errorf("%s: categories or domains are required", d.Path)
+	}
+	pd := brio.PathDefault{Path: d.Path, Tags: d.Categories, Domains: d.Domains}
+	if err := pd.Validate(); err != nil {
+		return brio.PathDefault{}, fmt.Errorf("%s: %w", d.Path, err)
+	}
+	return pd, nil
+}
+
+// configFlag holds the path of the config file given with --config.
+// pluginDirFlag overrides the plugin_dir setting of the config file.
+// fallbackFlag turns on the fallback setting of the config file.
+// jobsFlag overrides the jobs setting of the config file.
+// noDefaultIgnoresFlag turns on the no_default_ignores setting of the config file.
+var (
+	configFlag           string
+	pluginDirFlag        string
+	fallbackFlag         bool
+	jobsFlag             int
+	noDefaultIgnoresFlag bool
+)
+
+// cfg is the configuration loaded before any subcommand runs.
+var cfg = defaultConfig()
+
+// defaultConfig returns the configuration used when no config file is present.
+func defaultConfig() config {
+	return config{
+		Markers: markersBoth,
+		Payload: payloadJSON,
+		Theme:   defaultTheme,
+	}
+}
+
+// loadConfig reads the config file at path on top of the defaults.
+// A missing file is only an error when the path was given explicitly.
+func loadConfig(path string, explicit bool) (config, error) {
+	c := defaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return c, nil
+		}
+		return c, err
+	}
+
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	c.nativePaths()
+	c.dir, _ = filepath.Abs(filepath.Dir(path))
+	return c, nil
+}
+
+// pluginDirs returns the directories WASM modules and Go plugins are loaded from. Go plugins run
+// unsandboxed, so native is "" unless --plugin-dir was given: the plugin_dir of the config file
+// comes with the checkout, as the default directory does, and could point at a checkout's own.
+func (c config) pluginDirs() (wasm, native string) {
+	if c.PluginDir == "" {
+		return defaultPluginDir, c.nativePluginDir
+	}
+	return c.PluginDir, c.nativePluginDir
+}
+
+// nativePaths converts the paths of c to the separators of the system: the file may be shared by
+// Windows and Unix users, writing paths with either.
+func (c *config) nativePaths() {
+	c.PluginDir = brio.NativePath(c.PluginDir)
+	for i := range c.VirtualSnippets {
+		c.VirtualSnippets[i].File = brio.NativePath(c.VirtualSnippets[i].File)
+	}
+}
+
+// configError is a setting of the config file holding an unsupported value.
+type configError struct {
+	// at is the path of the setting in the file: a top-level key, then the keys of maps and the
+	// indexes of lists below it, e.g. ["languages", "2"].
+	at  []string
+	err error
+}
+
+func (e configError) Error() string {
+	return e.err.Error()
+}
+
+// validate reports the first setting that holds an unsupported value.
+func (c config) validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// problems reports every setting that holds an unsupported value, in the order of the fields of
+// config.
+func (c config) problems() []configError {
+	var problems []configError
+	add := func(err error, at ...string) {
+		problems = append(problems, configError{at: at, err: err})
+	}
+	switch c.Markers {
+	case markersArrows, markersKeywords, markersBoth:
+	default:
+		add(fmt.Errorf("markers must be one of %q, %q or %q, got %q",
+			markersArrows, markersKeywords, markersBoth, c.Markers), "markers")
+	}
+	for i, m := range c.CustomMarkers {
+		if m.Start == "" || m.End == "" {
+			add(errors.New("custom_markers: a start and an end pattern are required"), "custom_markers", strconv.Itoa(i))
+		} else if _, err := m.marker(); err != nil {
+			add(fmt.Errorf("custom_markers: %w", err), "custom_markers", strconv.Itoa(i))
+		}
+	}
+	for i, v := range c.VirtualSnippets {
+		if _, err := v.snippet(); err != nil {
+			add(fmt.Errorf("virtual_snippets: %w", err), "virtual_snippets", strconv.Itoa(i))
+		}
+	}
+	for i, d := range c.PathDefaults {
+		if _, err := d.pathDefault(); err != nil {
+			add(fmt.Errorf("path_defaults: %w", err), "path_defaults", strconv.Itoa(i))
+		}
+	}
+	switch c.Payload {
+	case payloadJSON, payloadYAML:
+	default:
+		add(fmt.Errorf("payload must be %q or %q, got %q", payloadJSON, payloadYAML, c.Payload), "payload")
+	}
+	for i, l := range c.Languages {
+		if err := l.validate(); err != nil {
+			add(fmt.Errorf("languages: %w", err), "languages", strconv.Itoa(i))
+		}
+	}
+	for _, ext := range sortedKeys(c.Extensions) {
+		if !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext, `/\`) {
+			add(fmt.Errorf("extensions: %q must be an extension starting with a dot, e.g. .pyx", ext), "extensions", ext)
+		} else if c.Extensions[ext] == "" {
+			add(fmt.Errorf("extensions: %s: a language is required", ext), "extensions", ext)
+		}
+	}
+	if c.Jobs < 0 {
+		add(fmt.Errorf("jobs must be 0 or more, got %d", c.Jobs), "jobs")
+	}
+	for i, pattern := range c.Ignore {
+		if err := brio.ValidatePattern(pattern); err != nil {
+			add(fmt.Errorf("ignore: invalid pattern %q: %w", pattern, err), "ignore", strconv.Itoa(i))
+		}
+	}
+	if _, ok := themes[c.Theme]; !ok {
+		add(fmt.Errorf("theme must be one of %s, got %q", strings.Join(sortedKeys(themes), ", "), c.Theme), "theme")
+	}
+	if c.TabsToSpaces < 0 {
+		add(fmt.Errorf("tabs_to_spaces must be 0 or more, got %d", c.TabsToSpaces), "tabs_to_spaces")
+	}
+	for i, t := range c.Transforms {
+		if err := t.validate(); err != nil {
+			add(fmt.Errorf("transforms: %w", err), "transforms", strconv.Itoa(i))
+		}
+	}
+	for _, category := range sortedKeys(c.MaxTokensPerCategory) {
+		if limit := c.MaxTokensPerCategory[category]; limit <= 0 {
+			add(fmt.Errorf("max_tokens_per_category: %s: the limit must be more than 0, got %d", category, limit),
+				"max_tokens_per_category", category)
+		}
+	}
+	return problems
+}
+
+// options returns the library options matching the configuration.
+func (c config) options() brio.Options {
+	var ignore []string
+	if !c.NoDefaultIgnores {
+		ignore = append(ignore, brio.DefaultIgnores...)
+	}
+	ignore = append(ignore, c.Ignore...)
+	var markers []brio.CustomMarker
+	for _, m := range c.CustomMarkers {
+		// Checked by validate.
+		if marker, err := m.marker(); err == nil {
+			markers = append(markers, marker)
+		}
+	}
+	var virtual []brio.VirtualSnippet
+	for _, v := range c.VirtualSnippets {
+		// Checked by validate.
+		if snippet, err := v.snippet(); err == nil {
+			virtual = append(virtual, snippet)
+		}
+	}
+	var pathDefaults []brio.PathDefault
+	for _, d := range c.PathDefaults {
+		// Checked by validate.
+		if pd, err := d.pathDefault(); err == nil {
+			pd.Base = c.dir
+			pathDefaults = append(pathDefaults, pd)
+		}
+	}
+	var processors []brio.Processor
+	if c.TabsToSpaces > 0 || c.TrimTrailingWhitespace {
+		processors = append(processors, brio.NormalizeWhitespace(c.TabsToSpaces, c.TrimTrailingWhitespace))
+	}
+	return brio.Options{
+		Ignore:          ignore,
+		Markers:         c.Markers,
+		Payload:         c.Payload,
+		Regions:         c.Regions,
+		Virtual:         virtual,
+		PathDefaults:    pathDefaults,
+		CustomMarkers:   markers,
+		IncludeComments: c.IncludeComments,
+		Fallback:        c.Fallback,
+		Jobs:            c.Jobs,
+		Now:             now,
+		Processors:      processors,
+	}
+}
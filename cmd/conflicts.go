@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// flagConflict is a flag that can't be used with any of others, with the reason why, if it isn't
+// plain, to tell along.
+type flagConflict struct {
+	flag   string
+	others []string
+	reason string
+}
+
+// extractConflicts are the flags of extract that can't be used together. A flag is used when it is
+// given a value other than its default, so that e.g. --max-snippets 0 is no limit, as when left out.
+var extractConflicts = []flagConflict{
+	{"symbol", []string{"categories", "regions", "max-snippets"}, ""},
+	{"range", []string{"symbol", "categories", "regions", "max-snippets"}, ""},
+	{"range", []string{"repo", "github", "watch"}, "--range reads files from the working directory"},
+	{"repo", []string{"github"}, ""},
+	{"closure-depth", []string{"max-snippets"}, ""},
+	{"split-tokens", []string{"max-snippets"}, ""},
+	{"count-only", []string{"template", "split-tokens", "max-snippets", "closure-depth", "with-diff", "open"},
+		"--count-only prints no snippets"},
+	{"format", []string{"template", "split-tokens", "count-only", "max-snippets", "upload"}, ""},
+	{"output-dir", []string{"template", "split-tokens", "count-only", "max-snippets", "upload", "clipboard"}, ""},
+	{"anchors", []string{"template", "split-tokens", "count-only", "max-snippets"},
+		"--anchors numbers the snippets of a single Markdown document"},
+	{"style", []string{"template", "count-only", "anchors"}, ""},
+	{"template", []string{"max-snippets", "split-tokens"}, ""},
+	{"clipboard", []string{"max-snippets"}, ""},
+	{"upload", []string{"clipboard", "max-snippets"}, ""},
+	{"since", []string{"repo", "github"}, "--since needs the git history of --dir, which --repo and --github don't fetch"},
+	{"with-diff", []string{"repo", "github"}, "--with-diff needs the git history of --dir, which --repo and --github don't fetch"},
+	{"with-diff", []string{"range", "symbol", "max-snippets"}, "--with-diff diffs tagged snippets"},
+	{"watch", []string{"repo", "github"}, "--watch only watches --dir"},
+	{"webhook", []string{"max-snippets"}, "--webhook compares the snippets of each run, which --max-snippets doesn't hold"},
+	{"open", []string{"repo", "github"}, "--open needs the files on disk, which --repo and --github don't write"},
+	{"open", []string{"watch", "max-snippets"}, ""},
+}
+
+// checkConflicts returns an error for the first conflict of conflicts whose flag is used in flags
+// along with some of its others, naming them, e.g. "--count-only prints no snippets, so it can't be
+// used with --template".
+func checkConflicts(flags *pflag.FlagSet, conflicts []flagConflict) error {
+	for _, c := range conflicts {
+		if !flagUsed(flags, c.flag) {
+			continue
+		}
+		var used []string
+		for _, other := range c.others {
+			if flagUsed(flags, other) {
+				used = append(used, "--"+other)
+			}
+		}
+		if len(used) == 0 {
+			continue
+		}
+		if c.reason != "" {
+			return fmt.Errorf("%s, so it can't be used with %s", c.reason, strings.Join(used, ", "))
+		}
+		return fmt.Errorf("--%s can't be used with %s", c.flag, strings.Join(used, ", "))
+	}
+	return nil
+}
+
+// flagUsed reports whether the flag name of flags was given a value other than its default.
+func flagUsed(flags *pflag.FlagSet, name string) bool {
+	f := flags.Lookup(name)
+	return f != nil && f.Changed && f.Value.String() != f.DefValue
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConflicts(t *testing.T) {
+	conflicts := []flagConflict{
+		{"count-only", []string{"template", "max-snippets"}, "--count-only prints no snippets"},
+		{"repo", []string{"github"}, ""},
+	}
+	parse := func(args ...string) *pflag.FlagSet {
+		flags := pflag.NewFlagSet("extract", pflag.ContinueOnError)
+		flags.String("count-only", "", "")
+		flags.String("template", "", "")
+		flags.Int("max-snippets", 0, "")
+		flags.String("repo", "", "")
+		flags.String("github", "", "")
+		require.NoError(t, flags.Parse(args))
+		return flags
+	}
+
+	assert.NoError(t, checkConflicts(parse("--count-only", "total", "--repo", "x"), conflicts))
+	assert.NoError(t, checkConflicts(parse("--count-only", "total", "--max-snippets", "0"), conflicts),
+		"a flag given its default isn't used")
+	assert.EqualError(t, checkConflicts(parse("--count-only", "total", "--template", "t", "--max-snippets", "5"), conflicts),
+		"--count-only prints no snippets, so it can't be used with --template, --max-snippets")
+	assert.EqualError(t, checkConflicts(parse("--github", "a/b", "--repo", "x"), conflicts),
+		"--repo can't be used with --github")
+}
+
+func TestExtractConflictsNameFlags(t *testing.T) {
+	for _, c := range extractConflicts {
+		for _, name := range append([]string{c.flag}, c.others...) {
+			assert.NotNil(t, extractCmd.Flags().Lookup(name), name)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// countOnlyFlag makes extract print how many snippets match instead of the snippets: countTotal for
+// their number alone, countCategories for their number in each category as well.
+var countOnlyFlag string
+
+// Values of --count-only.
+const (
+	countTotal      = "total"
+	countCategories = "categories"
+)
+
+// writeCounts writes the number of snips to w, as set by --count-only: the number alone with
+// countTotal, for shell conditionals, or with countCategories a tab-separated line per category
+// with its number of snippets, sorted by category, and a last one with the total.
+func writeCounts(w io.Writer, snips []snippet, mode string) error {
+	if mode == countTotal {
+		_, err := fmt.Fprintln(w, len(snips))
+		return err
+	}
+	counts := countTags(snips).categories
+	for _, category := range sortedKeys(counts) {
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", category, counts[category]); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "total\t%d\n", len(snips))
+	return err
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCounts(t *testing.T) {
+	snips := []snippet{
+		{File: "a.py", Categories: map[string][]string{"tests": {"messages"}, "foundation": {}}},
+		{File: "b.py", Categories: map[string][]string{"tests": {}}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, writeCounts(&out, snips, countTotal))
+	assert.Equal(t, "2\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeCounts(&out, snips, countCategories))
+	assert.Equal(t, "foundation\t1\ntests\t2\ntotal\t2\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeCounts(&out, nil, countTotal))
+	assert.Equal(t, "0\n", out.String())
+}
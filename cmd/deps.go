@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// depsFormat selects the output of deps: "mermaid", "dot" or "json".
+var depsFormat string
+
+// depsCmd renders the dependencies declared between snippets.
+var depsCmd = &cobra.Command{
+	Use:   "deps",
+	Short: "Render the dependency graph of snippets declaring _id and _depends_on",
+	Long: `Deps prints the graph of snippets that declare "_id" or "_depends_on", with an
+edge from each snippet to the snippets it depends on. Unlike graph, which links
+files, its nodes are the snippets themselves.
+
+Dependency cycles, "_depends_on" entries naming an id no snippet declares, and ids
+declared twice are reported once the run is over, and the command exits with
+status 1 if any was found.
+Usage example:
+brio deps --dir ./src --format dot | dot -Tsvg > deps.svg
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		catMap := brio.ParseCategories(categoriesArg)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		g := buildDepGraph(snips)
+		switch depsFormat {
+		case "mermaid":
+			fmt.Print(g.mermaid())
+		case "dot":
+			fmt.Print(g.dot())
+		case "json":
+			data, err := json.MarshalIndent(g, "", "  ")
+			if err != nil {
+				fatalf("Error encoding the graph: %v", err)
+			}
+			fmt.Println(string(data))
+		default:
+			fatalf("Unknown graph format %q, expected mermaid, dot or json", depsFormat)
+		}
+
+		if len(g.issues) > 0 {
+			report.Issues = append(report.Issues, g.issues...)
+			printReport()
+			exit(1)
+		}
+	},
+}
+
+// init registers depsCmd and its flags.
+func init() {
+	rootCmd.AddCommand(depsCmd)
+
+	depsCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	depsCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	depsCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to include, e.g. 'messages:foundation,tests' (default: all)")
+	depsCmd.Flags().StringVar(&depsFormat, "format", "mermaid", "Graph format: mermaid, dot or json")
+}
+
+// depNode is a snippet of the dependency graph, named by its "_id", or by its location if it has
+// none but depends on others.
+type depNode struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	Section   string `json:"section,omitempty"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// depEdge is a dependency of snippet From on snippet To.
+type depEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// depGraph is the dependency graph of snippets, along with its problems.
+type depGraph struct {
+	Nodes []depNode `json:"nodes"`
+	Edges []depEdge `json:"edges"`
+	// Cycles lists each dependency cycle once, starting from its smallest id.
+	Cycles [][]string `json:"cycles"`
+	// Dangling lists the dependencies on ids no snippet declares.
+	Dangling []depEdge `json:"dangling"`
+
+	issues []issue
+}
+
+// buildDepGraph links the snippets of snips that declare "_id" or "_depends_on" and finds the
+// dependency cycles, the dangling dependencies and the ids declared twice.
+func buildDepGraph(snips []snippet) *depGraph {
+	g := &depGraph{Nodes: []depNode{}, Edges: []depEdge{}, Cycles: [][]string{}, Dangling: []depEdge{}}
+	problem := func(s snippet, code, format string, args ...interface{}) {
+		g.issues = append(g.issues, issue{File: s.File, Section: s.Section, Line: s.StartLine, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	declared := make(map[string]snippet)
+	var dependents []snippet
+	for _, s := range snips {
+		id := s.Attr("id")
+		if id == "" {
+			if len(s.Attrs["depends_on"]) == 0 {
+				continue
+			}
+			id = fmt.Sprintf("%s:%d", brio.SectionPath(displayPath(s.File), s.Section), s.StartLine)
+		}
+		if first, ok := declared[id]; ok {
+			problem(s, brio.CodeDuplicateID, "snippet id %q is already declared at %s:%d",
+				id, brio.SectionPath(first.File, first.Section), first.StartLine)
+			continue
+		}
+		declared[id] = s
+		dependents = append(dependents, s)
+		g.Nodes = append(g.Nodes, depNode{
+			ID:        id,
+			File:      displayPath(s.File),
+			Section:   s.Section,
+			StartLine: s.StartLine,
+			EndLine:   s.EndLine,
+		})
+	}
+
+	deps := make(map[string][]string)
+	for i, s := range dependents {
+		from := g.Nodes[i].ID
+		for _, to := range s.Attrs["depends_on"] {
+			if _, ok := declared[to]; !ok {
+				g.Dangling = append(g.Dangling, depEdge{From: from, To: to})
+				problem(s, brio.CodeDanglingDependency, "_depends_on names unknown snippet id %q", to)
+				continue
+			}
+			g.Edges = append(g.Edges, depEdge{From: from, To: to})
+			deps[from] = append(deps[from], to)
+		}
+	}
+
+	for _, cycle := range findCycles(deps) {
+		g.Cycles = append(g.Cycles, cycle)
+		problem(declared[cycle[0]], brio.CodeDependencyCycle, "dependency cycle: %s -> %s",
+			strings.Join(cycle, " -> "), cycle[0])
+	}
+	return g
+}
+
+// findCycles returns each cycle of the directed graph deps once, rotated to start from its
+// smallest node and sorted.
+func findCycles(deps map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	seen := make(map[string]bool)
+	var cycles [][]string
+
+	var visit func(node string)
+	visit = func(node string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		for _, next := range deps[node] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				// The stack from next to node is a cycle.
+				start := len(stack) - 1
+				for stack[start] != next {
+					start--
+				}
+				cycle := rotateToMin(stack[start:])
+				if key := strings.Join(cycle, "\x00"); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[node] = done
+	}
+
+	for _, node := range sortedKeys(deps) {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], "\x00") < strings.Join(cycles[j], "\x00")
+	})
+	return cycles
+}
+
+// rotateToMin returns a copy of cycle starting from its smallest node.
+func rotateToMin(cycle []string) []string {
+	min := 0
+	for i, node := range cycle {
+		if node < cycle[min] {
+			min = i
+		}
+	}
+	return append(append([]string{}, cycle[min:]...), cycle[:min]...)
+}
+
+// mermaid renders the graph as a Mermaid flowchart; dangling dependencies point to a missing node.
+func (g *depGraph) mermaid() string {
+	ids := g.nodeIDs()
+	var out strings.Builder
+	out.WriteString("graph LR\n")
+	for i, n := range g.Nodes {
+		out.WriteString(fmt.Sprintf("  n%d[\"%s\"]\n", i, strings.ReplaceAll(n.ID, `"`, "#quot;")))
+	}
+	for _, e := range g.Edges {
+		out.WriteString(fmt.Sprintf("  %s --> %s\n", ids[e.From], ids[e.To]))
+	}
+	for i, e := range g.Dangling {
+		out.WriteString(fmt.Sprintf("  m%d[\"%s (missing)\"]\n", i, strings.ReplaceAll(e.To, `"`, "#quot;")))
+		out.WriteString(fmt.Sprintf("  %s -.-> m%d\n", ids[e.From], i))
+	}
+	return out.String()
+}
+
+// dot renders the graph in Graphviz DOT syntax; dangling dependencies point to a missing node.
+func (g *depGraph) dot() string {
+	ids := g.nodeIDs()
+	var out strings.Builder
+	out.WriteString("digraph deps {\n  rankdir=LR;\n")
+	for i, n := range g.Nodes {
+		out.WriteString(fmt.Sprintf("  n%d [label=%s, shape=box];\n", i, strconv.Quote(n.ID)))
+	}
+	for _, e := range g.Edges {
+		out.WriteString(fmt.Sprintf("  %s -> %s;\n", ids[e.From], ids[e.To]))
+	}
+	for i, e := range g.Dangling {
+		out.WriteString(fmt.Sprintf("  m%d [label=%s, shape=box, style=dashed];\n", i, strconv.Quote(e.To+" (missing)")))
+		out.WriteString(fmt.Sprintf("  %s -> m%d [style=dashed];\n", ids[e.From], i))
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// nodeIDs returns the generated identifiers of the nodes, by snippet id.
+func (g *depGraph) nodeIDs() map[string]string {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
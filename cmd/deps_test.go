@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepGraph(t *testing.T) {
+	snips := []snippet{
+		{File: "models.py", StartLine: 1, EndLine: 4, Attrs: map[string][]string{"id": {"model"}}},
+		{File: "views.py", StartLine: 2, EndLine: 9, Attrs: map[string][]string{"id": {"view"}, "depends_on": {"model", "missing"}}},
+		{File: "a.py", StartLine: 1, Attrs: map[string][]string{"id": {"a"}, "depends_on": {"b"}}},
+		{File: "b.py", StartLine: 1, Attrs: map[string][]string{"id": {"b"}, "depends_on": {"c"}}},
+		{File: "c.py", StartLine: 3, Attrs: map[string][]string{"id": {"c"}, "depends_on": {"a"}}},
+		{File: "tests.py", StartLine: 5, Attrs: map[string][]string{"depends_on": {"view"}}},
+		{File: "copy.py", StartLine: 7, Attrs: map[string][]string{"id": {"model"}}},
+		{File: "plain.py", StartLine: 1},
+	}
+
+	g := buildDepGraph(snips)
+	assert.Len(t, g.Nodes, 6)
+	assert.Equal(t, "tests.py:5", g.Nodes[5].ID)
+	assert.Equal(t, []depEdge{{From: "view", To: "missing"}}, g.Dangling)
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, g.Cycles)
+
+	var codes []string
+	for _, i := range g.issues {
+		codes = append(codes, i.Code)
+	}
+	assert.ElementsMatch(t, []string{brio.CodeDuplicateID, brio.CodeDanglingDependency, brio.CodeDependencyCycle}, codes)
+	assert.Contains(t, g.issues[len(g.issues)-1].String(), "a.py:1: BRIO012 dependency cycle: a -> b -> c -> a")
+
+	assert.Equal(t, `graph LR
+  n0["model"]
+  n1["view"]
+  n2["a"]
+  n3["b"]
+  n4["c"]
+  n5["tests.py:5"]
+  n1 --> n0
+  n2 --> n3
+  n3 --> n4
+  n4 --> n2
+  n5 --> n1
+  m0["missing (missing)"]
+  n1 -.-> m0
+`, g.mermaid())
+	assert.Contains(t, g.dot(), "  n1 -> m0 [style=dashed];\n")
+
+	data, err := json.Marshal(g)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"cycles":[["a","b","c"]]`)
+}
+
+func TestFindCycles(t *testing.T) {
+	deps := map[string][]string{
+		"a": {"b"},
+		"b": {"a", "c"},
+		"c": {"c"},
+		"d": {"a"},
+	}
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, findCycles(deps))
+	assert.Empty(t, findCycles(map[string][]string{"a": {"b"}, "b": {}}))
+}
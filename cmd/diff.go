@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// withDiffFlag names the revision extract --with-diff shows the changes of each snippet since.
+var withDiffFlag string
+
+// withDiffs returns snips with, after each snippet that changed since --with-diff, the diff of its
+// lines since then, in a section of its file named after the revision. Snippets whose changes
+// can't be read are kept without them, with a warning. It fails if the history of --dir can't be
+// read.
+func withDiffs(snips []snippet) ([]snippet, error) {
+	history, err := brio.OpenHistory(dirFlag, "", now())
+	if err != nil {
+		return nil, fmt.Errorf("--with-diff: %w", err)
+	}
+	results := make([]snippet, 0, len(snips))
+	for _, s := range snips {
+		results = append(results, s)
+		changes, err := history.Diff(s, withDiffFlag)
+		if err != nil {
+			log.Printf("Warning: leaving out the changes of %s:%d: %v",
+				brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, err)
+			continue
+		}
+		if changes == nil {
+			continue
+		}
+		results = append(results, snippet{
+			File:       s.File,
+			Section:    "changes since " + withDiffFlag,
+			StartLine:  s.StartLine,
+			EndLine:    s.EndLine,
+			Categories: s.Categories,
+			Attrs:      map[string][]string{"diff": {withDiffFlag}},
+			Content:    changes,
+		})
+	}
+	return results, nil
+}
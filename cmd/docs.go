@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// outputDir is the directory docs writes the generated pages to.
+// sourceURL is the base URL used to link snippets to their source (e.g. a GitHub blob URL).
+// frontMatterFlag heads the pages with YAML front matter, with permalinks below permalinkBase.
+var (
+	outputDir       string
+	sourceURL       string
+	frontMatterFlag bool
+	permalinkBase   string
+)
+
+// noDomain is the page that collects snippets whose categories name no domain.
+const noDomain = "general"
+
+// docsCmd renders tagged snippets as a documentation site.
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate a documentation site from tagged snippets",
+	Long: `Docs writes a Markdown directory that mdBook and Docusaurus can build directly:
+one page per domain, a section per category, and every snippet highlighted with a
+link back to its source. An index page, an mdBook SUMMARY.md and a manifest.json
+describing every snippet, as brio export writes, are generated too.
+
+Links point to the files on disk, or to --source-url when given, e.g.
+--source-url https://github.com/acme/app/blob/main
+
+With --front-matter, the index and the domain pages start with YAML front matter
+(title, categories, source files, generation time and permalink) for Hugo, Jekyll
+or Docusaurus to index them.
+Usage example:
+brio docs --dir ./src --output ./book/src
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var bucket *brio.Bucket
+		if uploadFlag != "" {
+			bucket = openUpload()
+		}
+		catMap := brio.ParseCategories(categoriesArg)
+		recordRun(cmd)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		var front *frontMatterConfig
+		if frontMatterFlag {
+			front = &frontMatterConfig{PermalinkBase: permalinkBase, Generated: now()}
+		}
+		if err := generateDocs(snips, outputDir, sourceURL, front); err != nil {
+			fatalf("Error generating docs: %v", err)
+		}
+		fmt.Printf("Wrote documentation for %d snippets to %s\n", len(snips), outputDir)
+		if bucket != nil {
+			if err := uploadDir(cmd.Context(), bucket, outputDir); err != nil {
+				fatalf("Error uploading documentation: %v", err)
+			}
+		}
+	},
+}
+
+// init registers docsCmd and its flags.
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	docsCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	docsCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to document, e.g. 'messages:foundation,tests' (default: all)")
+	docsCmd.Flags().StringVarP(&outputDir, "output", "o", "brio-docs", "Directory to write the pages to")
+	docsCmd.Flags().StringVar(&sourceURL, "source-url", "",
+		"Base URL for source links, e.g. https://github.com/acme/app/blob/main")
+	docsCmd.Flags().BoolVar(&frontMatterFlag, "front-matter", false,
+		"Start the pages with YAML front matter for static site generators such as Hugo, Jekyll or Docusaurus")
+	docsCmd.Flags().StringVar(&permalinkBase, "permalink-base", "/snippets/",
+		"With --front-matter, the URL path the permalinks of the pages start with")
+	docsCmd.Flags().StringVar(&uploadFlag, "upload", "", uploadUsage("the pages"))
+}
+
+// frontMatterConfig sets up the front matter of the pages of docs.
+type frontMatterConfig struct {
+	// PermalinkBase is the URL path the index is at; domain pages are below it.
+	PermalinkBase string
+	// Generated is the time the pages are stamped with.
+	Generated time.Time
+}
+
+// frontMatter is the YAML front matter of a page.
+type frontMatter struct {
+	Title       string   `yaml:"title"`
+	Categories  []string `yaml:"categories,omitempty"`
+	SourceFiles []string `yaml:"source_files,omitempty"`
+	GeneratedAt string   `yaml:"generated_at"`
+	Permalink   string   `yaml:"permalink"`
+}
+
+// render returns the page content headed by the front matter of the page titled title, showing
+// snips, whose categories are categories, at permalink slug below the base ("" for the index).
+func (c *frontMatterConfig) render(title, slug string, categories []string, snips []snippet, content string) (string, error) {
+	if c == nil {
+		return content, nil
+	}
+	sources := make(map[string]bool)
+	for _, s := range snips {
+		sources[filepath.ToSlash(displayPath(s.File))] = true
+	}
+	permalink := "/" + strings.Trim(c.PermalinkBase, "/") + "/"
+	if permalink == "//" {
+		permalink = "/"
+	}
+	if slug != "" {
+		permalink += slug + "/"
+	}
+	data, err := yaml.Marshal(frontMatter{
+		Title:       title,
+		Categories:  categories,
+		SourceFiles: sortedKeys(sources),
+		GeneratedAt: c.Generated.Format(time.RFC3339),
+		Permalink:   permalink,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(data) + "---\n\n" + content, nil
+}
+
+// generateDocs writes index.md, SUMMARY.md, manifest.json and one page per domain to outDir, named
+// as pageSlugs does. When front is set, the index and domain pages start with front matter;
+// SUMMARY.md never does, as mdBook reads it as is.
+func generateDocs(snips []snippet, outDir, sourceURL string, front *frontMatterConfig) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	byDomain := groupByDomain(snips)
+	domains := sortedKeys(byDomain)
+
+	var index, summary strings.Builder
+	index.WriteString("# Snippet Index\n\n")
+	index.WriteString("| Domain | Categories | Snippets |\n|---|---|---|\n")
+	summary.WriteString("# Summary\n\n[Snippet Index](index.md)\n\n")
+
+	slugs := pageSlugs(domains)
+	for _, domain := range domains {
+		byCategory := byDomain[domain]
+		categories := sortedKeys(byCategory)
+		page := slugs[domain] + ".md"
+
+		count := 0
+		var pageSnips []snippet
+		for _, category := range categories {
+			count += len(byCategory[category])
+			pageSnips = append(pageSnips, byCategory[category]...)
+		}
+		index.WriteString(fmt.Sprintf("| [%s](%s) | %s | %d |\n", domain, page, strings.Join(categories, ", "), count))
+		summary.WriteString(fmt.Sprintf("- [%s](%s)\n", domain, page))
+
+		content, err := front.render(domain, slugs[domain], categories, pageSnips,
+			renderDomainPage(domain, byCategory, categories, outDir, sourceURL))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, page), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	allCategories := make(map[string]bool)
+	for _, s := range snips {
+		for category := range s.Categories {
+			allCategories[category] = true
+		}
+	}
+	indexContent, err := front.render("Snippet Index", "", sortedKeys(allCategories), snips, index.String())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "index.md"), []byte(indexContent), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "SUMMARY.md"), []byte(summary.String()), 0644); err != nil {
+		return err
+	}
+	return exportSnippets(snips, func(name string, content []byte) error {
+		return os.WriteFile(filepath.Join(outDir, name), content, 0644)
+	}, nil)
+}
+
+// reservedPages are the names of the files generateDocs writes besides the domain pages, without
+// their extension and in lower case, as case-insensitive file systems see them.
+var reservedPages = map[string]bool{"index": true, "summary": true, "manifest": true}
+
+// pageSlugs returns the name of the page of each of domains, without its extension: its slug, or
+// "domain" for a name without letters or digits, followed by -2, -3 and so on when a domain before
+// it or a reserved page has it already, as domains differing only in case or punctuation do.
+func pageSlugs(domains []string) map[string]string {
+	slugs := make(map[string]string, len(domains))
+	taken := make(map[string]bool)
+	for _, domain := range domains {
+		base := brio.Slugify(domain)
+		if base == "" {
+			base = "domain"
+		}
+		slug := base
+		for n := 2; taken[slug] || reservedPages[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[slug] = true
+		slugs[domain] = slug
+	}
+	return slugs
+}
+
+// renderDomainPage renders the page of one domain with a section per category.
+func renderDomainPage(domain string, byCategory map[string][]snippet, categories []string, outDir, sourceURL string) string {
+	var page strings.Builder
+	page.WriteString(fmt.Sprintf("# %s\n", domain))
+
+	for _, category := range categories {
+		page.WriteString(fmt.Sprintf("\n## %s\n", category))
+		for _, s := range byCategory[category] {
+			location := fmt.Sprintf("%s:%d-%d", brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, s.EndLine)
+			page.WriteString(fmt.Sprintf("\n### [%s](%s)\n\n", location, sourceLink(s, outDir, sourceURL)))
+			page.WriteString(fmt.Sprintf("```%s\n", s.Language()))
+			for _, line := range s.Content {
+				page.WriteString(line + "\n")
+			}
+			page.WriteString("```\n")
+		}
+	}
+
+	return page.String()
+}
+
+// groupByDomain indexes snippets by domain and then by category. A snippet appears once under
+// every domain/category pair it is tagged with; categories without domains go to the noDomain page.
+func groupByDomain(snips []snippet) map[string]map[string][]snippet {
+	byDomain := make(map[string]map[string][]snippet)
+	for _, s := range snips {
+		for category, domains := range s.Categories {
+			if len(domains) == 0 {
+				domains = []string{noDomain}
+			}
+			for _, domain := range domains {
+				if domain == "" {
+					domain = noDomain
+				}
+				if byDomain[domain] == nil {
+					byDomain[domain] = make(map[string][]snippet)
+				}
+				byDomain[domain][category] = append(byDomain[domain][category], s)
+			}
+		}
+	}
+	return byDomain
+}
+
+// sourceLink returns the URL of a snippet's source: below sourceURL when given, otherwise a path
+// relative to the generated pages.
+func sourceLink(s snippet, outDir, sourceURL string) string {
+	if sourceURL != "" {
+		if s.Section != "" {
+			// Line numbers are relative to the section, so they don't anchor into the file
+			return fmt.Sprintf("%s/%s", strings.TrimSuffix(sourceURL, "/"), filepath.ToSlash(displayPath(s.File)))
+		}
+		return fmt.Sprintf("%s/%s#L%d-L%d", strings.TrimSuffix(sourceURL, "/"),
+			filepath.ToSlash(displayPath(s.File)), s.StartLine, s.EndLine)
+	}
+
+	link := s.File
+	absFile, fileErr := filepath.Abs(s.File)
+	absOut, outErr := filepath.Abs(outDir)
+	if fileErr == nil && outErr == nil {
+		if rel, err := filepath.Rel(absOut, absFile); err == nil {
+			link = rel
+		}
+	}
+	return filepath.ToSlash(link)
+}
+
+// displayPath returns path relative to the current directory when it is inside it.
+func displayPath(path string) string {
+	return brio.RelativePath(path)
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	outDir := t.TempDir()
+	python, _ := plugins.Get(".py")
+
+	snips := []snippet{
+		{
+			File:       "app/models.py",
+			StartLine:  3,
+			EndLine:    6,
+			Categories: map[string][]string{"foundation": {"messages"}, "model": {"messages", "alerts"}},
+			Content:    []string{"class Message(TenantModel):", "    pass"},
+			Plugin:     python,
+		},
+		{
+			File:       "app/tools.py",
+			StartLine:  1,
+			EndLine:    3,
+			Categories: map[string][]string{"Helpers": {}},
+			Content:    []string{"helper = 1"},
+			Plugin:     python,
+		},
+	}
+
+	err := generateDocs(snips, outDir, "https://github.com/acme/app/blob/main/", nil)
+	assert.Nil(t, err)
+
+	for _, name := range []string{"index.md", "SUMMARY.md", "manifest.json", "messages.md", "alerts.md", "general.md"} {
+		assert.FileExists(t, filepath.Join(outDir, name))
+	}
+
+	page, err := os.ReadFile(filepath.Join(outDir, "messages.md"))
+	assert.Nil(t, err)
+	assert.Equal(t, "# messages\n"+
+		"\n## foundation\n"+
+		"\n### [app/models.py:3-6](https://github.com/acme/app/blob/main/app/models.py#L3-L6)\n\n"+
+		"```python\nclass Message(TenantModel):\n    pass\n```\n"+
+		"\n## model\n"+
+		"\n### [app/models.py:3-6](https://github.com/acme/app/blob/main/app/models.py#L3-L6)\n\n"+
+		"```python\nclass Message(TenantModel):\n    pass\n```\n", string(page))
+
+	index, err := os.ReadFile(filepath.Join(outDir, "index.md"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), "| [messages](messages.md) | foundation, model | 2 |")
+	assert.Contains(t, string(index), "| [general](general.md) | Helpers | 1 |")
+}
+
+func TestGenerateDocsFrontMatter(t *testing.T) {
+	outDir := t.TempDir()
+	python, _ := plugins.Get(".py")
+	snips := []snippet{
+		{File: "app/models.py", StartLine: 3, EndLine: 6, Content: []string{"pass"}, Plugin: python,
+			Categories: map[string][]string{"foundation": {"User Accounts"}, "model": {"User Accounts"}}},
+		{File: "app/views.py", StartLine: 1, EndLine: 3, Content: []string{"pass"}, Plugin: python,
+			Categories: map[string][]string{"tests": {}}},
+	}
+	front := &frontMatterConfig{PermalinkBase: "docs/snippets", Generated: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	assert.Nil(t, generateDocs(snips, outDir, "", front))
+
+	page, err := os.ReadFile(filepath.Join(outDir, "user-accounts.md"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(page), "---\n"+
+		"title: User Accounts\n"+
+		"categories:\n    - foundation\n    - model\n"+
+		"source_files:\n    - app/models.py\n"+
+		"generated_at: \"2026-03-01T12:00:00Z\"\n"+
+		"permalink: /docs/snippets/user-accounts/\n"+
+		"---\n\n# User Accounts\n"), string(page))
+
+	index, err := os.ReadFile(filepath.Join(outDir, "index.md"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), "categories:\n    - foundation\n    - model\n    - tests\n")
+	assert.Contains(t, string(index), "permalink: /docs/snippets/\n---\n\n# Snippet Index\n")
+
+	summary, err := os.ReadFile(filepath.Join(outDir, "SUMMARY.md"))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(summary), "# Summary\n"))
+}
+
+func TestPageSlugs(t *testing.T) {
+	slugs := pageSlugs([]string{"Auth", "auth", "AUTH!", "???", "!!!", "Index", "summary", "Manifest", "domain"})
+	assert.Equal(t, map[string]string{
+		"Auth":     "auth",
+		"auth":     "auth-2",
+		"AUTH!":    "auth-3",
+		"???":      "domain",
+		"!!!":      "domain-2",
+		"Index":    "index-2",
+		"summary":  "summary-2",
+		"Manifest": "manifest-2",
+		"domain":   "domain-3",
+	}, slugs)
+}
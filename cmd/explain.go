@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// explainFileFlag and explainLineFlag locate the snippet explain looks at.
+var (
+	explainFileFlag string
+	explainLineFlag int
+)
+
+// explainCmd tells why a snippet is extracted or not.
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show why the snippet at a line of a file is extracted for the categories given, or not",
+	Long: `Explain looks at the snippets spanning a line of a file and shows, for each, the
+tag brio parsed, the categories and attributes it got from it, the categories
+requested with --categories, and whether extract would take the snippet and why:
+category by category, which requested domains it was tagged with, if any.
+It then runs the processors of the config file (and --with-imports) on it and
+checks it against --owner, owners coming from CODEOWNERS too, --path-filter,
+--min-lines and --max-snippet-lines, as extract does. The token limits of
+max_tokens_per_category aren't checked, as they depend on the other snippets
+extracted, nor --since, and transforms aren't run.
+
+When no snippet spans the line, the nearest snippets before and after it are
+shown instead, with the problems found in the annotations of the file, such as
+a start tag never closed.
+Usage example:
+brio explain --file src/models.py --line 57 -c "messages:foundation"
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if explainFileFlag == "" || explainLineFlag < 1 {
+			fatalf("--file and a --line of 1 or more are required")
+		}
+		if pathFilterArg != "" {
+			var err error
+			if pathFilter, err = regexp.Compile(pathFilterArg); err != nil {
+				fatalf("Invalid --path-filter: %v", err)
+			}
+		}
+		if err := explainLine(cmd.Context(), os.Stdout, explainFileFlag, explainLineFlag, categoriesArg); err != nil {
+			fatalf("Error explaining %s: %v", explainFileFlag, err)
+		}
+	},
+}
+
+// init registers explainCmd and its flags.
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVar(&explainFileFlag, "file", "", "File holding the snippet")
+	explainCmd.Flags().IntVar(&explainLineFlag, "line", 0, "Line of the snippet, from its start tag to its end tag")
+	explainCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Comma-separated list of domain:category pairs (e.g., messages:foundation,tests)")
+	explainCmd.Flags().BoolVar(&excludeExpired, "exclude-expired", false,
+		"Explain as extract --exclude-expired does, leaving out snippets whose _expires date has passed")
+	explainCmd.Flags().StringVar(&ownerArg, "owner", "",
+		"Explain as extract --owner does, for these comma-separated owners")
+	explainCmd.Flags().StringVar(&pathFilterArg, "path-filter", "",
+		"Explain as extract --path-filter does, for this regular expression")
+	explainCmd.Flags().IntVar(&minLinesFlag, "min-lines", 0,
+		"Explain as extract --min-lines does, leaving out snippets of fewer lines of content")
+	explainCmd.Flags().IntVar(&maxSnippetLinesFlag, "max-snippet-lines", 0,
+		"Explain as extract --max-snippet-lines does, leaving out snippets of more lines of content")
+	explainCmd.Flags().BoolVar(&withImportsFlag, "with-imports", false,
+		"Explain as extract --with-imports does, counting the imports prepended to snippets in their lines")
+}
+
+// explainLine writes to w why the snippets of filePath spanning line are extracted for categories,
+// written like --categories, or not; or the nearest ones and the problems of the file if none does.
+func explainLine(ctx context.Context, w io.Writer, filePath string, line int, categories string) error {
+	catMap := brio.ParseCategories(categories)
+	e := brio.New(cfg.options())
+	plugin, how, ok := e.Plugin(filePath)
+	if !ok {
+		return fmt.Errorf("no plugin handles %s", displayPath(filePath))
+	}
+	snips, issues, err := e.ScanFile(filePath)
+	if err != nil {
+		return err
+	}
+	virtual, virtualIssues := e.VirtualSnippets(ctx, filePath)
+	sidecar, sidecarIssues := e.SidecarSnippets(filePath)
+	issues = append(append(issues, virtualIssues...), sidecarIssues...)
+	var lines []string
+	if content, err := brio.ReadFile(filePath); err == nil {
+		lines = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	}
+	owners, err := loadCodeOwners(filepath.Dir(filePath))
+	if err != nil {
+		fmt.Fprintf(w, "Failed to read CODEOWNERS: %v\n", err)
+	}
+
+	fmt.Fprintf(w, "%s: %s plugin, by %s\n", displayPath(filePath), plugin.GetMarkdownIdentifier(), how)
+	if len(catMap) == 0 {
+		fmt.Fprintln(w, "Requested: every category (no --categories)")
+	} else {
+		fmt.Fprintf(w, "Requested: %q, that is %s\n", categories, jsonText(catMap))
+	}
+
+	var spanning, before, after []snippet
+	for _, s := range snips {
+		switch {
+		case s.StartLine <= line && line <= s.EndLine:
+			spanning = append(spanning, s)
+		case s.EndLine < line && s.Section == "":
+			before = []snippet{s}
+		case s.StartLine > line && s.Section == "" && after == nil:
+			after = []snippet{s}
+		}
+	}
+	// Snippets declared from outside of the file come last.
+	origins := make([]string, len(spanning))
+	for _, s := range virtual {
+		if s.StartLine <= line && line <= s.EndLine {
+			spanning = append(spanning, s)
+			origins = append(origins, "virtual_snippets of the config file")
+		}
+	}
+	for _, s := range sidecar {
+		spanning = append(spanning, s)
+		origins = append(origins, "a "+brio.SidecarFile+" file, for the whole file")
+	}
+
+	if len(spanning) == 0 {
+		fmt.Fprintf(w, "\nNo snippet spans line %d.\n", line)
+		for _, s := range append(before, after...) {
+			fmt.Fprintf(w, "\nNearest snippet, lines %d-%d:\n", s.StartLine, s.EndLine)
+			explainSnippet(w, s, lines, "", catMap, owners)
+		}
+		if len(issues) > 0 {
+			fmt.Fprintf(w, "\nProblems in the annotations of the file:\n")
+			for _, i := range issues {
+				fmt.Fprintf(w, "  %s\n", i)
+			}
+		}
+		return nil
+	}
+	for i, s := range spanning {
+		fmt.Fprintf(w, "\nSnippet %s, lines %d-%d:\n", brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, s.EndLine)
+		explainSnippet(w, s, lines, origins[i], catMap, owners)
+	}
+	return nil
+}
+
+// explainSnippet writes the tag of s, read from the lines of its file, what it was parsed into and
+// whether s matches catMap, then whether extract would keep it: after its processors, and with its
+// owners among owners, the filters of the flags. Snippets declared from outside of the file, in
+// origin, have no tag in it.
+func explainSnippet(w io.Writer, s snippet, lines []string, origin string, catMap map[string][]string, owners *codeOwners) {
+	switch {
+	case origin != "":
+		fmt.Fprintf(w, "  Tag: none, declared in %s\n", origin)
+	case s.Section != "":
+		// Lines count from the start of the section; the raw tags aren't looked up.
+	case s.StartLine <= len(lines) && s.EndLine <= len(lines):
+		fmt.Fprintf(w, "  Start tag (line %d): %s\n", s.StartLine, strings.TrimSpace(lines[s.StartLine-1]))
+		fmt.Fprintf(w, "  End tag (line %d): %s\n", s.EndLine, strings.TrimSpace(lines[s.EndLine-1]))
+	}
+	fmt.Fprintf(w, "  Categories: %s\n", jsonText(s.Categories))
+	if len(s.Attrs) > 0 {
+		fmt.Fprintf(w, "  Attributes: %s\n", jsonText(s.Attrs))
+	}
+
+	if len(catMap) == 0 {
+		fmt.Fprintln(w, "  Matches: yes, every snippet does when no category is requested")
+	} else {
+		for _, category := range sortedKeys(s.Categories) {
+			requested, ok := catMap[category]
+			fmt.Fprintf(w, "  - %s: %s\n", category, explainCategory(s.Categories[category], requested, ok))
+		}
+		if len(s.Categories) == 0 {
+			fmt.Fprintln(w, "  - the tag gives no category")
+		}
+		if s.Matches(catMap) {
+			fmt.Fprintln(w, "  Matches: yes")
+		} else {
+			fmt.Fprintln(w, "  Matches: no, none of its categories is requested with one of its domains")
+			return
+		}
+	}
+
+	warning := ""
+	if i, expired := s.ExpiryIssue(now()); expired {
+		if excludeExpired {
+			fmt.Fprintf(w, "  Extracted: no, left out by --exclude-expired (%s)\n", i.Message)
+			return
+		}
+		warning = fmt.Sprintf(", with a warning (%s)", i.Message)
+	}
+	if reason := explainFilters(s, owners); reason != "" {
+		fmt.Fprintf(w, "  Extracted: no, %s\n", reason)
+		return
+	}
+	fmt.Fprintf(w, "  Extracted: yes%s\n", warning)
+	fmt.Fprintln(w, "  Not checked: the token limits of max_tokens_per_category, --since and the transforms of the config file")
+}
+
+// explainFilters runs the processors of extract on s, then its filters, as filterSnippets does with
+// the owners of s resolved from owners. It returns why s is left out, or "" if it is kept.
+func explainFilters(s snippet, owners *codeOwners) string {
+	kept, err := brio.Chain(snippetProcessors(cfg.options())).Apply([]snippet{s})
+	if err != nil {
+		return fmt.Sprintf("a processor failed: %v", err)
+	}
+	if len(kept) == 0 {
+		return "dropped by a processor"
+	}
+	assignOwners(kept, owners)
+	s = kept[0]
+	switch {
+	case ownerArg != "" && len(filterByOwner(kept, ownerArg)) == 0:
+		owned := "no one"
+		if len(s.Attrs["owner"]) > 0 {
+			owned = strings.Join(s.Attrs["owner"], ", ")
+		}
+		return fmt.Sprintf("left out by --owner, as it is owned by %s", owned)
+	case pathFilter != nil && len(filterByPath(kept, pathFilter)) == 0:
+		return fmt.Sprintf("left out by --path-filter, which %s doesn't match", filepath.ToSlash(s.File))
+	case len(filterBySize(kept, minLinesFlag, maxSnippetLinesFlag)) == 0:
+		return fmt.Sprintf("left out by --min-lines or --max-snippet-lines, with %d lines of content", len(s.Content))
+	}
+	return ""
+}
+
+// explainCategory tells whether a category a snippet is tagged with, for domains, is requested, for
+// the requested domains, following the rules of Snippet.Matches.
+func explainCategory(domains, requested []string, isRequested bool) string {
+	if !isRequested {
+		return "not requested"
+	}
+	if len(requested) == 0 || slices.Contains(requested, "") {
+		return "requested for any domain"
+	}
+	for _, d := range domains {
+		if slices.Contains(requested, d) {
+			return fmt.Sprintf("requested for domain %s, which the snippet is tagged with", d)
+		}
+	}
+	tagged := "no domain"
+	if len(domains) > 0 {
+		tagged = strings.Join(domains, ", ")
+	}
+	label := "domain"
+	if len(requested) > 1 {
+		label = "domains"
+	}
+	return fmt.Sprintf("requested for %s %s, but the snippet is tagged with %s", label, strings.Join(requested, ", "), tagged)
+}
+
+// jsonText returns v as compact JSON, keys sorted, as tags are written.
+func jsonText(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainLine(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	tempDir := t.TempDir()
+	content := `# >: {"foundation": ["billing"], "tests": [], "_expires": "2025-06-01"}
+class Invoice:
+    pass
+# <: {"foundation": ["billing"]}
+
+def helper():
+    pass
+
+# >: {"foundation": ["messages"]}
+class Queue:
+`
+	filePath := filepath.Join(tempDir, "models.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	var out strings.Builder
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 2, "messages:foundation"))
+	assert.Contains(t, out.String(), `Requested: "messages:foundation", that is {"foundation":["messages"]}`)
+	assert.Contains(t, out.String(), `Start tag (line 1): # >: {"foundation": ["billing"], "tests": [], "_expires": "2025-06-01"}`)
+	assert.Contains(t, out.String(), `Categories: {"foundation":["billing"],"tests":[]}`)
+	assert.Contains(t, out.String(), `Attributes: {"expires":["2025-06-01"]}`)
+	assert.Contains(t, out.String(), "- foundation: requested for domain messages, but the snippet is tagged with billing\n")
+	assert.Contains(t, out.String(), "- tests: not requested\n")
+	assert.Contains(t, out.String(), "Matches: no")
+
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 3, "billing:foundation"))
+	assert.Contains(t, out.String(), "- foundation: requested for domain billing, which the snippet is tagged with\n")
+	assert.Contains(t, out.String(), "Matches: yes\n")
+	assert.Contains(t, out.String(), "Extracted: yes, with a warning")
+
+	excludeExpired = true
+	defer func() { excludeExpired = false }()
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 3, "tests"))
+	assert.Contains(t, out.String(), "- tests: requested for any domain\n")
+	assert.Contains(t, out.String(), "Extracted: no, left out by --exclude-expired")
+
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 10, ""))
+	assert.Contains(t, out.String(), "No snippet spans line 10.")
+	assert.Contains(t, out.String(), "Nearest snippet, lines 1-4:")
+	assert.Contains(t, out.String(), "BRIO001")
+}
+
+func TestExplainLineFilters(t *testing.T) {
+	defer func() { ownerArg, pathFilter, minLinesFlag = "", nil, 0 }()
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "CODEOWNERS"), []byte("*.py @billing-team\n"), 0644))
+	filePath := filepath.Join(tempDir, "models.py")
+	require.NoError(t, os.WriteFile(filePath, []byte("# >: {\"foundation\": []}\nclass Invoice:\n    pass\n# <: {\"foundation\": []}\n"), 0644))
+
+	var out strings.Builder
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 2, "foundation"))
+	assert.Contains(t, out.String(), "Extracted: yes\n  Not checked: the token limits of max_tokens_per_category")
+
+	ownerArg = "@messages-team"
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 2, "foundation"))
+	assert.Contains(t, out.String(), "Extracted: no, left out by --owner, as it is owned by @billing-team\n")
+	ownerArg = "@billing-team"
+
+	pathFilter = regexp.MustCompile(`^services/`)
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 2, "foundation"))
+	assert.Contains(t, out.String(), "Extracted: no, left out by --path-filter")
+	pathFilter = nil
+
+	minLinesFlag = 3
+	out.Reset()
+	require.NoError(t, explainLine(context.Background(), &out, filePath, 2, "foundation"))
+	assert.Contains(t, out.String(), "Extracted: no, left out by --min-lines or --max-snippet-lines, with 2 lines of content\n")
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bundlePath is the zip file export writes.
+// rawFlag adds the content of each snippet to the bundle as a file of its own.
+// exportDir, when set, is the directory export writes each snippet to instead of a bundle.
+// runCommand and runParameters are the command and the flags set for the run, for manifests.
+var (
+	bundlePath    string
+	rawFlag       bool
+	exportDir     string
+	runCommand    string
+	runParameters map[string]string
+)
+
+// exportCmd packs extraction results into a zip bundle that can be attached to a ticket.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export extracted snippets as a zip bundle or as individual files",
+	Long: `Export writes a zip bundle of the snippets matching --categories, to attach the
+full context to a ticket or share it with auditors. The bundle holds:
+
+snippets.md     the snippets rendered as by brio extract
+manifest.json   the metadata of every snippet: file, lines, categories, attributes,
+                hash and estimated tokens, along with the flags of the run
+snippets/       with --raw, the content of each snippet as a file of its own
+
+With --output, each snippet is written to a file of its own in a directory that
+mirrors the source tree instead (src/models.py.snippet-1.py), next to manifest.json.
+
+Usage example:
+brio export --bundle context.zip --categories "messages:foundation,tests" --raw
+brio export --output ./out --categories "tests"
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd.Flags().Changed("bundle") && exportDir != "" {
+			fatalf("--bundle and --output can't be used together")
+		}
+		var bucket *brio.Bucket
+		if uploadFlag != "" {
+			bucket = openUpload()
+		}
+		catMap := brio.ParseCategories(categoriesArg)
+		recordRun(cmd)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		if exportDir != "" {
+			if err := writeSnippetFiles(snips, exportDir, dirFlag); err != nil {
+				fatalf("Error writing snippets: %v", err)
+			}
+			fmt.Printf("Exported %d snippets to %s\n", len(snips), exportDir)
+			if bucket != nil {
+				if err := uploadDir(cmd.Context(), bucket, exportDir); err != nil {
+					fatalf("Error uploading snippets: %v", err)
+				}
+			}
+			return
+		}
+		if err := writeBundle(snips, bundlePath, rawFlag); err != nil {
+			fatalf("Error writing bundle: %v", err)
+		}
+		fmt.Printf("Exported %d snippets to %s\n", len(snips), bundlePath)
+		if bucket != nil {
+			if err := uploadFile(cmd.Context(), bucket, bundlePath); err != nil {
+				fatalf("Error uploading bundle: %v", err)
+			}
+		}
+	},
+}
+
+// init registers exportCmd and its flags.
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	exportCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	exportCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to export, e.g. 'messages:foundation,tests' (default: all)")
+	exportCmd.Flags().StringVar(&bundlePath, "bundle", "brio-bundle.zip", "Zip file to write")
+	exportCmd.Flags().BoolVar(&rawFlag, "raw", false, "Also add the content of each snippet as a file of its own")
+	exportCmd.Flags().StringVarP(&exportDir, "output", "o", "",
+		"Write each snippet to a file in this directory, mirroring the source tree, instead of a bundle")
+	exportCmd.Flags().StringVar(&uploadFlag, "upload", "", uploadUsage("the bundle, or the files of --output,"))
+}
+
+// recordRun records the command line of cmd for the manifests it writes.
+func recordRun(cmd *cobra.Command) {
+	runCommand = cmd.CommandPath()
+	runParameters = make(map[string]string)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		runParameters[f.Name] = f.Value.String()
+	})
+}
+
+// bundleManifest is the manifest.json of a bundle, an --output directory or generated docs.
+type bundleManifest struct {
+	Generated  time.Time `json:"generated"`
+	Command    string    `json:"command,omitempty"`
+	Categories string    `json:"categories,omitempty"`
+	// Parameters are the flags set on the command line, by name.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Snippets   []bundledSnippet  `json:"snippets"`
+}
+
+// bundledSnippet describes an exported snippet.
+type bundledSnippet struct {
+	File       string              `json:"file"`
+	Section    string              `json:"section,omitempty"`
+	StartLine  int                 `json:"start_line"`
+	EndLine    int                 `json:"end_line"`
+	Language   string              `json:"language"`
+	Categories map[string][]string `json:"categories"`
+	Attrs      map[string][]string `json:"attrs,omitempty"`
+	// Hash is the hash of the content, as recorded by "_hash" (see brio annotate).
+	Hash string `json:"hash"`
+	// Tokens estimates the tokens the snippet takes rendered as Markdown, as --split-tokens does.
+	Tokens int `json:"tokens"`
+	// Raw is the path of the snippet's content in the bundle with --raw, or in the --output directory.
+	Raw string `json:"raw,omitempty"`
+}
+
+// writeBundle writes snips to a zip file at path: rendered, described in a manifest, and with raw
+// set, each as a file of its own.
+func writeBundle(snips []snippet, path string, raw bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+
+	addFile := func(name string, content []byte) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now()})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+	var nameOf func(int, snippet) string
+	if raw {
+		nameOf = rawSnippetName
+	}
+	err = exportSnippets(snips, addFile, nameOf)
+	if err == nil {
+		err = addFile("snippets.md", []byte(brio.RenderMarkdown(snips)))
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeSnippetFiles writes each of snips to a file of its own below outDir, at the path of its
+// source relative to srcDir, along with a manifest.
+func writeSnippetFiles(snips []snippet, outDir, srcDir string) error {
+	addFile := func(name string, content []byte) error {
+		path := filepath.Join(outDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, content, 0644)
+	}
+
+	// Snippets are numbered within their file.
+	counts := make(map[string]int)
+	nameOf := func(_ int, s snippet) string {
+		counts[s.File]++
+		return fmt.Sprintf("%s.snippet-%d%s", sourcePath(s.File, srcDir), counts[s.File], snippetExt(s))
+	}
+	return exportSnippets(snips, addFile, nameOf)
+}
+
+// exportSnippets passes manifest.json to addFile, and when nameOf is set, the content of each
+// snippet under the name it returns.
+func exportSnippets(snips []snippet, addFile func(name string, content []byte) error, nameOf func(int, snippet) string) error {
+	manifest := bundleManifest{
+		Generated:  now().UTC(),
+		Command:    runCommand,
+		Categories: categoriesArg,
+		Parameters: runParameters,
+		Snippets:   []bundledSnippet{},
+	}
+	for i, s := range snips {
+		entry := bundledSnippet{
+			File:       filepath.ToSlash(displayPath(s.File)),
+			Section:    s.Section,
+			StartLine:  s.StartLine,
+			EndLine:    s.EndLine,
+			Language:   s.Language(),
+			Categories: s.Categories,
+			Attrs:      s.Attrs,
+			Hash:       s.Hash(),
+			Tokens:     estimateTokens(brio.RenderMarkdown([]snippet{s})),
+		}
+		if nameOf != nil {
+			entry.Raw = nameOf(i, s)
+			if err := addFile(entry.Raw, []byte(strings.Join(s.Content, "\n")+"\n")); err != nil {
+				return err
+			}
+		}
+		manifest.Snippets = append(manifest.Snippets, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return addFile("manifest.json", append(data, '\n'))
+}
+
+// rawSnippetName returns the path in the bundle of the content of the i-th snippet: numbered so
+// that names never collide, and named after its file. Snippets of a section (e.g. a notebook cell)
+// take the extension of their language.
+func rawSnippetName(i int, s snippet) string {
+	name := filepath.Base(s.File)
+	if s.Section != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + "-" + brio.Slugify(s.Section) + snippetExt(s)
+	}
+	return fmt.Sprintf("snippets/%03d-%s", i+1, name)
+}
+
+// snippetExt returns the file extension of the language of s.
+func snippetExt(s snippet) string {
+	if s.Plugin != nil {
+		if exts := s.Plugin.GetExtensions(); len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	if ext := filepath.Ext(s.File); ext != "" {
+		return ext
+	}
+	return ".txt"
+}
+
+// sourcePath returns the slash-separated path of file relative to srcDir, or relative to the
+// current directory if it isn't below srcDir.
+func sourcePath(file, srcDir string) string {
+	if rel, err := filepath.Rel(srcDir, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	return strings.TrimLeft(filepath.ToSlash(displayPath(file)), "/")
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBundle(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	snips := []snippet{
+		{
+			File:       "app/models.py",
+			StartLine:  3,
+			EndLine:    6,
+			Categories: map[string][]string{"foundation": {"messages"}},
+			Attrs:      map[string][]string{"owner": {"@core"}},
+			Content:    []string{"class Message:", "    pass"},
+			Plugin:     python,
+		},
+		{
+			File:       "notebooks/churn.ipynb",
+			Section:    "cell 3",
+			StartLine:  1,
+			EndLine:    3,
+			Categories: map[string][]string{"tests": {}},
+			Content:    []string{"churn = 1"},
+			Plugin:     python,
+		},
+	}
+
+	oldCommand, oldParameters := runCommand, runParameters
+	defer func() { runCommand, runParameters = oldCommand, oldParameters }()
+	runCommand, runParameters = "brio export", map[string]string{"raw": "true", "categories": "messages:foundation"}
+
+	bundle := filepath.Join(t.TempDir(), "out.zip")
+	assert.Nil(t, writeBundle(snips, bundle, true))
+
+	zr, err := zip.OpenReader(bundle)
+	assert.Nil(t, err)
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		assert.Nil(t, err)
+		content, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	assert.Equal(t, "class Message:\n    pass\n", files["snippets/001-models.py"])
+	assert.Equal(t, "churn = 1\n", files["snippets/002-churn-cell-3.py"])
+	assert.Contains(t, files["snippets.md"], "class Message:")
+
+	var manifest bundleManifest
+	assert.Nil(t, json.Unmarshal([]byte(files["manifest.json"]), &manifest))
+	assert.Len(t, manifest.Snippets, 2)
+	assert.Equal(t, bundledSnippet{
+		File:       "app/models.py",
+		StartLine:  3,
+		EndLine:    6,
+		Language:   "python",
+		Categories: map[string][]string{"foundation": {"messages"}},
+		Attrs:      map[string][]string{"owner": {"@core"}},
+		Hash:       snips[0].Hash(),
+		Tokens:     estimateTokens(brio.RenderMarkdown(snips[:1])),
+		Raw:        "snippets/001-models.py",
+	}, manifest.Snippets[0])
+	assert.Equal(t, "brio export", manifest.Command)
+	assert.Equal(t, map[string]string{"raw": "true", "categories": "messages:foundation"}, manifest.Parameters)
+	assert.Equal(t, "cell 3", manifest.Snippets[1].Section)
+
+	assert.Nil(t, writeBundle(snips, bundle, false))
+	zr2, err := zip.OpenReader(bundle)
+	assert.Nil(t, err)
+	defer zr2.Close()
+	assert.Len(t, zr2.File, 2)
+}
+
+func TestWriteSnippetFiles(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	srcDir := t.TempDir()
+	models := filepath.Join(srcDir, "src", "models.py")
+	snips := []snippet{
+		{File: models, StartLine: 1, EndLine: 3, Content: []string{"first = 1"}, Plugin: python},
+		{File: models, StartLine: 5, EndLine: 7, Content: []string{"second = 2"}, Plugin: python},
+		{File: filepath.Join(srcDir, "nb", "churn.ipynb"), Section: "cell 2", StartLine: 1, EndLine: 3, Content: []string{"churn = 1"}, Plugin: python},
+	}
+
+	outDir := t.TempDir()
+	assert.Nil(t, writeSnippetFiles(snips, outDir, srcDir))
+
+	for name, want := range map[string]string{
+		"src/models.py.snippet-1.py":  "first = 1\n",
+		"src/models.py.snippet-2.py":  "second = 2\n",
+		"nb/churn.ipynb.snippet-1.py": "churn = 1\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		assert.Nil(t, err, name)
+		assert.Equal(t, want, string(content))
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "manifest.json"))
+	assert.Nil(t, err)
+	var manifest bundleManifest
+	assert.Nil(t, json.Unmarshal(data, &manifest))
+	assert.Len(t, manifest.Snippets, 3)
+	assert.Equal(t, "src/models.py.snippet-2.py", manifest.Snippets[1].Raw)
+}
+
+func TestRecordRun(t *testing.T) {
+	oldCommand, oldParameters := runCommand, runParameters
+	defer func() { runCommand, runParameters = oldCommand, oldParameters }()
+
+	var dir string
+	var raw bool
+	cmd := &cobra.Command{Use: "export"}
+	cmd.Flags().StringVar(&dir, "dir", ".", "")
+	cmd.Flags().BoolVar(&raw, "raw", false, "")
+	assert.Nil(t, cmd.ParseFlags([]string{"--raw"}))
+
+	recordRun(cmd)
+	assert.Equal(t, "export", runCommand)
+	assert.Equal(t, map[string]string{"raw": "true"}, runParameters)
+}
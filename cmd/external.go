@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// registerExternalPlugins registers the brio-plugin-* executables found on $PATH. Plugins that
+// fail to describe themselves are skipped with a warning rather than stopping brio.
+func registerExternalPlugins() {
+	for _, path := range plugins.DiscoverExternal(os.Getenv("PATH")) {
+		p, err := plugins.LoadExternal(path)
+		if err != nil {
+			log.Printf("Skipping plugin %s: %v", path, err)
+			continue
+		}
+		plugins.RegisterFrom(p, plugins.OriginExternal)
+	}
+}
+
+// registerWASMPlugins registers the WASM plugin modules in dir, skipping those that fail to load.
+func registerWASMPlugins(dir string) {
+	for _, path := range plugins.DiscoverWASM(dir) {
+		p, err := plugins.LoadWASM(path)
+		if err != nil {
+			log.Printf("Skipping plugin %s: %v", path, err)
+			continue
+		}
+		plugins.RegisterFrom(p, plugins.OriginWASM)
+	}
+}
+
+// registerNativePlugins loads the Go plugins (.so files) in dir. Plugins either register themselves
+// when loaded or export a Plugin variable, which is registered here.
+func registerNativePlugins(dir string) {
+	paths := plugins.DiscoverNative(dir)
+	if len(paths) > 0 && !plugins.NativeSupported {
+		log.Printf("Skipping the .so plugins in %s: native plugins are only supported on Linux and macOS", dir)
+		return
+	}
+	for _, path := range paths {
+		loaded, err := plugins.LoadNative(path)
+		if err != nil {
+			log.Printf("Skipping plugin %s: %v", path, err)
+			continue
+		}
+		for _, p := range loaded {
+			plugins.RegisterFrom(p, plugins.OriginNative)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePlugin writes an executable shell script answering the describe and parse requests.
+func writePlugin(t *testing.T, dir, name, describe, parse string) string {
+	script := `#!/bin/sh
+request=$(cat)
+case "$request" in
+*'"method":"describe"'*) echo '` + describe + `' ;;
+*'"method":"parse"'*) echo '` + parse + `' ;;
+esac
+`
+	path := filepath.Join(dir, name)
+	assert.Nil(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestExternalPlugins(t *testing.T) {
+	binDir := t.TempDir()
+	srcDir := t.TempDir()
+
+	writePlugin(t, binDir, "brio-plugin-zed",
+		`{"protocol": 1, "name": "Zed", "extensions": [".zz"], "comments": {"single": ["%%"]}, "markdown": "zed"}`, "")
+	writePlugin(t, binDir, "brio-plugin-notebook",
+		`{"protocol": 1, "name": "Notebook", "extensions": [".nb"], "capabilities": ["parse"]}`,
+		`{"snippets": [{"start_line": 2, "end_line": 4, "tags": {"docs": ["intro"], "_owner": ["@docs"]}, "content": ["print(1)"]}], "issues": [{"line": 7, "message": "cell is not tagged"}]}`)
+	writePlugin(t, binDir, "brio-plugin-future", `{"protocol": 99, "name": "Future", "extensions": [".ft"]}`, "")
+	assert.Nil(t, os.WriteFile(filepath.Join(binDir, "brio-plugin-disabled"), []byte("#!/bin/sh\n"), 0644))
+
+	found := plugins.DiscoverExternal(binDir)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(binDir, "brio-plugin-future"),
+		filepath.Join(binDir, "brio-plugin-notebook"),
+		filepath.Join(binDir, "brio-plugin-zed"),
+	}, found)
+
+	_, err := plugins.LoadExternal(filepath.Join(binDir, "brio-plugin-future"))
+	assert.EqualError(t, err, "speaks protocol version 99, brio expects 1")
+
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	registerExternalPlugins()
+
+	zedPath := filepath.Join(srcDir, "rules.zz")
+	assert.Nil(t, os.WriteFile(zedPath, []byte("%% >: {\"rules\": []}\nallow all\n%% <: {\"rules\": []}\n"), 0644))
+	snips, issues, err := scanFile(zedPath)
+	assert.Nil(t, err)
+	assert.Empty(t, issues)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"allow all"}, snips[0].Content)
+	assert.Equal(t, "zed", snips[0].Language())
+
+	nbPath := filepath.Join(srcDir, "intro.nb")
+	assert.Nil(t, os.WriteFile(nbPath, []byte("{}"), 0644))
+	snips, issues, err = scanFile(nbPath)
+	assert.Nil(t, err)
+	assert.Equal(t, []snippet{{
+		File:       nbPath,
+		StartLine:  2,
+		EndLine:    4,
+		Categories: map[string][]string{"docs": {"intro"}},
+		Attrs:      map[string][]string{"owner": {"@docs"}},
+		Content:    []string{"print(1)"},
+		Plugin:     snips[0].Plugin,
+	}}, snips)
+	assert.Equal(t, []issue{{File: nbPath, Line: 7, Code: brio.CodePluginIssue, Message: "cell is not tagged"}}, issues)
+}
+
+// wasmPluginSource is a WASI plugin that describes itself and finds snippets between "@@" lines.
+const wasmPluginSource = `package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+func main() {
+	var req struct {
+		Method  string
+		Content string
+	}
+	json.NewDecoder(os.Stdin).Decode(&req)
+
+	if req.Method == "describe" {
+		os.Stdout.WriteString(` + "`" + `{"protocol": 1, "name": "Wiki", "extensions": [".wiki"], "markdown": "wiki", "capabilities": ["parse"]}` + "`" + `)
+		return
+	}
+	start := 0
+	for i, line := range strings.Split(req.Content, "\n") {
+		if line == "@@" && start == 0 {
+			start = i + 1
+		} else if line == "@@" {
+			json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"snippets": []interface{}{
+				map[string]interface{}{"start_line": start, "end_line": i + 1, "tags": map[string][]string{"wiki": {}}},
+			}})
+			return
+		}
+	}
+	os.Stdout.WriteString("{}")
+}
+`
+
+func TestWASMPlugins(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("building the test plugin needs the go tool")
+	}
+
+	buildDir := t.TempDir()
+	pluginDir := t.TempDir()
+	srcDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(buildDir, "main.go"), []byte(wasmPluginSource), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(buildDir, "go.mod"), []byte("module wikiplugin\n\ngo 1.21\n"), 0644))
+
+	build := exec.Command(goTool, "build", "-o", filepath.Join(pluginDir, "wiki.wasm"), ".")
+	build.Dir = buildDir
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	output, err := build.CombinedOutput()
+	assert.Nil(t, err, string(output))
+
+	assert.Equal(t, []string{filepath.Join(pluginDir, "wiki.wasm")}, plugins.DiscoverWASM(pluginDir))
+	registerWASMPlugins(pluginDir)
+
+	wikiPath := filepath.Join(srcDir, "home.wiki")
+	assert.Nil(t, os.WriteFile(wikiPath, []byte("intro\n@@\nbody\n@@\n"), 0644))
+	snips, _, err := scanFile(wikiPath)
+	assert.Nil(t, err)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, 2, snips[0].StartLine)
+	assert.Equal(t, 4, snips[0].EndLine)
+	assert.Equal(t, "wiki", snips[0].Language())
+}
+
+func TestNativePlugins(t *testing.T) {
+	pluginDir := t.TempDir()
+	brokenPath := filepath.Join(pluginDir, "broken.so")
+	assert.Nil(t, os.WriteFile(brokenPath, []byte("not a shared object"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(pluginDir, "README.md"), []byte("plugins"), 0644))
+
+	assert.Equal(t, []string{brokenPath}, plugins.DiscoverNative(pluginDir))
+
+	_, err := plugins.LoadNative(brokenPath)
+	assert.NotNil(t, err)
+
+	// A plugin that can't be loaded is skipped.
+	registerNativePlugins(pluginDir)
+}
+
+func TestPluginDirs(t *testing.T) {
+	wasm, native := defaultConfig().pluginDirs()
+	assert.Equal(t, defaultPluginDir, wasm)
+	assert.Equal(t, "", native, "Go plugins are never loaded from the default directory")
+
+	wasm, native = config{PluginDir: "ci/plugins"}.pluginDirs()
+	assert.Equal(t, "ci/plugins", wasm)
+	assert.Equal(t, "", native, "Go plugins are never loaded from plugin_dir")
+
+	wasm, native = config{PluginDir: "ci/plugins", nativePluginDir: "ci/plugins"}.pluginDirs()
+	assert.Equal(t, "ci/plugins", wasm)
+	assert.Equal(t, "ci/plugins", native)
+}
+
+func TestConfigPluginDirSkipsNativePlugins(t *testing.T) {
+	pluginDir := t.TempDir()
+	brokenPath := filepath.Join(pluginDir, "broken.so")
+	assert.Nil(t, os.WriteFile(brokenPath, []byte("not a shared object"), 0644))
+	configPath := filepath.Join(t.TempDir(), ".brio.yaml")
+	assert.Nil(t, os.WriteFile(configPath, []byte("plugin_dir: "+pluginDir+"\n"), 0644))
+	t.Setenv("PATH", t.TempDir())
+
+	c, err := loadConfig(configPath, true)
+	assert.Nil(t, err)
+	saved := cfg
+	cfg = c
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer func() {
+		cfg = saved
+		log.SetOutput(os.Stderr)
+	}()
+
+	assert.Nil(t, registerConfigPlugins())
+	assert.Contains(t, logged.String(), "Skipping the .so plugins in "+pluginDir+": ")
+	assert.NotContains(t, logged.String(), "Skipping plugin "+brokenPath, "the .so file isn't loaded")
+}
@@ -1,29 +1,63 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/rechati/brio/cmd/plugins"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/rechati/brio/pkg/brio"
 	"github.com/spf13/cobra"
 )
 
 // dirFlag specifies the directory path provided as a flag.
 // filePattern defines the pattern for matching file names.
 // categoriesArg holds the argument for specifying categories.
+// regionsFlag enables treating IDE region markers as snippets.
+// includeCommentsFlag keeps multi-line comments in snippet content.
+// excludeExpired drops snippets whose "_expires" date has passed.
+// ownerArg restricts the output to snippets owned by the given owners.
+// repoFlag and refFlag name a remote git repository to clone and scan instead of dirFlag.
+// githubFlag names a GitHub repository to read through the API instead, at refFlag.
+// pathFilterArg is a regular expression snippet paths must match; pathFilter is it compiled.
+// minLinesFlag and maxSnippetLinesFlag bound the number of lines of content of the snippets kept.
+// maxSnippetsFlag, when set, bounds how many snippets are held in memory (see streamSnippets).
+// withImportsFlag prepends the import statements of their file to snippets.
+// tabsToSpacesFlag and trimTrailingFlag override the whitespace settings of the config file.
 var (
-	dirFlag       string
-	filePattern   string
-	categoriesArg string
+	dirFlag             string
+	filePattern         string
+	categoriesArg       string
+	regionsFlag         bool
+	includeCommentsFlag bool
+	excludeExpired      bool
+	ownerArg            string
+	repoFlag            string
+	refFlag             string
+	githubFlag          string
+	pathFilterArg       string
+	pathFilter          *regexp.Regexp
+	minLinesFlag        int
+	maxSnippetLinesFlag int
+	maxSnippetsFlag     int
+	withImportsFlag     bool
+	tabsToSpacesFlag    int
+	trimTrailingFlag    bool
 )
 
+// defaultTabWidth is the value of a bare --tabs-to-spaces.
+const defaultTabWidth = "4"
+
+// now returns the current time; tests replace it to check expiry handling.
+var now = time.Now
+
 // extractCmd defines a Cobra command for extracting code snippets based on specified categories in annotated files.
 var extractCmd = &cobra.Command{
 	Use:   "extract",
@@ -34,24 +68,202 @@ var extractCmd = &cobra.Command{
 ... code ...
 # end: {"foundation": ["messages"], ...}
 
+The shorter # >: {...} / # <: {...} markers are accepted as well; set
+"markers: arrows" or "markers: keywords" in .brio.yaml to allow only one style.
+
 It requires you to specify the categories you’re looking for (e.g., foundation, tests).
 Usage example:
 brio extract --categories "messages:foundation,tests" --dir ./ --files "*.py"
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		// Flags given on the command line take precedence over .brio.yaml.
+		if cmd.Flags().Changed("regions") {
+			cfg.Regions = regionsFlag
+		}
+		if cmd.Flags().Changed("include-comments") {
+			cfg.IncludeComments = includeCommentsFlag
+		}
+		overrideTabWidth(cmd)
+		if cmd.Flags().Changed("trim-trailing-whitespace") {
+			cfg.TrimTrailingWhitespace = trimTrailingFlag
+		}
+		if maxSnippetsFlag < 0 {
+			fatalf("--max-snippets must be 0 or more, got %d", maxSnippetsFlag)
+		}
+		if splitTokensFlag < 0 {
+			fatalf("--split-tokens must be 0 or more, got %d", splitTokensFlag)
+		}
+		if err := checkConflicts(cmd.Flags(), extractConflicts); err != nil {
+			fatalf("%v", err)
+		}
+		if rangeArg != "" {
+			if _, err := parseRanges(rangeArg); err != nil {
+				fatalf("Invalid --range: %v", err)
+			}
+		}
+		if closureDepthFlag < 0 || closureTokensFlag < 0 {
+			fatalf("--closure-depth and --closure-tokens must be 0 or more")
+		}
+		if watchIntervalFlag <= 0 {
+			fatalf("--watch-interval must be positive, got %v", watchIntervalFlag)
+		}
+		if countOnlyFlag != "" && countOnlyFlag != countTotal && countOnlyFlag != countCategories {
+			fatalf("--count-only must be %q or %q, got %q", countTotal, countCategories, countOnlyFlag)
+		}
+		var err error
+		if outputFormats, err = parseFormats(formatFlag); err != nil {
+			fatalf("Invalid --format: %v", err)
+		}
+		if len(outputFormats) > 1 && outputDirFlag == "" {
+			fatalf("--format %s writes a file per format, so it needs --output-dir", formatFlag)
+		}
+		if compressFlag && outputDirFlag == "" && uploadFlag == "" {
+			fatalf("--compress gzips the files written, so it needs --output-dir or --upload")
+		}
+		if err := validateStyle(styleFlag); err != nil {
+			fatalf("Invalid --style: %v", err)
+		}
+		if cfg.MaxTokensPerCategory, err = categoryLimits(cfg.MaxTokensPerCategory, maxTokensPerCategoryFlag); err != nil {
+			fatalf("Invalid --max-tokens-per-category: %v", err)
+		}
+		if len(cfg.MaxTokensPerCategory) > 0 && maxSnippetsFlag > 0 {
+			fatalf("Token limits per category rank all the snippets, so they can't be used with --max-snippets")
+		}
+		var bucket *brio.Bucket
+		if uploadFlag != "" {
+			bucket = openUpload()
+		}
+		if webhookFlag != "" {
+			if !watchFlag {
+				fatalf("--webhook posts the snippets that change while watching, so it needs --watch")
+			}
+			if err := validateWebhook(webhookFlag); err != nil {
+				fatalf("Invalid --webhook: %v", err)
+			}
+		}
+		if minLinesFlag < 0 || maxSnippetLinesFlag < 0 {
+			fatalf("--min-lines and --max-snippet-lines must be 0 or more")
+		}
+		if maxSnippetLinesFlag > 0 && minLinesFlag > maxSnippetLinesFlag {
+			fatalf("--min-lines %d is over --max-snippet-lines %d", minLinesFlag, maxSnippetLinesFlag)
+		}
+		if pathFilterArg != "" {
+			var err error
+			if pathFilter, err = regexp.Compile(pathFilterArg); err != nil {
+				fatalf("Invalid --path-filter: %v", err)
+			}
+		}
+		if templateFlag != "" {
+			var err error
+			if outputTemplate, err = parseTemplate(templateFlag); err != nil {
+				fatalf("Invalid --template: %v", err)
+			}
+		}
+		if len(cfg.Transforms) > 0 && !transformsFlag {
+			log.Printf("Skipping the %d transforms of the config file; pass --transforms to run them", len(cfg.Transforms))
+		}
+		if cmd.Flags().Changed("theme") {
+			cfg.Theme = themeFlag
+		}
+		if colorOutput, err = resolveColor(colorFlag, cfg.Theme, os.Stdout); err != nil {
+			fatalf("%v", err)
+		}
+		if clipboardFlag || uploadFlag != "" || anchorsFlag || styleFlag != "" {
+			// The clipboard, the bucket, documents to link to and styled snippets get plain Markdown,
+			// whatever the terminal.
+			colorOutput = nil
+		}
+		if colorOutput != nil {
+			// The files of --repo and --github aren't on disk to link to.
+			colorOutput.links = repoFlag == "" && githubFlag == ""
+		}
+		if openFlag != "" {
+			if _, err := resolveEditor(openFlag); err != nil {
+				fatalf("Error with --open: %v", err)
+			}
+		}
+
 		// 1. Parse user-supplied categories into a map.
-		catMap := parseCategoryArg(categoriesArg)
+		catMap := brio.ParseCategories(categoriesArg)
 
-		// 2. Collect all matching files.
-		files, err := collectFiles(dirFlag, filePattern)
-		if err != nil {
-			log.Fatalf("Error collecting files: %v", err)
+		// 2. Collect all matching files, from a clone of --repo or through the API for --github if given.
+		if repoFlag != "" {
+			root, err := brio.Clone(cmd.Context(), repoFlag, refFlag)
+			if err != nil {
+				checkCanceled(cmd.Context())
+				fatalf("Error cloning repository: %v", err)
+			}
+			dirFlag = root
+		}
+		if githubFlag != "" {
+			root, err := fetchGitHub(cmd.Context(), githubFlag, refFlag)
+			if err != nil {
+				checkCanceled(cmd.Context())
+				fatalf("Error reading GitHub repository: %v", err)
+			}
+			dirFlag = root
 		}
 
-		// 3. Extract snippets from those files that match the categories.
-		matchedSnippets := extractSnippets(files, catMap)
-
-		printSnippets(matchedSnippets)
+		// 3. Extract and print the snippets, to the clipboard with --clipboard or to the bucket of
+		// --upload, and again whenever files change with --watch.
+		// run returns the snippets it printed, for --webhook.
+		run := func() ([]snippet, error) {
+			if !clipboardFlag && bucket == nil {
+				_, snips, err := writeExtraction(cmd.Context(), os.Stdout, catMap)
+				return snips, err
+			}
+			var out bytes.Buffer
+			written, snips, err := writeExtraction(cmd.Context(), &out, catMap)
+			if err != nil || cmd.Context().Err() != nil {
+				// Never replace the clipboard or the upload with partial results.
+				return snips, err
+			}
+			if bucket != nil {
+				name, body := "snippets.md", out.Bytes()
+				if compressFlag {
+					name, body = name+".gz", gzipBytes(name, body)
+				}
+				key, err := bucket.Put(cmd.Context(), name, body)
+				if err != nil {
+					return snips, fmt.Errorf("uploading snippets: %w", err)
+				}
+				log.Printf("Uploaded %d snippets to %s", written, bucket.URL(key))
+				return snips, nil
+			}
+			if err := copyToClipboard(out.String()); err != nil {
+				return snips, fmt.Errorf("copying to the clipboard: %w", err)
+			}
+			log.Printf("Copied %d snippets to the clipboard", written)
+			return snips, nil
+		}
+		if !watchFlag {
+			if _, err := run(); err != nil {
+				checkCanceled(cmd.Context())
+				fatalf("Error extracting snippets: %v", err)
+			}
+			checkCanceled(cmd.Context())
+			return
+		}
+		var notifier *webhookNotifier
+		if webhookFlag != "" {
+			notifier = newWebhookNotifier(webhookFlag)
+		}
+		watch(cmd.Context(), dirFlag, filePattern, watchIntervalFlag, func() {
+			// Each run gets a report of its own.
+			resetReport()
+			snips, err := run()
+			printReport()
+			if err != nil {
+				// A failed run ends only this run: the files may be fixed by the next change.
+				if cmd.Context().Err() == nil {
+					log.Printf("Error extracting snippets: %v", err)
+				}
+				return
+			}
+			if notifier != nil {
+				notifyWebhook(cmd.Context(), notifier, snips)
+			}
+		})
 	},
 }
 
@@ -70,352 +282,365 @@ func init() {
 
 	extractCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
 	extractCmd.Flags().StringVarP(&filePattern, "files", "f", defaultPattern,
-		fmt.Sprintf("File pattern to match (e.g., *.py). %s", supportedExtsHelp))
+		fmt.Sprintf("File pattern to match (e.g., *.py or *.{py,ts,tsx}). %s", supportedExtsHelp))
 	extractCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
 		"Categories to extract, e.g. 'messages:foundation,tests'")
+	extractCmd.Flags().StringVar(&symbolArg, "symbol", "",
+		"Extract the definitions of these comma-separated functions or classes, e.g. 'UserService.create_user', whether tagged or not")
+	extractCmd.Flags().StringVar(&rangeArg, "range", "",
+		"Extract these comma-separated line ranges, e.g. 'src/models.py:40-120', whether tagged or not")
+	extractCmd.Flags().BoolVar(&regionsFlag, "regions", false,
+		"Also treat #region/#endregion and // MARK: sections as snippets named after the region")
+	extractCmd.Flags().BoolVar(&includeCommentsFlag, "include-comments", false,
+		"Keep block comments and docstrings without tags in snippets")
+	extractCmd.Flags().BoolVar(&withImportsFlag, "with-imports", false,
+		"Prepend the import statements of their file to snippets, marked by comments")
+	extractCmd.Flags().IntVar(&closureDepthFlag, "closure-depth", 0,
+		"Add the definitions of the functions and types snippets use from other parts of --dir, following them this many levels deep")
+	extractCmd.Flags().IntVar(&closureTokensFlag, "closure-tokens", 0,
+		"Add at most about this many tokens of definitions with --closure-depth, nearest first; 0 means no limit")
+	addTabWidthFlag(extractCmd, "Expand tabs in snippets to spaces, to tab stops this many columns apart; a bare --tabs-to-spaces means 4")
+	extractCmd.Flags().BoolVar(&trimTrailingFlag, "trim-trailing-whitespace", false,
+		"Strip the whitespace ending the lines of snippets")
+	extractCmd.Flags().BoolVar(&excludeExpired, "exclude-expired", false,
+		"Leave out snippets whose _expires date has passed instead of only warning about them")
+	extractCmd.Flags().StringVar(&ownerArg, "owner", "",
+		"Only extract snippets owned by these comma-separated owners (from _owner tags or CODEOWNERS)")
+	extractCmd.Flags().StringVar(&repoFlag, "repo", "",
+		"Clone this git repository URL in memory and scan it instead of --dir")
+	extractCmd.Flags().StringVar(&refFlag, "ref", "",
+		"Branch or tag of --repo or --github to scan (default: its default branch)")
+	extractCmd.Flags().StringVar(&githubFlag, "github", "",
+		"Read this GitHub repository (owner/name) through the API, authenticated by $GITHUB_TOKEN, instead of --dir")
+	extractCmd.Flags().StringVar(&pathFilterArg, "path-filter", "",
+		"Only extract snippets whose file path matches this regular expression, e.g. 'services/(auth|billing)/'")
+	extractCmd.Flags().IntVar(&minLinesFlag, "min-lines", 0,
+		"Leave out snippets of fewer lines of content than this")
+	extractCmd.Flags().IntVar(&maxSnippetLinesFlag, "max-snippet-lines", 0,
+		"Leave out snippets of more lines of content than this; 0 means no limit")
+	extractCmd.Flags().StringVar(&withDiffFlag, "with-diff", "",
+		"Follow each snippet with the diff of its lines since this revision (e.g. main or HEAD~3)")
+	extractCmd.Flags().StringVar(&sinceFlag, "since", "",
+		"Only extract snippets whose lines changed since then, per git blame: an age (2w, 10d, 36h), a date (2006-01-02) or a revision (a commit, a tag, HEAD~5)")
+	extractCmd.Flags().IntVar(&maxSnippetsFlag, "max-snippets", 0,
+		"Hold at most this many snippets in memory, printing them in batches as they are found; 0 means no limit")
+	extractCmd.Flags().IntVar(&splitTokensFlag, "split-tokens", 0,
+		"Split the output into parts (\"Part 2/5\") of at most about this many tokens each, without splitting snippets")
+	extractCmd.Flags().StringVar(&countOnlyFlag, "count-only", "",
+		"Print only how many snippets match, without their content: total, or categories for a count per category too")
+	extractCmd.Flags().Lookup("count-only").NoOptDefVal = countTotal
+	extractCmd.Flags().StringVar(&formatFlag, "format", formatMarkdown,
+		"Formats to render the snippets in, comma-separated: md, json, or both with --output-dir")
+	extractCmd.Flags().StringVar(&outputDirFlag, "output-dir", "",
+		"Directory to write the snippets to, as snippets.md and snippets.json per --format, instead of printing them")
+	extractCmd.Flags().BoolVar(&compressFlag, "compress", false,
+		"Gzip the files of --output-dir and --upload, adding .gz to their names")
+	extractCmd.Flags().StringToIntVar(&maxTokensPerCategoryFlag, "max-tokens-per-category", nil,
+		"Cap the estimated tokens of the snippets of a category, e.g. foundation=4000, dropping those of "+
+			"the lowest _priority first; overrides max_tokens_per_category in the config file")
+	extractCmd.Flags().StringVar(&styleFlag, "style", "",
+		"How much metadata surrounds each snippet: compact (fences only), detailed (headings, categories, "+
+			"hashes and links) or prompt (XML tags for language models)")
+	extractCmd.Flags().BoolVar(&anchorsFlag, "anchors", false,
+		"Number the snippets and give each a stable anchor (from its _id, or its file and line) to link to")
+	extractCmd.Flags().StringVar(&templateFlag, "template", "",
+		"Render the snippets with this Go template file instead of as Markdown")
+	extractCmd.Flags().StringVar(&colorFlag, "color", colorAuto,
+		"Colorize the snippets: auto (when printing to a terminal and $NO_COLOR is unset), always or never")
+	extractCmd.Flags().StringVar(&themeFlag, "theme", defaultTheme, "Colors of --color: dark or light")
+	extractCmd.Flags().BoolVar(&transformsFlag, "transforms", false,
+		"Pipe the snippets through the transforms of the config file, which are run only with this flag")
+	extractCmd.Flags().StringVar(&openFlag, "open", "",
+		"Open each snippet printed at its start line in this editor; a bare --open uses $VISUAL or $EDITOR")
+	extractCmd.Flags().Lookup("open").NoOptDefVal = openDefault
+	extractCmd.Flags().BoolVar(&clipboardFlag, "clipboard", false,
+		"Copy the snippets to the clipboard instead of printing them")
+	extractCmd.Flags().StringVar(&uploadFlag, "upload", "",
+		uploadUsage("the snippets, as snippets.md below a prefix ending with a slash,")+" instead of printing them")
+	extractCmd.Flags().BoolVar(&watchFlag, "watch", false,
+		"Keep running, extracting again whenever files of --dir change, e.g. to keep --clipboard up to date")
+	extractCmd.Flags().StringVar(&webhookFlag, "webhook", "",
+		"With --watch, URL to post the snippets added, modified or removed to, as JSON")
+	extractCmd.Flags().DurationVar(&watchIntervalFlag, "watch-interval", time.Second,
+		"How often --watch checks files for changes")
 }
 
-// parseCategoryArg parses a string argument with categories and domains into a map of categories to their associated domains.
-func parseCategoryArg(categoryArg string) map[string][]string {
-	result := make(map[string][]string)
-	if categoryArg == "" {
-		return result
-	}
-
-	parts := strings.Split(categoryArg, ",")
-	var currentDomain string
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if strings.Contains(part, ":") {
-			// e.g., "messages:foundation"
-			splitPart := strings.SplitN(part, ":", 2)
-			currentDomain = strings.TrimSpace(splitPart[0])
-			category := strings.TrimSpace(splitPart[1])
-			addToCategoryMap(result, category, currentDomain)
-		} else {
-			// e.g., "tests" with inherited domain
-			addToCategoryMap(result, part, currentDomain)
-		}
-	}
+// snippet and issue are the results of the brio library, which the commands render.
+type (
+	snippet = brio.Snippet
+	issue   = brio.Issue
+)
 
-	return result
+// collectFiles returns the files below dir that a plugin handles and whose name matches pattern.
+func collectFiles(ctx context.Context, dir, pattern string) ([]string, error) {
+	opts := cfg.options()
+	opts.Dir, opts.Pattern = dir, pattern
+	return brio.New(opts).Files(ctx)
 }
 
-// addToCategoryMap adds a domain to the specified category in the map.
-// If the category does not exist, it initializes it with an empty slice.
-// Prevents duplicate domains within a category.
-// Ensures the slice contains an empty string if the domain is an empty string and the category is new.
-func addToCategoryMap(catMap map[string][]string, category, domain string) {
-	if _, exists := catMap[category]; !exists {
-		catMap[category] = []string{}
+// extractSnippets returns the snippets of files that match catMap. Problems with the annotations
+// and files that can't be scanned go to the report; expired snippets are reported, and dropped
+// when excludeExpired is set. With --with-imports, snippets start with the imports of their file.
+// When ctx is canceled, the snippets found so far are returned; callers check ctx themselves.
+func extractSnippets(ctx context.Context, files []string, catMap map[string][]string) []snippet {
+	opts := cfg.options()
+	opts.Categories = catMap
+	opts.ExcludeExpired = excludeExpired
+	opts.Processors = snippetProcessors(opts)
+
+	snips, issues, err := brio.New(opts).ExtractFiles(ctx, files)
+	report.Files += len(files)
+	report.Snippets += len(snips)
+	report.Issues = append(report.Issues, issues...)
+	if err != nil && ctx.Err() == nil {
+		report.addError(err)
 	}
-	if domain != "" {
-		// Avoid duplicates
-		for _, d := range catMap[category] {
-			if d == domain {
-				return
-			}
-		}
-		catMap[category] = append(catMap[category], domain)
-	} else {
-		// If domain is empty string, ensure the slice contains an empty string
-		if len(catMap[category]) == 0 {
-			catMap[category] = append(catMap[category], "")
-		}
+	return snips
+}
+
+// snippetProcessors returns the processors of opts, preceded with --with-imports by the one adding
+// imports, so that the imports are processed like the rest of the snippets.
+func snippetProcessors(opts brio.Options) []brio.Processor {
+	if !withImportsFlag {
+		return opts.Processors
 	}
+	return append([]brio.Processor{brio.WithImports()}, opts.Processors...)
 }
 
-// collectFiles scans the provided directory and returns a list of files matching the specified pattern.
-// dir is the root directory to start the search. pattern is the glob pattern for matching file names.
-// Returns a slice of matching file paths or an error if traversal fails.
-// cmd/extract.go
+// processSnippets returns snips, found otherwise than by extractSnippets, passed through the
+// processors of snippetProcessors. Failures go to the report.
+func processSnippets(snips []snippet) []snippet {
+	snips, err := brio.Chain(snippetProcessors(cfg.options())).Apply(snips)
+	if err != nil {
+		report.addError(err)
+	}
+	return snips
+}
 
-func collectFiles(dir, pattern string) ([]string, error) {
+// writeExtraction writes the snippets of the files of --dir that match catMap, or the definitions
+// of --symbol or the lines of --range, to out, as extract prints them: resolving their owners,
+// filtered as set by the flags (see filterSnippets) and transformed by the transforms of the config
+// file, followed by the definitions they use with --closure-depth, in batches with --max-snippets,
+// in parts with --split-tokens, rendered with --template, or only counted with --count-only. With
+// --open, they are then opened in the editor. It returns how many snippets were written and, unless
+// --max-snippets wrote them in batches, those matched, without the definitions. It fails if the
+// files can't be listed or read as the flags ask, if a transform fails, or if out does.
+func writeExtraction(ctx context.Context, out io.Writer, catMap map[string][]string) (int, []snippet, error) {
+	// Ranges name their files themselves.
 	var files []string
+	if rangeArg == "" {
+		var err error
+		files, err = collectFiles(ctx, dirFlag, filePattern)
+		if err != nil && ctx.Err() == nil {
+			return 0, nil, fmt.Errorf("collecting files: %w", err)
+		}
+	}
 
-	// Get all supported extensions from plugins
-	supportedExts := make(map[string]bool)
-	for _, ext := range plugins.ListExtensions() {
-		supportedExts[ext] = true
+	// Resolve owners from "_owner" tags or CODEOWNERS, to filter by them if asked to.
+	loadOwners := loadCodeOwners
+	if repoFlag != "" || githubFlag != "" {
+		loadOwners = loadRootCodeOwners
+	}
+	owners, err := loadOwners(dirFlag)
+	if err != nil {
+		log.Printf("Failed to read CODEOWNERS: %v", err)
 	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	// Read the history of --dir once per extraction, as it changes along with the files.
+	var history *brio.History
+	if sinceFlag != "" {
+		if history, err = brio.OpenHistory(dirFlag, sinceFlag, now()); err != nil {
+			return 0, nil, fmt.Errorf("--since: %w", err)
 		}
+	}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
+	if maxSnippetsFlag > 0 {
+		written, err := streamSnippets(ctx, out, files, catMap, owners, history, maxSnippetsFlag)
+		if err == nil && written == 0 {
+			err = writeSnippets(out, nil)
 		}
+		return written, nil, err
+	}
 
-		// Check if file extension is supported
-		ext := filepath.Ext(path)
-		if !supportedExts[ext] {
-			return nil
+	var snips []snippet
+	switch {
+	case rangeArg != "":
+		// --range was parsed when extract started.
+		ranges, _ := parseRanges(rangeArg)
+		if snips, err = rangeSnippets(ranges); err != nil {
+			return 0, nil, fmt.Errorf("--range: %w", err)
 		}
-
-		// If pattern is provided, check if file matches pattern
-		if pattern != "" && pattern != "*" {
-			matched, err := filepath.Match(pattern, filepath.Base(path))
-			if err != nil {
-				return err
-			}
-			if !matched {
-				return nil
-			}
+	case symbolArg != "":
+		if snips, err = symbolSnippets(ctx, files); err != nil {
+			return 0, nil, err
 		}
-
-		files = append(files, path)
-		return nil
-	})
-
-	return files, err
-}
-
-// parseTagJSON extracts JSON data from a line of text and parses it into a map of string slices.
-// Returns an error if JSON parsing fails or no JSON is found.
-func parseTagJSON(line string) (map[string][]string, error) {
-	startIdx := strings.Index(line, "{")
-	endIdx := strings.LastIndex(line, "}")
-	if startIdx == -1 || endIdx == -1 || endIdx < startIdx {
-		return nil, fmt.Errorf("no JSON found in line: %s", line)
-	}
-	jsonStr := line[startIdx : endIdx+1]
-
-	var data map[string][]string
-	err := json.Unmarshal([]byte(jsonStr), &data)
-	if err != nil {
-		return nil, err
+	default:
+		snips = extractSnippets(ctx, files, catMap)
 	}
-	return data, nil
-}
-
-type commentParser struct {
-	plugin          plugins.Plugin
-	startPattern    *regexp.Regexp
-	endPattern      *regexp.Regexp
-	multiStartToken *regexp.Regexp
-	multiEndToken   *regexp.Regexp
-	inMultiline     bool
-	buffer          bytes.Buffer
-	foundStartTag   bool // Add this to track if we've found a start tag
-}
-
-func newCommentParser(p plugins.Plugin) *commentParser {
-	style := p.GetCommentStyle()
-	return &commentParser{
-		plugin: p,
-		// Single line patterns remain the same
-		startPattern: regexp.MustCompile(
-			`(?i)` + regexp.QuoteMeta(style.Single) + `\s*>:\s*\{`,
-		),
-		endPattern: regexp.MustCompile(
-			`(?i)` + regexp.QuoteMeta(style.Single) + `\s*<:\s*\{`,
-		),
-		// Multi-line patterns now just match the comment tokens
-		multiStartToken: regexp.MustCompile(regexp.QuoteMeta(style.Multi.Start)),
-		multiEndToken:   regexp.MustCompile(regexp.QuoteMeta(style.Multi.End)),
+	assignOwners(snips, owners)
+	if snips, err = applyTransforms(ctx, filterSnippets(snips, history)); err != nil {
+		return 0, nil, err
 	}
-}
-
-func (p *commentParser) parseLine(line string) (isStart bool, isEnd bool, jsonData map[string][]string) {
-	// Check for single-line comments first
-	if p.startPattern.MatchString(line) {
-		data, err := parseTagJSON(line)
-		if err == nil {
-			return true, false, data
+	if len(cfg.MaxTokensPerCategory) > 0 {
+		var omitted int
+		if snips, omitted = limitCategories(snips, cfg.MaxTokensPerCategory, extractTokens); omitted > 0 {
+			log.Printf("Omitted %s over the token limits of their categories", snippetCount(omitted))
 		}
 	}
-	if p.endPattern.MatchString(line) {
-		data, err := parseTagJSON(line)
-		if err == nil {
-			return false, true, data
+	matched := snips
+	if countOnlyFlag != "" {
+		return len(snips), matched, writeCounts(out, snips, countOnlyFlag)
+	}
+	var definitions []snippet
+	if closureDepthFlag > 0 && ctx.Err() == nil {
+		if definitions, err = definitionsOf(ctx, snips); err != nil {
+			return 0, nil, err
 		}
 	}
-
-	// Handle multi-line comments
-	if !p.inMultiline {
-		if p.multiStartToken.MatchString(line) {
-			p.inMultiline = true
-			p.buffer.Reset()
-			p.buffer.WriteString(line + "\n")
-			return false, false, nil
-		}
-	} else {
-		p.buffer.WriteString(line + "\n")
-		if p.multiEndToken.MatchString(line) {
-			p.inMultiline = false
-			// Process the entire multi-line comment
-			fullComment := p.buffer.String()
-
-			// Look for >: {...} pattern in the full comment
-			startMatch := regexp.MustCompile(`>:\s*\{.*}`).FindString(fullComment)
-			if startMatch != "" {
-				data, err := parseTagJSON(startMatch)
-				if err == nil {
-					p.foundStartTag = true
-					return true, false, data
-				}
-			}
-
-			// Look for <: {...} pattern in the full comment
-			endMatch := regexp.MustCompile(`<:\s*\{.*}`).FindString(fullComment)
-			if endMatch != "" {
-				data, err := parseTagJSON(endMatch)
-				if err == nil {
-					return false, true, data
-				}
-			}
+	if withDiffFlag != "" && ctx.Err() == nil {
+		if snips, err = withDiffs(snips); err != nil {
+			return 0, nil, err
 		}
 	}
-
-	return false, false, nil
-}
-
-// snippet represents a code snippet with its associated metadata including file path, line range, categories, and content.
-type snippet struct {
-	File       string
-	StartLine  int
-	EndLine    int
-	Categories map[string][]string
-	Content    []string
-	Plugin     plugins.Plugin
+	snips = append(snips, definitions...)
+	if openFlag != "" && ctx.Err() == nil {
+		defer openSnippets(snips)
+	}
+	if outputTemplate != nil {
+		return len(snips), matched, writeTemplate(out, outputTemplate, snips)
+	}
+	if splitTokensFlag > 0 && len(snips) > 0 {
+		return len(snips), matched, writeParts(out, splitParts(snips, splitTokensFlag))
+	}
+	return len(snips), matched, writeFormats(out, snips, outputFormats, outputDirFlag, compressFlag)
 }
 
-// snippetData represents a snippet of code extracted from a file, including its associated metadata and content lines.
-type snippetData struct {
-	categories map[string][]string
-	startLine  int
-	lines      []string
+// filterSnippets keeps the snippets of snips owned by --owner, whose path matches --path-filter,
+// within --min-lines and --max-snippet-lines and, when history is set, that changed since --since.
+// snips must have their owners assigned.
+func filterSnippets(snips []snippet, history *brio.History) []snippet {
+	if ownerArg != "" {
+		snips = filterByOwner(snips, ownerArg)
+	}
+	if pathFilter != nil {
+		snips = filterByPath(snips, pathFilter)
+	}
+	if minLinesFlag > 0 || maxSnippetLinesFlag > 0 {
+		snips = filterBySize(snips, minLinesFlag, maxSnippetLinesFlag)
+	}
+	// Last, as it's the slowest.
+	if history != nil {
+		snips = filterChanged(snips, history)
+	}
+	return snips
 }
 
-// extractSnippets scans a list of files for code snippets annotated with start and end tags containing category metadata.
-// It extracts the matching snippets based on the provided category map and returns them as a slice of snippet objects.
-func extractSnippets(files []string, catMap map[string][]string) []snippet {
+// filterByPath keeps the snippets whose file path, with forward slashes, matches re anywhere.
+func filterByPath(snips []snippet, re *regexp.Regexp) []snippet {
 	var results []snippet
-
-	for _, filePath := range files {
-		ext := filepath.Ext(filePath)
-		plugin, ok := plugins.Get(ext)
-		if !ok {
-			log.Printf("No plugin found for file type: %s", filePath)
-			continue
-		}
-
-		parser := newCommentParser(plugin)
-
-		f, err := os.Open(filePath)
-		if err != nil {
-			log.Printf("Failed to open file %s: %v", filePath, err)
-			continue
+	for _, s := range snips {
+		if re.MatchString(filepath.ToSlash(s.File)) {
+			results = append(results, s)
 		}
-		scanner := bufio.NewScanner(f)
-
-		var activeSnippet *snippetData
-		lineNum := 0
-
-		for scanner.Scan() {
-			lineNum++
-			line := scanner.Text()
-
-			isStart, isEnd, data := parser.parseLine(line)
-
-			if isStart {
-				activeSnippet = &snippetData{
-					categories: data,
-					startLine:  lineNum,
-					lines:      []string{},
-				}
-				continue
-			}
-
-			if isEnd && activeSnippet != nil {
-				snippetObj := snippet{
-					File:       filePath,
-					StartLine:  activeSnippet.startLine,
-					EndLine:    lineNum,
-					Categories: activeSnippet.categories,
-					Content:    activeSnippet.lines,
-					Plugin:     plugin,
-				}
-
-				if snippetMatches(snippetObj, catMap) {
-					results = append(results, snippetObj)
-				}
-				activeSnippet = nil
-				continue
-			}
+	}
+	return results
+}
 
-			// Only collect lines if we have an active snippet
-			if activeSnippet != nil && !parser.inMultiline {
-				activeSnippet.lines = append(activeSnippet.lines, line)
-			}
+// filterBySize keeps the snippets of at least minLines lines of content and, unless maxLines is 0, at
+// most maxLines.
+func filterBySize(snips []snippet, minLines, maxLines int) []snippet {
+	var results []snippet
+	for _, s := range snips {
+		if len(s.Content) >= minLines && (maxLines == 0 || len(s.Content) <= maxLines) {
+			results = append(results, s)
 		}
-		_ = f.Close()
 	}
-
 	return results
 }
 
-// snippetMatches checks if a snippet matches the requested category-domain mapping specified in catMap.
-// If catMap is empty, the function returns true, indicating all snippets should match.
-// The function iterates through the snippet's categories and checks for intersections with the requested domains in catMap.
-func snippetMatches(s snippet, catMap map[string][]string) bool {
-	// If user specified no categories, everything matches.
-	if len(catMap) == 0 {
-		return true
+// streamSnippets writes the snippets of files that match catMap to w like extractSnippets and
+// writeSnippets do, but in batches of at most limit snippets, so that no more are held in memory at
+// once; only the report is kept until the end. Owners are resolved and snippets filtered as in
+// extract.
+// It returns how many snippets were written, and fails if a transform or w does.
+func streamSnippets(ctx context.Context, w io.Writer, files []string, catMap map[string][]string, owners *codeOwners, history *brio.History, limit int) (int, error) {
+	opts := cfg.options()
+	opts.Categories = catMap
+	opts.ExcludeExpired = excludeExpired
+	opts.Processors = snippetProcessors(opts)
+	opts.OnIssue = func(i issue) { report.Issues = append(report.Issues, i) }
+	report.Files += len(files)
+
+	batch := make([]snippet, 0, limit)
+	written := 0
+	var writeErr error
+	flush := func() {
+		assignOwners(batch, owners)
+		kept, err := applyTransforms(ctx, filterSnippets(batch, history))
+		if err == nil {
+			_, err = io.WriteString(w, renderSnippets(kept))
+		}
+		if writeErr = err; err == nil {
+			written += len(kept)
+		}
+		// Drop the snippets, not only the slice, so they can be collected.
+		clear(batch)
+		batch = batch[:0]
 	}
 
-	// e.g. snippet categories: {"foundation": ["messages"], "model": ["messages"]}
-	// catMap might be: {"foundation": ["messages"], "tests": ["messages"]}
-	for snippetCat, snippetDomains := range s.Categories {
-		if requestedDomains, found := catMap[snippetCat]; found {
-			// If category is requested with no domain => matches any domain for that category.
-			if len(requestedDomains) == 0 {
-				return true
-			}
-			// Otherwise, check domain intersection
-			for _, sd := range snippetDomains {
-				for _, rd := range requestedDomains {
-					if sd == rd {
-						return true
-					}
-				}
-			}
+	err := brio.New(opts).EachFiles(ctx, files, func(s snippet) error {
+		report.Snippets++
+		batch = append(batch, s)
+		if len(batch) == limit {
+			flush()
 		}
+		return writeErr
+	})
+	if writeErr != nil {
+		return written, writeErr
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	if err != nil && ctx.Err() == nil {
+		report.addError(err)
 	}
-	return false
+	return written, writeErr
 }
 
-// printSnippetsMarkdown prints a list of code snippets in Markdown format, including file name, line range, and categories.
-func printSnippets(snips []snippet) {
-	if len(snips) == 0 {
-		fmt.Println("No snippets found for the given categories.")
-		return
-	}
+// scanFile returns every snippet tagged in filePath, along with the problems found in its annotations.
+func scanFile(filePath string) ([]snippet, []issue, error) {
+	return brio.New(cfg.options()).ScanFile(filePath)
+}
 
-	wd, wdErr := os.Getwd()
-	var output strings.Builder
+// matchPlugin returns the plugin handling filePath and describes how it was chosen.
+func matchPlugin(filePath string) (plugins.Plugin, string, bool) {
+	return brio.New(cfg.options()).Plugin(filePath)
+}
 
-	for _, s := range snips {
-		relativePath := s.File
-		// If we successfully retrieved the current directory,
-		// try converting the snippet’s path into a relative path
-		if wdErr == nil {
-			if rp, err := filepath.Rel(wd, s.File); err == nil {
-				relativePath = rp
-			}
-		}
+// fetchGitHub reads the files of the GitHub repository repo at ref that match the --files pattern,
+// and its CODEOWNERS file for --owner, through the API and returns the root they are named under.
+// The token and the API of GitHub Enterprise are taken from GITHUB_TOKEN and GITHUB_API_URL, as set
+// in GitHub Actions.
+func fetchGitHub(ctx context.Context, repo, ref string) (string, error) {
+	opts := cfg.options()
+	opts.Pattern = filePattern
+	return brio.New(opts).FetchGitHub(ctx, brio.GitHubSource{
+		Repo:   repo,
+		Ref:    ref,
+		Token:  os.Getenv("GITHUB_TOKEN"),
+		APIURL: os.Getenv("GITHUB_API_URL"),
+		Extra:  codeOwnersLocations,
+	})
+}
 
-		output.WriteString(fmt.Sprintf("%s:\n", relativePath))
-		output.WriteString("```python\n")
-		for _, line := range s.Content {
-			output.WriteString(line + "\n")
-		}
-		output.WriteString("```\n\n")
+// writeSnippets writes a list of code snippets to w in Markdown format, colorized as set by --color,
+// or a notice if there are none.
+func writeSnippets(w io.Writer, snips []snippet) error {
+	if len(snips) == 0 {
+		_, err := fmt.Fprintln(w, "No snippets found for the given categories.")
+		return err
 	}
-
-	fmt.Print(output.String())
+	_, err := io.WriteString(w, renderSnippets(snips))
+	return err
 }
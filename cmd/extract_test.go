@@ -1,86 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/rechati/brio/pkg/brio"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestParseCategoryArg(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected map[string][]string
-	}{
-		{
-			input: "messages:foundation,tests",
-			expected: map[string][]string{
-				"foundation": {"messages"},
-				"tests":      {"messages"},
-			},
-		},
-		{
-			input:    "",
-			expected: map[string][]string{},
-		},
-		{
-			input: "foundation",
-			expected: map[string][]string{
-				"foundation": {""},
-			},
-		},
-		{
-			input: "messages:foundation,tests,additional",
-			expected: map[string][]string{
-				"foundation": {"messages"},
-				"tests":      {"messages"},
-				"additional": {"messages"},
-			},
-		},
-	}
-
-	for _, tc := range tests {
-		result := parseCategoryArg(tc.input)
-		assert.Equal(t, tc.expected, result, "Input: %s", tc.input)
-	}
-}
-
-func TestParseTagJSON(t *testing.T) {
-	line := `# start: {"foundation": ["messages"], "model": ["messages"]}`
-	data, err := parseTagJSON(line)
-	assert.Nil(t, err)
-	assert.Equal(t, []string{"messages"}, data["foundation"])
-	assert.Equal(t, []string{"messages"}, data["model"])
-
-	// Invalid JSON
-	invalid := `# start: foundation: [messages]`
-	data, err = parseTagJSON(invalid)
-	assert.Nil(t, data)
-	assert.NotNil(t, err)
-}
-
-func TestSnippetMatches(t *testing.T) {
-	snip := snippet{
-		Categories: map[string][]string{
-			"foundation": {"messages"},
-			"model":      {"messages"},
-		},
-	}
-
-	// Empty catMap => matches everything
-	assert.True(t, snippetMatches(snip, map[string][]string{}))
-
-	// Matching category + domain
-	assert.True(t, snippetMatches(snip, map[string][]string{"foundation": {"messages"}}))
-
-	// Matching category, but domain mismatch
-	assert.False(t, snippetMatches(snip, map[string][]string{"foundation": {"alert"}}))
-
-	// Category matches, but catMap has no domain => matches
-	assert.True(t, snippetMatches(snip, map[string][]string{"foundation": {}}))
-}
-
 func TestExtractSnippets(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -104,7 +36,7 @@ def test_message():
 		"foundation": {"messages"},
 	}
 
-	snips := extractSnippets(files, catMap)
+	snips := extractSnippets(context.Background(), files, catMap)
 	assert.Len(t, snips, 1, "Should only match the snippet labeled foundation:messages")
 
 	s := snips[0]
@@ -113,3 +45,775 @@ def test_message():
 	assert.Contains(t, s.Content[0], "class Message(TenantModel)")
 	assert.Contains(t, s.Content[1], "pass")
 }
+
+func TestExtractSnippetsMarkerStyles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `# start: {"foundation": ["messages"]}
+class Message(TenantModel):
+    pass
+# end: {"foundation": ["messages"]}
+
+# >: {"tests": ["messages"]}
+def test_message():
+    assert True
+# <: {"tests": ["messages"]}`
+
+	filePath := filepath.Join(tempDir, "markers.py")
+	err := os.WriteFile(filePath, []byte(fileContent), 0644)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		markers  string
+		expected int
+	}{
+		{markersBoth, 2},
+		{markersKeywords, 1},
+		{markersArrows, 1},
+	}
+
+	for _, tc := range tests {
+		cfg.Markers = tc.markers
+		snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+		assert.Len(t, snips, tc.expected, "Markers: %s", tc.markers)
+	}
+	cfg = defaultConfig()
+}
+
+func TestExtractSnippetsRegions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `// #region Messages
+export class Message {}
+//#region Helpers
+export const helper = 1;
+//#endregion
+// #endregion
+
+// MARK: - Alerts
+export class Alert {}
+// MARK: -
+export const untracked = 1;`
+
+	filePath := filepath.Join(tempDir, "regions.ts")
+	err := os.WriteFile(filePath, []byte(fileContent), 0644)
+	assert.Nil(t, err)
+
+	// Regions are opt-in.
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 0)
+
+	cfg.Regions = true
+	defer func() { cfg = defaultConfig() }()
+
+	snips = extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 3)
+
+	snips = extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("Messages"))
+	assert.Len(t, snips, 1)
+	assert.Equal(t, 1, snips[0].StartLine)
+	assert.Equal(t, 6, snips[0].EndLine)
+	assert.Equal(t, []string{
+		"export class Message {}",
+		"//#region Helpers",
+		"export const helper = 1;",
+		"//#endregion",
+	}, snips[0].Content)
+
+	snips = extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("Alerts"))
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"export class Alert {}"}, snips[0].Content)
+}
+
+func TestExtractSnippetsMultilineTags(t *testing.T) {
+	tempDir := t.TempDir()
+
+	pyContent := `# >: {
+#   "foundation": ["messages"],
+#   "model": ["messages"]
+# }
+class Message(TenantModel):
+    pass
+# <: {
+#   "foundation": ["messages"]
+# }`
+
+	tsContent := `/*
+ * >: {
+ *   "tests": ["messages"]
+ * }
+ */
+export const message = {};
+// <: {"tests": ["messages"]}`
+
+	pyPath := filepath.Join(tempDir, "multiline.py")
+	tsPath := filepath.Join(tempDir, "multiline.ts")
+	assert.Nil(t, os.WriteFile(pyPath, []byte(pyContent), 0644))
+	assert.Nil(t, os.WriteFile(tsPath, []byte(tsContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{pyPath, tsPath}, map[string][]string{})
+	assert.Len(t, snips, 2)
+
+	assert.Equal(t, map[string][]string{
+		"foundation": {"messages"},
+		"model":      {"messages"},
+	}, snips[0].Categories)
+	assert.Equal(t, 4, snips[0].StartLine)
+	assert.Equal(t, 9, snips[0].EndLine)
+	assert.Equal(t, []string{"class Message(TenantModel):", "    pass"}, snips[0].Content)
+
+	assert.Equal(t, map[string][]string{"tests": {"messages"}}, snips[1].Categories)
+	assert.Equal(t, []string{"export const message = {};"}, snips[1].Content)
+}
+
+func TestExtractSnippetsYAMLPayload(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `# start: the server before tagging anything
+# >: foundation: [messages]
+class Message(TenantModel):
+    pass
+# <: foundation: [messages]
+
+# >: {tests: messages, model: [messages, alerts]}
+def test_message():
+    assert True
+# <: {tests: messages}`
+
+	filePath := filepath.Join(tempDir, "yaml_tags.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	cfg.Payload = payloadYAML
+	defer func() { cfg = defaultConfig() }()
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 2)
+	assert.Equal(t, map[string][]string{"foundation": {"messages"}}, snips[0].Categories)
+	assert.Equal(t, map[string][]string{
+		"tests": {"messages"},
+		"model": {"messages", "alerts"},
+	}, snips[1].Categories)
+}
+
+func TestExtractSnippetsKVPayload(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `# >: foundation=messages,alerts tests=messages
+class Message(TenantModel):
+    pass
+# <: foundation=messages`
+
+	filePath := filepath.Join(tempDir, "kv_tags.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	for _, payload := range []string{payloadJSON, payloadYAML} {
+		cfg.Payload = payload
+		snips := extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("alerts:foundation"))
+		assert.Len(t, snips, 1, "Payload: %s", payload)
+		assert.Equal(t, []string{"class Message(TenantModel):", "    pass"}, snips[0].Content)
+	}
+	cfg = defaultConfig()
+}
+
+func TestExtractSnippetsAttributes(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `# >: {"foundation": ["messages"], "_expires": "2025-06-01", "_since": "v2.3"}
+WORKAROUND = True
+# <: {"foundation": ["messages"]}`
+
+	filePath := filepath.Join(tempDir, "attrs.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("_expires"))
+	assert.Len(t, snips, 0, "Attributes are not categories")
+
+	snips = extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, map[string][]string{"foundation": {"messages"}}, snips[0].Categories)
+	assert.Equal(t, "2025-06-01", snips[0].Attr("expires"))
+	assert.Equal(t, "v2.3", snips[0].Attr("since"))
+
+	excludeExpired = true
+	defer func() { excludeExpired = false }()
+	assert.Len(t, extractSnippets(context.Background(), []string{filePath}, map[string][]string{}), 0)
+}
+
+func TestExtractSnippetsRuby(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `=begin
+>: {"foundation": ["billing"]}
+=end
+class Invoice
+  attr_accessor :beginning, :ending
+  STATE=beginning
+end
+# <: {"foundation": ["billing"]}`
+
+	filePath := filepath.Join(tempDir, "invoice.rb")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Len(t, snips[0].Content, 4)
+	assert.Equal(t, "ruby", snips[0].Plugin.GetMarkdownIdentifier())
+}
+
+func TestExtractSnippetsPHP(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `<?php
+// >: {"foundation": ["billing"]}
+#[Entity]
+class Invoice {}
+# <: {"foundation": ["billing"]}
+
+/* >: {"tests": ["billing"]} */
+function test_invoice() {}
+// <: {"tests": ["billing"]}`
+
+	filePath := filepath.Join(tempDir, "invoice.php")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 2)
+	assert.Equal(t, []string{"#[Entity]", "class Invoice {}"}, snips[0].Content)
+	assert.Equal(t, []string{"function test_invoice() {}"}, snips[1].Content)
+}
+
+func TestExtractSnippetsShellShebang(t *testing.T) {
+	tempDir := t.TempDir()
+
+	script := `#!/usr/bin/env bash
+set -e
+# >: {"deploy": ["release"]}
+git tag "$VERSION"
+# <: {"deploy": ["release"]}`
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "release"), []byte(script), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "NOTES"), []byte("# >: {\"deploy\": []}\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "build.zsh"), []byte("make\n"), 0644))
+
+	files, err := collectFiles(context.Background(), tempDir, "*")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{filepath.Join(tempDir, "release"), filepath.Join(tempDir, "build.zsh")}, files)
+
+	snips := extractSnippets(context.Background(), []string{filepath.Join(tempDir, "release")}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{`git tag "$VERSION"`}, snips[0].Content)
+	assert.Equal(t, "bash", snips[0].Language())
+}
+
+func TestExtractSnippetsConfigFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	values := `replicaCount: 2
+# >: {"deploy": ["api"]}
+resources:
+  limits:
+    memory: 512Mi
+# <: {"deploy": ["api"]}`
+	settings := `[server]
+# >: {"deploy": ["api"]}
+port = 8080
+# <: {"deploy": ["api"]}`
+	valuesPath := filepath.Join(tempDir, "values.yaml")
+	settingsPath := filepath.Join(tempDir, "settings.toml")
+	assert.Nil(t, os.WriteFile(valuesPath, []byte(values), 0644))
+	assert.Nil(t, os.WriteFile(settingsPath, []byte(settings), 0644))
+
+	snips := extractSnippets(context.Background(), []string{valuesPath, settingsPath}, map[string][]string{"deploy": {"api"}})
+	assert.Len(t, snips, 2)
+	assert.Equal(t, []string{"resources:", "  limits:", "    memory: 512Mi"}, snips[0].Content)
+	assert.Equal(t, "yaml", snips[0].Language())
+	assert.Equal(t, []string{"port = 8080"}, snips[1].Content)
+	assert.Equal(t, "toml", snips[1].Language())
+}
+
+func TestCollectFilesByName(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"Dockerfile", "Dockerfile.dev", "api.Dockerfile", "Makefile", "rules.mk", "README"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(tempDir, name), []byte("# >: {\"build\": []}\n"), 0644))
+	}
+
+	files, err := collectFiles(context.Background(), tempDir, "*")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tempDir, "Dockerfile"),
+		filepath.Join(tempDir, "Dockerfile.dev"),
+		filepath.Join(tempDir, "api.Dockerfile"),
+		filepath.Join(tempDir, "Makefile"),
+		filepath.Join(tempDir, "rules.mk"),
+	}, files)
+
+	tests := []struct {
+		name       string
+		identifier string
+	}{
+		{"Dockerfile.dev", "dockerfile"},
+		{"api.Dockerfile", "dockerfile"},
+		{"Makefile", "makefile"},
+		{"rules.mk", "makefile"},
+	}
+	for _, tt := range tests {
+		plugin, _, ok := matchPlugin(filepath.Join(tempDir, tt.name))
+		assert.True(t, ok, tt.name)
+		assert.Equal(t, tt.identifier, plugin.GetMarkdownIdentifier(), tt.name)
+	}
+}
+
+func TestExtractSnippetsAPIContracts(t *testing.T) {
+	tempDir := t.TempDir()
+
+	proto := `syntax = "proto3";
+/* >: {"api": ["orders"]} */
+message Order {
+  string id = 1;
+}
+// <: {"api": ["orders"]}`
+	schema := `# >: {"api": ["orders"]}
+type Order {
+  id: ID!
+}
+# <: {"api": ["orders"]}`
+	protoPath := filepath.Join(tempDir, "orders.proto")
+	schemaPath := filepath.Join(tempDir, "orders.graphql")
+	assert.Nil(t, os.WriteFile(protoPath, []byte(proto), 0644))
+	assert.Nil(t, os.WriteFile(schemaPath, []byte(schema), 0644))
+
+	snips := extractSnippets(context.Background(), []string{protoPath, schemaPath}, map[string][]string{"api": {"orders"}})
+	assert.Len(t, snips, 2)
+	assert.Equal(t, []string{"message Order {", "  string id = 1;", "}"}, snips[0].Content)
+	assert.Equal(t, "protobuf", snips[0].Language())
+	assert.Equal(t, []string{"type Order {", "  id: ID!", "}"}, snips[1].Content)
+	assert.Equal(t, "graphql", snips[1].Language())
+}
+
+func TestExtractSnippetsSwiftNestedComments(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `/* >: {"ui": ["checkout"]}
+   Disabled for now:
+   /* let legacy = LegacyCart() */
+*/
+struct CheckoutView: View {
+    var body: some View { Text("Pay") }
+}
+// <: {"ui": ["checkout"]}`
+
+	filePath := filepath.Join(tempDir, "CheckoutView.swift")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{
+		"struct CheckoutView: View {",
+		`    var body: some View { Text("Pay") }`,
+		"}",
+	}, snips[0].Content)
+	assert.Equal(t, "swift", snips[0].Language())
+}
+
+func TestExtractSnippetsDart(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `/// Shows the cart total.
+// >: {"ui": ["cart"]}
+class CartTotal extends StatelessWidget {
+  const CartTotal({super.key});
+}
+/* <: {"ui": ["cart"]} */`
+
+	filePath := filepath.Join(tempDir, "cart_total.dart")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{
+		"class CartTotal extends StatelessWidget {",
+		"  const CartTotal({super.key});",
+		"}",
+	}, snips[0].Content)
+	assert.Equal(t, "dart", snips[0].Language())
+}
+
+func TestExtractSnippetsVue(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `<template>
+  <!-- >: {"ui": ["cart"]} -->
+  <button @click="checkout">Pay</button>
+  <!-- <: {"ui": ["cart"]} -->
+</template>
+
+<script lang="ts">
+// >: {"logic": ["cart"]}
+export default { methods: { checkout() {} } }
+// <: {"logic": ["cart"]}
+</script>
+
+<style lang="scss">
+/* >: {"ui": ["cart"]} */
+button { color: $primary; }
+// <: {"ui": ["cart"]}
+</style>
+
+<!-- >: {"whole": ["cart"]} -->
+<script setup>
+const total = 0
+</script>
+<!-- <: {"whole": ["cart"]} -->`
+
+	filePath := filepath.Join(tempDir, "Cart.vue")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 4)
+
+	var fences []string
+	for _, s := range snips {
+		fences = append(fences, s.Language())
+	}
+	assert.Equal(t, []string{"html", "typescript", "scss", "vue"}, fences)
+	assert.Equal(t, []string{`  <button @click="checkout">Pay</button>`}, snips[0].Content)
+	assert.Equal(t, []string{"export default { methods: { checkout() {} } }"}, snips[1].Content)
+	assert.Equal(t, []string{"button { color: $primary; }"}, snips[2].Content)
+	assert.Equal(t, []string{"<script setup>", "const total = 0", "</script>"}, snips[3].Content)
+}
+
+func TestExtractSnippetsMarkdown(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := "# Billing\n" +
+		"\n" +
+		"```python brio={\"tests\": [\"docs\"]}\n" +
+		"def test_invoice():\n" +
+		"    assert invoice.total == 10\n" +
+		"```\n" +
+		"\n" +
+		"<!-- >: {\"guide\": [\"billing\"]} -->\n" +
+		"Invoices are sent monthly.\n" +
+		"<!-- <: {\"guide\": [\"billing\"]} -->\n" +
+		"\n" +
+		"~~~~yaml brio=tests=config\n" +
+		"# >: {\"ignored\": []}\n" +
+		"```\n" +
+		"billing: true\n" +
+		"~~~~\n" +
+		"\n" +
+		"```bash\n" +
+		"brio extract\n" +
+		"```\n"
+
+	filePath := filepath.Join(tempDir, "billing.md")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips, issues, err := scanFile(filePath)
+	assert.Nil(t, err)
+	assert.Empty(t, issues)
+	assert.Len(t, snips, 3)
+
+	assert.Equal(t, map[string][]string{"tests": {"docs"}}, snips[0].Categories)
+	assert.Equal(t, []string{"def test_invoice():", "    assert invoice.total == 10"}, snips[0].Content)
+	assert.Equal(t, "python", snips[0].Language())
+	assert.Equal(t, 3, snips[0].StartLine)
+	assert.Equal(t, 6, snips[0].EndLine)
+
+	assert.Equal(t, []string{"Invoices are sent monthly."}, snips[1].Content)
+	assert.Equal(t, "markdown", snips[1].Language())
+
+	assert.Equal(t, map[string][]string{"tests": {"config"}}, snips[2].Categories)
+	assert.Equal(t, []string{`# >: {"ignored": []}`, "```", "billing: true"}, snips[2].Content)
+	assert.Equal(t, "yaml", snips[2].Language())
+}
+
+func TestExtractSnippetsJupyter(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `{
+ "cells": [
+  {"cell_type": "markdown", "source": ["# Churn model\n", "# >: {\"ignored\": []}\n"]},
+  {"cell_type": "code", "source": ["import pandas as pd\n", "# >: {\"model\": [\"churn\"]}\n", "df = pd.read_csv(\"churn.csv\")\n", "# <: {\"model\": [\"churn\"]}"]},
+  {"cell_type": "code", "source": "# >: {\"tests\": [\"churn\"]}\nassert len(df) > 0"}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`
+
+	filePath := filepath.Join(tempDir, "churn.ipynb")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips, issues, err := scanFile(filePath)
+	assert.Nil(t, err)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, "cell 2", snips[0].Section)
+	assert.Equal(t, 2, snips[0].StartLine)
+	assert.Equal(t, 4, snips[0].EndLine)
+	assert.Equal(t, []string{`df = pd.read_csv("churn.csv")`}, snips[0].Content)
+	assert.Equal(t, "python", snips[0].Language())
+
+	assert.Len(t, issues, 1)
+	assert.Equal(t, filePath+" (cell 3):1: BRIO001 start tag is never closed", issues[0].String())
+}
+
+func TestExtractSnippetsPythonDocstrings(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `"""Billing helpers."""
+
+# >: {"billing": ["invoice"]}
+def total(lines):
+    """
+    Sum the amounts of the invoice lines.
+    """
+    return sum(line.amount for line in lines)
+"""
+<: {"billing": ["invoice"]}
+"""`
+
+	filePath := filepath.Join(tempDir, "billing.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips, issues, err := scanFile(filePath)
+	assert.Nil(t, err)
+	assert.Empty(t, issues)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{
+		"def total(lines):",
+		`    """`,
+		"    Sum the amounts of the invoice lines.",
+		`    """`,
+		"    return sum(line.amount for line in lines)",
+	}, snips[0].Content)
+}
+
+func TestExtractSnippetsIncludeComments(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `// >: {"billing": ["invoice"]}
+/*
+ * Totals are rounded once, at the end.
+ */
+const total = lines.reduce((sum, line) => sum + line.amount, 0)
+/* <: {"billing": ["invoice"]} */`
+
+	filePath := filepath.Join(tempDir, "billing.ts")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{
+		"const total = lines.reduce((sum, line) => sum + line.amount, 0)",
+	}, snips[0].Content)
+
+	cfg.IncludeComments = true
+	defer func() { cfg = defaultConfig() }()
+
+	snips = extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{
+		"/*",
+		" * Totals are rounded once, at the end.",
+		" */",
+		"const total = lines.reduce((sum, line) => sum + line.amount, 0)",
+	}, snips[0].Content)
+}
+
+func TestExtractSnippetsWhitespace(t *testing.T) {
+	tempDir := t.TempDir()
+	fileContent := "// >: {\"foundation\": [\"billing\"]}\nfunction total() {\t\n\treturn 1 \n}\n// <: {\"foundation\": [\"billing\"]}\n"
+	filePath := filepath.Join(tempDir, "total.ts")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	snips := extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"function total() {\t", "\treturn 1 ", "}"}, snips[0].Content)
+
+	cfg.TabsToSpaces = 2
+	cfg.TrimTrailingWhitespace = true
+	defer func() { cfg = defaultConfig() }()
+
+	snips = extractSnippets(context.Background(), []string{filePath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"function total() {", "  return 1", "}"}, snips[0].Content)
+}
+
+func TestExtractSnippetsCustomMarkers(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".brio.yaml")
+	config := `custom_markers:
+  - start: '--8<-- \[start:(?P<name>[\w.-]+)\]'
+    end: '--8<-- \[end:(?P<name>[\w.-]+)\]'
+`
+	assert.Nil(t, os.WriteFile(configPath, []byte(config), 0644))
+	filePath := filepath.Join(tempDir, "setup.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte("# --8<-- [start:install]\nsetup()\n# --8<-- [end:install]\n"), 0644))
+
+	var err error
+	cfg, err = loadConfig(configPath, true)
+	assert.Nil(t, err)
+	defer func() { cfg = defaultConfig() }()
+
+	snips := extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("install"))
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"setup()"}, snips[0].Content)
+
+	assert.Nil(t, os.WriteFile(configPath, []byte("custom_markers:\n  - start: 'snippet'\n    end: 'end'\n"), 0644))
+	_, err = loadConfig(configPath, true)
+	assert.EqualError(t, err, configPath+`: custom_markers: start pattern "snippet" needs a (?P<name>...) or (?P<payload>...) group`)
+}
+
+func TestExtractSnippetsVirtual(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, ".brio.yaml")
+	filePath := filepath.Join(tempDir, "models.py")
+	content := "# >: {\"models\": []}\nimport os\n# <: {\"models\": []}\n\n" +
+		"class User:\n    def save(self):\n        pass\n\nVERSION = 2\n"
+	assert.Nil(t, os.WriteFile(filePath, []byte(content), 0644))
+	config := "virtual_snippets:\n" +
+		"  - file: " + filePath + "\n    symbol: User.save\n    categories: {models: [user], _owner: [team-a]}\n" +
+		"  - file: " + filePath + "\n    lines: 9-9\n    categories: {models: [version]}\n" +
+		"  - file: " + filePath + "\n    lines: 9-12\n    categories: {models: []}\n"
+	assert.Nil(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	var err error
+	cfg, err = loadConfig(configPath, true)
+	assert.Nil(t, err)
+	defer func() { cfg = defaultConfig() }()
+	saved := report
+	defer func() { report = saved }()
+	resetReport()
+
+	snips := extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("models"))
+	assert.Len(t, snips, 3)
+	assert.Equal(t, []string{"import os"}, snips[0].Content)
+	assert.Equal(t, []string{"    def save(self):", "        pass"}, snips[1].Content)
+	assert.Equal(t, "team-a", snips[1].Attr("owner"))
+	assert.Equal(t, []string{"VERSION = 2"}, snips[2].Content)
+	assert.Equal(t, 9, snips[2].StartLine)
+
+	issues := lintFiles(context.Background(), []string{filePath})
+	assert.Len(t, issues, 1)
+	assert.Equal(t, brio.CodeStaleVirtual, issues[0].Code)
+
+	assert.Nil(t, os.WriteFile(configPath, []byte("virtual_snippets:\n  - file: a.py\n    symbol: f\n    lines: 1-2\n"), 0644))
+	_, err = loadConfig(configPath, true)
+	assert.EqualError(t, err, configPath+": virtual_snippets: a.py: either a symbol or lines is required")
+}
+
+func TestExtractSnippetsPathDefaults(t *testing.T) {
+	project := t.TempDir()
+	filePath := filepath.Join(project, "services", "messages", "queue.py")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	assert.Nil(t, os.WriteFile(filePath, []byte("# >: {\"tests\": []}\nassert True\n# <: {\"tests\": []}\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(project, ".brio.yaml"),
+		[]byte("path_defaults:\n  - path: services/messages\n    domains: [messages]\n"), 0644))
+
+	// Run from another directory, as with --config ../.brio.yaml and an absolute --dir.
+	other := filepath.Join(project, "docs")
+	assert.Nil(t, os.Mkdir(other, 0755))
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(other))
+	defer func() { _ = os.Chdir(wd) }()
+
+	cfg, err = loadConfig(filepath.Join("..", ".brio.yaml"), true)
+	assert.Nil(t, err)
+	defer func() { cfg = defaultConfig() }()
+
+	snips := extractSnippets(context.Background(), []string{filePath}, brio.ParseCategories("messages:tests"))
+	assert.Len(t, snips, 1)
+	assert.Equal(t, map[string][]string{"tests": {"messages"}}, snips[0].Categories)
+}
+
+func TestExtractSnippetsCanceled(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "a.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte("# >: {\"tests\": []}\npass\n# <: {\"tests\": []}\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, extractSnippets(ctx, []string{filePath}, map[string][]string{}))
+
+	files, err := collectFiles(ctx, tempDir, "*")
+	assert.Empty(t, files)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStreamSnippets(t *testing.T) {
+	saved := report
+	defer func() { report = saved }()
+
+	tempDir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.py", "b.py", "c.py"} {
+		content := "# >: {\"tests\": [\"" + name + "\"]}\npass\n# <: {\"tests\": []}\n" +
+			"# >: {\"docs\": []}\nx = 1\n# <: {\"docs\": []}\n# <: {\"tests\": []}\n"
+		filePath := filepath.Join(tempDir, name)
+		assert.Nil(t, os.WriteFile(filePath, []byte(content), 0644))
+		files = append(files, filePath)
+	}
+	files = append(files, filepath.Join(tempDir, "missing.py"))
+
+	report = &scanReport{Failed: []fileFailure{}, Issues: []issue{}}
+	want := brio.RenderMarkdown(extractSnippets(context.Background(), files, brio.ParseCategories("tests")))
+
+	for _, limit := range []int{1, 2, 5} {
+		report = &scanReport{Failed: []fileFailure{}, Issues: []issue{}}
+		var out strings.Builder
+		written, err := streamSnippets(context.Background(), &out, files, brio.ParseCategories("tests"), nil, nil, limit)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, written, "limit=%d", limit)
+		assert.Equal(t, want, out.String(), "limit=%d", limit)
+		assert.Equal(t, 4, report.Files)
+		assert.Len(t, report.Failed, 1)
+		assert.Len(t, report.Issues, 3)
+	}
+}
+
+func TestWriteExtractionReturnsErrors(t *testing.T) {
+	resetReport()
+	defer resetReport()
+	savedDir := dirFlag
+	defer func() { dirFlag, cfg = savedDir, defaultConfig() }()
+	dirFlag = t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dirFlag, "app.py"), []byte("# >: {\"tests\": []}\npass\n# <: {\"tests\": []}\n"), 0644))
+	cfg.Transforms = []transformConfig{{Name: "broken", Command: []string{"sh", "-c", "exit 3"}}}
+
+	// The transforms of the config file aren't run without --transforms.
+	_, _, err := writeExtraction(context.Background(), io.Discard, nil)
+	assert.Nil(t, err)
+
+	transformsFlag = true
+	defer func() { transformsFlag = false }()
+	// --watch logs the error and goes on, rather than exiting.
+	_, _, err = writeExtraction(context.Background(), io.Discard, nil)
+	assert.ErrorContains(t, err, "transforming snippets: transform broken failed on ")
+
+	dirFlag = filepath.Join(dirFlag, "missing")
+	_, _, err = writeExtraction(context.Background(), io.Discard, nil)
+	assert.ErrorContains(t, err, "collecting files: ")
+}
+
+func TestFilterByPath(t *testing.T) {
+	snips := []snippet{
+		{File: filepath.Join("services", "auth", "login.py")},
+		{File: filepath.Join("services", "billing", "invoice.py")},
+		{File: filepath.Join("services", "search", "index.py")},
+		{File: filepath.Join("libs", "auth", "token.py")},
+	}
+	filtered := filterByPath(snips, regexp.MustCompile(`services/(auth|billing)/`))
+	assert.Equal(t, snips[:2], filtered)
+	assert.Equal(t, []snippet{snips[0], snips[3]}, filterByPath(snips, regexp.MustCompile(`/auth/`)))
+	assert.Empty(t, filterByPath(snips, regexp.MustCompile(`^docs/`)))
+}
+
+func TestFilterBySize(t *testing.T) {
+	lines := func(n int) snippet { return snippet{Content: make([]string, n)} }
+	snips := []snippet{lines(1), lines(3), lines(10), lines(200)}
+	assert.Equal(t, snips[1:], filterBySize(snips, 2, 0))
+	assert.Equal(t, snips[:3], filterBySize(snips, 0, 10))
+	assert.Equal(t, snips[1:3], filterBySize(snips, 3, 10))
+	assert.Empty(t, filterBySize(snips, 11, 199))
+}
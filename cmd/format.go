@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// Output formats of extract --format.
+const (
+	formatMarkdown = "md"
+	formatJSON     = "json"
+)
+
+// formatFlag lists the formats extract renders the snippets in, comma-separated; outputDirFlag is
+// the directory they are written to, as snippets.md and snippets.json, instead of stdout.
+// outputFormats are the formats of formatFlag, as parseFormats returns them.
+// compressFlag gzips the files written, adding .gz to their names.
+// anchorsFlag numbers the snippets of the Markdown and gives each an anchor to link to.
+var (
+	formatFlag    string
+	outputDirFlag string
+	outputFormats = []string{formatMarkdown}
+	compressFlag  bool
+	anchorsFlag   bool
+)
+
+// parseFormats returns the formats of arg, such as "md,json", in order and without repeats;
+// "markdown" is "md".
+func parseFormats(arg string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, format := range strings.Split(arg, ",") {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "markdown" {
+			format = formatMarkdown
+		}
+		if format != formatMarkdown && format != formatJSON {
+			return nil, fmt.Errorf("unknown format %q, expected md or json", format)
+		}
+		if !seen[format] {
+			seen[format] = true
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+// writeFormats renders snips in each of formats, rendering the expensive scan once: to out when
+// there is a single format and no dir, and to snippets.md and snippets.json in dir otherwise,
+// gzipped as snippets.md.gz and snippets.json.gz with compress, writing to out where they went.
+func writeFormats(out io.Writer, snips []snippet, formats []string, dir string, compress bool) error {
+	if dir == "" {
+		if len(formats) != 1 {
+			return fmt.Errorf("%d formats need an output directory", len(formats))
+		}
+		if formats[0] == formatJSON {
+			return writeJSONSnippets(out, snips)
+		}
+		return writeSnippets(out, snips)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var written []string
+	for _, format := range formats {
+		path := filepath.Join(dir, "snippets."+format)
+		if compress {
+			path += ".gz"
+		}
+		if err := writeFormatFile(path, snips, format, compress); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		written = append(written, path)
+	}
+	_, err := fmt.Fprintf(out, "Wrote %s to %s\n", snippetCount(len(snips)), strings.Join(written, ", "))
+	return err
+}
+
+// writeFormatFile writes snips to path in format, gzipped with compress.
+func writeFormatFile(path string, snips []snippet, format string, compress bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(f)
+		zw.Name = strings.TrimSuffix(filepath.Base(path), ".gz")
+		w = zw
+	}
+	if format == formatJSON {
+		err = writeJSONSnippets(w, snips)
+	} else {
+		// Files get plain Markdown, whatever the terminal.
+		_, err = io.WriteString(w, plainMarkdown(snips))
+	}
+	if zw != nil {
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// plainMarkdown renders snips as Markdown without colors, in the preset of --style or anchored with
+// --anchors.
+func plainMarkdown(snips []snippet) string {
+	if styleFlag != "" {
+		return renderStyled(snips, styleFlag)
+	}
+	if anchorsFlag {
+		return brio.RenderAnchoredMarkdown(snips)
+	}
+	return brio.RenderMarkdown(snips)
+}
+
+// gzipBytes returns data gzipped, named name inside the archive.
+func gzipBytes(name string, data []byte) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Name = name
+	// Writes to a bytes.Buffer don't fail.
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return b.Bytes()
+}
+
+// jsonSnippets is the document of --format json.
+type jsonSnippets struct {
+	Snippets []rpcSnippet `json:"snippets"`
+}
+
+// writeJSONSnippets writes snips to w as a JSON document, with the content of each and its path
+// relative to the current directory, with forward slashes.
+func writeJSONSnippets(w io.Writer, snips []snippet) error {
+	doc := jsonSnippets{Snippets: make([]rpcSnippet, 0, len(snips))}
+	for _, s := range snips {
+		js := newRPCSnippet(s)
+		js.File = filepath.ToSlash(displayPath(s.File))
+		doc.Snippets = append(doc.Snippets, js)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
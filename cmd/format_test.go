@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormats(t *testing.T) {
+	formats, err := parseFormats("md, JSON,markdown")
+	require.NoError(t, err)
+	assert.Equal(t, []string{formatMarkdown, formatJSON}, formats)
+
+	_, err = parseFormats("md,yaml")
+	assert.ErrorContains(t, err, `unknown format "yaml"`)
+}
+
+func TestWriteFormats(t *testing.T) {
+	snips := []snippet{{
+		File:       filepath.Join("src", "queue.py"),
+		StartLine:  3,
+		EndLine:    6,
+		Categories: map[string][]string{"tests": {"messages"}},
+		Content:    []string{"def push(item):", "    return item"},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, writeFormats(&out, snips, []string{formatJSON}, "", false))
+	var doc struct {
+		Snippets []map[string]any `json:"snippets"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	require.Len(t, doc.Snippets, 1)
+	assert.Equal(t, "src/queue.py", doc.Snippets[0]["file"])
+	assert.Equal(t, []any{"def push(item):", "    return item"}, doc.Snippets[0]["content"])
+
+	dir := filepath.Join(t.TempDir(), "out")
+	out.Reset()
+	require.NoError(t, writeFormats(&out, snips, []string{formatMarkdown, formatJSON}, dir, false))
+	markdown, err := os.ReadFile(filepath.Join(dir, "snippets.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(markdown), "src/queue.py:\n```\ndef push(item):\n")
+	data, err := os.ReadFile(filepath.Join(dir, "snippets.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"file": "src/queue.py"`)
+	assert.Equal(t, "Wrote 1 snippet to "+filepath.Join(dir, "snippets.md")+", "+filepath.Join(dir, "snippets.json")+"\n", out.String())
+
+	assert.Error(t, writeFormats(&out, snips, []string{formatMarkdown, formatJSON}, "", false))
+}
+
+func TestWriteFormatsCompressed(t *testing.T) {
+	snips := []snippet{{File: "queue.py", StartLine: 1, EndLine: 3, Content: []string{"pass"}}}
+	dir := t.TempDir()
+	var out bytes.Buffer
+	require.NoError(t, writeFormats(&out, snips, []string{formatMarkdown}, dir, true))
+	assert.NoFileExists(t, filepath.Join(dir, "snippets.md"))
+
+	f, err := os.Open(filepath.Join(dir, "snippets.md.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	assert.Equal(t, "snippets.md", zr.Name)
+	content, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "queue.py:\n```\npass\n```\n\n", string(content))
+
+	zr, err = gzip.NewReader(bytes.NewReader(gzipBytes("snippets.md", content)))
+	require.NoError(t, err)
+	unzipped, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, content, unzipped)
+}
+
+func TestWriteFormatsAnchored(t *testing.T) {
+	anchorsFlag = true
+	defer func() { anchorsFlag = false }()
+	snips := []snippet{
+		{File: "queue.py", StartLine: 1, EndLine: 3, Content: []string{"pass"}},
+		{File: "queue.py", StartLine: 9, EndLine: 12, Content: []string{"pass"}, Attrs: map[string][]string{"id": {"Queue push"}}},
+	}
+	dir := t.TempDir()
+	var out bytes.Buffer
+	require.NoError(t, writeFormats(&out, snips, []string{formatMarkdown}, dir, false))
+	markdown, err := os.ReadFile(filepath.Join(dir, "snippets.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(markdown), "1. <a id=\"queue-py-l1\"></a>queue.py:\n")
+	assert.Contains(t, string(markdown), "2. <a id=\"queue-push\"></a>queue.py:\n")
+}
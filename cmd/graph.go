@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// graphFormat selects the graph syntax: "mermaid" or "dot".
+var graphFormat string
+
+// Kinds of nodes and edges in the annotation graph.
+const (
+	nodeCategory = "category"
+	nodeDomain   = "domain"
+	nodeFile     = "file"
+
+	edgeTagged    = "tagged"     // category -- domain
+	edgeContains  = "contains"   // domain (or category without domain) --> file
+	edgeDependsOn = "depends_on" // file --> file, from "_depends_on" attributes
+)
+
+// graphCmd visualizes the annotation taxonomy.
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Render the category/domain taxonomy as a Mermaid or Graphviz graph",
+	Long: `Graph prints a graph of which domains appear in which categories and which files
+connect them. When snippets declare "_id" and "_depends_on", the dependencies between
+their files are drawn as well.
+
+Mermaid output can be pasted into GitHub Markdown; DOT output renders with Graphviz.
+Usage example:
+brio graph --dir ./src --format dot | dot -Tsvg > taxonomy.svg
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		catMap := brio.ParseCategories(categoriesArg)
+
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, catMap)
+		checkCanceled(cmd.Context())
+
+		g := buildTaxonomyGraph(snips)
+		switch graphFormat {
+		case "mermaid":
+			fmt.Print(g.mermaid())
+		case "dot":
+			fmt.Print(g.dot())
+		default:
+			fatalf("Unknown graph format %q, expected mermaid or dot", graphFormat)
+		}
+	},
+}
+
+// init registers graphCmd and its flags.
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	graphCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	graphCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"Categories to include, e.g. 'messages:foundation,tests' (default: all)")
+	graphCmd.Flags().StringVar(&graphFormat, "format", "mermaid", "Graph format: mermaid or dot")
+}
+
+// graphNode is a node of the annotation graph.
+type graphNode struct {
+	kind  string
+	label string
+}
+
+// graphEdge connects two nodes of the annotation graph.
+type graphEdge struct {
+	from, to graphNode
+	kind     string
+}
+
+// taxonomyGraph is the set of nodes and edges derived from snippet annotations.
+type taxonomyGraph struct {
+	nodes map[graphNode]bool
+	edges map[graphEdge]bool
+}
+
+// buildTaxonomyGraph links categories to their domains and domains to the files tagging them,
+// plus file-to-file edges for snippets whose "_depends_on" names another snippet's "_id".
+func buildTaxonomyGraph(snips []snippet) *taxonomyGraph {
+	g := &taxonomyGraph{nodes: make(map[graphNode]bool), edges: make(map[graphEdge]bool)}
+
+	filesByID := make(map[string]string)
+	for _, s := range snips {
+		if id := s.Attr("id"); id != "" {
+			filesByID[id] = displayPath(s.File)
+		}
+	}
+
+	for _, s := range snips {
+		file := graphNode{kind: nodeFile, label: displayPath(s.File)}
+		for category, domains := range s.Categories {
+			categoryNode := graphNode{kind: nodeCategory, label: category}
+			if len(domains) == 0 {
+				g.addEdge(categoryNode, file, edgeContains)
+			}
+			for _, domain := range domains {
+				if domain == "" {
+					g.addEdge(categoryNode, file, edgeContains)
+					continue
+				}
+				domainNode := graphNode{kind: nodeDomain, label: domain}
+				g.addEdge(categoryNode, domainNode, edgeTagged)
+				g.addEdge(domainNode, file, edgeContains)
+			}
+		}
+
+		for _, dependency := range s.Attrs["depends_on"] {
+			if target, ok := filesByID[dependency]; ok && target != file.label {
+				g.addEdge(file, graphNode{kind: nodeFile, label: target}, edgeDependsOn)
+			}
+		}
+	}
+
+	return g
+}
+
+// addEdge adds an edge and both of its nodes.
+func (g *taxonomyGraph) addEdge(from, to graphNode, kind string) {
+	g.nodes[from] = true
+	g.nodes[to] = true
+	g.edges[graphEdge{from: from, to: to, kind: kind}] = true
+}
+
+// sortedNodes returns the nodes ordered by kind and label, with their generated identifiers.
+func (g *taxonomyGraph) sortedNodes() ([]graphNode, map[graphNode]string) {
+	order := map[string]int{nodeCategory: 0, nodeDomain: 1, nodeFile: 2}
+	nodes := make([]graphNode, 0, len(g.nodes))
+	for n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].kind != nodes[j].kind {
+			return order[nodes[i].kind] < order[nodes[j].kind]
+		}
+		return nodes[i].label < nodes[j].label
+	})
+
+	ids := make(map[graphNode]string, len(nodes))
+	for i, n := range nodes {
+		ids[n] = fmt.Sprintf("%s%d", n.kind[:1], i)
+	}
+	return nodes, ids
+}
+
+// sortedEdges returns the edges ordered by kind and endpoints.
+func (g *taxonomyGraph) sortedEdges() []graphEdge {
+	edges := make([]graphEdge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	order := map[string]int{edgeTagged: 0, edgeContains: 1, edgeDependsOn: 2}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.kind != b.kind {
+			return order[a.kind] < order[b.kind]
+		}
+		if a.from != b.from {
+			return a.from.kind+a.from.label < b.from.kind+b.from.label
+		}
+		return a.to.kind+a.to.label < b.to.kind+b.to.label
+	})
+	return edges
+}
+
+// mermaid renders the graph as a Mermaid flowchart.
+func (g *taxonomyGraph) mermaid() string {
+	nodes, ids := g.sortedNodes()
+	var out strings.Builder
+	out.WriteString("graph LR\n")
+
+	for _, n := range nodes {
+		label := strings.ReplaceAll(n.label, `"`, "#quot;")
+		switch n.kind {
+		case nodeCategory:
+			out.WriteString(fmt.Sprintf("  %s[[\"%s\"]]\n", ids[n], label))
+		case nodeDomain:
+			out.WriteString(fmt.Sprintf("  %s((\"%s\"))\n", ids[n], label))
+		default:
+			out.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", ids[n], label))
+		}
+	}
+	for _, e := range g.sortedEdges() {
+		switch e.kind {
+		case edgeTagged:
+			out.WriteString(fmt.Sprintf("  %s --- %s\n", ids[e.from], ids[e.to]))
+		case edgeDependsOn:
+			out.WriteString(fmt.Sprintf("  %s -. depends on .-> %s\n", ids[e.from], ids[e.to]))
+		default:
+			out.WriteString(fmt.Sprintf("  %s --> %s\n", ids[e.from], ids[e.to]))
+		}
+	}
+
+	return out.String()
+}
+
+// dot renders the graph in Graphviz DOT syntax.
+func (g *taxonomyGraph) dot() string {
+	nodes, ids := g.sortedNodes()
+	shapes := map[string]string{nodeCategory: "box", nodeDomain: "ellipse", nodeFile: "note"}
+	var out strings.Builder
+	out.WriteString("digraph brio {\n  rankdir=LR;\n")
+
+	for _, n := range nodes {
+		out.WriteString(fmt.Sprintf("  %s [label=%s, shape=%s];\n", ids[n], strconv.Quote(n.label), shapes[n.kind]))
+	}
+	for _, e := range g.sortedEdges() {
+		switch e.kind {
+		case edgeTagged:
+			out.WriteString(fmt.Sprintf("  %s -> %s [arrowhead=none];\n", ids[e.from], ids[e.to]))
+		case edgeDependsOn:
+			out.WriteString(fmt.Sprintf("  %s -> %s [style=dashed, label=\"depends on\"];\n", ids[e.from], ids[e.to]))
+		default:
+			out.WriteString(fmt.Sprintf("  %s -> %s;\n", ids[e.from], ids[e.to]))
+		}
+	}
+
+	out.WriteString("}\n")
+	return out.String()
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxonomyGraph(t *testing.T) {
+	snips := []snippet{
+		{
+			File:       "models.py",
+			Categories: map[string][]string{"foundation": {"messages"}},
+			Attrs:      map[string][]string{"id": {"message-model"}},
+		},
+		{
+			File:       "views.py",
+			Categories: map[string][]string{"api": {"messages"}, "Helpers": {}},
+			Attrs:      map[string][]string{"depends_on": {"message-model", "missing"}},
+		},
+	}
+
+	g := buildTaxonomyGraph(snips)
+
+	assert.Equal(t, `graph LR
+  c0[["Helpers"]]
+  c1[["api"]]
+  c2[["foundation"]]
+  d3(("messages"))
+  f4["models.py"]
+  f5["views.py"]
+  c1 --- d3
+  c2 --- d3
+  c0 --> f5
+  d3 --> f4
+  d3 --> f5
+  f5 -. depends on .-> f4
+`, g.mermaid())
+
+	assert.Equal(t, `digraph brio {
+  rankdir=LR;
+  c0 [label="Helpers", shape=box];
+  c1 [label="api", shape=box];
+  c2 [label="foundation", shape=box];
+  d3 [label="messages", shape=ellipse];
+  f4 [label="models.py", shape=note];
+  f5 [label="views.py", shape=note];
+  c1 -> d3 [arrowhead=none];
+  c2 -> d3 [arrowhead=none];
+  c0 -> f5;
+  d3 -> f4;
+  d3 -> f5;
+  f5 -> f4 [style=dashed, label="depends on"];
+}
+`, g.dot())
+}
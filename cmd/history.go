@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// historyFile selects the snippets of history by file instead of by id.
+var historyFile string
+
+// historyCmd shows the commits that changed a snippet, like git log -L on its lines.
+var historyCmd = &cobra.Command{
+	Use:   "history [id]",
+	Short: "Show the commits that changed a snippet",
+	Long: `History lists the commits that changed the lines of a snippet, newest first, like
+git log -L does for a line range: the lines are followed as code is added or removed
+around them and as their file is moved, back to the commit that added them.
+
+Select the snippet by its "_id", or with --file by its file, along with --categories
+if the file holds several. Changes not committed yet are mentioned first.
+Usage example:
+brio history token-check
+brio history --file src/auth/login.py --categories security
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if (len(args) == 1) == (historyFile != "") {
+			fatalf("Give either the id of a snippet or --file")
+		}
+
+		var snips []snippet
+		root := dirFlag
+		if historyFile != "" {
+			root = filepath.Dir(historyFile)
+			found, _, err := scanFile(historyFile)
+			if err != nil {
+				fatalf("Error reading %s: %v", historyFile, err)
+			}
+			catMap := brio.ParseCategories(categoriesArg)
+			for _, s := range found {
+				if s.Matches(catMap) {
+					snips = append(snips, s)
+				}
+			}
+			if len(snips) == 0 {
+				fatalf("No snippets of %s match the given categories", historyFile)
+			}
+		} else {
+			files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+			if err != nil && cmd.Context().Err() == nil {
+				fatalf("Error collecting files: %v", err)
+			}
+			for _, s := range extractSnippets(cmd.Context(), files, nil) {
+				if s.Attr("id") == args[0] {
+					snips = append(snips, s)
+				}
+			}
+			checkCanceled(cmd.Context())
+			if len(snips) == 0 {
+				fatalf("No snippet declares the _id %q", args[0])
+			}
+		}
+
+		history, err := brio.OpenHistory(root, "", now())
+		if err != nil {
+			fatalf("Error reading the git history: %v", err)
+		}
+		for i, s := range snips {
+			origins, err := history.Origins(s)
+			if err != nil {
+				fatalf("Error reading the history of %s: %v", displayPath(s.File), err)
+			}
+			uncommitted := 0
+			for _, o := range origins {
+				if o == nil {
+					uncommitted++
+				}
+			}
+			changes, err := history.Log(cmd.Context(), s)
+			checkCanceled(cmd.Context())
+			if err != nil {
+				fatalf("Error reading the history of %s: %v", displayPath(s.File), err)
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(renderHistory(s, uncommitted, changes))
+		}
+	},
+}
+
+// init registers historyCmd and its flags.
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to look for the snippet id in")
+	historyCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	historyCmd.Flags().StringVar(&historyFile, "file", "", "Show the history of the snippets of this file instead")
+	historyCmd.Flags().StringVarP(&categoriesArg, "categories", "c", "",
+		"With --file, the categories of the snippets to show, e.g. 'messages:foundation'")
+}
+
+// renderHistory renders the history of s as Markdown: its location, how many of its lines aren't
+// committed yet, and a line per change, with the path of its file when it was elsewhere.
+func renderHistory(s snippet, uncommitted int, changes []brio.Change) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "## %s:%d-%d", brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, s.EndLine)
+	if id := s.Attr("id"); id != "" {
+		fmt.Fprintf(&out, " (%s)", id)
+	}
+	out.WriteString("\n\n")
+
+	if uncommitted > 0 {
+		fmt.Fprintf(&out, "- not committed yet (%s)\n", lineCount(uncommitted))
+	}
+	for _, c := range changes {
+		fmt.Fprintf(&out, "- %s `%.7s` %s: %s", c.Date.Format(time.DateOnly), c.Hash, c.Author, c.Summary)
+		if c.MovedFrom != "" {
+			fmt.Fprintf(&out, " (in %s)", c.MovedFrom)
+		}
+		out.WriteString("\n")
+	}
+	if uncommitted == 0 && len(changes) == 0 {
+		out.WriteString("No commits yet.\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHistory(t *testing.T) {
+	s := snippet{File: "auth/login.py", StartLine: 12, EndLine: 40, Attrs: map[string][]string{"id": {"token-check"}}}
+	date := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	changes := []brio.Change{
+		{Origin: brio.Origin{Hash: "a1b2c3d4e5f6", Author: "Jane Doe <jane@example.com>", Date: date, Summary: "Check token expiry"}},
+		{Origin: brio.Origin{Hash: "0f9e8d7c6b5a", Author: "Sam Roe <sam@example.com>", Date: date.AddDate(-1, 0, 0), Summary: "Add login"},
+			MovedFrom: "login.py"},
+	}
+	assert.Equal(t, "## auth/login.py:12-40 (token-check)\n\n"+
+		"- not committed yet (3 lines)\n"+
+		"- 2026-03-04 `a1b2c3d` Jane Doe <jane@example.com>: Check token expiry\n"+
+		"- 2025-03-04 `0f9e8d7` Sam Roe <sam@example.com>: Add login (in login.py)\n",
+		renderHistory(s, 3, changes))
+
+	s.Attrs = nil
+	assert.Equal(t, "## auth/login.py:12-40\n\nNo commits yet.\n", renderHistory(s, 0, nil))
+}
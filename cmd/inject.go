@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// checkFlag makes inject report outdated documents instead of rewriting them.
+var checkFlag bool
+
+// injectStartPattern matches the comment opening an injected block; it captures the categories
+// to inject, written like the --categories flag of extract.
+var injectStartPattern = regexp.MustCompile(`^\s*<!--\s*brio:\s*(.*?)\s*-->\s*$`)
+
+// injectEndPattern matches the comment closing an injected block.
+var injectEndPattern = regexp.MustCompile(`^\s*<!--\s*/brio\s*-->\s*$`)
+
+// injectCmd keeps code samples in Markdown documentation in sync with tagged snippets.
+var injectCmd = &cobra.Command{
+	Use:   "inject [markdown files...]",
+	Short: "Sync tagged snippets into Markdown documentation",
+	Long: `Inject replaces the contents of marked blocks in Markdown files with the snippets
+that currently match them:
+
+<!-- brio:messages:foundation,tests -->
+... replaced on every run ...
+<!-- /brio -->
+
+The text after "brio:" selects categories exactly like --categories does for extract.
+With --check nothing is written; the command lists the files that are out of date
+and exits with status 1, which is handy in CI.
+Usage example:
+brio inject README.md docs/architecture.md --dir ./src
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+		snips := extractSnippets(cmd.Context(), files, map[string][]string{})
+		// Injecting partial results would drop snippets from the documents.
+		checkCanceled(cmd.Context())
+
+		outdated := 0
+		for _, docPath := range args {
+			original, err := os.ReadFile(docPath)
+			if err != nil {
+				fatalf("Error reading %s: %v", docPath, err)
+			}
+
+			updated, err := injectSnippets(string(original), snips)
+			if err != nil {
+				fatalf("Error in %s: %v", docPath, err)
+			}
+			if updated == string(original) {
+				continue
+			}
+
+			outdated++
+			if checkFlag {
+				fmt.Printf("%s is out of date\n", docPath)
+				continue
+			}
+			if err := os.WriteFile(docPath, []byte(updated), 0644); err != nil {
+				fatalf("Error writing %s: %v", docPath, err)
+			}
+			fmt.Printf("Updated %s\n", docPath)
+		}
+
+		if checkFlag && outdated > 0 {
+			printReport()
+			exit(1)
+		}
+	},
+}
+
+// init registers injectCmd and its flags.
+func init() {
+	rootCmd.AddCommand(injectCmd)
+
+	injectCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan for snippets")
+	injectCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	injectCmd.Flags().BoolVar(&checkFlag, "check", false,
+		"Don't write anything; exit with status 1 if a document is out of date")
+}
+
+// injectSnippets returns doc with the contents of every brio block replaced by the matching snippets.
+func injectSnippets(doc string, snips []snippet) (string, error) {
+	lines := strings.SplitAfter(doc, "\n")
+	var output strings.Builder
+
+	for i := 0; i < len(lines); i++ {
+		output.WriteString(lines[i])
+
+		m := injectStartPattern.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n"))
+		if m == nil {
+			continue
+		}
+
+		// Skip the old contents up to the closing marker.
+		end := i + 1
+		for end < len(lines) && !injectEndPattern.MatchString(strings.TrimRight(lines[end], "\r\n")) {
+			end++
+		}
+		if end == len(lines) {
+			return "", fmt.Errorf("line %d: <!-- brio:%s --> has no closing <!-- /brio -->", i+1, m[1])
+		}
+
+		catMap := brio.ParseCategories(m[1])
+		var matched []snippet
+		for _, s := range snips {
+			if s.Matches(catMap) {
+				matched = append(matched, s)
+			}
+		}
+		output.WriteString(strings.TrimSuffix(brio.RenderMarkdown(matched), "\n"))
+
+		i = end - 1
+	}
+
+	return output.String(), nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjectSnippets(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	snips := []snippet{
+		{
+			File:       "models.py",
+			Categories: map[string][]string{"foundation": {"messages"}},
+			Content:    []string{"class Message(TenantModel):", "    pass"},
+			Plugin:     python,
+		},
+		{
+			File:       "test_models.py",
+			Categories: map[string][]string{"tests": {"messages"}},
+			Content:    []string{"def test_message():", "    assert True"},
+			Plugin:     python,
+		},
+	}
+
+	doc := "# Messages\n\n<!-- brio:messages:foundation -->\nstale content\n<!-- /brio -->\n\nMore text.\n"
+	expected := "# Messages\n\n<!-- brio:messages:foundation -->\n" +
+		"models.py:\n```python\nclass Message(TenantModel):\n    pass\n```\n" +
+		"<!-- /brio -->\n\nMore text.\n"
+
+	updated, err := injectSnippets(doc, snips)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, updated)
+
+	// Injecting again is a no-op.
+	again, err := injectSnippets(updated, snips)
+	assert.Nil(t, err)
+	assert.Equal(t, updated, again)
+
+	_, err = injectSnippets("<!-- brio:tests -->\nno end marker\n", snips)
+	assert.NotNil(t, err)
+}
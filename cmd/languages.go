@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// languageConfig declares a language in .brio.yaml, for file types without a built-in plugin:
+//
+//	languages:
+//	  - name: Rego
+//	    extensions: [.rego]
+//	    comments:
+//	      single: ["#"]
+//	    markdown: rego
+type languageConfig struct {
+	Name       string   `yaml:"name"`
+	Extensions []string `yaml:"extensions"`
+	// Filenames are glob patterns matched against file names, e.g. "Jenkinsfile".
+	Filenames []string `yaml:"filenames"`
+	Comments  struct {
+		// Single lists the single line comment prefixes, e.g. ["--"].
+		Single []string `yaml:"single"`
+		// Multi holds the start and end tokens of multi-line comments, e.g. ["{-", "-}"].
+		Multi []string `yaml:"multi"`
+		// Nested allows multi-line comments to nest.
+		Nested bool `yaml:"nested"`
+	} `yaml:"comments"`
+	// Markdown is the language identifier used for fenced code blocks.
+	Markdown string `yaml:"markdown"`
+	// Imports are the prefixes of the lines starting import statements, e.g. ["#include "], which
+	// extract --with-imports prepends to snippets.
+	Imports []string `yaml:"imports"`
+	// Definitions are regular expressions matching the first line of a definition, whose first
+	// group is the name defined, e.g. `^func\s+(?:\([^)]*\)\s*)?(\w+)`, for extract
+	// --closure-depth and --symbol.
+	Definitions []string `yaml:"definitions"`
+	// Indented makes definitions end where their indentation does, as in Python, rather than where
+	// their braces close.
+	Indented bool `yaml:"indented"`
+	// Priority decides which plugin handles a file type claimed by several; by default
+	// declared languages win over all plugins.
+	Priority *int `yaml:"priority"`
+}
+
+// validate reports declarations that brio can't turn into a plugin.
+func (l languageConfig) validate() error {
+	if l.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(l.Extensions) == 0 && len(l.Filenames) == 0 {
+		return fmt.Errorf("%s: at least one extension or file name is required", l.Name)
+	}
+	for _, ext := range l.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("%s: extension %q must start with a dot", l.Name, ext)
+		}
+	}
+	if len(l.Comments.Single) == 0 && len(l.Comments.Multi) == 0 {
+		return fmt.Errorf("%s: at least one comment syntax is required", l.Name)
+	}
+	for _, prefix := range l.Comments.Single {
+		if prefix == "" {
+			return fmt.Errorf("%s: single line comment prefixes can't be empty", l.Name)
+		}
+	}
+	if len(l.Comments.Multi) > 0 && (len(l.Comments.Multi) != 2 || l.Comments.Multi[0] == "" || l.Comments.Multi[1] == "") {
+		return fmt.Errorf("%s: multi must hold a start and an end token", l.Name)
+	}
+	for _, pattern := range l.Definitions {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: definitions: %v", l.Name, err)
+		}
+		if re.NumSubexp() == 0 {
+			return fmt.Errorf("%s: definitions: %q has no group capturing the name", l.Name, pattern)
+		}
+	}
+	return nil
+}
+
+// plugin builds the plugin described by the declaration.
+func (l languageConfig) plugin() *plugins.ConfigPlugin {
+	return &plugins.ConfigPlugin{
+		Name:        l.Name,
+		Extensions:  l.Extensions,
+		Filenames:   l.Filenames,
+		Style:       plugins.NewCommentStyle(l.Comments.Single, l.Comments.Multi, l.Comments.Nested),
+		Markdown:    l.Markdown,
+		Imports:     l.Imports,
+		Definitions: l.Definitions,
+		Indented:    l.Indented,
+		Priority:    l.Priority,
+	}
+}
+
+// registerLanguages adds a plugin for every language declared in the config. Unless it sets a
+// lower priority, a declared language takes over the extensions of any other plugin.
+func registerLanguages(languages []languageConfig) {
+	for _, l := range languages {
+		plugins.RegisterFrom(l.plugin(), plugins.OriginConfig)
+	}
+}
+
+// registerExtensions makes the plugins of the languages extensions maps extensions to handle them,
+// once every plugin is registered, so that they may be declared languages or external plugins.
+func registerExtensions(extensions map[string]string) error {
+	for _, ext := range sortedKeys(extensions) {
+		if err := plugins.MapExtension(ext, extensions[ext]); err != nil {
+			return fmt.Errorf("extensions: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigLanguages(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			config: `languages:
+  - name: Acme Rules
+    extensions: [.acme]
+    filenames: [Acmefile]
+    comments:
+      single: ["--", ";"]
+      multi: ["{-", "-}"]
+      nested: true
+    markdown: acme
+`,
+		},
+		{
+			name:    "missing name",
+			config:  "languages:\n  - extensions: [.acme]\n    comments: {single: ['--']}\n",
+			wantErr: "languages: name is required",
+		},
+		{
+			name:    "no files",
+			config:  "languages:\n  - name: Acme\n    comments: {single: ['--']}\n",
+			wantErr: "languages: Acme: at least one extension or file name is required",
+		},
+		{
+			name:    "extension without dot",
+			config:  "languages:\n  - name: Acme\n    extensions: [acme]\n    comments: {single: ['--']}\n",
+			wantErr: `languages: Acme: extension "acme" must start with a dot`,
+		},
+		{
+			name:    "no comments",
+			config:  "languages:\n  - name: Acme\n    extensions: [.acme]\n",
+			wantErr: "languages: Acme: at least one comment syntax is required",
+		},
+		{
+			name:    "incomplete multi",
+			config:  "languages:\n  - name: Acme\n    extensions: [.acme]\n    comments: {multi: ['{-']}\n",
+			wantErr: "languages: Acme: multi must hold a start and an end token",
+		},
+		{
+			name:    "definition without a name",
+			config:  "languages:\n  - name: Acme\n    extensions: [.acme]\n    comments: {single: ['--']}\n    definitions: ['^rule\\s+\\w+']\n",
+			wantErr: `languages: Acme: definitions: "^rule\\s+\\w+" has no group capturing the name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".brio.yaml")
+			assert.Nil(t, os.WriteFile(path, []byte(tt.config), 0644))
+
+			c, err := loadConfig(path, true)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, path+": "+tt.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			p := c.Languages[0].plugin()
+			assert.Equal(t, "Acme Rules", p.GetName())
+			assert.Equal(t, []string{"--", ";"}, p.GetCommentStyle().SinglePrefixes())
+			assert.Equal(t, "{-", p.GetCommentStyle().Multi.Start)
+			assert.True(t, p.GetCommentStyle().MultiNested)
+		})
+	}
+}
+
+func TestExtractSnippetsConfigLanguage(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var l languageConfig
+	l.Name = "Querylang"
+	l.Extensions = []string{".qlx"}
+	l.Comments.Multi = []string{"{-", "-}"}
+	l.Markdown = "qlx"
+	registerLanguages([]languageConfig{l})
+
+	fileContent := `{- >: {"queries": ["orders"]} -}
+select * from orders
+{- <: {"queries": ["orders"]} -}`
+
+	filePath := filepath.Join(tempDir, "orders.qlx")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	files, err := collectFiles(context.Background(), tempDir, "*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filePath}, files)
+
+	snips := extractSnippets(context.Background(), files, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"select * from orders"}, snips[0].Content)
+	assert.Equal(t, "qlx", snips[0].Language())
+}
+
+func TestConfigLanguageDefinitions(t *testing.T) {
+	var l languageConfig
+	l.Name = "Golang"
+	l.Extensions = []string{".golang"}
+	l.Comments.Single = []string{"//"}
+	l.Definitions = []string{`^func\s+(?:\([^)]*\)\s*)?(\w+)`, `^type\s+(\w+)`}
+	registerLanguages([]languageConfig{l})
+
+	filePath := filepath.Join(t.TempDir(), "cart.golang")
+	src := "type Cart struct {\n\titems []int\n}\n\nfunc (c Cart) Total() int {\n\treturn sum(c.items)\n}\n"
+	assert.Nil(t, os.WriteFile(filePath, []byte(src), 0644))
+
+	defs, err := brio.New(cfg.options()).Definitions(context.Background(), []string{filePath})
+	assert.Nil(t, err)
+	if assert.Len(t, defs["Total"], 1) {
+		assert.Equal(t, 5, defs["Total"][0].StartLine)
+		assert.Equal(t, 7, defs["Total"][0].EndLine)
+	}
+	assert.Len(t, defs["Cart"], 1)
+}
+
+func TestRegisterExtensions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".brio.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("extensions:\n  pyx: python\n"), 0644))
+	_, err := loadConfig(path, true)
+	assert.EqualError(t, err, path+`: extensions: "pyx" must be an extension starting with a dot, e.g. .pyx`)
+
+	assert.EqualError(t, registerExtensions(map[string]string{".pyz": "cobol"}),
+		"extensions: can't map .pyz to cobol: no plugin handles that language")
+
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "fast.pyx")
+	fileContent := `# >: {"hot": []}
+cdef int add(int a, int b):
+    return a + b
+# <: {"hot": []}
+`
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+	assert.Nil(t, registerExtensions(map[string]string{".pyx": "python"}))
+
+	files, err := collectFiles(context.Background(), tempDir, "*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filePath}, files)
+	snips := extractSnippets(context.Background(), files, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, "python", snips[0].Language())
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd checks annotations without extracting anything, for use in CI.
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check snippet annotations for mistakes",
+	Long: `Lint scans your files like extract does and reports annotation problems:
+invalid tag payloads, start tags that are never closed, end tags without a start,
+snippets whose "_expires" date has passed, and snippets whose content no longer
+matches their "_hash".
+
+Each problem is printed as file:line: message. The command exits with status 1
+if any problem was found.
+
+To adopt lint gradually, record the current problems with --update-baseline; with
+--baseline, lint then only reports and fails on the problems that aren't in it.
+Usage example:
+brio lint --dir ./ --files "*.py"
+brio lint --baseline brio-baseline.json --update-baseline
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if updateBaselineFlag && baselineFlag == "" {
+			fatalf("--update-baseline needs the --baseline file to write")
+		}
+		files, err := collectFiles(cmd.Context(), dirFlag, filePattern)
+		if err != nil && cmd.Context().Err() == nil {
+			fatalf("Error collecting files: %v", err)
+		}
+
+		issues := lintFiles(cmd.Context(), files)
+		if updateBaselineFlag {
+			// A baseline of partial results would hide the problems of the files left out.
+			checkCanceled(cmd.Context())
+			if err := newBaseline(dirFlag, issues).write(baselineFlag); err != nil {
+				fatalf("Error writing the baseline: %v", err)
+			}
+			log.Printf("Problems recorded in %s: %d", baselineFlag, len(issues))
+			return
+		}
+		if baselineFlag != "" {
+			b, err := readBaseline(baselineFlag)
+			if err != nil {
+				fatalf("Error reading the baseline: %v", err)
+			}
+			found := len(issues)
+			var fixed int
+			issues, fixed = b.filter(dirFlag, issues)
+			log.Printf("Problems in the baseline: %d, new: %d", found-len(issues), len(issues))
+			if fixed > 0 {
+				log.Printf("Problems of the baseline fixed since: %d; run brio lint --update-baseline to drop them", fixed)
+			}
+		}
+
+		for _, i := range issues {
+			fmt.Println(i)
+		}
+		checkCanceled(cmd.Context())
+		if len(issues) > 0 {
+			printReport()
+			exit(1)
+		}
+	},
+}
+
+// init registers lintCmd with the same directory and file pattern flags as extract.
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to scan")
+	lintCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	lintCmd.Flags().StringVar(&baselineFlag, "baseline", "",
+		"JSON file of known problems to leave out, failing only on new ones")
+	lintCmd.Flags().BoolVar(&updateBaselineFlag, "update-baseline", false,
+		"Record the problems found in the --baseline file instead of reporting them")
+}
+
+// lintFiles returns every annotation problem found in files, including expired and stale snippets, virtual
+// ones included. It stops early when ctx is canceled.
+func lintFiles(ctx context.Context, files []string) []issue {
+	var issues []issue
+
+	// One extractor reads each .briotags file once.
+	e := brio.New(cfg.options())
+	for _, filePath := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Files++
+		snips, fileIssues, err := scanFile(filePath)
+		if err != nil {
+			report.addError(err)
+			continue
+		}
+		issues = append(issues, fileIssues...)
+		virtual, virtualIssues := e.VirtualSnippets(ctx, filePath)
+		sidecar, sidecarIssues := e.SidecarSnippets(filePath)
+		issues = append(append(issues, virtualIssues...), sidecarIssues...)
+
+		for _, s := range append(append(snips, virtual...), sidecar...) {
+			if i, expired := s.ExpiryIssue(now()); expired {
+				issues = append(issues, i)
+			}
+			if i, stale := s.HashIssue(); stale {
+				issues = append(issues, i)
+			}
+		}
+	}
+
+	return issues
+}
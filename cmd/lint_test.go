@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fileContent := `# >: {"foundation": ["messages"], "_expires": "2025-06-01", "_since": "v2.3"}
+WORKAROUND = True
+# <: {"foundation": ["messages"]}
+
+# >: {"tests": ["messages"], "_expires": "2030-01-01"}
+def test_message():
+    assert True
+# <: {"tests": ["messages"]}
+
+# <: {"tests": ["messages"]}
+# >: {"tests": "messages"
+# >: {"model": ["messages"]}
+class Message(TenantModel):
+    pass`
+
+	filePath := filepath.Join(tempDir, "lint.py")
+	assert.Nil(t, os.WriteFile(filePath, []byte(fileContent), 0644))
+
+	now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+
+	var messages []string
+	for _, i := range lintFiles(context.Background(), []string{filePath}) {
+		messages = append(messages, i.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		filePath + ":10: BRIO001 end tag without a matching start tag",
+		filePath + ":11: BRIO002 ignoring invalid tag: tag payload is never closed",
+		filePath + ":12: BRIO001 start tag is never closed",
+		filePath + ":1: BRIO008 snippet expired on 2025-06-01 (in place since v2.3)",
+	}, messages)
+}
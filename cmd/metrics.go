@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsAddrFlag is the address brio rpc serves Prometheus metrics on, e.g. ":9464".
+var metricsAddrFlag string
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the request duration histogram.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// rpcMetrics counts the requests brio rpc answers and the work they took, for Prometheus to scrape.
+type rpcMetrics struct {
+	mu sync.Mutex
+	// requests counts the requests by method and outcome, "ok" or "error".
+	requests  map[[2]string]int
+	durations map[string]*histogram
+	files     int
+	failed    int
+	snippets  int
+	issues    int
+}
+
+// histogram counts observations by bucket of durationBuckets, the last one counting those over all.
+type histogram struct {
+	buckets []int
+	sum     float64
+	count   int
+}
+
+func newRPCMetrics() *rpcMetrics {
+	return &rpcMetrics{requests: make(map[[2]string]int), durations: make(map[string]*histogram)}
+}
+
+// observe records a request to method that took d, its outcome, and what its scan went through.
+func (m *rpcMetrics) observe(method string, d time.Duration, ok bool, r *scanReport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcome := "ok"
+	if !ok {
+		outcome = "error"
+	}
+	m.requests[[2]string{method, outcome}]++
+	h := m.durations[method]
+	if h == nil {
+		h = &histogram{buckets: make([]int, len(durationBuckets)+1)}
+		m.durations[method] = h
+	}
+	seconds := d.Seconds()
+	h.buckets[sort.SearchFloat64s(durationBuckets, seconds)]++
+	h.sum += seconds
+	h.count++
+	m.files += r.Files
+	m.failed += len(r.Failed)
+	m.snippets += r.Snippets
+	m.issues += len(r.Issues)
+}
+
+// write writes the metrics to w in the Prometheus text format.
+func (m *rpcMetrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# HELP brio_rpc_requests_total Requests answered, by method and outcome.\n")
+	printf("# TYPE brio_rpc_requests_total counter\n")
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		printf("brio_rpc_requests_total{method=%q,outcome=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+
+	printf("# HELP brio_rpc_request_duration_seconds Time taken to answer requests, by method.\n")
+	printf("# TYPE brio_rpc_request_duration_seconds histogram\n")
+	for _, method := range sortedKeys(m.durations) {
+		h := m.durations[method]
+		cumulative := 0
+		for i, bound := range durationBuckets {
+			cumulative += h.buckets[i]
+			printf("brio_rpc_request_duration_seconds_bucket{method=%q,le=%q} %d\n", method,
+				strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		printf("brio_rpc_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, h.count)
+		printf("brio_rpc_request_duration_seconds_sum{method=%q} %g\n", method, h.sum)
+		printf("brio_rpc_request_duration_seconds_count{method=%q} %d\n", method, h.count)
+	}
+
+	for _, c := range []struct {
+		name, help string
+		value      int
+	}{
+		{"brio_files_scanned_total", "Files scanned to answer requests.", m.files},
+		{"brio_files_failed_total", "Files that could not be scanned.", m.failed},
+		{"brio_snippets_matched_total", "Snippets matched by extractions.", m.snippets},
+		{"brio_annotation_issues_total", "Problems found in annotations.", m.issues},
+	} {
+		printf("# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value)
+	}
+	return err
+}
+
+// ServeHTTP serves the metrics to Prometheus.
+func (m *rpcMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.write(w); err != nil {
+		log.Printf("Failed to serve metrics: %v", err)
+	}
+}
+
+// serveMetrics serves m on addr at /metrics, in the background. It fails if addr can't be listened on.
+func serveMetrics(addr string, m *rpcMetrics) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Warning: metrics server stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
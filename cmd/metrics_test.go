@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRPCMetrics(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `# >: {"tests": ["messages"]}
+def push(item):
+    return item
+# <: {"tests": ["messages"]}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "queue.py"), []byte(content), 0644))
+
+	var spans []*tracepb.Span
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "Bearer xyz", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var payload coltracepb.ExportTraceServiceRequest
+		assert.NoError(t, proto.Unmarshal(body, &payload))
+		for _, rs := range payload.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20xyz")
+	provider, err := newTracerProvider(context.Background(), collector.URL)
+	require.NoError(t, err)
+	rpcMetricsSink, rpcTracer = newRPCMetrics(), provider.Tracer("brio")
+	defer func() { rpcMetricsSink, rpcTracer = nil, nil }()
+
+	requests := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "extract", "params": {"dir": "` + tempDir + `", "categories": "tests"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "annotateRange", "params": {"file": "missing.py"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "format"}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "initialize", "params": {"_meta": {"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}}`,
+	}, "\n")
+	require.NoError(t, serveRPC(context.Background(), strings.NewReader(requests), io.Discard))
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	server, err := serveMetrics("127.0.0.1:0", rpcMetricsSink)
+	require.NoError(t, err)
+	defer server.Close()
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	metrics := recorder.Body.String()
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, metrics, `brio_rpc_requests_total{method="extract",outcome="ok"} 1`)
+	assert.Contains(t, metrics, `brio_rpc_requests_total{method="annotateRange",outcome="error"} 1`)
+	assert.NotContains(t, metrics, `method="format"`, "unknown methods aren't counted")
+	assert.Contains(t, metrics, `brio_rpc_request_duration_seconds_bucket{method="extract",le="+Inf"} 1`)
+	assert.Contains(t, metrics, `brio_rpc_request_duration_seconds_count{method="extract"} 1`)
+	assert.Contains(t, metrics, "brio_files_scanned_total 1\n")
+	assert.Contains(t, metrics, "brio_snippets_matched_total 1\n")
+
+	require.Len(t, spans, 3)
+	assert.Equal(t, "extract", spans[0].Name)
+	assert.Len(t, spans[0].TraceId, 16)
+	assert.Empty(t, spans[0].ParentSpanId, "a request without a trace context starts a trace")
+	assert.Equal(t, tracepb.Status_STATUS_CODE_OK, spans[0].Status.Code)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, spans[1].Status.Code)
+	assert.Equal(t, "initialize", spans[2].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(spans[2].TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(spans[2].ParentSpanId))
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	m := newRPCMetrics()
+	for _, d := range []time.Duration{time.Millisecond, 200 * time.Millisecond, time.Minute} {
+		m.observe("lint", d, true, &scanReport{})
+	}
+	var out strings.Builder
+	require.NoError(t, m.write(&out))
+	assert.Contains(t, out.String(), `brio_rpc_request_duration_seconds_bucket{method="lint",le="0.005"} 1`)
+	assert.Contains(t, out.String(), `brio_rpc_request_duration_seconds_bucket{method="lint",le="0.25"} 2`)
+	assert.Contains(t, out.String(), `brio_rpc_request_duration_seconds_bucket{method="lint",le="30"} 2`)
+	assert.Contains(t, out.String(), `brio_rpc_request_duration_seconds_bucket{method="lint",le="+Inf"} 3`)
+}
+
+func TestNewOTLPExporterRejectsBadEndpoint(t *testing.T) {
+	_, err := newTracerProvider(context.Background(), "localhost:4318")
+	assert.Error(t, err)
+}
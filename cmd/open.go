@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// openFlag names the editor extract opens the snippets it prints in; openDefault, the value of a
+// bare --open, means $VISUAL or $EDITOR.
+var openFlag string
+
+// openDefault is the value of --open given without an editor.
+const openDefault = "auto"
+
+// maxOpened bounds how many snippets --open opens, so that a broad extraction doesn't spawn an
+// editor per snippet of the tree.
+const maxOpened = 10
+
+// resolveEditor returns the command line of the editor named by --open: the one given, or else
+// $VISUAL or $EDITOR.
+func resolveEditor(name string) ([]string, error) {
+	if name == openDefault {
+		name = os.Getenv("VISUAL")
+		if name == "" {
+			name = os.Getenv("EDITOR")
+		}
+		if name == "" {
+			return nil, errors.New("set $VISUAL or $EDITOR, or give the editor with --open=editor")
+		}
+	}
+	editor := strings.Fields(name)
+	if len(editor) == 0 {
+		return nil, errors.New("the editor is empty")
+	}
+	return editor, nil
+}
+
+// editorArgs returns the command line opening file at line in editor, in the syntax of editor:
+// "-g file:line" for VS Code and its forks, "file:line" for Sublime Text, Zed and Helix, "--line
+// line file" for JetBrains IDEs, and "+line file" for vi, Emacs, nano and most others.
+func editorArgs(editor []string, file string, line int) []string {
+	args := append([]string(nil), editor...)
+	name := strings.TrimSuffix(filepath.Base(editor[0]), ".exe")
+	switch name {
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		return append(args, "-g", file+":"+strconv.Itoa(line))
+	case "subl", "sublime_text", "zed", "hx", "helix":
+		return append(args, file+":"+strconv.Itoa(line))
+	case "idea", "goland", "pycharm", "webstorm", "phpstorm", "rubymine", "clion", "rider":
+		return append(args, "--line", strconv.Itoa(line), file)
+	}
+	return append(args, "+"+strconv.Itoa(line), file)
+}
+
+// openSnippets opens each of snips in the editor of --open at its start line, one after another,
+// and exits if one can't be. Snippets in a section of their file (e.g. a notebook cell), whose
+// line numbers are relative to it, are opened at the top of the file.
+func openSnippets(snips []snippet) {
+	editor, err := resolveEditor(openFlag)
+	if err != nil {
+		fatalf("Error with --open: %v", err)
+	}
+	if len(snips) > maxOpened {
+		log.Printf("Warning: opening the first %d of %d snippets only", maxOpened, len(snips))
+		snips = snips[:maxOpened]
+	}
+	for _, s := range snips {
+		line := s.StartLine
+		if s.Section != "" {
+			line = 1
+		}
+		args := editorArgs(editor, s.File, line)
+		command := exec.Command(args[0], args[1:]...)
+		// Editors running in the terminal take it over until they quit.
+		command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := command.Run(); err != nil {
+			fatalf("Error opening %s in %s: %v", displayPath(s.File), editor[0], err)
+		}
+	}
+}
+
+// sourceURI returns the vscode:// URI of the start line of s, which terminals open in VS Code when
+// its link is clicked.
+func sourceURI(s snippet) string {
+	path, err := filepath.Abs(s.File)
+	if err != nil {
+		path = s.File
+	}
+	uri := "vscode://file" + filepath.ToSlash(path)
+	if !strings.HasPrefix(filepath.ToSlash(path), "/") {
+		uri = "vscode://file/" + filepath.ToSlash(path)
+	}
+	if s.Section != "" {
+		return uri
+	}
+	return fmt.Sprintf("%s:%d", uri, s.StartLine)
+}
+
+// hyperlink wraps text in an OSC 8 escape sequence linking it to uri, which most terminals show as
+// a clickable link.
+func hyperlink(uri, text string) string {
+	return "\x1b]8;;" + uri + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
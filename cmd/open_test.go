@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditorArgs(t *testing.T) {
+	for _, tt := range []struct {
+		editor []string
+		want   []string
+	}{
+		{[]string{"vim"}, []string{"vim", "+12", "app.py"}},
+		{[]string{"emacsclient", "-n"}, []string{"emacsclient", "-n", "+12", "app.py"}},
+		{[]string{"/usr/local/bin/code", "--reuse-window"}, []string{"/usr/local/bin/code", "--reuse-window", "-g", "app.py:12"}},
+		{[]string{"subl"}, []string{"subl", "app.py:12"}},
+		{[]string{"goland"}, []string{"goland", "--line", "12", "app.py"}},
+	} {
+		assert.Equal(t, tt.want, editorArgs(tt.editor, "app.py", 12))
+	}
+}
+
+func TestResolveEditor(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nvim -p")
+	editor, err := resolveEditor(openDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nvim", "-p"}, editor)
+
+	t.Setenv("VISUAL", "code")
+	editor, err = resolveEditor(openDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"code"}, editor)
+
+	editor, err = resolveEditor("hx")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hx"}, editor)
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	_, err = resolveEditor(openDefault)
+	assert.EqualError(t, err, "set $VISUAL or $EDITOR, or give the editor with --open=editor")
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/spf13/cobra"
+)
+
+// pluginsCmd groups the commands inspecting the language plugins.
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Inspect the language plugins",
+	Long: `Plugins shows which languages brio knows about and where their support comes from:
+builtin, config (.brio.yaml), external (brio-plugin-* on PATH), wasm or native (plugin directory).`,
+}
+
+// pluginsListCmd lists the registered plugins.
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered plugins with their files and comment syntax",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printPlugins(os.Stdout, plugins.List())
+	},
+}
+
+// pluginsWhichCmd explains which plugin handles a file.
+var pluginsWhichCmd = &cobra.Command{
+	Use:   "which [files...]",
+	Short: "Show which plugin handles each file",
+	Long: `Which prints the plugin brio uses for each file and how it was chosen: by file name,
+by extension, or by the shebang line of a file without extension. It exits with status 1
+if brio would skip any of the files.
+Usage example:
+brio plugins which scripts/deploy src/app.py
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		skipped := false
+		for _, path := range args {
+			plugin, how, ok := matchPlugin(path)
+			if !ok {
+				fmt.Printf("%s: no plugin, the file is skipped\n", path)
+				skipped = true
+				continue
+			}
+			fmt.Printf("%s: %s (%s), matched by %s\n", path, plugin.GetName(), originOf(plugin), how)
+		}
+		if skipped {
+			exit(1)
+		}
+	},
+}
+
+// init registers pluginsCmd and its subcommands.
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsWhichCmd)
+}
+
+// printPlugins writes a table of the registered plugins to w.
+func printPlugins(w io.Writer, registrations []plugins.Registration) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tORIGIN\tPRIORITY\tFILES\tCOMMENTS\tMARKDOWN")
+	for _, r := range registrations {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\t%s\n", r.Plugin.GetName(), r.Origin, r.Priority,
+			strings.Join(pluginFiles(r.Plugin), " "), describeCommentStyle(r.Plugin.GetCommentStyle()),
+			r.Plugin.GetMarkdownIdentifier())
+	}
+	_ = table.Flush()
+}
+
+// pluginFiles lists the extensions, file name patterns and shebang interpreters a plugin handles.
+func pluginFiles(p plugins.Plugin) []string {
+	files := append([]string(nil), p.GetExtensions()...)
+	if fp, ok := p.(plugins.FilenamePlugin); ok {
+		files = append(files, fp.GetFilenames()...)
+	}
+	if ip, ok := p.(plugins.InterpreterPlugin); ok {
+		for _, name := range ip.GetInterpreters() {
+			files = append(files, "#!"+name)
+		}
+	}
+	return files
+}
+
+// describeCommentStyle renders the comment tokens of a style, e.g. `// /* */`.
+func describeCommentStyle(style plugins.CommentStyle) string {
+	tokens := style.SinglePrefixes()
+	if style.Multi.Start != "" {
+		multi := style.Multi.Start + " " + style.Multi.End
+		if style.MultiNested {
+			multi += " (nested)"
+		}
+		tokens = append(tokens, multi)
+	}
+	if len(tokens) == 0 {
+		return "-"
+	}
+	return strings.Join(tokens, ", ")
+}
+
+// originOf returns the origin of the most recent registration of p.
+func originOf(p plugins.Plugin) string {
+	// Comparing interfaces panics for plugin types that aren't comparable.
+	if !reflect.TypeOf(p).Comparable() {
+		return plugins.OriginBuiltin
+	}
+	registrations := plugins.List()
+	for i := len(registrations) - 1; i >= 0; i-- {
+		if registrations[i].Plugin == p {
+			return registrations[i].Origin
+		}
+	}
+	return plugins.OriginBuiltin
+}
@@ -0,0 +1,57 @@
+package plugins
+
+// ConfigPlugin is a language declared in the brio config file rather than in Go code
+type ConfigPlugin struct {
+	Name       string
+	Extensions []string
+	Filenames  []string
+	Style      CommentStyle
+	Markdown   string
+	// Imports are the prefixes of the lines starting import statements, if any
+	Imports []string
+	// Definitions are the patterns of the first lines of definitions, if any (see DefinitionPlugin)
+	Definitions []string
+	// Indented reports whether definitions end where their indentation does
+	Indented bool
+	// Priority overrides the priority of config plugins when set
+	Priority *int
+}
+
+func (p *ConfigPlugin) GetName() string {
+	return p.Name
+}
+
+func (p *ConfigPlugin) GetExtensions() []string {
+	return p.Extensions
+}
+
+func (p *ConfigPlugin) GetFilenames() []string {
+	return p.Filenames
+}
+
+func (p *ConfigPlugin) GetCommentStyle() CommentStyle {
+	return p.Style
+}
+
+func (p *ConfigPlugin) GetMarkdownIdentifier() string {
+	return p.Markdown
+}
+
+func (p *ConfigPlugin) GetImportPrefixes() []string {
+	return p.Imports
+}
+
+func (p *ConfigPlugin) GetDefinitionPatterns() []string {
+	return p.Definitions
+}
+
+func (p *ConfigPlugin) IndentedBlocks() bool {
+	return p.Indented
+}
+
+func (p *ConfigPlugin) GetPriority() (int, bool) {
+	if p.Priority == nil {
+		return 0, false
+	}
+	return *p.Priority, true
+}
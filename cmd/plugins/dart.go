@@ -0,0 +1,48 @@
+package plugins
+
+type DartPlugin struct{}
+
+func init() {
+	Register(&DartPlugin{})
+}
+
+func (p *DartPlugin) GetName() string {
+	return "Dart"
+}
+
+func (p *DartPlugin) GetExtensions() []string {
+	return []string{".dart"}
+}
+
+func (p *DartPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "//",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+		MultiNested: true,
+	}
+}
+
+func (p *DartPlugin) GetMarkdownIdentifier() string {
+	return "dart"
+}
+
+func (p *DartPlugin) GetImportPrefixes() []string {
+	return []string{"import ", "export "}
+}
+
+func (p *DartPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:abstract\s+)?(?:class|mixin|enum|extension)\s+(\w+)`,
+		`^\s*(?:static\s+)?[\w<>?,]+\s+(\w+)\s*\([^;]*\)\s*(?:async\s*)?\{`,
+	}
+}
+
+func (p *DartPlugin) IndentedBlocks() bool {
+	return false
+}
@@ -0,0 +1,33 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiscoverWASM returns the .wasm modules in dir, or nothing if dir doesn't exist
+func DiscoverWASM(dir string) []string {
+	return filesWithSuffix(dir, ".wasm")
+}
+
+// DiscoverNative returns the Go plugins (.so files) in dir, or nothing if dir doesn't exist
+func DiscoverNative(dir string) []string {
+	return filesWithSuffix(dir, ".so")
+}
+
+// filesWithSuffix returns the files in dir whose name ends with suffix
+func filesWithSuffix(dir, suffix string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var found []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), suffix) {
+			found = append(found, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return found
+}
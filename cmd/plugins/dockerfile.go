@@ -0,0 +1,29 @@
+package plugins
+
+type DockerfilePlugin struct{}
+
+func init() {
+	Register(&DockerfilePlugin{})
+}
+
+func (p *DockerfilePlugin) GetName() string {
+	return "Dockerfile"
+}
+
+func (p *DockerfilePlugin) GetExtensions() []string {
+	return []string{".dockerfile"}
+}
+
+func (p *DockerfilePlugin) GetFilenames() []string {
+	return []string{"Dockerfile", "Dockerfile.*", "*.Dockerfile", "Containerfile"}
+}
+
+func (p *DockerfilePlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *DockerfilePlugin) GetMarkdownIdentifier() string {
+	return "dockerfile"
+}
@@ -0,0 +1,229 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExternalPrefix is the name prefix of external plugin executables on PATH
+const ExternalPrefix = "brio-plugin-"
+
+// ProtocolVersion is the version of the JSON protocol spoken with external plugins
+const ProtocolVersion = 1
+
+// externalTimeout bounds how long a single request to an external plugin may take
+const externalTimeout = 30 * time.Second
+
+// externalRequest is written as JSON to the standard input of an external plugin. Method is
+// "describe" or "parse"; Path and Content are only set for "parse".
+type externalRequest struct {
+	Protocol int    `json:"protocol"`
+	Method   string `json:"method"`
+	Path     string `json:"path,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// externalDescription is the answer of an external plugin to "describe"
+type externalDescription struct {
+	Protocol   int      `json:"protocol"`
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+	Filenames  []string `json:"filenames"`
+	Comments   struct {
+		Single []string `json:"single"`
+		Multi  []string `json:"multi"`
+		Nested bool     `json:"nested"`
+	} `json:"comments"`
+	Markdown string `json:"markdown"`
+	// Priority overrides the default priority of the plugin's origin
+	Priority *int `json:"priority"`
+	// Capabilities lists optional methods the plugin supports; "parse" means it finds snippets itself
+	Capabilities []string `json:"capabilities"`
+	Error        string   `json:"error"`
+}
+
+// externalParseResult is the answer of an external plugin to "parse"
+type externalParseResult struct {
+	Snippets []struct {
+		StartLine int                 `json:"start_line"`
+		EndLine   int                 `json:"end_line"`
+		Tags      map[string][]string `json:"tags"`
+		Content   []string            `json:"content"`
+	} `json:"snippets"`
+	Issues []struct {
+		Line    int    `json:"line"`
+		Message string `json:"message"`
+	} `json:"issues"`
+	Error string `json:"error"`
+}
+
+// transport sends a JSON request to a plugin and returns its JSON response
+type transport func(input []byte) ([]byte, error)
+
+// ExternalPlugin is a language plugin implemented outside of brio, by an executable or a WASM
+// module that brio talks to over JSON on standard input and output
+type ExternalPlugin struct {
+	Path string
+	desc externalDescription
+	send transport
+}
+
+// externalParserPlugin is an ExternalPlugin that announced the "parse" capability
+type externalParserPlugin struct {
+	*ExternalPlugin
+}
+
+func (p *ExternalPlugin) GetName() string {
+	return p.desc.Name
+}
+
+func (p *ExternalPlugin) GetExtensions() []string {
+	return p.desc.Extensions
+}
+
+func (p *ExternalPlugin) GetFilenames() []string {
+	return p.desc.Filenames
+}
+
+func (p *ExternalPlugin) GetCommentStyle() CommentStyle {
+	return NewCommentStyle(p.desc.Comments.Single, p.desc.Comments.Multi, p.desc.Comments.Nested)
+}
+
+func (p *ExternalPlugin) GetMarkdownIdentifier() string {
+	return p.desc.Markdown
+}
+
+func (p *ExternalPlugin) GetPriority() (int, bool) {
+	if p.desc.Priority == nil {
+		return 0, false
+	}
+	return *p.desc.Priority, true
+}
+
+// ParseSnippets asks the plugin for the snippets tagged in a file
+func (p externalParserPlugin) ParseSnippets(path string, content []byte) ([]Snippet, []Issue, error) {
+	var result externalParseResult
+	err := p.call(externalRequest{Method: "parse", Path: path, Content: string(content)}, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result.Error != "" {
+		return nil, nil, fmt.Errorf("%s: %s", p.desc.Name, result.Error)
+	}
+
+	snippets := make([]Snippet, 0, len(result.Snippets))
+	for _, s := range result.Snippets {
+		snippets = append(snippets, Snippet{StartLine: s.StartLine, EndLine: s.EndLine, Tags: s.Tags, Content: s.Content})
+	}
+	issues := make([]Issue, 0, len(result.Issues))
+	for _, i := range result.Issues {
+		issues = append(issues, Issue{Line: i.Line, Message: i.Message})
+	}
+	return snippets, issues, nil
+}
+
+// DiscoverExternal returns the brio-plugin-* executables in the directories of pathList (formatted
+// like $PATH). When several directories hold a plugin of the same name, the first one wins.
+func DiscoverExternal(pathList string) []string {
+	var found []string
+	seen := make(map[string]bool)
+
+	for _, dir := range filepath.SplitList(pathList) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, ExternalPrefix) || seen[name] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			found = append(found, filepath.Join(dir, name))
+		}
+	}
+
+	return found
+}
+
+// LoadExternal asks the executable at path to describe itself and returns the plugin it implements
+func LoadExternal(path string) (Plugin, error) {
+	return loadExternal(path, runExecutable(path))
+}
+
+// loadExternal asks the plugin behind send to describe itself and returns the plugin it implements
+func loadExternal(path string, send transport) (Plugin, error) {
+	p := &ExternalPlugin{Path: path, send: send}
+	if err := p.call(externalRequest{Method: "describe"}, &p.desc); err != nil {
+		return nil, err
+	}
+
+	switch desc := p.desc; {
+	case desc.Error != "":
+		return nil, errors.New(desc.Error)
+	case desc.Protocol != ProtocolVersion:
+		return nil, fmt.Errorf("speaks protocol version %d, brio expects %d", desc.Protocol, ProtocolVersion)
+	case desc.Name == "":
+		return nil, errors.New("describe returned no name")
+	case len(desc.Extensions) == 0 && len(desc.Filenames) == 0:
+		return nil, errors.New("describe returned no extensions or file names")
+	}
+
+	for _, capability := range p.desc.Capabilities {
+		if capability == "parse" {
+			return externalParserPlugin{p}, nil
+		}
+	}
+	return p, nil
+}
+
+// call sends req to the plugin and decodes its answer into resp
+func (p *ExternalPlugin) call(req externalRequest, resp interface{}) error {
+	req.Protocol = ProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	output, err := p.send(input)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", filepath.Base(p.Path), req.Method, err)
+	}
+	if err := json.Unmarshal(output, resp); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %v", filepath.Base(p.Path), req.Method, err)
+	}
+	return nil
+}
+
+// runExecutable returns a transport that runs the executable at path once per request
+func runExecutable(path string) transport {
+	return func(input []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), externalTimeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		command := exec.CommandContext(ctx, path)
+		command.Stdin = bytes.NewReader(input)
+		command.Stdout = &stdout
+		command.Stderr = &stderr
+		if err := command.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%v: %s", err, msg)
+			}
+			return nil, err
+		}
+		return stdout.Bytes(), nil
+	}
+}
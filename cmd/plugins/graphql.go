@@ -0,0 +1,25 @@
+package plugins
+
+type GraphQLPlugin struct{}
+
+func init() {
+	Register(&GraphQLPlugin{})
+}
+
+func (p *GraphQLPlugin) GetName() string {
+	return "GraphQL"
+}
+
+func (p *GraphQLPlugin) GetExtensions() []string {
+	return []string{".graphql", ".gql"}
+}
+
+func (p *GraphQLPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *GraphQLPlugin) GetMarkdownIdentifier() string {
+	return "graphql"
+}
@@ -0,0 +1,63 @@
+package plugins
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// heuristicTagLine matches lines that look like a brio tag behind one of the common comment
+// prefixes, capturing the prefix. ";;" comes before ";" so Lisp comments keep both semicolons
+var heuristicTagLine = regexp.MustCompile(`(?im)^\s*(//|--|;;|;|#)\s*(?:>|<|start|end)\s*:`)
+
+// HeuristicPlugin handles a file of unknown type with a comment prefix guessed from its content
+type HeuristicPlugin struct {
+	Prefix   string
+	Markdown string
+}
+
+// GuessCommentPrefix returns the comment prefix used by most of the tag-like lines in content
+func GuessCommentPrefix(content []byte) (string, bool) {
+	counts := make(map[string]int)
+	best := ""
+	for _, m := range heuristicTagLine.FindAllSubmatch(content, -1) {
+		prefix := string(m[1])
+		counts[prefix]++
+		if best == "" || counts[prefix] > counts[best] {
+			best = prefix
+		}
+	}
+	return best, best != ""
+}
+
+// NewHeuristic returns a plugin for a file of unknown type, guessing its comment prefix from
+// content. ext is used as the Markdown identifier
+func NewHeuristic(content []byte, ext string) (*HeuristicPlugin, bool) {
+	// Binary files have no comments to guess from.
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil, false
+	}
+	prefix, ok := GuessCommentPrefix(content)
+	if !ok {
+		return nil, false
+	}
+	return &HeuristicPlugin{Prefix: prefix, Markdown: strings.TrimPrefix(ext, ".")}, true
+}
+
+func (p *HeuristicPlugin) GetName() string {
+	return "Unknown (" + p.Prefix + " comments)"
+}
+
+func (p *HeuristicPlugin) GetExtensions() []string {
+	return nil
+}
+
+func (p *HeuristicPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: p.Prefix,
+	}
+}
+
+func (p *HeuristicPlugin) GetMarkdownIdentifier() string {
+	return p.Markdown
+}
@@ -0,0 +1,47 @@
+package plugins
+
+type JavaPlugin struct{}
+
+func init() {
+	Register(&JavaPlugin{})
+}
+
+func (p *JavaPlugin) GetName() string {
+	return "Java"
+}
+
+func (p *JavaPlugin) GetExtensions() []string {
+	return []string{".java"}
+}
+
+func (p *JavaPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "//",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+	}
+}
+
+func (p *JavaPlugin) GetMarkdownIdentifier() string {
+	return "java"
+}
+
+func (p *JavaPlugin) GetImportPrefixes() []string {
+	return []string{"import "}
+}
+
+func (p *JavaPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:(?:public|protected|private|static|final|abstract|sealed)\s+)*(?:class|interface|enum|record)\s+(\w+)`,
+		`^\s*(?:(?:public|protected|private|static|final|abstract|synchronized)\s+)+[\w<>\[\],.? ]+\s+(\w+)\s*\(`,
+	}
+}
+
+func (p *JavaPlugin) IndentedBlocks() bool {
+	return false
+}
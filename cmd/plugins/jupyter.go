@@ -0,0 +1,102 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JupyterPlugin handles Jupyter notebooks. Tags are read from the code cells, using the comment
+// style of the notebook's kernel language
+type JupyterPlugin struct{}
+
+func init() {
+	Register(&JupyterPlugin{})
+}
+
+// notebook holds the parts of the .ipynb JSON format brio reads
+type notebook struct {
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		CellType string `json:"cell_type"`
+		// Source is a string or a list of lines that keep their line endings
+		Source json.RawMessage `json:"source"`
+	} `json:"cells"`
+}
+
+func (p *JupyterPlugin) GetName() string {
+	return "Jupyter Notebook"
+}
+
+func (p *JupyterPlugin) GetExtensions() []string {
+	return []string{".ipynb"}
+}
+
+func (p *JupyterPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{}
+}
+
+func (p *JupyterPlugin) GetMarkdownIdentifier() string {
+	return "json"
+}
+
+// Sections returns the code cells of the notebook, named after their position among all cells
+func (p *JupyterPlugin) Sections(content []byte) ([]Section, error) {
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %v", err)
+	}
+	kernel := kernelPlugin(nb)
+
+	var sections []Section
+	for i, cell := range nb.Cells {
+		if cell.CellType != "code" {
+			continue
+		}
+		source, err := cellSource(cell.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source in cell %d: %v", i+1, err)
+		}
+		sections = append(sections, Section{Name: fmt.Sprintf("cell %d", i+1), Content: []byte(source), Plugin: kernel})
+	}
+	return sections, nil
+}
+
+// kernelPlugin returns the plugin of the notebook's kernel language. Languages brio doesn't know
+// get "#" comments, which most notebook kernels (Python, R, Julia) use
+func kernelPlugin(nb notebook) Plugin {
+	language := nb.Metadata.Kernelspec.Language
+	if language == "" {
+		language = nb.Metadata.LanguageInfo.Name
+	}
+	if language == "" {
+		language = "python"
+	}
+	if p, ok := GetByLanguage(language); ok {
+		return p
+	}
+	return &blockPlugin{name: language, style: NewCommentStyle([]string{"#"}, nil, false), markdown: strings.ToLower(language)}
+}
+
+// cellSource joins the source of a cell, stored as a string or as a list of lines
+func cellSource(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var source string
+	if err := json.Unmarshal(raw, &source); err == nil {
+		return source, nil
+	}
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, ""), nil
+}
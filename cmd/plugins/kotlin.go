@@ -0,0 +1,47 @@
+package plugins
+
+type KotlinPlugin struct{}
+
+func init() {
+	Register(&KotlinPlugin{})
+}
+
+func (p *KotlinPlugin) GetName() string {
+	return "Kotlin"
+}
+
+func (p *KotlinPlugin) GetExtensions() []string {
+	return []string{".kt", ".kts"}
+}
+
+func (p *KotlinPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "//",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+	}
+}
+
+func (p *KotlinPlugin) GetMarkdownIdentifier() string {
+	return "kotlin"
+}
+
+func (p *KotlinPlugin) GetImportPrefixes() []string {
+	return []string{"import "}
+}
+
+func (p *KotlinPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:\w+\s+)*fun\s+(?:<[^>]*>\s*)?(?:\w+\.)?(\w+)\s*\(`,
+		`^\s*(?:\w+\s+)*(?:class|interface|object)\s+(\w+)`,
+	}
+}
+
+func (p *KotlinPlugin) IndentedBlocks() bool {
+	return false
+}
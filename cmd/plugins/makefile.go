@@ -0,0 +1,29 @@
+package plugins
+
+type MakefilePlugin struct{}
+
+func init() {
+	Register(&MakefilePlugin{})
+}
+
+func (p *MakefilePlugin) GetName() string {
+	return "Makefile"
+}
+
+func (p *MakefilePlugin) GetExtensions() []string {
+	return []string{".mk"}
+}
+
+func (p *MakefilePlugin) GetFilenames() []string {
+	return []string{"Makefile", "makefile", "GNUmakefile"}
+}
+
+func (p *MakefilePlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *MakefilePlugin) GetMarkdownIdentifier() string {
+	return "makefile"
+}
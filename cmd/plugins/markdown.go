@@ -0,0 +1,95 @@
+package plugins
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// markdownFenceOpen matches the opening line of a fenced code block, capturing the fence and the
+	// info string
+	markdownFenceOpen = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`]*)$")
+	// markdownBlockTag matches a brio tag in the info string of a code fence
+	markdownBlockTag = regexp.MustCompile(`(?:^|\s)brio=(.*)$`)
+)
+
+// MarkdownPlugin handles Markdown documents. Tags are written in HTML comments, and fenced code
+// blocks can be tagged from their info string: ```python brio={"tests": ["docs"]}
+type MarkdownPlugin struct{}
+
+func init() {
+	Register(&MarkdownPlugin{})
+}
+
+func (p *MarkdownPlugin) GetName() string {
+	return "Markdown"
+}
+
+func (p *MarkdownPlugin) GetExtensions() []string {
+	return []string{".md", ".mdx", ".markdown"}
+}
+
+func (p *MarkdownPlugin) GetCommentStyle() CommentStyle {
+	return markupComments
+}
+
+func (p *MarkdownPlugin) GetMarkdownIdentifier() string {
+	return "markdown"
+}
+
+func (p *MarkdownPlugin) OuterBlock() Plugin {
+	return p
+}
+
+func (p *MarkdownPlugin) SwitchBlock(current Plugin, line string) (Plugin, bool) {
+	if fence, ok := current.(*fencedBlock); ok {
+		// A fence is closed by a line of at least as many of the same characters
+		closing := strings.TrimSpace(line)
+		if strings.HasPrefix(closing, fence.fence) && strings.Trim(closing, fence.fence[:1]) == "" {
+			return p, true
+		}
+		return nil, false
+	}
+
+	m := markdownFenceOpen.FindStringSubmatch(line)
+	if m == nil {
+		return nil, false
+	}
+	info := strings.TrimSpace(m[2])
+	block := &fencedBlock{fence: m[1]}
+	if fields := strings.Fields(info); len(fields) > 0 && !strings.HasPrefix(fields[0], "brio=") {
+		block.language = fields[0]
+	}
+	if tag := markdownBlockTag.FindStringSubmatch(info); tag != nil {
+		block.tag = strings.TrimSpace(tag[1])
+	}
+	return block, true
+}
+
+// fencedBlock is a fenced code block of a Markdown document. Its content is code, not Markdown, so
+// it has no comments brio looks at
+type fencedBlock struct {
+	fence    string
+	language string
+	tag      string
+}
+
+func (b *fencedBlock) GetName() string {
+	return "Markdown code block"
+}
+
+func (b *fencedBlock) GetExtensions() []string {
+	return nil
+}
+
+func (b *fencedBlock) GetCommentStyle() CommentStyle {
+	return CommentStyle{}
+}
+
+func (b *fencedBlock) GetMarkdownIdentifier() string {
+	return b.language
+}
+
+func (b *fencedBlock) BlockTag() string {
+	return b.tag
+}
@@ -0,0 +1,37 @@
+//go:build (linux || darwin) && cgo
+
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// NativeSupported reports whether native plugins can be loaded on this platform
+const NativeSupported = true
+
+// LoadNative opens the Go plugin (.so) at path. The plugin either registers its languages from an
+// init function, or exports a variable named Plugin that implements the Plugin interface.
+func LoadNative(path string) ([]Plugin, error) {
+	// Plugins registering themselves in init do so while the library is opened.
+	defaultOrigin = OriginNative
+	lib, err := plugin.Open(path)
+	defaultOrigin = OriginBuiltin
+	if err != nil {
+		return nil, err
+	}
+
+	symbol, err := lib.Lookup("Plugin")
+	if err != nil {
+		// Nothing exported: the plugin registered itself in init.
+		return nil, nil
+	}
+	switch p := symbol.(type) {
+	case *Plugin:
+		return []Plugin{*p}, nil
+	case Plugin:
+		return []Plugin{p}, nil
+	default:
+		return nil, fmt.Errorf("symbol Plugin has type %T, which doesn't implement plugins.Plugin", symbol)
+	}
+}
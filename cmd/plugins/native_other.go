@@ -0,0 +1,13 @@
+//go:build !((linux || darwin) && cgo)
+
+package plugins
+
+import "errors"
+
+// NativeSupported reports whether native plugins can be loaded on this platform
+const NativeSupported = false
+
+// LoadNative always fails: Go plugins are only supported on Linux and macOS, with cgo
+func LoadNative(path string) ([]Plugin, error) {
+	return nil, errors.New("native plugins are only supported on Linux and macOS builds with cgo")
+}
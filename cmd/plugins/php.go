@@ -0,0 +1,48 @@
+package plugins
+
+type PHPPlugin struct{}
+
+func init() {
+	Register(&PHPPlugin{})
+}
+
+func (p *PHPPlugin) GetName() string {
+	return "PHP"
+}
+
+func (p *PHPPlugin) GetExtensions() []string {
+	return []string{".php"}
+}
+
+func (p *PHPPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single:      "//",
+		ExtraSingle: []string{"#"},
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+	}
+}
+
+func (p *PHPPlugin) GetMarkdownIdentifier() string {
+	return "php"
+}
+
+func (p *PHPPlugin) GetImportPrefixes() []string {
+	return []string{"use ", "require ", "require_once ", "include ", "include_once "}
+}
+
+func (p *PHPPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:(?:public|protected|private|static|final|abstract)\s+)*function\s+(\w+)`,
+		`^\s*(?:(?:final|abstract|readonly)\s+)*(?:class|interface|trait|enum)\s+(\w+)`,
+	}
+}
+
+func (p *PHPPlugin) IndentedBlocks() bool {
+	return false
+}
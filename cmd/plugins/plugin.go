@@ -1,14 +1,54 @@
 package plugins
 
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
 // CommentStyle represents how comments are formatted in a language
 type CommentStyle struct {
 	// Single line comment prefix (e.g., "//", "#")
 	Single string
+	// Additional single line comment prefixes for languages that have several (e.g., PHP's "#")
+	ExtraSingle []string
 	// Multi-line comment start and end tokens (e.g., ["/*", "*/"])
 	Multi struct {
 		Start string
 		End   string
 	}
+	// MultiAtLineStart restricts the multi-line tokens to the start of a line (e.g. Ruby's =begin/=end)
+	MultiAtLineStart bool
+	// MultiNested allows multi-line comments to nest (e.g. Swift's /* /* */ */)
+	MultiNested bool
+	// MultiIsCode marks multi-line tokens that delimit string literals rather than comments (e.g.
+	// Python's docstrings): they may carry tags, but otherwise their lines belong to snippets
+	MultiIsCode bool
+}
+
+// NewCommentStyle builds a comment style from a list of single line prefixes and an optional
+// pair of multi-line start and end tokens
+func NewCommentStyle(single []string, multi []string, nested bool) CommentStyle {
+	var style CommentStyle
+	if len(single) > 0 {
+		style.Single = single[0]
+		style.ExtraSingle = single[1:]
+	}
+	if len(multi) == 2 {
+		style.Multi.Start = multi[0]
+		style.Multi.End = multi[1]
+		style.MultiNested = nested
+	}
+	return style
+}
+
+// SinglePrefixes returns every single line comment prefix, starting with Single
+func (c CommentStyle) SinglePrefixes() []string {
+	var prefixes []string
+	if c.Single != "" {
+		prefixes = append(prefixes, c.Single)
+	}
+	return append(prefixes, c.ExtraSingle...)
 }
 
 // Plugin defines the interface that all language plugins must implement
@@ -22,20 +62,309 @@ type Plugin interface {
 	GetMarkdownIdentifier() string
 }
 
-// registry stores all available plugins
-var registry = make(map[string]Plugin)
+// Snippet is a tagged section of a file found by a SnippetParser
+type Snippet struct {
+	StartLine int
+	EndLine   int
+	// Tags holds the categories and underscore-prefixed attributes of the snippet, as written in its tag
+	Tags    map[string][]string
+	Content []string
+}
+
+// Issue is a problem a SnippetParser found in the annotations of a file
+type Issue struct {
+	Line    int
+	Message string
+}
+
+// SnippetParser is implemented by plugins that find the tagged snippets of a file themselves
+// instead of leaving it to brio's comment parser
+type SnippetParser interface {
+	// ParseSnippets returns the snippets tagged in the file at path, whose contents are given
+	ParseSnippets(path string, content []byte) ([]Snippet, []Issue, error)
+}
+
+// InterpreterPlugin is implemented by plugins for scripting languages whose files may have no
+// extension and are recognized by their shebang line instead
+type InterpreterPlugin interface {
+	// GetInterpreters returns the interpreter names found in shebang lines (e.g., "bash")
+	GetInterpreters() []string
+}
+
+// FilenamePlugin is implemented by plugins for files recognized by their name rather than their
+// extension (e.g., Dockerfile)
+type FilenamePlugin interface {
+	// GetFilenames returns glob patterns matched against the base name of a file (e.g., "Dockerfile.*")
+	GetFilenames() []string
+}
+
+// ImportPlugin is implemented by plugins for languages whose files declare what they use in import
+// statements (e.g., Python's "import" and "from"), which brio can prepend to snippets
+type ImportPlugin interface {
+	// GetImportPrefixes returns the prefixes of the lines starting an import statement, at the
+	// start of a line (e.g., "import ")
+	GetImportPrefixes() []string
+}
+
+// DefinitionPlugin is implemented by plugins that can find the functions and types defined in a
+// file, so that brio can pull in the definitions a snippet refers to
+type DefinitionPlugin interface {
+	// GetDefinitionPatterns returns regular expressions matching the first line of a definition,
+	// whose first group is the name defined (e.g., `^\s*def\s+(\w+)`)
+	GetDefinitionPatterns() []string
+	// IndentedBlocks reports whether definitions end where their indentation does, as in Python,
+	// rather than where their braces close
+	IndentedBlocks() bool
+}
+
+// Section is a part of a document scanned on its own, such as a code cell of a notebook. Line
+// numbers of its snippets and issues are relative to the section
+type Section struct {
+	// Name identifies the section in reports (e.g., "cell 3")
+	Name    string
+	Content []byte
+	// Plugin is the plugin whose comment style applies to the section
+	Plugin Plugin
+}
+
+// DocumentPlugin is implemented by plugins for files whose code is stored inside a container format,
+// such as Jupyter notebooks, and must be taken out of it before being scanned
+type DocumentPlugin interface {
+	// Sections returns the parts of the document that hold code
+	Sections(content []byte) ([]Section, error)
+}
+
+// PriorityPlugin is implemented by plugins that may set their own priority. When several plugins
+// claim the same file type, the one with the highest priority handles it
+type PriorityPlugin interface {
+	// GetPriority returns the priority of the plugin, and false to use the default of its origin
+	GetPriority() (int, bool)
+}
+
+// Origins of registered plugins
+const (
+	OriginBuiltin  = "builtin"  // compiled into brio
+	OriginConfig   = "config"   // declared in the brio config file
+	OriginExternal = "external" // brio-plugin-* executable on PATH
+	OriginWASM     = "wasm"     // WASM module in the plugin directory
+	OriginNative   = "native"   // Go plugin (.so) in the plugin directory
+)
+
+// originPriorities holds the default priority of the plugins of each origin: the more specific
+// to the project a plugin is, the higher
+var originPriorities = map[string]int{
+	OriginBuiltin:  0,
+	OriginExternal: 10,
+	OriginWASM:     20,
+	OriginNative:   30,
+	OriginConfig:   40,
+}
+
+// Registration is a registered plugin, where it came from and its priority
+type Registration struct {
+	Plugin   Plugin
+	Origin   string
+	Priority int
+}
+
+// Conflict records two plugins of equal priority claiming the same extension, file name pattern or
+// interpreter; the one registered last was kept
+type Conflict struct {
+	Claim   string
+	Kept    Registration
+	Dropped Registration
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s is claimed by both %s (%s) and %s (%s), using %s; pin it in the config to choose",
+		c.Claim, c.Dropped.Plugin.GetName(), c.Dropped.Origin, c.Kept.Plugin.GetName(), c.Kept.Origin,
+		c.Kept.Plugin.GetName())
+}
+
+// filenamePattern associates a file name glob with its plugin
+type filenamePattern struct {
+	pattern string
+	reg     Registration
+}
+
+// registry stores the plugin handling each extension
+var registry = make(map[string]Registration)
+
+// registrations lists every registered plugin in registration order
+var registrations []Registration
+
+// defaultOrigin is the origin of plugins registered with Register
+var defaultOrigin = OriginBuiltin
+
+// filenames stores the plugins of files recognized by name, in registration order
+var filenames []filenamePattern
+
+// interpreters stores the plugins of scripting languages by interpreter name
+var interpreters = make(map[string]Registration)
+
+// pins maps extensions and file names to the name of the plugin that must handle them
+var pins = make(map[string]string)
+
+// conflicts lists the claims that were resolved by registration order only
+var conflicts []Conflict
 
 // Register adds a plugin to the registry
 func Register(p Plugin) {
+	RegisterFrom(p, defaultOrigin)
+}
+
+// RegisterFrom adds a plugin to the registry, recording its origin. A plugin takes over the file types
+// of plugins with a lower priority, and of plugins with the same priority registered before it
+func RegisterFrom(p Plugin, origin string) {
+	reg := Registration{Plugin: p, Origin: origin, Priority: originPriorities[origin]}
+	if pp, ok := p.(PriorityPlugin); ok {
+		if priority, set := pp.GetPriority(); set {
+			reg.Priority = priority
+		}
+	}
+	registrations = append(registrations, reg)
+
 	for _, ext := range p.GetExtensions() {
-		registry[ext] = p
+		claim(registry, "extension "+ext, ext, reg)
+	}
+	if fp, ok := p.(FilenamePlugin); ok {
+		for _, pattern := range fp.GetFilenames() {
+			for _, f := range filenames {
+				if f.pattern == pattern && f.reg.Priority == reg.Priority {
+					conflicts = append(conflicts, Conflict{Claim: "file name " + pattern, Kept: reg, Dropped: f.reg})
+				}
+			}
+			filenames = append(filenames, filenamePattern{pattern: pattern, reg: reg})
+		}
+	}
+	if ip, ok := p.(InterpreterPlugin); ok {
+		for _, name := range ip.GetInterpreters() {
+			claim(interpreters, "interpreter "+name, name, reg)
+		}
+	}
+}
+
+// claim assigns key of claims to reg unless a plugin with a higher priority holds it
+func claim(claims map[string]Registration, description, key string, reg Registration) {
+	current, exists := claims[key]
+	switch {
+	case !exists || reg.Priority > current.Priority:
+		claims[key] = reg
+	case reg.Priority == current.Priority:
+		conflicts = append(conflicts, Conflict{Claim: description, Kept: reg, Dropped: current})
+		claims[key] = reg
+	}
+}
+
+// Conflicts returns the claims that were resolved by registration order only
+func Conflicts() []Conflict {
+	return append([]Conflict(nil), conflicts...)
+}
+
+// Pin makes the plugin named name handle the files with the given extension (e.g., ".h") or file
+// name (e.g., "Jenkinsfile"), whatever the priorities. The latest plugin registered under that name
+// is used
+func Pin(key, name string) error {
+	if _, ok := lookupName(name); !ok {
+		return fmt.Errorf("can't pin %s to %s: no plugin of that name is registered", key, name)
+	}
+	pins[key] = name
+	return nil
+}
+
+// MapExtension makes the plugin of language, given by its name or Markdown identifier (e.g.,
+// "python"), handle the files with the extension ext (e.g., ".pyx") as well, over the plugins
+// claiming it
+func MapExtension(ext, language string) error {
+	p, ok := GetByLanguage(language)
+	if !ok {
+		return fmt.Errorf("can't map %s to %s: no plugin handles that language", ext, language)
+	}
+	registry[ext] = Registration{Plugin: p, Origin: OriginConfig, Priority: originPriorities[OriginConfig]}
+	return nil
+}
+
+// lookupName returns the latest plugin registered under name, ignoring case
+func lookupName(name string) (Plugin, bool) {
+	for i := len(registrations) - 1; i >= 0; i-- {
+		if strings.EqualFold(registrations[i].Plugin.GetName(), name) {
+			return registrations[i].Plugin, true
+		}
 	}
+	return nil, false
 }
 
 // Get returns the appropriate plugin for a given file extension
 func Get(extension string) (Plugin, bool) {
-	plugin, exists := registry[extension]
-	return plugin, exists
+	if name, pinned := pins[extension]; pinned {
+		return lookupName(name)
+	}
+	reg, exists := registry[extension]
+	return reg.Plugin, exists
+}
+
+// GetByFilename returns the plugin whose file name patterns match the base name of a file. When
+// several match, the one with the highest priority, then the latest registered, wins
+func GetByFilename(name string) (Plugin, bool) {
+	if pinnedName, pinned := pins[name]; pinned {
+		return lookupName(pinnedName)
+	}
+
+	var best *Registration
+	for i := range filenames {
+		f := &filenames[i]
+		if matched, _ := path.Match(f.pattern, name); matched && (best == nil || f.reg.Priority >= best.Priority) {
+			best = &f.reg
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	return best.Plugin, true
+}
+
+// GetByShebang returns the plugin for the interpreter named in a shebang line such as
+// "#!/bin/bash" or "#!/usr/bin/env -S zsh -e"
+func GetByShebang(line string) (Plugin, bool) {
+	if !strings.HasPrefix(line, "#!") {
+		return nil, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's options to get to the command it runs
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+
+	reg, exists := interpreters[interpreter]
+	return reg.Plugin, exists
+}
+
+// GetByLanguage returns the latest plugin registered for a language, given by its name or its
+// Markdown identifier (e.g., "python"), ignoring case
+func GetByLanguage(language string) (Plugin, bool) {
+	for i := len(registrations) - 1; i >= 0; i-- {
+		p := registrations[i].Plugin
+		if strings.EqualFold(p.GetMarkdownIdentifier(), language) || strings.EqualFold(p.GetName(), language) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// List returns every registered plugin in registration order
+func List() []Registration {
+	return append([]Registration(nil), registrations...)
 }
 
 // ListExtensions returns all supported file extensions
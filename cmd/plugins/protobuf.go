@@ -0,0 +1,36 @@
+package plugins
+
+type ProtobufPlugin struct{}
+
+func init() {
+	Register(&ProtobufPlugin{})
+}
+
+func (p *ProtobufPlugin) GetName() string {
+	return "Protocol Buffers"
+}
+
+func (p *ProtobufPlugin) GetExtensions() []string {
+	return []string{".proto"}
+}
+
+func (p *ProtobufPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "//",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+	}
+}
+
+func (p *ProtobufPlugin) GetMarkdownIdentifier() string {
+	return "protobuf"
+}
+
+func (p *ProtobufPlugin) GetImportPrefixes() []string {
+	return []string{"import "}
+}
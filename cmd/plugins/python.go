@@ -24,9 +24,26 @@ func (p *PythonPlugin) GetCommentStyle() CommentStyle {
 			Start: `"""`,
 			End:   `"""`,
 		},
+		// Triple-quoted strings are mostly docstrings, which are part of the code
+		MultiIsCode: true,
 	}
 }
 
 func (p *PythonPlugin) GetMarkdownIdentifier() string {
 	return "python"
 }
+
+func (p *PythonPlugin) GetImportPrefixes() []string {
+	return []string{"import ", "from "}
+}
+
+func (p *PythonPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:async\s+)?def\s+(\w+)`,
+		`^\s*class\s+(\w+)`,
+	}
+}
+
+func (p *PythonPlugin) IndentedBlocks() bool {
+	return true
+}
@@ -0,0 +1,37 @@
+package plugins
+
+type RubyPlugin struct{}
+
+func init() {
+	Register(&RubyPlugin{})
+}
+
+func (p *RubyPlugin) GetName() string {
+	return "Ruby"
+}
+
+func (p *RubyPlugin) GetExtensions() []string {
+	return []string{".rb", ".rake"}
+}
+
+func (p *RubyPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "=begin",
+			End:   "=end",
+		},
+		MultiAtLineStart: true,
+	}
+}
+
+func (p *RubyPlugin) GetMarkdownIdentifier() string {
+	return "ruby"
+}
+
+func (p *RubyPlugin) GetImportPrefixes() []string {
+	return []string{"require ", "require_relative "}
+}
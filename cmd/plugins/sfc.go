@@ -0,0 +1,126 @@
+package plugins
+
+import "regexp"
+
+// CompositePlugin is implemented by plugins for files made of blocks written in different languages,
+// such as Vue single-file components. Each block is scanned with the comment style of its own plugin
+type CompositePlugin interface {
+	// OuterBlock returns the plugin of the lines outside any block
+	OuterBlock() Plugin
+	// SwitchBlock returns the plugin of the lines following line when line opens or closes a block.
+	// current is the plugin of the block containing line
+	SwitchBlock(current Plugin, line string) (Plugin, bool)
+}
+
+// TaggedBlock is implemented by the block plugins of a CompositePlugin for blocks that carry a tag
+// on their opening line, such as Markdown code fences. The whole block becomes a snippet
+type TaggedBlock interface {
+	// BlockTag returns the tag payload of the block, or "" if it has none
+	BlockTag() string
+}
+
+var (
+	sfcScriptOpen  = regexp.MustCompile(`^\s*<script\b([^>]*)>`)
+	sfcStyleOpen   = regexp.MustCompile(`^\s*<style\b([^>]*)>`)
+	sfcBlockClose  = regexp.MustCompile(`</(?:script|style)>\s*$`)
+	sfcLangAttr    = regexp.MustCompile(`\blang\s*=\s*["']?(\w+)`)
+	markupComments = NewCommentStyle(nil, []string{"<!--", "-->"}, false)
+)
+
+// blockPlugin is the language of one block of a composite file
+type blockPlugin struct {
+	name     string
+	style    CommentStyle
+	markdown string
+}
+
+func (p *blockPlugin) GetName() string {
+	return p.name
+}
+
+func (p *blockPlugin) GetExtensions() []string {
+	return nil
+}
+
+func (p *blockPlugin) GetCommentStyle() CommentStyle {
+	return p.style
+}
+
+func (p *blockPlugin) GetMarkdownIdentifier() string {
+	return p.markdown
+}
+
+// SFCPlugin handles single-file components (Vue, Svelte): HTML markup with <script> and <style> blocks
+type SFCPlugin struct {
+	name       string
+	extensions []string
+	markdown   string
+}
+
+func init() {
+	Register(&SFCPlugin{name: "Vue", extensions: []string{".vue"}, markdown: "vue"})
+	Register(&SFCPlugin{name: "Svelte", extensions: []string{".svelte"}, markdown: "svelte"})
+}
+
+func (p *SFCPlugin) GetName() string {
+	return p.name
+}
+
+func (p *SFCPlugin) GetExtensions() []string {
+	return p.extensions
+}
+
+func (p *SFCPlugin) GetCommentStyle() CommentStyle {
+	return markupComments
+}
+
+func (p *SFCPlugin) GetMarkdownIdentifier() string {
+	return p.markdown
+}
+
+func (p *SFCPlugin) OuterBlock() Plugin {
+	return &blockPlugin{name: p.name + " template", style: markupComments, markdown: "html"}
+}
+
+func (p *SFCPlugin) SwitchBlock(current Plugin, line string) (Plugin, bool) {
+	switch {
+	case sfcBlockClose.MatchString(line):
+		// A block opened and closed on the same line leaves us outside
+		return p.OuterBlock(), true
+	case sfcScriptOpen.MatchString(line):
+		return p.scriptBlock(sfcScriptOpen.FindStringSubmatch(line)[1]), true
+	case sfcStyleOpen.MatchString(line):
+		return p.styleBlock(sfcStyleOpen.FindStringSubmatch(line)[1]), true
+	}
+	return nil, false
+}
+
+// scriptBlock returns the plugin of a <script> block with the given attributes
+func (p *SFCPlugin) scriptBlock(attrs string) Plugin {
+	style := NewCommentStyle([]string{"//"}, []string{"/*", "*/"}, false)
+	switch lang(attrs) {
+	case "ts", "typescript":
+		return &blockPlugin{name: p.name + " script", style: style, markdown: "typescript"}
+	default:
+		return &blockPlugin{name: p.name + " script", style: style, markdown: "javascript"}
+	}
+}
+
+// styleBlock returns the plugin of a <style> block with the given attributes
+func (p *SFCPlugin) styleBlock(attrs string) Plugin {
+	switch l := lang(attrs); l {
+	case "scss", "less", "stylus":
+		// Preprocessors add single line comments to CSS
+		return &blockPlugin{name: p.name + " style", style: NewCommentStyle([]string{"//"}, []string{"/*", "*/"}, false), markdown: l}
+	default:
+		return &blockPlugin{name: p.name + " style", style: NewCommentStyle(nil, []string{"/*", "*/"}, false), markdown: "css"}
+	}
+}
+
+// lang returns the value of the lang attribute in attrs, or ""
+func lang(attrs string) string {
+	if m := sfcLangAttr.FindStringSubmatch(attrs); m != nil {
+		return m[1]
+	}
+	return ""
+}
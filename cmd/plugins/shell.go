@@ -0,0 +1,29 @@
+package plugins
+
+type ShellPlugin struct{}
+
+func init() {
+	Register(&ShellPlugin{})
+}
+
+func (p *ShellPlugin) GetName() string {
+	return "Shell"
+}
+
+func (p *ShellPlugin) GetExtensions() []string {
+	return []string{".sh", ".bash", ".zsh"}
+}
+
+func (p *ShellPlugin) GetInterpreters() []string {
+	return []string{"sh", "bash", "zsh", "dash", "ksh"}
+}
+
+func (p *ShellPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *ShellPlugin) GetMarkdownIdentifier() string {
+	return "bash"
+}
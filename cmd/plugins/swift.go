@@ -0,0 +1,48 @@
+package plugins
+
+type SwiftPlugin struct{}
+
+func init() {
+	Register(&SwiftPlugin{})
+}
+
+func (p *SwiftPlugin) GetName() string {
+	return "Swift"
+}
+
+func (p *SwiftPlugin) GetExtensions() []string {
+	return []string{".swift"}
+}
+
+func (p *SwiftPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "//",
+		Multi: struct {
+			Start string
+			End   string
+		}{
+			Start: "/*",
+			End:   "*/",
+		},
+		MultiNested: true,
+	}
+}
+
+func (p *SwiftPlugin) GetMarkdownIdentifier() string {
+	return "swift"
+}
+
+func (p *SwiftPlugin) GetImportPrefixes() []string {
+	return []string{"import ", "@testable import "}
+}
+
+func (p *SwiftPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:@\w+\s+)*(?:\w+\s+)*func\s+(\w+)`,
+		`^\s*(?:@\w+\s+)*(?:\w+\s+)*(?:class|struct|enum|protocol|actor)\s+(\w+)`,
+	}
+}
+
+func (p *SwiftPlugin) IndentedBlocks() bool {
+	return false
+}
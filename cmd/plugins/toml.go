@@ -0,0 +1,25 @@
+package plugins
+
+type TOMLPlugin struct{}
+
+func init() {
+	Register(&TOMLPlugin{})
+}
+
+func (p *TOMLPlugin) GetName() string {
+	return "TOML"
+}
+
+func (p *TOMLPlugin) GetExtensions() []string {
+	return []string{".toml"}
+}
+
+func (p *TOMLPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *TOMLPlugin) GetMarkdownIdentifier() string {
+	return "toml"
+}
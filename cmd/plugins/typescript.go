@@ -30,3 +30,20 @@ func (p *TypeScriptPlugin) GetCommentStyle() CommentStyle {
 func (p *TypeScriptPlugin) GetMarkdownIdentifier() string {
 	return "typescript"
 }
+
+func (p *TypeScriptPlugin) GetImportPrefixes() []string {
+	return []string{"import "}
+}
+
+func (p *TypeScriptPlugin) GetDefinitionPatterns() []string {
+	return []string{
+		`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`,
+		`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?(?:class|interface|enum)\s+(\w+)`,
+		`^\s*(?:export\s+)?type\s+(\w+)\s*(?:<[^=]*>)?\s*=`,
+		`^\s*(?:export\s+)?const\s+(\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:\([^)]*\)|\w+)\s*(?::[^=]+)?=>`,
+	}
+}
+
+func (p *TypeScriptPlugin) IndentedBlocks() bool {
+	return false
+}
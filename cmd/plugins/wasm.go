@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// LoadWASM compiles the WASM module at path and returns the plugin it implements. The module must
+// be a WASI command speaking the same protocol as external executables: it reads one JSON request
+// from standard input and writes its response to standard output. It runs without access to the
+// file system, the environment or the network.
+func LoadWASM(path string) (Plugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("compiling %s: %v", filepath.Base(path), err)
+	}
+
+	p, err := loadExternal(path, runWASM(runtime, compiled))
+	if err != nil {
+		_ = runtime.Close(ctx)
+	}
+	return p, err
+}
+
+// runWASM returns a transport that runs a fresh instance of the compiled module once per request
+func runWASM(runtime wazero.Runtime, compiled wazero.CompiledModule) transport {
+	return func(input []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), externalTimeout)
+		defer cancel()
+
+		var stdout, stderr bytes.Buffer
+		config := wazero.NewModuleConfig().
+			WithName("").
+			WithStdin(bytes.NewReader(input)).
+			WithStdout(&stdout).
+			WithStderr(&stderr)
+
+		module, err := runtime.InstantiateModule(ctx, compiled, config)
+		if module != nil {
+			_ = module.Close(ctx)
+		}
+
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+			err = nil
+		}
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%v: %s", err, msg)
+			}
+			return nil, err
+		}
+		return stdout.Bytes(), nil
+	}
+}
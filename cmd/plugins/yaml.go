@@ -0,0 +1,25 @@
+package plugins
+
+type YAMLPlugin struct{}
+
+func init() {
+	Register(&YAMLPlugin{})
+}
+
+func (p *YAMLPlugin) GetName() string {
+	return "YAML"
+}
+
+func (p *YAMLPlugin) GetExtensions() []string {
+	return []string{".yml", ".yaml"}
+}
+
+func (p *YAMLPlugin) GetCommentStyle() CommentStyle {
+	return CommentStyle{
+		Single: "#",
+	}
+}
+
+func (p *YAMLPlugin) GetMarkdownIdentifier() string {
+	return "yaml"
+}
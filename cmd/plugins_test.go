@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintPlugins(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	shell, _ := plugins.Get(".sh")
+	custom := &plugins.ConfigPlugin{
+		Name:       "Elm",
+		Extensions: []string{".elm"},
+		Style:      plugins.NewCommentStyle([]string{"--"}, []string{"{-", "-}"}, true),
+		Markdown:   "elm",
+	}
+
+	var out bytes.Buffer
+	printPlugins(&out, []plugins.Registration{
+		{Plugin: python, Origin: plugins.OriginBuiltin},
+		{Plugin: shell, Origin: plugins.OriginBuiltin},
+		{Plugin: custom, Origin: plugins.OriginConfig, Priority: 40},
+	})
+
+	expected := `NAME    ORIGIN   PRIORITY  FILES                                          COMMENTS            MARKDOWN
+Python  builtin  0         .py .pyc                                       #, """ """          python
+Shell   builtin  0         .sh .bash .zsh #!sh #!bash #!zsh #!dash #!ksh  #                   bash
+Elm     config   40        .elm                                           --, {- -} (nested)  elm
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestMatchPlugin(t *testing.T) {
+	tempDir := t.TempDir()
+	scriptPath := filepath.Join(tempDir, "deploy")
+	assert.Nil(t, os.WriteFile(scriptPath, []byte("#!/bin/sh\necho hi\n"), 0755))
+	notesPath := filepath.Join(tempDir, "NOTES")
+	assert.Nil(t, os.WriteFile(notesPath, []byte("hello\n"), 0644))
+
+	tests := []struct {
+		path   string
+		plugin string
+		how    string
+	}{
+		{"app.py", "Python", "extension .py"},
+		{"build/Dockerfile.prod", "Dockerfile", "file name Dockerfile.prod"},
+		{scriptPath, "Shell", "shebang #!/bin/sh"},
+		{notesPath, "", ""},
+		{"report.xyz", "", ""},
+	}
+
+	for _, tt := range tests {
+		plugin, how, ok := matchPlugin(tt.path)
+		if tt.plugin == "" {
+			assert.False(t, ok, tt.path)
+			continue
+		}
+		assert.True(t, ok, tt.path)
+		assert.Equal(t, tt.plugin, plugin.GetName(), tt.path)
+		assert.Equal(t, tt.how, how, tt.path)
+		assert.Equal(t, plugins.OriginBuiltin, originOf(plugin), tt.path)
+	}
+}
+
+func TestPluginPriorities(t *testing.T) {
+	newPlugin := func(name string, priority *int) *plugins.ConfigPlugin {
+		return &plugins.ConfigPlugin{
+			Name:       name,
+			Extensions: []string{".prio"},
+			Filenames:  []string{"Priofile"},
+			Style:      plugins.NewCommentStyle([]string{"#"}, nil, false),
+			Priority:   priority,
+		}
+	}
+	low := -5
+
+	plugins.RegisterFrom(newPlugin("Prio External", nil), plugins.OriginExternal)
+	plugins.RegisterFrom(newPlugin("Prio Builtin", nil), plugins.OriginBuiltin)
+	plugins.RegisterFrom(newPlugin("Prio Config", &low), plugins.OriginConfig)
+	plugins.RegisterFrom(newPlugin("Prio Other External", nil), plugins.OriginExternal)
+
+	// The two external plugins outrank the others and tie with each other; the last one wins.
+	plugin, _ := plugins.Get(".prio")
+	assert.Equal(t, "Prio Other External", plugin.GetName())
+	plugin, _ = plugins.GetByFilename("Priofile")
+	assert.Equal(t, "Prio Other External", plugin.GetName())
+
+	var messages []string
+	for _, c := range plugins.Conflicts() {
+		messages = append(messages, c.String())
+	}
+	assert.Contains(t, messages, "extension .prio is claimed by both Prio External (external) and "+
+		"Prio Other External (external), using Prio Other External; pin it in the config to choose")
+	assert.Contains(t, messages, "file name Priofile is claimed by both Prio External (external) and "+
+		"Prio Other External (external), using Prio Other External; pin it in the config to choose")
+
+	assert.Nil(t, plugins.Pin(".prio", "prio builtin"))
+	assert.Nil(t, plugins.Pin("Priofile", "Prio Config"))
+	plugin, _ = plugins.Get(".prio")
+	assert.Equal(t, "Prio Builtin", plugin.GetName())
+	plugin, _ = plugins.GetByFilename("Priofile")
+	assert.Equal(t, "Prio Config", plugin.GetName())
+
+	assert.EqualError(t, plugins.Pin(".prio", "Nope"), "can't pin .prio to Nope: no plugin of that name is registered")
+}
+
+func TestFallbackPlugin(t *testing.T) {
+	tempDir := t.TempDir()
+
+	sqlPath := filepath.Join(tempDir, "orders.sqlx")
+	assert.Nil(t, os.WriteFile(sqlPath, []byte(`-- >: {"queries": ["orders"]}
+SELECT * FROM orders;
+-- <: {"queries": ["orders"]}
+# not a comment in this language
+`), 0644))
+	lispPath := filepath.Join(tempDir, "init.el")
+	assert.Nil(t, os.WriteFile(lispPath, []byte(";; >: {\"editor\": []}\n(setq x 1)\n;; <: {\"editor\": []}\n"), 0644))
+	untaggedPath := filepath.Join(tempDir, "notes.txt")
+	assert.Nil(t, os.WriteFile(untaggedPath, []byte("# heading\n// nothing tagged here\n"), 0644))
+	binaryPath := filepath.Join(tempDir, "blob.bin")
+	assert.Nil(t, os.WriteFile(binaryPath, []byte("# >: {\"x\": []}\x00\x01"), 0644))
+
+	_, _, ok := matchPlugin(sqlPath)
+	assert.False(t, ok, "the fallback is opt-in")
+
+	cfg.Fallback = true
+	defer func() { cfg = defaultConfig() }()
+
+	files, err := collectFiles(context.Background(), tempDir, "*")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{sqlPath, lispPath}, files)
+
+	_, how, _ := matchPlugin(lispPath)
+	assert.Equal(t, "guessing ;; comments", how)
+
+	snips := extractSnippets(context.Background(), []string{sqlPath}, map[string][]string{})
+	assert.Len(t, snips, 1)
+	assert.Equal(t, []string{"SELECT * FROM orders;"}, snips[0].Content)
+	assert.Equal(t, "sqlx", snips[0].Language())
+}
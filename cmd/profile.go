@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// profileCPUFlag and profileMemFlag are the files the CPU and heap profiles of a run are written to.
+// cpuProfile is the file the running CPU profile goes to, if any.
+var (
+	profileCPUFlag string
+	profileMemFlag string
+	cpuProfile     *os.File
+)
+
+// startProfiling starts the CPU profile asked for with --profile-cpu.
+func startProfiling() error {
+	if profileCPUFlag == "" {
+		return nil
+	}
+	f, err := os.Create(profileCPUFlag)
+	if err != nil {
+		return fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	cpuProfile = f
+	return nil
+}
+
+// stopProfiling writes the CPU and heap profiles asked for, once. It is called by finish.
+func stopProfiling() {
+	if cpuProfile != nil {
+		pprof.StopCPUProfile()
+		if err := cpuProfile.Close(); err != nil {
+			log.Printf("Failed to write CPU profile: %v", err)
+		}
+		cpuProfile = nil
+	}
+	if profileMemFlag != "" {
+		if err := writeHeapProfile(profileMemFlag); err != nil {
+			log.Printf("Failed to write memory profile: %v", err)
+		}
+		profileMemFlag = ""
+	}
+}
+
+// writeHeapProfile writes a profile of the memory allocated so far to path.
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Up-to-date statistics need a garbage collection.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exit writes the profiles and the summary and exits with code, for commands that report failure
+// through their exit status.
+func exit(code int) {
+	finish(code, nil)
+	os.Exit(code)
+}
+
+// finish ends the run with code and cmdErr: it releases the timeout, writes the profiles and then
+// the summary. Every way out of a command goes through it: Execute, exit and fatalf.
+func finish(code int, cmdErr error) {
+	cancelTimeout()
+	stopProfiling()
+	writeSummary(code, cmdErr)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// rangeArg lists the line ranges extract --range extracts, comma-separated, instead of tagged
+// snippets, e.g. "src/models.py:40-120".
+var rangeArg string
+
+// lineRange is a range of lines of a file, from 1, parsed from --range.
+type lineRange struct {
+	file       string
+	start, end int
+}
+
+// parseRanges parses the comma-separated ranges of s, each a file followed by ":start-end" or
+// ":line".
+func parseRanges(s string) ([]lineRange, error) {
+	var ranges []lineRange
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("%q is not file:start-end", item)
+		}
+		r := lineRange{file: item[:i]}
+		first, last, isRange := strings.Cut(item[i+1:], "-")
+		var err error
+		if r.start, err = strconv.Atoi(first); err != nil {
+			return nil, fmt.Errorf("%q is not file:start-end", item)
+		}
+		r.end = r.start
+		if isRange {
+			if r.end, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("%q is not file:start-end", item)
+			}
+		}
+		if r.start < 1 || r.end < r.start {
+			return nil, fmt.Errorf("%q: lines count from 1, and the range can't end before it starts", item)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// rangeSnippets returns the lines of ranges as snippets, in the language of their file if a plugin
+// handles it, processed as tagged snippets are (see extractSnippets). A range past the end of its
+// file is an error.
+func rangeSnippets(ranges []lineRange) ([]snippet, error) {
+	var snips []snippet
+	for _, r := range ranges {
+		report.Files++
+		content, err := brio.ReadFile(r.file)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+		if r.end > len(lines) {
+			return nil, fmt.Errorf("%s:%d-%d is past the end of the file, at line %d", r.file, r.start, r.end, len(lines))
+		}
+		plugin, _, _ := matchPlugin(r.file)
+		snips = append(snips, snippet{
+			File:      r.file,
+			StartLine: r.start,
+			EndLine:   r.end,
+			Content:   lines[r.start-1 : r.end],
+			Plugin:    plugin,
+		})
+	}
+	return processSnippets(snips), nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := parseRanges("src/models.py:40-120, C:/app/main.ts:7")
+	require.NoError(t, err)
+	assert.Equal(t, []lineRange{{file: "src/models.py", start: 40, end: 120}, {file: "C:/app/main.ts", start: 7, end: 7}}, ranges)
+
+	for _, invalid := range []string{"models.py", "models.py:a-b", "models.py:0-3", "models.py:9-3", ":1-2"} {
+		_, err := parseRanges(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRangeSnippets(t *testing.T) {
+	saved := report
+	defer func() { report = saved }()
+
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "models.py")
+	require.NoError(t, os.WriteFile(filePath, []byte("import os\r\n\r\nclass User:\r\n    pass\r\n"), 0644))
+
+	snips, err := rangeSnippets([]lineRange{{file: filePath, start: 3, end: 4}})
+	require.NoError(t, err)
+	require.Len(t, snips, 1)
+	assert.Equal(t, []string{"class User:", "    pass"}, snips[0].Content)
+	assert.Equal(t, "python", snips[0].Language())
+
+	_, err = rangeSnippets([]lineRange{{file: filePath, start: 3, end: 5}})
+	assert.EqualError(t, err, filePath+":3-5 is past the end of the file, at line 4")
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// Formats of the end-of-run report.
+const (
+	reportText = "text"
+	reportJSON = "json"
+	reportNone = "none"
+)
+
+// reportFlag selects how the end-of-run report is printed.
+var reportFlag string
+
+// scanReport collects what went wrong while a command scanned files, so it can be summarized once
+// the command is done instead of scrolling past.
+type scanReport struct {
+	Files  int           `json:"files"`
+	Failed []fileFailure `json:"failed"`
+	Issues []issue       `json:"issues"`
+	// Snippets counts the snippets matched, for --summary-json.
+	Snippets int `json:"-"`
+	// printed is set once the report is printed, or reported otherwise, so it isn't printed again.
+	printed bool
+}
+
+// fileFailure is a file that couldn't be scanned.
+type fileFailure struct {
+	File  string `json:"file"`
+	Code  string `json:"code"`
+	Kind  string `json:"kind"`
+	Error string `json:"error"`
+}
+
+// report is the report of the running command.
+var report = &scanReport{Failed: []fileFailure{}, Issues: []issue{}}
+
+// resetReport starts a new report for the running command, e.g. for each run of extract --watch.
+func resetReport() {
+	report = &scanReport{Failed: []fileFailure{}, Issues: []issue{}}
+}
+
+// addError records the files err reports as not scanned; err may join several errors.
+func (r *scanReport) addError(err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			r.addError(e)
+		}
+		return
+	}
+
+	var fileErr *brio.FileError
+	if errors.As(err, &fileErr) {
+		r.Failed = append(r.Failed, fileFailure{File: fileErr.File, Code: fileErr.Code(), Kind: fileErr.Kind, Error: fileErr.Error()})
+		return
+	}
+	r.Failed = append(r.Failed, fileFailure{Kind: "error", Error: err.Error()})
+}
+
+// write prints the report to w in the given format. The text report is only printed when something
+// went wrong.
+func (r *scanReport) write(w io.Writer, format string) error {
+	switch format {
+	case reportNone:
+		return nil
+	case reportJSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(w, "%d of %d files could not be scanned:\n", len(r.Failed), r.Files)
+		for _, f := range r.Failed {
+			label := f.Kind
+			if f.Code != "" {
+				label = f.Code + " " + f.Kind
+			}
+			fmt.Fprintf(w, "  [%s] %s\n", label, f.Error)
+		}
+	}
+	if len(r.Issues) > 0 {
+		fmt.Fprintf(w, "%d annotation issues:\n", len(r.Issues))
+		for _, i := range r.Issues {
+			fmt.Fprintf(w, "  %s\n", i)
+		}
+	}
+	return nil
+}
+
+// printReport prints the report of the running command to stderr, unless it scanned nothing or
+// it was printed already. Commands that exit early call it first.
+func printReport() {
+	if report.printed || report.Files == 0 && len(report.Failed) == 0 {
+		return
+	}
+	report.printed = true
+	if err := report.write(os.Stderr, reportFlag); err != nil {
+		log.Printf("Failed to print the report: %v", err)
+	}
+}
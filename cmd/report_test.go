@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanReport(t *testing.T) {
+	tempDir := t.TempDir()
+	notebook := filepath.Join(tempDir, "broken.ipynb")
+	assert.Nil(t, os.WriteFile(notebook, []byte("{not json"), 0644))
+	missing := filepath.Join(tempDir, "missing.py")
+	tagged := filepath.Join(tempDir, "tagged.py")
+	assert.Nil(t, os.WriteFile(tagged, []byte("# >: {\"tests\": []}\npass\n"), 0644))
+
+	saved := report
+	report = &scanReport{Failed: []fileFailure{}, Issues: []issue{}}
+	defer func() { report = saved }()
+
+	extractSnippets(context.Background(), []string{notebook, missing, tagged}, map[string][]string{})
+	assert.Equal(t, 3, report.Files)
+	assert.Len(t, report.Failed, 2)
+	assert.Equal(t, brio.FailureUndecodable, report.Failed[0].Kind)
+	assert.Equal(t, notebook, report.Failed[0].File)
+	assert.Equal(t, brio.FailureUnreadable, report.Failed[1].Kind)
+	assert.Len(t, report.Issues, 1)
+
+	var text bytes.Buffer
+	assert.Nil(t, report.write(&text, reportText))
+	assert.Contains(t, text.String(), "2 of 3 files could not be scanned:\n  [BRIO006 undecodable] failed to parse file "+notebook)
+	assert.Contains(t, text.String(), "1 annotation issues:\n  "+tagged+":1: BRIO001 start tag is never closed\n")
+
+	var out bytes.Buffer
+	assert.Nil(t, report.write(&out, reportJSON))
+	var decoded scanReport
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, report.Failed, decoded.Failed)
+	assert.Equal(t, 1, decoded.Issues[0].Line)
+	assert.Equal(t, brio.CodeUnmatchedTag, decoded.Issues[0].Code)
+	assert.Equal(t, brio.CodeUndecodableFile, decoded.Failed[0].Code)
+
+	var none bytes.Buffer
+	assert.Nil(t, report.write(&none, reportNone))
+	assert.Empty(t, none.String())
+}
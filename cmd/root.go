@@ -1,12 +1,24 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"time"
 
+	"github.com/rechati/brio/cmd/plugins"
 	"github.com/spf13/cobra"
 )
 
+// timeoutFlag bounds how long a command may scan files; 0 means no limit.
+// cancelTimeout releases the timer set up for it.
+var (
+	timeoutFlag   time.Duration
+	cancelTimeout context.CancelFunc = func() {}
+)
+
 // rootCmd is the base command for your CLI. It doesn’t run anything itself
 // unless the user runs it with no subcommands.
 var rootCmd = &cobra.Command{
@@ -19,21 +31,154 @@ you've tagged, enabling a streamlined workflow for LLM-based code analysis.`,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	fmt.Println("Please use a subcommand (e.g., 'extract').")
 	// },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		startSummary(cmd.CommandPath())
+		// Load .brio.yaml (or the file given with --config) before any subcommand runs.
+		loaded, err := loadConfig(configFlag, cmd.Flags().Changed("config"))
+		if err != nil {
+			// A broken config file is not a usage mistake, so skip the usage dump.
+			cmd.SilenceUsage = true
+			return err
+		}
+		cfg = loaded
+		switch reportFlag {
+		case reportText, reportJSON, reportNone:
+		default:
+			return fmt.Errorf("--report must be %q, %q or %q, got %q", reportText, reportJSON, reportNone, reportFlag)
+		}
+		if err := startProfiling(); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		if timeoutFlag > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeoutFlag)
+			cmd.SetContext(ctx)
+		}
+		if _, err := overrideConfig(&cfg, cmd); err != nil {
+			return err
+		}
+		if err := registerConfigPlugins(); err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%s: %w", configFlag, err)
+		}
+		return nil
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		printReport()
+	},
 }
 
 // Execute is called by main.go to run the root command.
 // If an error occurs, we print to stderr and exit.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	registerCompletions()
+	runStarted = time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	if err != nil {
+		// Commands given flags they don't know never start.
+		if summaryCommand == "" {
+			summaryCommand = cmd.CommandPath()
+		}
+		finish(1, err)
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	finish(0, nil)
+}
+
+// overrideConfig applies the persistent flags overriding settings of the config file to c, returning
+// the keys of the settings they changed.
+func overrideConfig(c *config, cmd *cobra.Command) ([]string, error) {
+	var overridden []string
+	if cmd.Flags().Changed("plugin-dir") {
+		c.PluginDir = pluginDirFlag
+		c.nativePluginDir = pluginDirFlag
+		overridden = append(overridden, "plugin_dir")
+	}
+	if cmd.Flags().Changed("fallback") {
+		c.Fallback = fallbackFlag
+		overridden = append(overridden, "fallback")
+	}
+	if cmd.Flags().Changed("no-default-ignores") {
+		c.NoDefaultIgnores = noDefaultIgnoresFlag
+		overridden = append(overridden, "no_default_ignores")
+	}
+	if cmd.Flags().Changed("jobs") {
+		if jobsFlag < 0 {
+			return nil, fmt.Errorf("--jobs must be 0 or more, got %d", jobsFlag)
+		}
+		c.Jobs = jobsFlag
+		overridden = append(overridden, "jobs")
+	}
+	return overridden, nil
+}
+
+// registerConfigPlugins registers the plugins of cfg, reporting as a configError the extensions and
+// pins it can't map to one.
+func registerConfigPlugins() error {
+	// Later registrations take precedence: plugins on $PATH override built-in ones,
+	// the plugin directory (WASM, then native) overrides those, and the config file wins.
+	registerExternalPlugins()
+	wasmDir, nativeDir := cfg.pluginDirs()
+	registerWASMPlugins(wasmDir)
+	if nativeDir != "" {
+		registerNativePlugins(nativeDir)
+	} else if len(plugins.DiscoverNative(wasmDir)) > 0 {
+		log.Printf("Skipping the .so plugins in %s: Go plugins are only loaded from a --plugin-dir given explicitly", wasmDir)
+	}
+	registerLanguages(cfg.Languages)
+	if err := registerExtensions(cfg.Extensions); err != nil {
+		return configError{at: []string{"extensions"}, err: err}
+	}
+	for _, conflict := range plugins.Conflicts() {
+		log.Printf("Warning: %s", conflict)
+	}
+	for _, key := range sortedKeys(cfg.Pin) {
+		if err := plugins.Pin(key, cfg.Pin[key]); err != nil {
+			return configError{at: []string{"pin", key}, err: err}
+		}
+	}
+	return nil
+}
+
+// checkCanceled exits when ctx was canceled, e.g. by --timeout, so partial results are never taken
+// for complete ones. Commands that print results call it after printing what they found.
+func checkCanceled(ctx context.Context) {
+	err := ctx.Err()
+	if err == nil {
+		return
+	}
+	printReport()
+	if errors.Is(err, context.DeadlineExceeded) {
+		fatalf("Scan canceled: timed out after %s, results are incomplete", timeoutFlag)
+	}
+	fatalf("Scan canceled: %v, results are incomplete", err)
 }
 
 // init runs before main() and sets up persistent flags or subcommands.
 func init() {
 	// Here, you can set up global persistent flags if you like, for example:
 	// rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", defaultConfigFile, "Path to the brio config file")
+	rootCmd.PersistentFlags().StringVar(&pluginDirFlag, "plugin-dir", "",
+		"Directory to load plugins (.wasm, .so) from; overrides plugin_dir in the config file, which only loads .wasm (default: .wasm from .brio/plugins)")
+	rootCmd.PersistentFlags().BoolVar(&fallbackFlag, "fallback", false,
+		"Guess the comment prefix (#, //, --, ;) of files no plugin handles from their tags")
+	rootCmd.PersistentFlags().BoolVar(&noDefaultIgnoresFlag, "no-default-ignores", false,
+		"Also scan node_modules, vendor, .git, .venv, dist, build and target directories")
+	rootCmd.PersistentFlags().IntVarP(&jobsFlag, "jobs", "j", 0,
+		"How many files to read and parse at once; 0 means one per CPU. Overrides jobs in the config file")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0,
+		"Stop scanning after this long (e.g. 30s), reporting the results as incomplete; 0 means no limit")
+	rootCmd.PersistentFlags().StringVar(&reportFlag, "report", reportText,
+		"How to print the files that could not be scanned and the annotation issues at the end of the run: text, json or none")
+	rootCmd.PersistentFlags().StringVar(&summaryFlag, "summary-json", "",
+		"Write a JSON summary of the run (files scanned and skipped, snippets matched, warnings, duration) to this file, or to stderr with -")
+	rootCmd.PersistentFlags().StringVar(&profileCPUFlag, "profile-cpu", "",
+		"Write a pprof CPU profile of the run to this file")
+	rootCmd.PersistentFlags().StringVar(&profileMemFlag, "profile-mem", "",
+		"Write a pprof heap profile to this file once the run is over")
 
 	// We add the subcommands here, or you can do so in their init() functions.
 	// In this example, the extract subcommand is added in extract.go’s init().
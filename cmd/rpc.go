@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rpcProtocolVersion is the version of the methods of brio rpc, bumped when they change in a way
+// clients can't ignore.
+const rpcProtocolVersion = 1
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcFailed         = -32000 // the request was valid but couldn't be carried out
+)
+
+// rpcCmd serves brio to editor extensions over JSON-RPC.
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve extraction and linting as JSON-RPC over stdin and stdout, for IDE extensions",
+	Long: `Rpc reads JSON-RPC 2.0 requests from stdin, one per line, and writes a response
+per line to stdout, until stdin is closed. It is a stable interface for editor
+extensions, lighter than a language server. The methods are:
+
+initialize       the protocol version and the methods available
+extract          the snippets matching "categories", with their content
+lint             the problems of the annotations, as brio lint reports them
+listCategories   the categories and domains tagged, with their snippet counts
+annotateRange    the tags to insert around lines of a file, or "write" them
+
+extract, lint and listCategories scan "dir" (default: --dir) for the files
+matching "files" (default: every file). Paths in results are absolute.
+
+With --metrics-addr, Prometheus metrics of the requests are served at /metrics;
+with --otlp-endpoint, a trace span per request is sent to an OpenTelemetry
+collector over OTLP/HTTP, in the trace of the "traceparent" of the "_meta"
+object of its params, or of $TRACEPARENT.
+Usage example:
+echo '{"jsonrpc": "2.0", "id": 1, "method": "lint"}' | brio rpc --dir ./src
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if metricsAddrFlag != "" {
+			rpcMetricsSink = newRPCMetrics()
+			server, err := serveMetrics(metricsAddrFlag, rpcMetricsSink)
+			if err != nil {
+				fatalf("Error serving metrics: %v", err)
+			}
+			defer server.Close()
+		}
+		if otlpEndpointFlag != "" {
+			provider, err := newTracerProvider(cmd.Context(), otlpEndpointFlag)
+			if err != nil {
+				fatalf("Error setting up --otlp-endpoint: %v", err)
+			}
+			rpcTracer = provider.Tracer("brio")
+			defer func() {
+				// The spans still queued are sent, waiting for at most otlpTimeout.
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(cmd.Context()), otlpTimeout)
+				defer cancel()
+				if err := provider.Shutdown(ctx); err != nil {
+					log.Printf("Warning: failed to send traces: %v", err)
+				}
+			}()
+		}
+		if err := serveRPC(cmd.Context(), os.Stdin, os.Stdout); err != nil {
+			fatalf("Error serving requests: %v", err)
+		}
+		// Each request reported its own problems.
+		report.printed = true
+	},
+}
+
+// init registers rpcCmd and its flags.
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+
+	rpcCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory requests scan by default")
+	rpcCmd.Flags().StringVar(&metricsAddrFlag, "metrics-addr", "", "Address to serve Prometheus metrics on at /metrics, e.g. :9464")
+	rpcCmd.Flags().StringVar(&otlpEndpointFlag, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP endpoint to send request traces to, e.g. http://localhost:4318")
+}
+
+// rpcRequest is a JSON-RPC request; a request without ID is a notification, left unanswered.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is the answer to a request, with either a result or an error.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcMethods are the methods of brio rpc, by name. They get the params of the request, and return
+// their result or an error, a *rpcError to set its code.
+var rpcMethods map[string]func(ctx context.Context, params json.RawMessage) (any, error)
+
+func init() {
+	rpcMethods = map[string]func(ctx context.Context, params json.RawMessage) (any, error){
+		"initialize":     rpcInitialize,
+		"extract":        rpcExtract,
+		"lint":           rpcLint,
+		"listCategories": rpcListCategories,
+		"annotateRange":  rpcAnnotateRange,
+	}
+}
+
+// serveRPC answers the requests read from in on out, one after another, until in ends or ctx is
+// done. It fails only if in or out do.
+func serveRPC(ctx context.Context, in io.Reader, out io.Writer) error {
+	requests := bufio.NewReader(in)
+	responses := json.NewEncoder(out)
+	for ctx.Err() == nil {
+		line, err := requests.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if response, ok := handleRPC(ctx, line); ok {
+				if err := responses.Encode(response); err != nil {
+					return err
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rpcMetricsSink and rpcTracer record the requests served, when --metrics-addr and --otlp-endpoint
+// are set.
+var (
+	rpcMetricsSink *rpcMetrics
+	rpcTracer      trace.Tracer
+)
+
+// observeRPC records a request to method started at started, and failed with err if not nil, along
+// with the report of its scan, ending its span.
+func observeRPC(method string, started time.Time, span trace.Span, err error) {
+	if rpcMetricsSink != nil {
+		rpcMetricsSink.observe(method, now().Sub(started), err == nil, report)
+	}
+	endRPCSpan(span, err, report)
+}
+
+// handleRPC returns the response to the request in line, and false for notifications.
+func handleRPC(ctx context.Context, line []byte) (rpcResponse, bool) {
+	response := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	var request rpcRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		response.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+		return response, true
+	}
+	if len(request.ID) > 0 {
+		response.ID = request.ID
+	}
+	method, ok := rpcMethods[request.Method]
+	switch {
+	case request.JSONRPC != "2.0" || request.Method == "":
+		response.Error = &rpcError{Code: rpcInvalidRequest, Message: `expected "jsonrpc": "2.0" and a method`}
+	case !ok:
+		response.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+	default:
+		// Each request gets a report of its own.
+		resetReport()
+		started := now()
+		ctx, span := startRPCSpan(ctx, request.Method, request.Params)
+		result, err := method(ctx, request.Params)
+		observeRPC(request.Method, started, span, err)
+		var rpcErr *rpcError
+		switch {
+		case errors.As(err, &rpcErr):
+			response.Error = rpcErr
+		case err != nil:
+			response.Error = &rpcError{Code: rpcFailed, Message: err.Error()}
+		default:
+			response.Result = result
+		}
+	}
+	return response, len(request.ID) > 0
+}
+
+// decodeParams decodes params into v, which is left as is when there are none.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// rpcScanParams selects the files extract, lint and listCategories scan.
+type rpcScanParams struct {
+	Dir        string `json:"dir"`
+	Files      string `json:"files"`
+	Categories string `json:"categories"`
+}
+
+// files returns the files p selects.
+func (p rpcScanParams) files(ctx context.Context) ([]string, error) {
+	if p.Dir == "" {
+		p.Dir = dirFlag
+	}
+	if p.Files == "" {
+		p.Files = "*"
+	}
+	return collectFiles(ctx, p.Dir, p.Files)
+}
+
+// rpcSnippet is a snippet as extract returns it.
+type rpcSnippet struct {
+	File       string              `json:"file"`
+	Section    string              `json:"section,omitempty"`
+	StartLine  int                 `json:"start_line"`
+	EndLine    int                 `json:"end_line"`
+	Language   string              `json:"language"`
+	Categories map[string][]string `json:"categories"`
+	Attrs      map[string][]string `json:"attrs,omitempty"`
+	Content    []string            `json:"content"`
+}
+
+func newRPCSnippet(s snippet) rpcSnippet {
+	return rpcSnippet{
+		File:       absPath(s.File),
+		Section:    s.Section,
+		StartLine:  s.StartLine,
+		EndLine:    s.EndLine,
+		Language:   s.Language(),
+		Categories: s.Categories,
+		Attrs:      s.Attrs,
+		Content:    s.Content,
+	}
+}
+
+// rpcScanResult lists the problems met along the results of a scan.
+type rpcScanResult struct {
+	Issues []issue       `json:"issues"`
+	Failed []fileFailure `json:"failed"`
+}
+
+// absPath returns path made absolute, for clients running in another directory.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// absIssues returns issues with absolute paths.
+func absIssues(issues []issue) []issue {
+	results := make([]issue, len(issues))
+	for i, is := range issues {
+		is.File = absPath(is.File)
+		results[i] = is
+	}
+	return results
+}
+
+// rpcInitialize describes the protocol.
+func rpcInitialize(ctx context.Context, params json.RawMessage) (any, error) {
+	return map[string]any{"protocol": rpcProtocolVersion, "methods": sortedKeys(rpcMethods)}, nil
+}
+
+// rpcExtract returns the snippets matching params.categories, like extract, within the
+// max_tokens_per_category of the config file.
+func rpcExtract(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcScanParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	files, err := p.files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := struct {
+		Snippets []rpcSnippet `json:"snippets"`
+		Omitted  int          `json:"omitted"`
+		rpcScanResult
+	}{Snippets: []rpcSnippet{}}
+	snips := extractSnippets(ctx, files, brio.ParseCategories(p.Categories))
+	if len(cfg.MaxTokensPerCategory) > 0 {
+		snips, result.Omitted = limitCategories(snips, cfg.MaxTokensPerCategory, rpcTokens)
+	}
+	for _, s := range snips {
+		result.Snippets = append(result.Snippets, newRPCSnippet(s))
+	}
+	report.Snippets = len(snips)
+	result.rpcScanResult = rpcScanResult{Issues: absIssues(report.Issues), Failed: report.Failed}
+	return result, ctx.Err()
+}
+
+// rpcLint returns the problems of the annotations of the files params select, like lint.
+func rpcLint(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcScanParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	files, err := p.files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	issues := lintFiles(ctx, files)
+	if issues == nil {
+		issues = []issue{}
+	}
+	return rpcScanResult{Issues: absIssues(issues), Failed: report.Failed}, ctx.Err()
+}
+
+// rpcCategory is a category tagged in the files, with the number of its snippets in all and by
+// domain.
+type rpcCategory struct {
+	Name     string         `json:"name"`
+	Snippets int            `json:"snippets"`
+	Domains  map[string]int `json:"domains"`
+}
+
+// rpcListCategories returns the categories tagged in the files params select, for completion.
+func rpcListCategories(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcScanParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	files, err := p.files(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := countTags(extractSnippets(ctx, files, nil))
+	result := struct {
+		Categories []rpcCategory  `json:"categories"`
+		Domains    map[string]int `json:"domains"`
+	}{Categories: []rpcCategory{}, Domains: counts.domains}
+	for _, name := range sortedKeys(counts.categories) {
+		domains := counts.byDomain[name]
+		delete(domains, "")
+		result.Categories = append(result.Categories, rpcCategory{Name: name, Snippets: counts.categories[name], Domains: domains})
+	}
+	return result, ctx.Err()
+}
+
+// rpcAnnotateParams are the params of annotateRange.
+type rpcAnnotateParams struct {
+	File string `json:"file"`
+	// StartLine and EndLine are the first and last lines to tag, from 1.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Categories are written like --categories, e.g. "messages:foundation,tests".
+	Categories string `json:"categories"`
+	// Content is the text of the file as edited, if it isn't saved.
+	Content *string `json:"content"`
+	// Write makes brio write the tags to the file itself.
+	Write bool `json:"write"`
+}
+
+// rpcEdit inserts Text as a new line before line Line of the original text, from 1; edits are
+// applied from the last.
+type rpcEdit struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// rpcAnnotateRange returns the start and end tags to insert around lines of a file, in the comment
+// syntax of its language, and writes them with params.write.
+func rpcAnnotateRange(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpcAnnotateParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.File == "" || len(brio.ParseCategories(p.Categories)) == 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "file and categories are required"}
+	}
+	if p.Write && p.Content != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "content can't be written, only the file"}
+	}
+	plugin, _, ok := matchPlugin(p.File)
+	if !ok {
+		return nil, fmt.Errorf("no plugin handles %s", p.File)
+	}
+	var text string
+	if p.Content != nil {
+		text = *p.Content
+	} else {
+		data, err := os.ReadFile(p.File)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	lines := strings.Split(text, "\n")
+	if p.StartLine < 1 || p.EndLine < p.StartLine || p.EndLine > len(lines) {
+		return nil, &rpcError{Code: rpcInvalidParams,
+			Message: fmt.Sprintf("lines %d-%d are out of the %s of the file", p.StartLine, p.EndLine, lineCount(len(lines)))}
+	}
+
+	updated, err := insertTags(lines, []newTag{{start: p.StartLine - 1, end: p.EndLine - 1, categories: p.Categories}},
+		plugin.GetCommentStyle(), cfg.Markers)
+	if err != nil {
+		return nil, err
+	}
+	// The start tag is now before the first line, and the end tag after the last one.
+	edits := []rpcEdit{
+		{Line: p.StartLine, Text: updated[p.StartLine-1]},
+		{Line: p.EndLine + 1, Text: updated[p.EndLine+1]},
+	}
+	if p.Write {
+		if err := os.WriteFile(p.File, []byte(strings.Join(updated, "\n")), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{"edits": edits, "written": p.Write}, nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeRPC(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "queue.py")
+	content := `# >: {"foundation": ["messages"], "tests": ["messages", "billing"]}
+class Queue:
+    pass
+# <: {"foundation": ["messages"], "tests": ["messages", "billing"]}
+
+# <: {"tests": ["messages"]}
+def push(item):
+    return item
+`
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	requests := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "extract", "params": {"dir": "` + tempDir + `", "categories": "billing:tests"}}`,
+		`{"jsonrpc": "2.0", "method": "lint"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "lint", "params": {"dir": "` + tempDir + `"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "listCategories", "params": {"dir": "` + tempDir + `"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "annotateRange", "params": {"file": "` + filePath + `", "start_line": 7, "end_line": 8, "categories": "messages:fixtures"}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "annotateRange", "params": {"file": "` + filePath + `", "start_line": 0, "end_line": 8, "categories": "fixtures"}}`,
+		`{"jsonrpc": "2.0", "id": "six", "method": "format"}`,
+		`{"jsonrpc": "2.0", "id": 7`,
+	}, "\n")
+	var out bytes.Buffer
+	require.NoError(t, serveRPC(context.Background(), strings.NewReader(requests), &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 7, "the notification is left unanswered")
+	responses := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &responses[i]))
+	}
+
+	extracted := responses[0]["result"].(map[string]any)
+	snips := extracted["snippets"].([]any)
+	require.Len(t, snips, 1)
+	assert.Equal(t, filePath, snips[0].(map[string]any)["file"])
+	assert.Equal(t, []any{"class Queue:", "    pass"}, snips[0].(map[string]any)["content"])
+	assert.Len(t, extracted["issues"], 1)
+
+	issues := responses[1]["result"].(map[string]any)["issues"].([]any)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "BRIO001", issues[0].(map[string]any)["code"])
+
+	categories := responses[2]["result"].(map[string]any)["categories"].([]any)
+	assert.Equal(t, []any{
+		map[string]any{"name": "foundation", "snippets": 1.0, "domains": map[string]any{"messages": 1.0}},
+		map[string]any{"name": "tests", "snippets": 1.0, "domains": map[string]any{"messages": 1.0, "billing": 1.0}},
+	}, categories)
+
+	assert.Equal(t, map[string]any{
+		"edits": []any{
+			map[string]any{"line": 7.0, "text": `# >: {"fixtures": ["messages"]}`},
+			map[string]any{"line": 9.0, "text": `# <: {"fixtures": ["messages"]}`},
+		},
+		"written": false,
+	}, responses[3]["result"])
+
+	assert.Equal(t, float64(rpcInvalidParams), responses[4]["error"].(map[string]any)["code"])
+	assert.Equal(t, "six", responses[5]["id"])
+	assert.Equal(t, float64(rpcMethodNotFound), responses[5]["error"].(map[string]any)["code"])
+	assert.Equal(t, float64(rpcParseError), responses[6]["error"].(map[string]any)["code"])
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/spf13/cobra"
+)
+
+// Formats of the snippets of a run request.
+const (
+	runFormatJSON     = "json"
+	runFormatMarkdown = "markdown"
+)
+
+// runCmd answers a single request given as a JSON document.
+var runCmd = &cobra.Command{
+	Use:   "run <request.json | ->",
+	Short: "Extract snippets as a JSON request on stdin specifies, answering in JSON",
+	Long: `Run reads a single JSON document specifying the whole request from a file, or
+from stdin with "-", and writes the result as JSON on stdout. Programs calling brio
+from other languages don't have to build command lines:
+
+{
+  "dirs": ["./src", "./lib"],   directories to scan (default: the current one)
+  "patterns": ["*.py", "*.ts"], file patterns to match (default: every file)
+  "categories": "messages:foundation,tests",
+  "format": "json",             "json" for the snippets with their content, or
+                                "markdown" for them rendered as by brio extract
+  "budget": 8000                at most this many estimated tokens of snippets
+}
+
+The result holds the "snippets" (or the "markdown"), their estimated "tokens",
+how many were "omitted" to fit the budget and the max_tokens_per_category of
+the config file, and the "issues" and "failed" files
+of the scan. A request that can't be carried out gets an "error" instead, and the
+exit status is 1. Paths in results are absolute.
+Usage example:
+echo '{"dirs": ["src"], "categories": "tests", "budget": 4000}' | brio run -
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fatalf("Error reading the request: %v", err)
+			}
+			defer f.Close()
+			in = f
+		}
+		result := runRequest(cmd.Context(), in)
+		// The result holds the problems of the scan; the report isn't printed again, but it is kept
+		// for the summary.
+		report.printed = true
+		if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+			fatalf("Error writing the result: %v", err)
+		}
+		if result.Error != "" {
+			exit(1)
+		}
+	},
+}
+
+// init registers runCmd.
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
+
+// runParams is the request of brio run.
+type runParams struct {
+	Dirs     []string `json:"dirs"`
+	Patterns []string `json:"patterns"`
+	// Categories are written like --categories, e.g. "messages:foundation,tests".
+	Categories string `json:"categories"`
+	Format     string `json:"format"`
+	// Budget, when positive, bounds the estimated tokens of the snippets returned.
+	Budget int `json:"budget"`
+}
+
+// runResult is the answer of brio run: the snippets, as JSON or Markdown, or an error.
+type runResult struct {
+	Snippets *[]rpcSnippet `json:"snippets,omitempty"`
+	Markdown *string       `json:"markdown,omitempty"`
+	Tokens   int           `json:"tokens"`
+	Omitted  int           `json:"omitted"`
+	rpcScanResult
+	Error string `json:"error,omitempty"`
+}
+
+// runRequest decodes the request in, extracts the snippets it asks for and returns the result; a
+// request that is invalid or fails gets a result with an error.
+func runRequest(ctx context.Context, in io.Reader) runResult {
+	result := runResult{rpcScanResult: rpcScanResult{Issues: []issue{}, Failed: []fileFailure{}}}
+	p, err := decodeRunParams(in)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid request: %v", err)
+		return result
+	}
+
+	// A file in several dirs, or matching several patterns, is scanned once.
+	var files []string
+	seen := map[string]bool{}
+	for _, dir := range p.Dirs {
+		for _, pattern := range p.Patterns {
+			found, err := collectFiles(ctx, dir, pattern)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			for _, f := range found {
+				if abs := absPath(f); !seen[abs] {
+					seen[abs] = true
+					files = append(files, f)
+				}
+			}
+		}
+	}
+	snips := extractSnippets(ctx, files, brio.ParseCategories(p.Categories))
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// Snippets are measured in the format asked for. The limits of the categories of the config file
+	// apply first.
+	tokensOf := rpcTokens
+	if p.Format == runFormatMarkdown {
+		tokensOf = markdownTokens
+	}
+	if len(cfg.MaxTokensPerCategory) > 0 {
+		snips, result.Omitted = limitCategories(snips, cfg.MaxTokensPerCategory, tokensOf)
+	}
+
+	// Snippets are kept in order as long as they fit the budget; smaller ones may still fit after
+	// one that doesn't.
+	var kept []snippet
+	for _, s := range snips {
+		tokens := tokensOf(s)
+		if p.Budget > 0 && result.Tokens+tokens > p.Budget {
+			result.Omitted++
+			continue
+		}
+		result.Tokens += tokens
+		kept = append(kept, s)
+	}
+	if p.Format == runFormatMarkdown {
+		markdown := brio.RenderMarkdown(kept)
+		result.Markdown = &markdown
+	} else {
+		snippets := []rpcSnippet{}
+		for _, s := range kept {
+			snippets = append(snippets, newRPCSnippet(s))
+		}
+		result.Snippets = &snippets
+	}
+	result.rpcScanResult = rpcScanResult{Issues: absIssues(report.Issues), Failed: report.Failed}
+	if result.Failed == nil {
+		result.Failed = []fileFailure{}
+	}
+	return result
+}
+
+// decodeRunParams decodes the single JSON document of in, with the defaults of the fields left
+// out. Unknown fields are errors, so that typos don't go unnoticed.
+func decodeRunParams(in io.Reader) (runParams, error) {
+	decoder := json.NewDecoder(in)
+	decoder.DisallowUnknownFields()
+	var p runParams
+	if err := decoder.Decode(&p); err != nil {
+		return runParams{}, err
+	}
+	if decoder.More() {
+		return runParams{}, errors.New("expected a single JSON document")
+	}
+	if len(p.Dirs) == 0 {
+		p.Dirs = []string{"."}
+	}
+	if len(p.Patterns) == 0 {
+		p.Patterns = []string{"*"}
+	}
+	if p.Format == "" {
+		p.Format = runFormatJSON
+	}
+	if p.Format != runFormatJSON && p.Format != runFormatMarkdown {
+		return runParams{}, fmt.Errorf("unknown format %q, expected json or markdown", p.Format)
+	}
+	if p.Budget < 0 {
+		return runParams{}, fmt.Errorf("budget must be 0 or more, got %d", p.Budget)
+	}
+	return p, nil
+}
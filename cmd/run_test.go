@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRequest(t *testing.T) {
+	resetReport()
+	defer resetReport()
+	src, lib := t.TempDir(), t.TempDir()
+	queue := filepath.Join(src, "queue.py")
+	require.NoError(t, os.WriteFile(queue, []byte(`# >: {"tests": ["messages"]}
+class Queue:
+    pass
+# <: {"tests": ["messages"]}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(lib, "big.ts"), []byte(`// >: {"tests": []}
+export const rows = [`+strings.Repeat(`"row", `, 100)+`]
+// <: {"tests": []}
+`), 0644))
+
+	request := `{"dirs": ["` + src + `", "` + lib + `", "` + src + `"], "patterns": ["*.py", "*.ts"], "categories": "tests", "budget": 100}`
+	result := runRequest(context.Background(), strings.NewReader(request))
+	require.Empty(t, result.Error)
+	require.NotNil(t, result.Snippets)
+	snips := *result.Snippets
+	require.Len(t, snips, 1, "queue.py is scanned once, and big.ts is over the budget")
+	assert.Equal(t, queue, snips[0].File)
+	assert.Equal(t, []string{"class Queue:", "    pass"}, snips[0].Content)
+	assert.Equal(t, 1, result.Omitted)
+	assert.Positive(t, result.Tokens)
+	assert.Nil(t, result.Markdown)
+
+	result = runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`"], "format": "markdown"}`))
+	require.Empty(t, result.Error)
+	require.NotNil(t, result.Markdown)
+	assert.Contains(t, *result.Markdown, "```python\nclass Queue:\n    pass\n```")
+	assert.Nil(t, result.Snippets)
+
+	// The budget measures the JSON returned.
+	result = runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`"]}`))
+	require.Len(t, *result.Snippets, 1)
+	data, err := json.Marshal((*result.Snippets)[0])
+	require.NoError(t, err)
+	jsonTokens := estimateTokens(string(data))
+	assert.Equal(t, jsonTokens, result.Tokens)
+	markdown := runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`"], "format": "markdown"}`))
+	require.Less(t, markdown.Tokens, jsonTokens)
+	result = runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`"], "budget": `+strconv.Itoa(jsonTokens-1)+`}`))
+	assert.Empty(t, *result.Snippets, "queue.py fits the budget as Markdown, not as JSON")
+	assert.Equal(t, 1, result.Omitted)
+	result = runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`"], "budget": `+strconv.Itoa(jsonTokens)+`}`))
+	assert.Len(t, *result.Snippets, 1)
+
+	cfg.MaxTokensPerCategory = map[string]int{"tests": rpcTokens(snippet{File: queue, Content: []string{"class Queue:", "    pass"}}) + 20}
+	defer func() { cfg = defaultConfig() }()
+	result = runRequest(context.Background(), strings.NewReader(`{"dirs": ["`+src+`", "`+lib+`"], "categories": "tests"}`))
+	require.Empty(t, result.Error)
+	require.Len(t, *result.Snippets, 1, "big.ts is over the limit of tests in the config file")
+	assert.Equal(t, 1, result.Omitted)
+
+	for request, message := range map[string]string{
+		`{"dir": "src"}`:          `invalid request: json: unknown field "dir"`,
+		`{"format": "yaml"}`:      `invalid request: unknown format "yaml", expected json or markdown`,
+		`{"budget": -1}`:          "invalid request: budget must be 0 or more, got -1",
+		`{} {}`:                   "invalid request: expected a single JSON document",
+		`{"dirs": ["` + src + `"`: "invalid request: unexpected EOF",
+	} {
+		assert.Equal(t, message, runRequest(context.Background(), strings.NewReader(request)).Error, request)
+	}
+}
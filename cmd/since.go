@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// sinceFlag, when set, keeps only the snippets changed since then: an age, a date or a revision.
+var sinceFlag string
+
+// filterChanged keeps the snippets history says changed recently. Snippets whose history can't be
+// read are kept, with a warning, rather than silently dropped.
+func filterChanged(snips []snippet, history *brio.History) []snippet {
+	var results []snippet
+	for _, s := range snips {
+		changed, err := history.Changed(s)
+		if err != nil {
+			log.Printf("Warning: keeping %s:%d, whose history can't be read: %v",
+				brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, err)
+			changed = true
+		}
+		if changed {
+			results = append(results, s)
+		}
+	}
+	return results
+}
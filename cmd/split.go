@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"unicode/utf8"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// splitTokensFlag, when set, splits the output of extract into parts of at most that many tokens.
+var splitTokensFlag int
+
+// partHeader heads each part of a split output.
+const partHeader = "## Part %d/%d\n\n"
+
+// estimateTokens returns roughly how many tokens s makes for a language model: about one per four
+// characters, which is what common tokenizers average on code.
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// splitParts groups snips into consecutive parts whose rendering, header included, is estimated at
+// limit tokens at most. Snippets are never split: one over the limit gets a part of its own.
+func splitParts(snips []snippet, limit int) [][]snippet {
+	// There are never more parts than snippets.
+	header := estimateTokens(fmt.Sprintf(partHeader, len(snips), len(snips)))
+
+	var parts [][]snippet
+	var part []snippet
+	size := header
+	for _, s := range snips {
+		tokens := estimateTokens(brio.RenderMarkdown([]snippet{s}))
+		if len(part) > 0 && size+tokens > limit {
+			parts = append(parts, part)
+			part, size = nil, header
+		}
+		if header+tokens > limit {
+			log.Printf("Warning: %s:%d is about %d tokens, over --split-tokens %d; it gets a part of its own",
+				brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, tokens, limit)
+		}
+		part = append(part, s)
+		size += tokens
+	}
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// writeParts writes the snippets of parts to w, each part under its header.
+func writeParts(w io.Writer, parts [][]snippet) error {
+	for i, part := range parts {
+		if _, err := fmt.Fprintf(w, partHeader+"%s", i+1, len(parts), renderSnippets(part)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
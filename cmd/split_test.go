@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/rechati/brio/pkg/brio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, estimateTokens(""))
+	assert.Equal(t, 1, estimateTokens("abcd"))
+	assert.Equal(t, 2, estimateTokens("abcde"))
+	assert.Equal(t, 1, estimateTokens("été"))
+}
+
+func TestSplitParts(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	snip := func(name string, lines int) snippet {
+		s := snippet{File: "/src/" + name + ".py", StartLine: 1, Plugin: python}
+		for i := 0; i < lines; i++ {
+			s.Content = append(s.Content, "x = 123")
+		}
+		return s
+	}
+	snips := []snippet{snip("a", 20), snip("b", 20), snip("c", 100), snip("d", 5), snip("e", 5)}
+	tokens := func(s snippet) int { return estimateTokens(brio.RenderMarkdown([]snippet{s})) }
+	header := estimateTokens("## Part 5/5\n\n")
+	limit := header + tokens(snips[0]) + tokens(snips[1])
+
+	parts := splitParts(snips, limit)
+	assert.Equal(t, [][]snippet{snips[:2], snips[2:3], snips[3:]}, parts)
+	assert.Greater(t, header+tokens(snips[2]), limit, "c should not fit in a part")
+
+	// Every snippet fits in a single part.
+	assert.Equal(t, [][]snippet{snips}, splitParts(snips, 100000))
+
+	var out strings.Builder
+	assert.Nil(t, writeParts(&out, parts))
+	assert.Equal(t, "## Part 1/3\n\n"+brio.RenderMarkdown(snips[:2])+
+		"## Part 2/3\n\n"+brio.RenderMarkdown(snips[2:3])+
+		"## Part 3/3\n\n"+brio.RenderMarkdown(snips[3:]), out.String())
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// Presets of --style, from the least metadata around each snippet to the most.
+const (
+	styleCompact  = "compact"
+	styleDetailed = "detailed"
+	stylePrompt   = "prompt"
+)
+
+// styleFlag selects how much metadata surrounds each snippet extract renders; "" is the default of
+// a path heading and a fence.
+var styleFlag string
+
+// validateStyle returns an error if style isn't one of the presets of --style.
+func validateStyle(style string) error {
+	switch style {
+	case "", styleCompact, styleDetailed, stylePrompt:
+		return nil
+	}
+	return fmt.Errorf("unknown style %q, expected %s, %s or %s", style, styleCompact, styleDetailed, stylePrompt)
+}
+
+// renderStyled renders snips in the preset of --style:
+//   - compact: the fences alone, for the least tokens;
+//   - detailed: a heading with the path and lines, then the categories, the hash of the content and
+//     a link to the source, for files on disk;
+//   - prompt: each snippet wrapped in a <snippet> tag holding its metadata as attributes, which
+//     language models tell from the code more reliably than Markdown headings.
+func renderStyled(snips []snippet, style string) string {
+	var out strings.Builder
+	for _, s := range snips {
+		path := brio.SectionPath(relpath(s.File), s.Section)
+		text := strings.Join(s.Content, "\n")
+		switch style {
+		case styleCompact:
+			out.WriteString(codefence(s.Language(), text) + "\n\n")
+		case styleDetailed:
+			fmt.Fprintf(&out, "### %s:%d-%d\n\n", path, s.StartLine, s.EndLine)
+			if len(s.Categories) > 0 {
+				fmt.Fprintf(&out, "- Categories: %s\n", joinCategories(s.Categories))
+			}
+			fmt.Fprintf(&out, "- Hash: %s\n", s.Hash())
+			// The files of --repo and --github aren't on disk to link to.
+			if repoFlag == "" && githubFlag == "" {
+				fmt.Fprintf(&out, "- Source: %s\n", sourceURI(s))
+			}
+			out.WriteString("\n" + codefence(s.Language(), text) + "\n\n")
+		case stylePrompt:
+			fmt.Fprintf(&out, "<snippet path=\"%s\" lines=\"%d-%d\"", html.EscapeString(path), s.StartLine, s.EndLine)
+			if language := s.Language(); language != "" {
+				fmt.Fprintf(&out, " language=\"%s\"", html.EscapeString(language))
+			}
+			if len(s.Categories) > 0 {
+				fmt.Fprintf(&out, " categories=\"%s\"", html.EscapeString(joinCategories(s.Categories)))
+			}
+			// The content is left as it is: escaping would change the code the model reads.
+			out.WriteString(">\n" + text + "\n</snippet>\n\n")
+		}
+	}
+	return out.String()
+}
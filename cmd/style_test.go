@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderStyled(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	snips := []snippet{{
+		File:       filepath.Join(wd, "src", "queue.py"),
+		StartLine:  3,
+		EndLine:    6,
+		Categories: map[string][]string{"foundation": {"messages"}, "tests": {}},
+		Content:    []string{"if a < b:", "    pass"},
+	}}
+
+	assert.Equal(t, "```\nif a < b:\n    pass\n```\n\n", renderStyled(snips, styleCompact))
+
+	detailed := renderStyled(snips, styleDetailed)
+	assert.Contains(t, detailed, "### src/queue.py:3-6\n\n- Categories: foundation: messages; tests\n")
+	assert.Contains(t, detailed, "- Hash: "+snips[0].Hash()+"\n")
+	assert.Contains(t, detailed, "- Source: "+sourceURI(snips[0])+"\n\n```\nif a < b:\n")
+
+	assert.Equal(t, "<snippet path=\"src/queue.py\" lines=\"3-6\" categories=\"foundation: messages; tests\">\n"+
+		"if a < b:\n    pass\n</snippet>\n\n", renderStyled(snips, stylePrompt))
+
+	assert.NoError(t, validateStyle(""))
+	assert.ErrorContains(t, validateStyle("verbose"), `unknown style "verbose"`)
+}
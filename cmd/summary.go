@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// summaryFlag is where the JSON summary of the run is written once the command is done: a file, or
+// "-" for stderr.
+var summaryFlag string
+
+// runStarted is when the command started, for the duration of the summary.
+// summaryCommand is the running command, e.g. "brio extract".
+// summaryWritten is set once the summary is written, so that it is written only once.
+var (
+	runStarted     time.Time
+	summaryCommand string
+	summaryWritten bool
+)
+
+// runSummary is the summary --summary-json writes, for scripts to act on without parsing the
+// output meant for people.
+type runSummary struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	// Error is why the command failed, when it failed on its arguments or its configuration.
+	Error        string        `json:"error,omitempty"`
+	FilesScanned int           `json:"files_scanned"`
+	FilesSkipped []fileFailure `json:"files_skipped"`
+	Snippets     int           `json:"snippets_matched"`
+	Issues       []issue       `json:"issues"`
+	// Warnings are the messages logged to stderr along the way.
+	Warnings   []string  `json:"warnings"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// summaryLog passes the messages logged to stderr, keeping them as warnings for the summary.
+type summaryLog struct {
+	w        io.Writer
+	mu       sync.Mutex
+	warnings []string
+}
+
+// logPrefix matches the date and time log prefixes messages with.
+var logPrefix = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `)
+
+// warningLog is the summaryLog of the run, when --summary-json is set.
+var warningLog *summaryLog
+
+func (l *summaryLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	l.warnings = append(l.warnings, logPrefix.ReplaceAllString(string(bytes.TrimRight(p, "\n")), ""))
+	l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// startSummary records the start of the command, and the messages it logs from then on when
+// --summary-json is set.
+func startSummary(command string) {
+	summaryCommand = command
+	if summaryFlag != "" && warningLog == nil {
+		warningLog = &summaryLog{w: os.Stderr}
+		log.SetOutput(warningLog)
+	}
+}
+
+// newRunSummary returns the summary of the run so far, ending with code and cmdErr.
+func newRunSummary(code int, cmdErr error) runSummary {
+	s := runSummary{
+		Command:      summaryCommand,
+		ExitCode:     code,
+		FilesSkipped: report.Failed,
+		Snippets:     report.Snippets,
+		Issues:       report.Issues,
+		Warnings:     []string{},
+		StartedAt:    runStarted,
+		DurationMS:   time.Since(runStarted).Milliseconds(),
+	}
+	if cmdErr != nil {
+		s.Error = cmdErr.Error()
+	}
+	skipped := 0
+	for _, f := range report.Failed {
+		if f.File != "" {
+			skipped++
+		}
+	}
+	s.FilesScanned = max(report.Files-skipped, 0)
+	if warningLog != nil {
+		warningLog.mu.Lock()
+		s.Warnings = append(s.Warnings, warningLog.warnings...)
+		warningLog.mu.Unlock()
+	}
+	return s
+}
+
+// writeSummary writes the summary of the run to --summary-json, if set, once. It is called once the
+// command is done, including by commands that exit with a status of 1 through exit or fatalf.
+func writeSummary(code int, cmdErr error) {
+	if summaryFlag == "" || summaryWritten {
+		return
+	}
+	summaryWritten = true
+	data, err := json.MarshalIndent(newRunSummary(code, cmdErr), "", "  ")
+	if err != nil {
+		log.Printf("Failed to write the summary: %v", err)
+		return
+	}
+	data = append(data, '\n')
+	if summaryFlag == "-" {
+		_, err = os.Stderr.Write(data)
+	} else {
+		err = os.WriteFile(summaryFlag, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Failed to write the summary to %s: %v", summaryFlag, err)
+	}
+}
+
+// fatalf logs the error a command stops on and exits with a status of 1, like log.Fatalf, writing
+// the profiles and the summary first, with the error in it.
+func fatalf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	finish(1, errors.New(msg))
+	log.Print(msg)
+	os.Exit(1)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.json")
+	summaryFlag = path
+	defer func() {
+		summaryFlag, summaryCommand, summaryWritten, warningLog = "", "", false, nil
+		log.SetOutput(os.Stderr)
+		resetReport()
+	}()
+
+	runStarted = time.Now().Add(-time.Second)
+	startSummary("brio extract")
+	warningLog.w = io.Discard
+	log.Printf("Warning: no definition of %s found", "nope")
+
+	resetReport()
+	report.Files = 3
+	report.Snippets = 4
+	report.Failed = []fileFailure{
+		{File: "big.log", Code: "BRIO102", Kind: "oversized", Error: "big.log: too large"},
+		{Kind: "error", Error: "walk failed"},
+	}
+
+	writeSummary(1, errors.New("--dir is required"))
+	writeSummary(0, nil)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var summary runSummary
+	require.NoError(t, json.Unmarshal(data, &summary))
+	assert.Equal(t, "brio extract", summary.Command)
+	assert.Equal(t, 1, summary.ExitCode, "the summary is written once")
+	assert.Equal(t, "--dir is required", summary.Error)
+	assert.Equal(t, 2, summary.FilesScanned)
+	assert.Len(t, summary.FilesSkipped, 2)
+	assert.Equal(t, 4, summary.Snippets)
+	assert.Equal(t, []string{"Warning: no definition of nope found"}, summary.Warnings)
+	assert.GreaterOrEqual(t, summary.DurationMS, int64(1000))
+}
+
+func TestFatalfWritesSummary(t *testing.T) {
+	if path := os.Getenv("BRIO_TEST_SUMMARY"); path != "" {
+		summaryFlag, summaryCommand = path, "brio changelog"
+		report.Files = 2
+		fatalf("Error resolving --since %s: %v", "notarev", "reference not found")
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalfWritesSummary$")
+	cmd.Env = append(os.Environ(), "BRIO_TEST_SUMMARY="+path)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode())
+	assert.Contains(t, string(out), "Error resolving --since notarev: reference not found")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var summary runSummary
+	require.NoError(t, json.Unmarshal(data, &summary))
+	assert.Equal(t, 1, summary.ExitCode)
+	assert.Equal(t, "Error resolving --since notarev: reference not found", summary.Error)
+	assert.Equal(t, 2, summary.FilesScanned)
+}
+
+func TestFatalfWritesProfiles(t *testing.T) {
+	if path := os.Getenv("BRIO_TEST_PROFILE"); path != "" {
+		profileMemFlag = path
+		fatalf("Error reading the request: %v", "missing")
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "mem.pprof")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalfWritesProfiles$")
+	cmd.Env = append(os.Environ(), "BRIO_TEST_PROFILE="+path)
+	assert.Error(t, cmd.Run())
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+}
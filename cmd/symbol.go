@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// symbolArg names the functions and classes extract --symbol extracts, comma-separated, instead of
+// tagged snippets.
+var symbolArg string
+
+// symbolSnippets returns the definitions of the symbols of --symbol in files, whether tagged or not,
+// processed as tagged snippets are (see extractSnippets). Symbols without definition are warned
+// about. It fails if the definitions can't be found.
+func symbolSnippets(ctx context.Context, files []string) ([]snippet, error) {
+	report.Files += len(files)
+	opts := cfg.options()
+	defs, err := brio.New(opts).Definitions(ctx, files)
+	if err != nil {
+		return nil, fmt.Errorf("finding definitions: %w", err)
+	}
+
+	var snips []snippet
+	for _, symbol := range strings.Split(symbolArg, ",") {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			continue
+		}
+		found := defs.Lookup(symbol)
+		if len(found) == 0 {
+			log.Printf("Warning: no definition of %s found", symbol)
+		}
+		snips = append(snips, found...)
+	}
+
+	return processSnippets(snips), nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolSnippets(t *testing.T) {
+	saved := report
+	defer func() { report = saved }()
+
+	tempDir := t.TempDir()
+	content := `from app.models import User
+
+class UserService:
+    def create_user(self, name):
+        return User(name)
+
+    def delete_user(self, user):
+        user.delete()
+`
+	filePath := filepath.Join(tempDir, "users.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	symbolArg, withImportsFlag = "UserService.create_user, missing", true
+	defer func() { symbolArg, withImportsFlag = "", false }()
+
+	snips, err := symbolSnippets(context.Background(), []string{filePath})
+	require.NoError(t, err)
+	require.Len(t, snips, 1)
+	assert.Equal(t, 4, snips[0].StartLine)
+	assert.Equal(t, []string{
+		"# imports of users.py",
+		"from app.models import User",
+		"# ...",
+		"    def create_user(self, name):",
+		"        return User(name)",
+	}, snips[0].Content)
+}
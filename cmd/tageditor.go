@@ -0,0 +1,437 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/rechati/brio/pkg/brio"
+	"golang.org/x/term"
+)
+
+// interactiveFlag makes annotate open a file in the tag editor.
+var interactiveFlag bool
+
+// tagEditorHelp sums up the keys of the tag editor.
+const tagEditorHelp = "↑↓/jk move  PgUp/PgDn page  space mark start/end  u undo  w write and quit  q quit"
+
+// newTag is a snippet marked in the tag editor, to be tagged when the file is written.
+type newTag struct {
+	start, end int // 0-based, inclusive
+	categories string
+}
+
+// tagEditor is the state of an interactive annotate session: the lines of the file, the cursor,
+// the snippet being marked and those marked so far. It is updated by handle and drawn by view,
+// apart from the terminal so it can be tested.
+type tagEditor struct {
+	name  string
+	lines []string
+	// tagged flags the lines of the snippets the file already has.
+	tagged []bool
+	counts tagCounts
+
+	cursor int
+	top    int
+	// anchor is the first line of the snippet being marked, or -1.
+	anchor int
+	// typing is set while the categories of the marked lines are typed in input.
+	typing bool
+	input  string
+	hints  []string
+
+	added   []newTag
+	message string
+	done    bool
+	save    bool
+}
+
+// newTagEditor returns a tag editor on the lines of the file name, whose existing snippets are
+// snips, completing categories from counts.
+func newTagEditor(name string, lines []string, snips []snippet, counts tagCounts) *tagEditor {
+	e := &tagEditor{name: name, lines: lines, tagged: make([]bool, len(lines)), counts: counts, anchor: -1}
+	for _, s := range snips {
+		for i := s.StartLine - 1; i < s.EndLine && i < len(lines); i++ {
+			if i >= 0 {
+				e.tagged[i] = true
+			}
+		}
+	}
+	return e
+}
+
+// handle updates the editor for a key: a character, or the name of a special key such as "up",
+// "enter" or "esc". height is the number of lines of the file shown at once.
+func (e *tagEditor) handle(key string, height int) {
+	e.message = ""
+	if key == "ctrl-c" {
+		e.done = true
+		return
+	}
+	if e.typing {
+		e.handleInput(key)
+		return
+	}
+
+	switch key {
+	case "up", "k":
+		e.cursor--
+	case "down", "j":
+		e.cursor++
+	case "pgup":
+		e.cursor -= height
+	case "pgdn":
+		e.cursor += height
+	case "home", "g":
+		e.cursor = 0
+	case "end", "G":
+		e.cursor = len(e.lines) - 1
+	case " ", "v":
+		if e.anchor < 0 {
+			e.anchor = e.cursor
+			e.message = "Move to the last line of the snippet and press space"
+			return
+		}
+		e.typing, e.input = true, ""
+		e.hints = categoryHints(e.counts, "")
+	case "esc":
+		e.anchor = -1
+	case "u":
+		if len(e.added) > 0 {
+			e.added = e.added[:len(e.added)-1]
+		}
+	case "w":
+		e.done, e.save = true, true
+	case "q":
+		e.done = true
+	}
+	e.cursor = max(0, min(e.cursor, len(e.lines)-1))
+	// Keep the cursor on screen.
+	e.top = max(min(e.top, e.cursor), e.cursor-height+1, 0)
+}
+
+// handleInput updates the categories being typed for a key.
+func (e *tagEditor) handleInput(key string) {
+	switch key {
+	case "esc":
+		e.typing, e.anchor = false, -1
+		return
+	case "enter":
+		if len(brio.ParseCategories(e.input)) == 0 {
+			e.message = "Type at least one category, e.g. messages:foundation"
+			return
+		}
+		start, end := min(e.anchor, e.cursor), max(e.anchor, e.cursor)
+		e.added = append(e.added, newTag{start: start, end: end, categories: e.input})
+		e.typing, e.anchor = false, -1
+		e.message = fmt.Sprintf("Marked lines %d-%d", start+1, end+1)
+		return
+	case "backspace":
+		if e.input != "" {
+			_, size := utf8.DecodeLastRuneInString(e.input)
+			e.input = e.input[:len(e.input)-size]
+		}
+	case "tab":
+		e.input = completeInput(e.input, categoryHints(e.counts, e.input))
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			e.input += key
+		}
+	}
+	e.hints = categoryHints(e.counts, e.input)
+}
+
+// categoryHints returns the completions of the categories typed so far, without their description.
+func categoryHints(counts tagCounts, input string) []string {
+	completions := categoryCompletions(counts, input)
+	for i, c := range completions {
+		completions[i], _, _ = strings.Cut(c, "\t")
+	}
+	return completions
+}
+
+// completeInput extends input with the prefix hints have in common.
+func completeInput(input string, hints []string) string {
+	if len(hints) == 0 {
+		return input
+	}
+	prefix := hints[0]
+	for _, h := range hints[1:] {
+		for !strings.HasPrefix(h, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(input) {
+		return prefix
+	}
+	return input
+}
+
+// view draws the editor on a screen of width by height characters: a title, the lines of the file
+// from top with their numbers, and a status line. Lines of snippets the file already has are
+// marked with "│", those marked in the editor with "+", and the lines being marked are shown in
+// reverse video.
+func (e *tagEditor) view(width, height int) string {
+	var out strings.Builder
+	out.WriteString(fitWidth(fmt.Sprintf("brio annotate %s — %s", e.name, tagEditorHelp), width) + "\r\n")
+
+	marked := make(map[int]bool)
+	for _, t := range e.added {
+		for i := t.start; i <= t.end; i++ {
+			marked[i] = true
+		}
+	}
+	digits := len(fmt.Sprint(len(e.lines)))
+	for row := 0; row < e.bodyHeight(height); row++ {
+		i := e.top + row
+		if i >= len(e.lines) {
+			out.WriteString("~\r\n")
+			continue
+		}
+		gutter := " "
+		switch {
+		case marked[i]:
+			gutter = "+"
+		case e.tagged[i]:
+			gutter = "│"
+		}
+		pointer := " "
+		if i == e.cursor {
+			pointer = ">"
+		}
+		line := fitWidth(fmt.Sprintf("%s%s%*d  %s", pointer, gutter, digits, i+1,
+			strings.ReplaceAll(strings.TrimRight(e.lines[i], "\r"), "\t", "    ")), width)
+		if e.anchor >= 0 && i >= min(e.anchor, e.cursor) && i <= max(e.anchor, e.cursor) {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		out.WriteString(line + "\r\n")
+	}
+
+	switch {
+	case e.typing:
+		status := "Categories (tab completes, enter tags, esc cancels): " + e.input
+		if len(e.hints) > 0 {
+			status += "   " + strings.Join(e.hints, "  ")
+		}
+		out.WriteString(fitWidth(status, width))
+	case e.message != "":
+		out.WriteString(fitWidth(e.message, width))
+	default:
+		out.WriteString(fitWidth(fmt.Sprintf("Line %d/%d, %s marked", e.cursor+1, len(e.lines), snippetCount(len(e.added))), width))
+	}
+	return out.String()
+}
+
+// bodyHeight returns how many lines of the file fit on a screen of height lines.
+func (e *tagEditor) bodyHeight(height int) int {
+	return max(1, height-2)
+}
+
+// fitWidth cuts s to width characters.
+func fitWidth(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
+// insertTags returns lines with start and end tags around each of added, written in the comment
+// style of style, indented like the first line of their snippet. Snippets may nest. Lines ending
+// with "\r" get tags ending with it too.
+func insertTags(lines []string, added []newTag, style plugins.CommentStyle, markers string) ([]string, error) {
+	startMarker, endMarker := ">:", "<:"
+	if markers == markersKeywords {
+		startMarker, endMarker = "start:", "end:"
+	}
+	tag := func(marker, payload string) (string, error) {
+		switch {
+		case style.Single != "":
+			return style.Single + " " + marker + " " + payload, nil
+		case style.Multi.Start != "" && !style.MultiIsCode:
+			return style.Multi.Start + " " + marker + " " + payload + " " + style.Multi.End, nil
+		}
+		return "", errors.New("the language of the file has no comments to write tags in")
+	}
+
+	// Outer snippets open before and close after the snippets they hold.
+	sorted := append([]newTag(nil), added...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].start != sorted[j].start {
+			return sorted[i].start < sorted[j].start
+		}
+		return sorted[i].end > sorted[j].end
+	})
+	before := make(map[int][]string)
+	after := make(map[int][]string)
+	for _, t := range sorted {
+		payload, err := tagPayload(brio.ParseCategories(t.categories))
+		if err != nil {
+			return nil, err
+		}
+		start, err := tag(startMarker, payload)
+		if err != nil {
+			return nil, err
+		}
+		end, _ := tag(endMarker, payload)
+
+		first := lines[t.start]
+		indent := first[:len(first)-len(strings.TrimLeftFunc(first, unicode.IsSpace))]
+		eol := ""
+		if strings.HasSuffix(first, "\r") {
+			eol = "\r"
+		}
+		before[t.start] = append(before[t.start], indent+start+eol)
+		after[t.end] = append([]string{indent + end + eol}, after[t.end]...)
+	}
+
+	result := make([]string, 0, len(lines)+2*len(added))
+	for i, line := range lines {
+		result = append(result, before[i]...)
+		result = append(result, line)
+		result = append(result, after[i]...)
+	}
+	return result, nil
+}
+
+// tagPayload renders categories as the JSON payload of a tag, e.g. {"foundation": ["messages"]},
+// with its keys sorted.
+func tagPayload(categories map[string][]string) (string, error) {
+	var fields []string
+	for _, name := range sortedKeys(categories) {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return "", err
+		}
+		// A category given without a domain has the domain "".
+		domains := []string{}
+		for _, d := range categories[name] {
+			if d != "" {
+				domains = append(domains, d)
+			}
+		}
+		values, err := json.Marshal(domains)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, string(key)+": "+strings.ReplaceAll(string(values), `","`, `", "`))
+	}
+	return "{" + strings.Join(fields, ", ") + "}", nil
+}
+
+// runTagEditor runs e on the terminal in and out until it is done.
+func runTagEditor(e *tagEditor, in, out *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("--interactive needs a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	// Use the alternate screen, as full-screen programs do, and hide the cursor.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := bufio.NewReader(in)
+	for !e.done {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || height == 0 {
+			width, height = 80, 24
+		}
+		if _, err := fmt.Fprint(out, "\x1b[H\x1b[2J"+e.view(width, height)); err != nil {
+			return err
+		}
+		key, err := readKey(keys)
+		if err != nil {
+			return err
+		}
+		e.handle(key, e.bodyHeight(height))
+	}
+	return nil
+}
+
+// escapeKeys names the keys sending escape sequences.
+var escapeKeys = map[string]string{
+	"[A": "up", "[B": "down", "[5~": "pgup", "[6~": "pgdn",
+	"[H": "home", "[F": "end", "[1~": "home", "[4~": "end", "OA": "up", "OB": "down",
+}
+
+// readKey reads a key pressed on a terminal in raw mode: a character, or the name of a special key.
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case '\t':
+		return "tab", nil
+	case 127, 8:
+		return "backspace", nil
+	case 27:
+		// A lone escape, or the start of the sequence of a special key, sent at once.
+		var seq strings.Builder
+		for r.Buffered() > 0 {
+			b, err := r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			seq.WriteByte(b)
+			if seq.Len() > 1 && (b == '~' || b >= 'A' && b <= 'Z') {
+				break
+			}
+		}
+		if seq.Len() == 0 {
+			return "esc", nil
+		}
+		return escapeKeys[seq.String()], nil
+	}
+	return string(c), nil
+}
+
+// annotateInteractively opens filePath in the tag editor on the terminal in and out, completing
+// categories from those of the snippets of --dir, and writes the tags marked in it. It returns how
+// many snippets were tagged.
+func annotateInteractively(ctx context.Context, filePath string, in, out *os.File) (int, error) {
+	plugin, _, ok := matchPlugin(filePath)
+	if !ok {
+		return 0, fmt.Errorf("no plugin handles %s", filePath)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	snips, _, err := scanFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	// Completing from part of the categories is better than not at all.
+	files, _ := collectFiles(ctx, dirFlag, filePattern)
+	counts := countTags(extractSnippets(ctx, files, nil))
+
+	lines := strings.Split(string(original), "\n")
+	e := newTagEditor(displayPath(filePath), lines, snips, counts)
+	if err := runTagEditor(e, in, out); err != nil {
+		return 0, err
+	}
+	if !e.save || len(e.added) == 0 {
+		return 0, nil
+	}
+	updated, err := insertTags(lines, e.added, plugin.GetCommentStyle(), cfg.Markers)
+	if err != nil {
+		return 0, err
+	}
+	return len(e.added), os.WriteFile(filePath, []byte(strings.Join(updated, "\n")), 0644)
+}
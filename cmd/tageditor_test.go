@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagEditor(t *testing.T) {
+	lines := []string{"import os", "", "def send(message):", "    return os.write(message)", ""}
+	existing := []snippet{{StartLine: 1, EndLine: 1}}
+	counts := countTags([]snippet{
+		{Categories: map[string][]string{"foundation": {"messages"}}},
+		{Categories: map[string][]string{"fixtures": {"messages"}}},
+	})
+	e := newTagEditor("send.py", lines, existing, counts)
+
+	for _, key := range []string{"j", "j", " ", "down", " "} {
+		e.handle(key, 10)
+	}
+	require.True(t, e.typing)
+	for _, key := range []string{"m", "e", "tab", "f", "tab", "o"} {
+		e.handle(key, 10)
+	}
+	assert.Equal(t, "messages:fo", e.input, "completes the common prefix")
+	assert.Equal(t, []string{"messages:foundation"}, e.hints)
+	e.handle("tab", 10)
+	e.handle("enter", 10)
+	assert.False(t, e.typing)
+	assert.Equal(t, []newTag{{start: 2, end: 3, categories: "messages:foundation"}}, e.added)
+
+	view := e.view(60, 8)
+	assert.Contains(t, view, " │1  import os\r\n")
+	assert.Contains(t, view, " +3  def send(message):\r\n")
+	assert.Contains(t, view, ">+4      return os.write(message)\r\n")
+	assert.True(t, strings.HasSuffix(view, "Marked lines 3-4"))
+
+	// Marking lines can be canceled, and marks undone.
+	e.handle(" ", 10)
+	e.handle("esc", 10)
+	assert.Equal(t, -1, e.anchor)
+	e.handle("u", 10)
+	assert.Empty(t, e.added)
+
+	e.handle("G", 3)
+	assert.Equal(t, 4, e.cursor)
+	assert.Equal(t, 2, e.top, "the cursor stays on screen")
+	e.handle("w", 3)
+	assert.True(t, e.done)
+	assert.True(t, e.save)
+}
+
+func TestInsertTags(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	lines := []string{"class Queue:", "    def push(self, item):", "        self.items.append(item)", ""}
+	added := []newTag{
+		{start: 1, end: 2, categories: "messages:tests"},
+		{start: 0, end: 2, categories: "messages:foundation,fixtures"},
+	}
+	got, err := insertTags(lines, added, python.GetCommentStyle(), markersBoth)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`# >: {"fixtures": ["messages"], "foundation": ["messages"]}`,
+		"class Queue:",
+		`    # >: {"tests": ["messages"]}`,
+		"    def push(self, item):",
+		"        self.items.append(item)",
+		`    # <: {"tests": ["messages"]}`,
+		`# <: {"fixtures": ["messages"], "foundation": ["messages"]}`,
+		"",
+	}, got)
+
+	html := plugins.NewCommentStyle(nil, []string{"<!--", "-->"}, false)
+	got, err = insertTags([]string{"<p>Hi</p>\r", ""}, []newTag{{start: 0, end: 0, categories: "tests"}}, html, markersKeywords)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`<!-- start: {"tests": []} -->` + "\r", "<p>Hi</p>\r", `<!-- end: {"tests": []} -->` + "\r", ""}, got)
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[6~\r\t\x7fé\x03"))
+	var keys []string
+	for range 8 {
+		key, err := readKey(r)
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"j", "up", "pgdn", "enter", "tab", "backspace", "é", "ctrl-c"}, keys)
+
+	key, err := readKey(bufio.NewReader(strings.NewReader("\x1b")))
+	require.NoError(t, err)
+	assert.Equal(t, "esc", key)
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// templateFlag names a Go template file extract renders the snippets with instead of Markdown;
+// outputTemplate is it parsed.
+var (
+	templateFlag   string
+	outputTemplate *template.Template
+)
+
+// templateFuncs are the functions templates can call besides those of text/template, for building
+// prompts and reports without piping the output through other tools.
+var templateFuncs = template.FuncMap{
+	"tokencount":     estimateTokens,
+	"truncateLines":  truncateLines,
+	"dedent":         dedent,
+	"slugify":        brio.Slugify,
+	"relpath":        relpath,
+	"basename":       filepath.Base,
+	"joinCategories": joinCategories,
+	"codefence":      codefence,
+}
+
+// templateData is what templates are executed with.
+type templateData struct {
+	Snippets []templateSnippet
+}
+
+// templateSnippet is a snippet as templates see it: its fields and methods, along with its
+// content as a single string and its path relative to the current directory.
+type templateSnippet struct {
+	snippet
+	Text string
+	Path string
+}
+
+// parseTemplate reads and parses the template file at path, with templateFuncs.
+func parseTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+}
+
+// writeTemplate executes tmpl with snips and writes the result to w.
+func writeTemplate(w io.Writer, tmpl *template.Template, snips []snippet) error {
+	data := templateData{Snippets: make([]templateSnippet, len(snips))}
+	for i, s := range snips {
+		data.Snippets[i] = templateSnippet{
+			snippet: s,
+			Text:    strings.Join(s.Content, "\n"),
+			Path:    brio.SectionPath(displayPath(s.File), s.Section),
+		}
+	}
+	return tmpl.Execute(w, data)
+}
+
+// truncateLines returns the first n lines of text, followed by a line counting those left out
+// if any. Its argument order lets it end a pipeline: {{ .Text | truncateLines 20 }}.
+func truncateLines(n int, text string) string {
+	lines := strings.Split(text, "\n")
+	if n < 0 || len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n... (%s left out)", lineCount(len(lines)-n))
+}
+
+// dedent removes the leading whitespace common to the lines of text that aren't blank, such as the
+// indentation of a method body.
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return text
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+		if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// relpath returns path relative to --dir, with forward slashes, or path itself if it isn't below
+// it.
+func relpath(path string) string {
+	root, err := filepath.Abs(dirFlag)
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// joinCategories renders categories on one line, sorted, e.g. "foundation: messages, alerts; tests".
+func joinCategories(categories map[string][]string) string {
+	labels := make([]string, 0, len(categories))
+	for _, name := range sortedKeys(categories) {
+		label := name
+		if domains := categories[name]; len(domains) > 0 {
+			label += ": " + strings.Join(domains, ", ")
+		}
+		labels = append(labels, label)
+	}
+	return strings.Join(labels, "; ")
+}
+
+// codefence fences text as a Markdown code block of language, with a fence longer than any run of
+// backticks in text so it can't end the block early.
+func codefence(language, text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + language + "\n" + strings.TrimSuffix(text, "\n") + "\n" + fence
+}
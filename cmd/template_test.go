@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	assert.Equal(t, "a\nb\n... (2 lines left out)", truncateLines(2, "a\nb\nc\nd"))
+	assert.Equal(t, "a\nb", truncateLines(2, "a\nb"))
+
+	assert.Equal(t, "if x:\n    y()\n\nz()", dedent("    if x:\n        y()\n  \n    z()"))
+	assert.Equal(t, "\tif x:\n  y()", dedent("\tif x:\n  y()"), "no common indentation")
+
+	assert.Equal(t, "foundation: alerts, messages; tests", joinCategories(map[string][]string{
+		"tests": {}, "foundation": {"alerts", "messages"},
+	}))
+
+	assert.Equal(t, "```go\nx := 1\n```", codefence("go", "x := 1\n"))
+	assert.Equal(t, "````md\n```sh\nls\n```\n````", codefence("md", "```sh\nls\n```"))
+
+	oldDir := dirFlag
+	defer func() { dirFlag = oldDir }()
+	dirFlag = t.TempDir()
+	assert.Equal(t, "src/a.py", relpath(filepath.Join(dirFlag, "src", "a.py")))
+	assert.Equal(t, "/elsewhere/a.py", relpath("/elsewhere/a.py"))
+}
+
+func TestWriteTemplate(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	snips := []snippet{{
+		File:       "src/auth/login.py",
+		StartLine:  3,
+		EndLine:    6,
+		Categories: map[string][]string{"security": {"auth"}},
+		Content:    []string{"    def login(user):", "        return check(user)"},
+		Plugin:     python,
+	}}
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	text := `{{ range .Snippets }}### {{ basename .File }} ({{ joinCategories .Categories }}, ~{{ tokencount .Text }} tokens)
+{{ codefence .Language (dedent .Text) }}
+{{ end }}`
+	require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+
+	tmpl, err := parseTemplate(path)
+	require.NoError(t, err)
+	var out strings.Builder
+	require.NoError(t, writeTemplate(&out, tmpl, snips))
+	assert.Equal(t, "### login.py (security: auth, ~12 tokens)\n```python\ndef login(user):\n    return check(user)\n```\n", out.String())
+
+	require.NoError(t, os.WriteFile(path, []byte("{{ .Snippets"), 0644))
+	_, err = parseTemplate(path)
+	assert.Error(t, err)
+}
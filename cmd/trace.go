@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otlpEndpointFlag is the OTLP/HTTP endpoint brio rpc sends the traces of its requests to, e.g.
+// http://localhost:4318; OTEL_EXPORTER_OTLP_ENDPOINT by default.
+var otlpEndpointFlag string
+
+// otlpBatchSize and otlpBatchDelay bound how many spans are sent at once and how long they wait.
+// otlpQueueSize bounds the spans waiting to be sent; more are dropped rather than slowing requests.
+const (
+	otlpBatchSize  = 100
+	otlpBatchDelay = 2 * time.Second
+	otlpQueueSize  = 1000
+	otlpTimeout    = 10 * time.Second
+)
+
+// newTracerProvider returns a tracer provider sending spans to the collector at endpoint, in batches,
+// in the background, at the /v1/traces path unless OTEL_EXPORTER_OTLP_TRACES_ENDPOINT gives the full
+// URL. The exporter of the OpenTelemetry SDK reads the other OTEL_EXPORTER_OTLP_* variables, such as
+// the headers of OTEL_EXPORTER_OTLP_HEADERS, and the service is named by OTEL_SERVICE_NAME, "brio" by
+// default. Spans are sent until the provider is shut down.
+func newTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	target := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if target == "" {
+		target = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http or https URL", target)
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(target), otlptracehttp.WithTimeout(otlpTimeout))
+	if err != nil {
+		return nil, err
+	}
+	// The variables of the environment win over the default name.
+	res, err := resource.New(ctx, resource.WithAttributes(attribute.String("service.name", "brio")), resource.WithFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxExportBatchSize(otlpBatchSize),
+			sdktrace.WithBatchTimeout(otlpBatchDelay),
+			sdktrace.WithMaxQueueSize(otlpQueueSize),
+			sdktrace.WithExportTimeout(otlpTimeout)),
+	), nil
+}
+
+// rpcTraceContext is the trace context a request may carry in the _meta object of its params, as the
+// W3C Trace Context headers do, e.g. {"_meta": {"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}.
+type rpcTraceContext struct {
+	Meta struct {
+		TraceParent string `json:"traceparent"`
+		TraceState  string `json:"tracestate"`
+	} `json:"_meta"`
+}
+
+// startRPCSpan starts the server span of a request to method with rpcTracer, as a child of the
+// trace context of params, or else of $TRACEPARENT and $TRACESTATE, so that the spans of brio join
+// the trace of the editor or job calling it. Without rpcTracer, the span records nothing.
+func startRPCSpan(ctx context.Context, method string, params json.RawMessage) (context.Context, trace.Span) {
+	if rpcTracer == nil {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	carrier := propagation.MapCarrier{"traceparent": os.Getenv("TRACEPARENT"), "tracestate": os.Getenv("TRACESTATE")}
+	var tc rpcTraceContext
+	if json.Unmarshal(params, &tc) == nil && tc.Meta.TraceParent != "" {
+		carrier = propagation.MapCarrier{"traceparent": tc.Meta.TraceParent, "tracestate": tc.Meta.TraceState}
+	}
+	ctx = propagation.TraceContext{}.Extract(ctx, carrier)
+	return rpcTracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", method),
+	))
+}
+
+// endRPCSpan ends span with the files scanned and failed and the snippets matched of r, and an error
+// status if err is not nil.
+func endRPCSpan(span trace.Span, err error, r *scanReport) {
+	span.SetAttributes(
+		attribute.Int("brio.files_scanned", r.Files),
+		attribute.Int("brio.files_failed", len(r.Failed)),
+		attribute.Int("brio.snippets_matched", r.Snippets),
+	)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// transformsFlag makes extract run the transforms of the config file. They are commands a cloned
+// repository could declare, so they are never run without it.
+var transformsFlag bool
+
+// defaultTransformTimeout bounds a run of a transform whose timeout isn't set.
+const defaultTransformTimeout = 30 * time.Second
+
+// transformConfig declares a command the content of each snippet is piped through before extract
+// prints it, such as a formatter or a secret scrubber.
+type transformConfig struct {
+	Name string `yaml:"name"`
+	// Command is the program and its arguments, e.g. ["black", "-q", "-"]. It reads the content
+	// of a snippet on stdin and writes what to print instead on stdout; nothing leaves the
+	// snippet out. It is run without a shell.
+	Command []string `yaml:"command"`
+	// Languages restricts the transform to the snippets of these languages, named like their
+	// Markdown fences (e.g. "python"); it applies to all by default.
+	Languages []string `yaml:"languages"`
+	// Timeout bounds each run, e.g. "10s"; 30 seconds by default.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// validate reports transforms brio can't run.
+func (t transformConfig) validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Command) == 0 || t.Command[0] == "" {
+		return fmt.Errorf("%s: command is required", t.Name)
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("%s: timeout must be positive, got %s", t.Name, t.Timeout)
+	}
+	return nil
+}
+
+// transformSnippets pipes the content of snips through transforms, in order, and returns them
+// transformed, leaving out those a transform printed nothing for. A transform failing stops
+// everything: printing the snippet untransformed could leak what it was meant to scrub.
+func transformSnippets(ctx context.Context, snips []snippet, transforms []transformConfig) ([]snippet, error) {
+	if len(transforms) == 0 {
+		return snips, nil
+	}
+	var results []snippet
+	for _, s := range snips {
+		kept := true
+		for _, t := range transforms {
+			if len(t.Languages) > 0 && !slices.Contains(t.Languages, s.Language()) {
+				continue
+			}
+			content, err := t.run(ctx, s)
+			if err != nil {
+				return nil, fmt.Errorf("transform %s failed on %s:%d: %w",
+					t.Name, brio.SectionPath(displayPath(s.File), s.Section), s.StartLine, err)
+			}
+			if len(content) == 0 {
+				kept = false
+				break
+			}
+			s = s.WithContent(content)
+		}
+		if kept {
+			results = append(results, s)
+		}
+	}
+	return results, nil
+}
+
+// applyTransforms returns snips as transformed by the transforms of the config file if
+// --transforms is set, or an error if a transform fails.
+func applyTransforms(ctx context.Context, snips []snippet) ([]snippet, error) {
+	if !transformsFlag {
+		return snips, nil
+	}
+	snips, err := transformSnippets(ctx, snips, cfg.Transforms)
+	if err != nil {
+		return nil, fmt.Errorf("transforming snippets: %w", err)
+	}
+	return snips, nil
+}
+
+// run pipes the content of s through the command of t and returns the lines it printed. The
+// command also gets the file, lines and language of s in $BRIO_FILE, $BRIO_LINES (e.g. "12-40")
+// and $BRIO_LANGUAGE.
+func (t transformConfig) run(ctx context.Context, s snippet) ([]string, error) {
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = defaultTransformTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var input strings.Builder
+	for _, line := range s.Content {
+		input.WriteString(line + "\n")
+	}
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	command.Stdin = strings.NewReader(input.String())
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	command.Env = append(os.Environ(),
+		"BRIO_FILE="+brio.SectionPath(displayPath(s.File), s.Section),
+		"BRIO_LINES="+strconv.Itoa(s.StartLine)+"-"+strconv.Itoa(s.EndLine),
+		"BRIO_LANGUAGE="+s.Language(),
+	)
+	if err := command.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	output := strings.TrimSuffix(stdout.String(), "\n")
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".brio.yaml")
+	config := "transforms:\n  - name: upper\n    command: [tr, a-z, A-Z]\n    languages: [python]\n    timeout: 5s\n"
+	assert.Nil(t, os.WriteFile(path, []byte(config), 0644))
+	c, err := loadConfig(path, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []transformConfig{
+		{Name: "upper", Command: []string{"tr", "a-z", "A-Z"}, Languages: []string{"python"}, Timeout: 5 * time.Second},
+	}, c.Transforms)
+
+	assert.Nil(t, os.WriteFile(path, []byte("transforms:\n  - name: upper\n"), 0644))
+	_, err = loadConfig(path, true)
+	assert.EqualError(t, err, path+": transforms: upper: command is required")
+}
+
+func TestTransformSnippets(t *testing.T) {
+	python, _ := plugins.Get(".py")
+	golang, _ := plugins.Get(".go")
+	snips := []snippet{
+		{File: "app.py", StartLine: 1, EndLine: 4, Content: []string{"token = 'abc'", "send(token)"}, Plugin: python},
+		{File: "main.go", StartLine: 7, EndLine: 9, Content: []string{"token := \"abc\""}, Plugin: golang},
+		{File: "drop.py", StartLine: 1, EndLine: 3, Content: []string{"drop me"}, Plugin: python},
+	}
+	transforms := []transformConfig{
+		// Scrubs the secret everywhere.
+		{Name: "scrub", Command: []string{"sed", "s/abc/***/"}},
+		// Leaves out the snippets saying so, and tags the others of Python with their location.
+		{Name: "locate", Languages: []string{"python"}, Command: []string{"sh", "-c",
+			`in=$(cat); case "$in" in *"drop me"*) exit 0;; esac; echo "# $BRIO_FILE:$BRIO_LINES ($BRIO_LANGUAGE)"; echo "$in"`}},
+	}
+	got, err := transformSnippets(context.Background(), snips, transforms)
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, []string{"# app.py:1-4 (python)", "token = '***'", "send(token)"}, got[0].Content)
+	assert.Equal(t, []string{"token := \"***\""}, got[1].Content)
+	assert.Equal(t, []string{"token = 'abc'", "send(token)"}, snips[0].Content, "the input is left alone")
+
+	_, err = transformSnippets(context.Background(), snips, []transformConfig{
+		{Name: "broken", Command: []string{"sh", "-c", "echo oops >&2; exit 3"}},
+	})
+	assert.EqualError(t, err, "transform broken failed on app.py:1: exit status 3: oops")
+
+	_, err = transformSnippets(context.Background(), snips, []transformConfig{
+		{Name: "slow", Command: []string{"sleep", "5"}, Timeout: 10 * time.Millisecond},
+	})
+	assert.ErrorContains(t, err, "transform slow failed on app.py:1")
+}
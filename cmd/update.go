@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// idFlag names the snippet update replaces by its _id; fromFlag is the file holding its new content;
+// hashFlag is the hash of the content of the snippet this new content was made from.
+var (
+	idFlag   string
+	fromFlag string
+	hashFlag string
+)
+
+// updateCmd replaces the content of a single snippet.
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Replace the content of a tagged snippet",
+	Long: `Update replaces the lines between the start and end tags of the snippet whose _id
+is given with the content of a file, or of the standard input, for scripted
+refactors. The tags themselves are kept, and the new lines are indented like the
+lines they replace, as apply does.
+
+With --hash, the hash of the snippet when the new content was made from it, a
+snippet that changed since is a conflict, left alone: --merge merges both changes
+given the content it was made from, and --force overwrites them.
+Usage example:
+brio update --id auth-flow --from new_auth.py --dir ./src
+generate-handler | brio update --id auth-flow --hash 3f2a9c81d04e
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if idFlag == "" {
+			fatalf("--id is required")
+		}
+		overrideTabWidth(cmd)
+		var text []byte
+		var err error
+		if fromFlag == "" || fromFlag == "-" {
+			text, err = io.ReadAll(os.Stdin)
+		} else {
+			text, err = os.ReadFile(fromFlag)
+		}
+		if err != nil {
+			fatalf("Error reading the new content: %v", err)
+		}
+
+		snips, scanned := scanTagged(cmd.Context(), map[string][]string{})
+		var found []snippet
+		for _, s := range snips {
+			if s.Attr("id") == idFlag {
+				found = append(found, s)
+			}
+		}
+		switch len(found) {
+		case 0:
+			fatalf("No snippet declares the _id %q", idFlag)
+		case 1:
+		default:
+			fatalf("%d snippets declare the _id %q (BRIO013); make it unique first", len(found), idFlag)
+		}
+
+		s := found[0]
+		edit := snippetEdit{snippet: s, content: contentLines(string(text)), hash: hashFlag}
+		if mergeFlag != "" {
+			base, err := os.ReadFile(mergeFlag)
+			if err != nil {
+				fatalf("Error reading %s: %v", mergeFlag, err)
+			}
+			edit.base = contentLines(string(base))
+		}
+		if writeEdits(s.File, []snippetEdit{edit}, scanned[s.File]) == 0 {
+			exit(1)
+		}
+	},
+}
+
+// init registers updateCmd and its flags.
+func init() {
+	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().StringVarP(&dirFlag, "dir", "d", ".", "Directory to look for the snippet id in")
+	updateCmd.Flags().StringVarP(&filePattern, "files", "f", "*", "File pattern to match (e.g., *.py)")
+	updateCmd.Flags().StringVar(&idFlag, "id", "", "The _id of the snippet to replace")
+	updateCmd.Flags().StringVar(&fromFlag, "from", "",
+		"File holding the new content of the snippet; the standard input by default")
+	updateCmd.Flags().StringVar(&hashFlag, "hash", "",
+		"Hash of the content of the snippet the new content was made from, to detect conflicts")
+	addGuardFlags(updateCmd,
+		"File holding the content of the snippet the new content was made from, to merge changes made since into")
+	addTabWidthFlag(updateCmd, "Tab width the snippet was extracted with, to indent it with tabs again where the file does")
+}
+
+// contentLines splits text into lines, without the line ending the last one.
+func contentLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentLines(t *testing.T) {
+	assert.Equal(t, []string{"a = 1", "", "b = 2"}, contentLines("a = 1\r\n\r\nb = 2\r\n"))
+	assert.Equal(t, []string{"a = 1"}, contentLines("a = 1"))
+	assert.Equal(t, []string{}, contentLines(""))
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// uploadFlag is the bucket URL, s3://bucket/prefix/ or gs://bucket/prefix/, extract, export and docs
+// upload what they write to.
+var uploadFlag string
+
+// uploadTimeout bounds how long the upload of a single file may take.
+const uploadTimeout = 5 * time.Minute
+
+// uploadUsage is the help of --upload; what names the output uploaded.
+func uploadUsage(what string) string {
+	return "Upload " + what + " to s3://bucket/prefix/ or gs://bucket/prefix/, with the credentials of the environment"
+}
+
+// openUpload returns the bucket of --upload, exiting if it isn't a valid bucket URL.
+func openUpload() *brio.Bucket {
+	bucket, err := brio.OpenBucket(uploadFlag, &http.Client{Timeout: uploadTimeout})
+	if err != nil {
+		fatalf("Invalid --upload: %v", err)
+	}
+	return bucket
+}
+
+// uploadFile uploads the file at path to bucket, named after its base name (see brio.Bucket.Key).
+func uploadFile(ctx context.Context, bucket *brio.Bucket, path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key, err := bucket.Put(ctx, filepath.Base(path), body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Uploaded %s to %s\n", path, bucket.URL(key))
+	return nil
+}
+
+// uploadDir uploads the files below dir to bucket.
+func uploadDir(ctx context.Context, bucket *brio.Bucket, dir string) error {
+	n, err := bucket.PutDir(ctx, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Uploaded %d files of %s to %s\n", n, dir, bucket)
+	return nil
+}
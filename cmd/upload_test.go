@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadFile(t *testing.T) {
+	uploads := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads[r.URL.Query().Get("name")] = string(body)
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+
+	bundle := filepath.Join(t.TempDir(), "context.zip")
+	require.NoError(t, os.WriteFile(bundle, []byte("zip"), 0644))
+
+	uploadFlag = "gs://packs/nightly/"
+	defer func() { uploadFlag = "" }()
+	require.NoError(t, uploadFile(context.Background(), openUpload(), bundle))
+	assert.Equal(t, map[string]string{"nightly/context.zip": "zip"}, uploads)
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// validateConfigCmd checks the config file and prints the configuration commands run with.
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate-config",
+	Short: "Check the config file for errors and print the effective configuration",
+	Long: `Validate-config reads .brio.yaml (or the file given with --config) and reports
+every problem it finds, each with its line: YAML syntax errors, settings of the
+wrong type, unknown settings, which other commands silently ignore, and
+unsupported values, such as an invalid ignore pattern or a language without a
+comment syntax. Extensions and pins are checked against the built-in plugins and
+the languages of the file; plugins on $PATH or in the plugin directory are never
+run or loaded, so settings naming one are only matched to the names of their
+files.
+
+It then prints the effective configuration, as YAML: the file merged with the
+defaults and the flags overriding it (--plugin-dir, --fallback, --jobs,
+--no-default-ignores), each setting the file doesn't set marked as a default.
+It exits with a status of 1 if there is any problem.
+Usage example:
+brio validate-config --config ci/.brio.yaml
+`,
+	Args: cobra.NoArgs,
+	// The config file is loaded by Run, reporting every problem instead of failing on the first.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		startSummary(cmd.CommandPath())
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		c, doc, problems, err := checkConfig(configFlag, cmd.Flags().Changed("config"))
+		if err != nil {
+			fatalf("Error reading %s: %v", configFlag, err)
+		}
+		if doc == nil && len(problems) == 0 {
+			log.Printf("No %s found, using the defaults", configFlag)
+		}
+		overridden, err := overrideConfig(&c, cmd)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if len(problems) == 0 {
+			cfg = c
+			problems = checkPluginSettings(doc)
+		}
+		for _, p := range problems {
+			if p.line > 0 {
+				fmt.Fprintf(os.Stderr, "%s:%d: %s\n", configFlag, p.line, p.message)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", configFlag, p.message)
+			}
+		}
+		// A file that isn't YAML has no settings to show.
+		if doc != nil || len(problems) == 0 {
+			if err := writeEffectiveConfig(os.Stdout, c, doc, overridden); err != nil {
+				fatalf("Error writing the configuration: %v", err)
+			}
+		}
+		if len(problems) > 0 {
+			exit(1)
+		}
+	},
+}
+
+// init registers validateConfigCmd.
+func init() {
+	rootCmd.AddCommand(validateConfigCmd)
+}
+
+// configProblem is a problem of the config file, at a line of it, or 0 if it is about the whole file.
+type configProblem struct {
+	line    int
+	message string
+}
+
+// Patterns of the errors of yaml.v3, to report their lines apart and name unknown settings plainly.
+var (
+	yamlLineError    = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	yamlUnknownField = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+)
+
+// checkConfig reads the config file at path like loadConfig, but returns every problem it finds
+// instead of the first, by line, with the parsed document to locate settings in. doc is nil when
+// the file is missing, which is only a problem when the path was given explicitly, or isn't YAML.
+func checkConfig(path string, explicit bool) (c config, doc *yaml.Node, problems []configProblem, err error) {
+	c = defaultConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return c, nil, nil, nil
+		}
+		return c, nil, nil, err
+	}
+
+	doc = new(yaml.Node)
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return c, nil, []configProblem{yamlProblem(err.Error())}, nil
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		// Decoding goes on past settings of the wrong type and unknown ones, reporting them all.
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return c, doc, []configProblem{yamlProblem(err.Error())}, nil
+		}
+		for _, message := range typeErr.Errors {
+			problems = append(problems, yamlProblem(message))
+		}
+	}
+	for _, p := range c.problems() {
+		problems = append(problems, locateProblem(doc, p))
+	}
+	slices.SortStableFunc(problems, func(a, b configProblem) int { return a.line - b.line })
+	c.nativePaths()
+	c.dir, _ = filepath.Abs(filepath.Dir(path))
+	return c, doc, problems, nil
+}
+
+// checkPluginSettings checks the extensions and pin settings of cfg as registerConfigPlugins does,
+// but against the built-in plugins and the languages cfg declares only: no other plugin is run or
+// loaded. A setting naming a plugin of pluginPaths is logged as left unchecked instead.
+func checkPluginSettings(doc *yaml.Node) []configProblem {
+	registerLanguages(cfg.Languages)
+	files := pluginPaths()
+	var problems []configProblem
+	check := func(at []string, name string, err error) {
+		if err == nil {
+			return
+		}
+		if path, ok := files[strings.ToLower(name)]; ok {
+			log.Printf("%s: %s may be the plugin %s, which isn't run to check it", strings.Join(at, ": "), name, path)
+			return
+		}
+		problems = append(problems, locateProblem(doc, configError{at: at, err: err}))
+	}
+	for _, ext := range sortedKeys(cfg.Extensions) {
+		err := plugins.MapExtension(ext, cfg.Extensions[ext])
+		if err != nil {
+			err = fmt.Errorf("extensions: %w", err)
+		}
+		check([]string{"extensions", ext}, cfg.Extensions[ext], err)
+	}
+	for _, key := range sortedKeys(cfg.Pin) {
+		check([]string{"pin", key}, cfg.Pin[key], plugins.Pin(key, cfg.Pin[key]))
+	}
+	return problems
+}
+
+// pluginPaths returns the paths of the plugins registerConfigPlugins would run or load, the
+// executables on $PATH and the modules of the plugin directories, by the name their file gives,
+// lowercased and without its brio-plugin- prefix or its extension.
+func pluginPaths() map[string]string {
+	wasmDir, nativeDir := cfg.pluginDirs()
+	paths := append(plugins.DiscoverExternal(os.Getenv("PATH")), plugins.DiscoverWASM(wasmDir)...)
+	paths = append(paths, plugins.DiscoverNative(nativeDir)...)
+	files := make(map[string]string, len(paths))
+	for _, path := range paths {
+		name := strings.TrimPrefix(filepath.Base(path), plugins.ExternalPrefix)
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		files[strings.ToLower(name)] = path
+	}
+	return files
+}
+
+// yamlProblem turns an error message of yaml.v3, such as "line 3: field profiles not found in type
+// cmd.config", into a configProblem.
+func yamlProblem(message string) configProblem {
+	var p configProblem
+	p.message = message
+	if m := yamlLineError.FindStringSubmatch(message); m != nil {
+		p.line, _ = strconv.Atoi(m[1])
+		p.message = m[2]
+	}
+	if m := yamlUnknownField.FindStringSubmatch(p.message); m != nil {
+		p.message = fmt.Sprintf("unknown setting %q, ignored", m[1])
+	}
+	return p
+}
+
+// locateProblem returns err as a configProblem, at the line of its setting in doc if it is a
+// configError.
+func locateProblem(doc *yaml.Node, err error) configProblem {
+	p := configProblem{message: err.Error()}
+	var ce configError
+	if errors.As(err, &ce) {
+		p.line = settingLine(doc, ce.at)
+	}
+	return p
+}
+
+// settingLine returns the line of the setting at path in doc, as far down the path as it goes, or 0
+// if not even its top-level key is in doc.
+func settingLine(doc *yaml.Node, path []string) int {
+	if doc == nil || len(doc.Content) == 0 {
+		return 0
+	}
+	node, line := doc.Content[0], 0
+	for _, step := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == step {
+					line, next = node.Content[i].Line, node.Content[i+1]
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(step); err == nil && i >= 0 && i < len(node.Content) {
+				next = node.Content[i]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+// writeEffectiveConfig writes c to w as YAML, commenting the top-level settings overridden by a
+// flag and those doc, the config file, doesn't set, so they come from the defaults.
+func writeEffectiveConfig(w io.Writer, c config, doc *yaml.Node, overridden []string) error {
+	var root yaml.Node
+	if err := root.Encode(c); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		comment := ""
+		switch {
+		case slices.Contains(overridden, key.Value):
+			comment = "overridden by a flag"
+		case settingLine(doc, []string{key.Value}) == 0:
+			comment = "default"
+		}
+		if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+			value.LineComment = comment
+		} else {
+			key.LineComment = comment
+		}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
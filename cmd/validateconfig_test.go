@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".brio.yaml")
+	content := `markers: arrows
+profiles:
+  ci:
+    categories: tests
+jobs: many
+ignore:
+  - vendor
+  - "[oops"
+languages:
+  - name: Rego
+    extensions: [.rego]
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	c, doc, problems, err := checkConfig(path, true)
+	require.NoError(t, err)
+	require.NotNil(t, doc)
+	assert.Equal(t, []configProblem{
+		{line: 2, message: `unknown setting "profiles", ignored`},
+		{line: 5, message: "cannot unmarshal !!str `many` into int"},
+		{line: 8, message: `ignore: invalid pattern "[oops": syntax error in pattern`},
+		{line: 10, message: "languages: Rego: at least one comment syntax is required"},
+	}, problems)
+	assert.Equal(t, markersArrows, c.Markers)
+
+	var out strings.Builder
+	c.PluginDir = "ci/plugins"
+	require.NoError(t, writeEffectiveConfig(&out, c, doc, []string{"plugin_dir"}))
+	assert.Contains(t, out.String(), "markers: arrows\n")
+	assert.Contains(t, out.String(), "payload: json # default\n")
+	assert.Contains(t, out.String(), "plugin_dir: ci/plugins # overridden by a flag\n")
+	assert.Contains(t, out.String(), "ignore:\n  - vendor\n")
+
+	require.NoError(t, os.WriteFile(path, []byte("markers: [\n"), 0644))
+	_, doc, problems, err = checkConfig(path, true)
+	require.NoError(t, err)
+	assert.Nil(t, doc)
+	assert.Equal(t, []configProblem{{line: 1, message: "did not find expected node content"}}, problems)
+
+	_, doc, problems, err = checkConfig(filepath.Join(t.TempDir(), "missing.yaml"), false)
+	require.NoError(t, err)
+	assert.Nil(t, doc)
+	assert.Empty(t, problems)
+}
+
+func TestCheckPluginSettings(t *testing.T) {
+	defer func() { cfg = defaultConfig() }()
+	bin := t.TempDir()
+	ran := filepath.Join(bin, "ran")
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "brio-plugin-hcl"), []byte("#!/bin/sh\ntouch "+ran+"\n"), 0755))
+	t.Setenv("PATH", bin)
+
+	path := filepath.Join(t.TempDir(), ".brio.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`languages:
+  - name: Rego
+    extensions: [.rego]
+    comments:
+      single: ["#"]
+extensions:
+  .pyx: python
+  .policy: rego
+  .tf: hcl
+  .nope: cobol
+pin:
+  .h: HCL
+  Jenkinsfile: groovy
+`), 0644))
+	c, doc, problems, err := checkConfig(path, true)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	cfg = c
+	assert.Equal(t, []configProblem{
+		{line: 10, message: "extensions: can't map .nope to cobol: no plugin handles that language"},
+		{line: 13, message: "can't pin Jenkinsfile to groovy: no plugin of that name is registered"},
+	}, checkPluginSettings(doc))
+	assert.NoFileExists(t, ran, "plugins on $PATH aren't run")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"maps"
+	"os"
+	"time"
+)
+
+// watchFlag keeps extract running, extracting again whenever the files of --dir change.
+// watchIntervalFlag is how often they are checked.
+var (
+	watchFlag         bool
+	watchIntervalFlag time.Duration
+)
+
+// fileState is what watch compares to tell that a file changed.
+type fileState struct {
+	modTime int64 // in nanoseconds, as time.Time can't be compared with ==
+	size    int64
+}
+
+// snapshot returns the state of each of files; files that can't be read are left out, so that they
+// count as changed once they can.
+func snapshot(files []string) map[string]fileState {
+	states := make(map[string]fileState, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		states[f] = fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+	}
+	return states
+}
+
+// watch calls run, then again every time the files below dir that match pattern change: when one
+// is added, removed or modified. It polls them every interval, since there's no portable way to be
+// notified, and returns when ctx is done.
+func watch(ctx context.Context, dir, pattern string, interval time.Duration, run func()) {
+	files, _ := collectFiles(ctx, dir, pattern)
+	last := snapshot(files)
+	run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		files, err := collectFiles(ctx, dir, pattern)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to list files: %v", err)
+			}
+			continue
+		}
+		current := snapshot(files)
+		if maps.Equal(current, last) {
+			continue
+		}
+		last = current
+		log.Printf("Files changed, extracting again")
+		run()
+	}
+}
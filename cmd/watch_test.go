@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.py")
+	assert.Nil(t, os.WriteFile(app, []byte("x = 1\n"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		watch(ctx, dir, "*", 10*time.Millisecond, func() { runs <- struct{}{} })
+		close(done)
+	}()
+	<-runs
+
+	// Adding a file and modifying one both trigger a run.
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "lib.py"), []byte("y = 2\n"), 0o644))
+	<-runs
+	assert.Nil(t, os.WriteFile(app, []byte("x = 10\n"), 0o644))
+	<-runs
+
+	// Nothing changing, nothing runs.
+	select {
+	case <-runs:
+		t.Fatal("ran again without changes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/pkg/brio"
+)
+
+// webhookFlag is the URL extract --watch posts the snippets that changed to.
+var webhookFlag string
+
+// webhookTimeout bounds how long a webhook may take to answer.
+const webhookTimeout = 10 * time.Second
+
+// webhookPayload is the JSON body posted to --webhook. Text sums it up, so that Slack and other
+// chat incoming webhooks can post it as it is.
+type webhookPayload struct {
+	Text     string           `json:"text"`
+	Snippets []webhookSnippet `json:"snippets"`
+}
+
+// webhookSnippet is a snippet that changed, in a webhook payload.
+type webhookSnippet struct {
+	// Change is "added", "modified" or "removed".
+	Change     string              `json:"change"`
+	File       string              `json:"file"`
+	Section    string              `json:"section,omitempty"`
+	StartLine  int                 `json:"start_line"`
+	EndLine    int                 `json:"end_line"`
+	ID         string              `json:"id,omitempty"`
+	Categories map[string][]string `json:"categories"`
+	// Diff is the changes of its content (see brio.DiffLines).
+	Diff []string `json:"diff,omitempty"`
+}
+
+// validateWebhook reports a --webhook that isn't an HTTP URL.
+func validateWebhook(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
+	return nil
+}
+
+// webhookNotifier posts the snippets that changed between two extractions to a webhook.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+	// last holds the snippets of the last extraction, nil before the first.
+	last []snippet
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+// update compares snips to the snippets of the last extraction and posts those added, modified or
+// removed since, if any. The first extraction is only remembered.
+func (w *webhookNotifier) update(ctx context.Context, snips []snippet) error {
+	previous := w.last
+	w.last = append([]snippet{}, snips...)
+	if previous == nil {
+		return nil
+	}
+	changes := snippetChanges(previous, snips)
+	if len(changes) == 0 {
+		return nil
+	}
+	return w.post(ctx, webhookPayload{Text: webhookText(changes), Snippets: changes})
+}
+
+// post sends payload to the webhook.
+func (w *webhookNotifier) post(ctx context.Context, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s answered %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+// notifyWebhook passes snips, the snippets an extraction printed, to notifier, warning about any
+// failure: watching goes on.
+func notifyWebhook(ctx context.Context, notifier *webhookNotifier, snips []snippet) {
+	if ctx.Err() != nil {
+		// Partial results would look like removed snippets.
+		return
+	}
+	if err := notifier.update(ctx, snips); err != nil {
+		log.Printf("Warning: failed to notify --webhook: %v", err)
+	}
+}
+
+// webhookKeys identify snippets across extractions, tried in order: by _id; by file, categories and
+// content, so that moving a snippet isn't a change; by file and start line, for a snippet changed
+// in place; then by file and categories, for one moved and changed. A key of "" matches nothing.
+var webhookKeys = []func(s snippet) string{
+	func(s snippet) string {
+		if id := s.Attr("id"); id != "" {
+			return "id:" + id
+		}
+		return ""
+	},
+	func(s snippet) string { return snippetPlace(s) + ":" + joinCategories(s.Categories) + "@" + s.Hash() },
+	func(s snippet) string { return fmt.Sprintf("%s:%d", snippetPlace(s), s.StartLine) },
+	func(s snippet) string { return snippetPlace(s) + ":" + joinCategories(s.Categories) },
+}
+
+// snippetPlace returns the file of s, and its section if any.
+func snippetPlace(s snippet) string {
+	return brio.SectionPath(displayPath(s.File), s.Section)
+}
+
+// snippetChanges returns the snippets of current added or modified since previous, and those of
+// previous it no longer has, sorted by file and line. Snippets are paired up by webhookKeys, each
+// snippet with at most one other, in the order they come in.
+func snippetChanges(previous, current []snippet) []webhookSnippet {
+	pairs := make([]int, len(current)) // the index in previous of each snippet of current, or -1
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	paired := make([]bool, len(previous))
+	for _, key := range webhookKeys {
+		unpaired := make(map[string][]int)
+		for j, s := range previous {
+			if k := key(s); !paired[j] && k != "" {
+				unpaired[k] = append(unpaired[k], j)
+			}
+		}
+		for i, s := range current {
+			k := key(s)
+			if pairs[i] >= 0 || k == "" || len(unpaired[k]) == 0 {
+				continue
+			}
+			pairs[i], unpaired[k] = unpaired[k][0], unpaired[k][1:]
+			paired[pairs[i]] = true
+		}
+	}
+
+	var changes []webhookSnippet
+	for i, s := range current {
+		switch j := pairs[i]; {
+		case j < 0:
+			changes = append(changes, newWebhookSnippet("added", s, brio.DiffLines(nil, s.Content)))
+		case previous[j].Hash() != s.Hash():
+			changes = append(changes, newWebhookSnippet("modified", s, brio.DiffLines(previous[j].Content, s.Content)))
+		}
+	}
+	for j, s := range previous {
+		if !paired[j] {
+			changes = append(changes, newWebhookSnippet("removed", s, brio.DiffLines(s.Content, nil)))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].StartLine < changes[j].StartLine
+	})
+	return changes
+}
+
+func newWebhookSnippet(change string, s snippet, diff []string) webhookSnippet {
+	return webhookSnippet{
+		Change:     change,
+		File:       displayPath(s.File),
+		Section:    s.Section,
+		StartLine:  s.StartLine,
+		EndLine:    s.EndLine,
+		ID:         s.Attr("id"),
+		Categories: s.Categories,
+		Diff:       diff,
+	}
+}
+
+// webhookText sums up changes on a line each, e.g. "src/auth.py:12 (security: auth) modified".
+func webhookText(changes []webhookSnippet) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s changed:", snippetCount(len(changes)))
+	for _, c := range changes {
+		fmt.Fprintf(&text, "\n%s:%d (%s) %s", brio.SectionPath(c.File, c.Section), c.StartLine,
+			joinCategories(c.Categories), c.Change)
+	}
+	return text.String()
+}
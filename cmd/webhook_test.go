@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var payloads []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var p webhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		payloads = append(payloads, p)
+	}))
+	defer server.Close()
+
+	auth := snippet{File: "auth.py", StartLine: 3, EndLine: 6, Categories: map[string][]string{"security": {"auth"}},
+		Content: []string{"def login(user):", "    return True"}}
+	tokens := snippet{File: "tokens.py", StartLine: 1, EndLine: 3, Categories: map[string][]string{"security": {}},
+		Attrs: map[string][]string{"id": {"tokens"}}, Content: []string{"SECRET = env()"}}
+
+	notifier := newWebhookNotifier(server.URL)
+	ctx := context.Background()
+	require.NoError(t, notifier.update(ctx, []snippet{auth, tokens}))
+	assert.Empty(t, payloads, "the first extraction is only remembered")
+
+	// auth.py moves down and changes; tokens.py is gone.
+	moved := auth
+	moved.StartLine, moved.EndLine = 10, 13
+	moved.Content = []string{"def login(user):", "    return check(user)"}
+	require.NoError(t, notifier.update(ctx, []snippet{moved}))
+	require.NoError(t, notifier.update(ctx, []snippet{moved}))
+	require.Len(t, payloads, 1, "nothing changed the second time")
+
+	assert.Equal(t, "2 snippets changed:\nauth.py:10 (security: auth) modified\ntokens.py:1 (security) removed", payloads[0].Text)
+	assert.Equal(t, []webhookSnippet{
+		{Change: "modified", File: "auth.py", StartLine: 10, EndLine: 13, Categories: auth.Categories,
+			Diff: []string{" def login(user):", "-    return True", "+    return check(user)"}},
+		{Change: "removed", File: "tokens.py", StartLine: 1, EndLine: 3, ID: "tokens", Categories: tokens.Categories,
+			Diff: []string{"-SECRET = env()"}},
+	}, payloads[0].Snippets)
+
+	failing := newWebhookNotifier(server.URL + "/missing")
+	failing.last = []snippet{}
+	server.Config.Handler = http.NotFoundHandler()
+	assert.ErrorContains(t, failing.update(ctx, []snippet{auth}), "answered 404 Not Found")
+}
+
+func TestSnippetChanges(t *testing.T) {
+	cats := map[string][]string{"security": {}}
+	first := snippet{File: "auth.py", StartLine: 1, EndLine: 3, Categories: cats, Content: []string{"check()"}}
+	second := snippet{File: "auth.py", StartLine: 5, EndLine: 7, Categories: cats, Content: []string{"verify()"}}
+
+	// A snippet of the same categories inserted above the others moves them: only it is new.
+	inserted := snippet{File: "auth.py", StartLine: 1, EndLine: 3, Categories: cats, Content: []string{"audit()"}}
+	movedFirst, movedSecond := first, second
+	movedFirst.StartLine, movedFirst.EndLine = 5, 7
+	movedSecond.StartLine, movedSecond.EndLine = 9, 11
+	changes := snippetChanges([]snippet{first, second}, []snippet{inserted, movedFirst, movedSecond})
+	require.Len(t, changes, 1)
+	assert.Equal(t, "added", changes[0].Change)
+	assert.Equal(t, []string{"+audit()"}, changes[0].Diff)
+
+	// A snippet changed in place is modified.
+	changed := second
+	changed.Content = []string{"verify(strict=True)"}
+	changes = snippetChanges([]snippet{first, second}, []snippet{first, changed})
+	require.Len(t, changes, 1)
+	assert.Equal(t, "modified", changes[0].Change)
+	assert.Equal(t, 5, changes[0].StartLine)
+}
+
+func TestValidateWebhook(t *testing.T) {
+	assert.NoError(t, validateWebhook("https://hooks.slack.com/services/T0/B0/x"))
+	assert.EqualError(t, validateWebhook("hooks.slack.com/x"), `"hooks.slack.com/x" is not an http or https URL`)
+}
@@ -0,0 +1,272 @@
+package brio
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// archiveExtensions are the file name suffixes of the archives brio scans inside.
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// isArchive reports whether name is the name of an archive brio can scan.
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxArchiveEntrySize and maxArchiveSize bound the decompressed size of each file of an archive and
+// of all of them, which are held in memory: an archive over them is refused rather than let a small
+// one, such as a zip bomb, exhaust the memory.
+var (
+	maxArchiveEntrySize int64 = 64 << 20
+	maxArchiveSize      int64 = 512 << 20
+)
+
+// archive holds the regular files of an archive or of a cloned repository, read into memory so they
+// can be scanned without writing them to disk.
+type archive struct {
+	names []string // sorted
+	files map[string][]byte
+	size  int64 // of the files read from an archive, decompressed
+}
+
+// lastArchive caches the archive read last: files are scanned one after another, and reading a
+// compressed archive again for each of its members would be slow. Only one is kept so that scanning
+// many archives doesn't hold them all in memory.
+var lastArchive struct {
+	sync.Mutex
+	path    string
+	archive *archive
+}
+
+// openArchive returns the content of the archive at archivePath.
+func openArchive(archivePath string) (*archive, error) {
+	lastArchive.Lock()
+	defer lastArchive.Unlock()
+	if lastArchive.archive != nil && lastArchive.path == archivePath {
+		return lastArchive.archive, nil
+	}
+
+	a, err := readArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	lastArchive.path, lastArchive.archive = archivePath, a
+	return a, nil
+}
+
+// readArchive reads the regular files of the zip or tar archive at archivePath.
+func readArchive(archivePath string) (*archive, error) {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	a := &archive{files: make(map[string][]byte)}
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		err = a.readZip(data)
+	} else {
+		err = a.readTar(archivePath, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive %s: %v", archivePath, err)
+	}
+	sort.Strings(a.names)
+	return a, nil
+}
+
+func (a *archive) readZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = a.read(f.Name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archive) readTar(archivePath string, data []byte) error {
+	var r io.Reader = bytes.NewReader(data)
+	if !strings.HasSuffix(strings.ToLower(archivePath), ".tar") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := a.read(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// read adds the file name of the archive, read from r, failing once it decompresses to more than
+// maxArchiveEntrySize or the files read so far to more than maxArchiveSize.
+func (a *archive) read(name string, r io.Reader) error {
+	limit := min(maxArchiveEntrySize, maxArchiveSize-a.size)
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(content)) > limit {
+		if limit < maxArchiveEntrySize {
+			return fmt.Errorf("its files are over %d MiB decompressed", maxArchiveSize>>20)
+		}
+		return fmt.Errorf("%s is over %d MiB decompressed", name, maxArchiveEntrySize>>20)
+	}
+	a.size += int64(len(content))
+	a.add(name, content)
+	return nil
+}
+
+// add stores a file of the archive under its cleaned, slash-separated name. Names leaving the
+// archive root ("../x") are skipped.
+func (a *archive) add(name string, content []byte) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return
+	}
+	if _, ok := a.files[name]; !ok {
+		a.names = append(a.names, name)
+	}
+	a.files[name] = content
+}
+
+// remove removes the file name from a.
+func (a *archive) remove(name string) {
+	delete(a.files, name)
+	a.names = slices.DeleteFunc(a.names, func(n string) bool { return n == name })
+}
+
+// splitArchivePath splits the path of a file inside an archive, such as vendor.zip/src/app.py, into
+// the path of the archive and the name of the file inside it.
+func splitArchivePath(filePath string) (string, string, bool) {
+	parts := strings.Split(filepath.ToSlash(filePath), "/")
+	for i := 1; i < len(parts); i++ {
+		if !isArchive(parts[i-1]) {
+			continue
+		}
+		archivePath := filepath.FromSlash(strings.Join(parts[:i], "/"))
+		if info, err := os.Stat(archivePath); err == nil && info.Mode().IsRegular() {
+			return archivePath, strings.Join(parts[i:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// ReadFile returns the content of filePath like os.ReadFile, except that filePath may also name a
+// file inside an archive, such as vendor.zip/src/app.py.
+func ReadFile(filePath string) ([]byte, error) {
+	content, err := os.ReadFile(filePath)
+	if err == nil {
+		return content, nil
+	}
+	return readArchived(filePath, err)
+}
+
+// openFile opens filePath like os.Open, except that filePath may also name a file inside an archive.
+func openFile(filePath string) (io.ReadCloser, error) {
+	f, err := os.Open(filePath)
+	if err == nil {
+		return f, nil
+	}
+	content, err := readArchived(filePath, err)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// mounted holds the trees that aren't read from a file, such as cloned repositories, by the root
+// their files are named under.
+var mounted = struct {
+	sync.Mutex
+	trees map[string]*archive
+}{trees: make(map[string]*archive)}
+
+// mount makes the files of a available under root.
+func mount(root string, a *archive) {
+	mounted.Lock()
+	defer mounted.Unlock()
+	mounted.trees[filepath.Clean(root)] = a
+}
+
+// mountedTree returns the mounted tree filePath names a file of, along with the name of the file in it.
+func mountedTree(filePath string) (*archive, string, bool) {
+	mounted.Lock()
+	defer mounted.Unlock()
+	filePath = filepath.Clean(filePath)
+	for root, a := range mounted.trees {
+		if rel, err := filepath.Rel(root, filePath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a, filepath.ToSlash(rel), true
+		}
+	}
+	return nil, "", false
+}
+
+// readArchived returns the content of filePath if it names a file inside an archive or a mounted
+// tree, and err, the error of opening it as a regular file, otherwise.
+func readArchived(filePath string, err error) ([]byte, error) {
+	if a, name, ok := mountedTree(filePath); ok {
+		content, ok := a.files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such file", filePath)
+		}
+		return content, nil
+	}
+
+	archivePath, name, ok := splitArchivePath(filePath)
+	if !ok {
+		return nil, err
+	}
+	a, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	content, ok := a.files[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: no such file in archive %s", name, archivePath)
+	}
+	return content, nil
+}
@@ -0,0 +1,120 @@
+package brio
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const archivedSource = "# >: {\"tests\": [\"vendor\"]}\ndef vendored():\n    pass\n# <: {\"tests\": [\"vendor\"]}\n"
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, gw.Close())
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestExtractorExtractArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"src/vendored.py": archivedSource,
+		"README.txt":      "not scanned",
+		"../escape.py":    archivedSource,
+	}
+
+	tests := []struct {
+		name  string
+		write func(*testing.T, string, map[string]string)
+	}{
+		{"drop.zip", writeZip},
+		{"drop.tar.gz", writeTarGz},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := filepath.Join(tempDir, tt.name)
+			tt.write(t, archivePath, files)
+
+			extractor := New(Options{Dir: archivePath, Pattern: "*.py"})
+			found, err := extractor.Files(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, []string{filepath.Join(archivePath, "src", "vendored.py")}, found)
+
+			snips, issues, err := extractor.Extract(context.Background())
+			assert.Nil(t, err)
+			assert.Empty(t, issues)
+			assert.Len(t, snips, 1)
+			assert.Equal(t, found[0], snips[0].File)
+			assert.Equal(t, []string{"def vendored():", "    pass"}, snips[0].Content)
+
+			content, err := ReadFile(found[0])
+			assert.Nil(t, err)
+			assert.Equal(t, archivedSource, string(content))
+
+			_, err = ReadFile(filepath.Join(archivePath, "missing.py"))
+			assert.ErrorContains(t, err, "no such file in archive")
+		})
+	}
+}
+
+func TestExtractorExtractInvalidArchive(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "broken.zip")
+	assert.Nil(t, os.WriteFile(archivePath, []byte("not a zip"), 0644))
+
+	_, _, err := New(Options{Dir: archivePath}).Extract(context.Background())
+	assert.ErrorContains(t, err, "invalid archive")
+}
+
+func TestReadArchiveLimits(t *testing.T) {
+	defer func(entry, total int64) { maxArchiveEntrySize, maxArchiveSize = entry, total }(maxArchiveEntrySize, maxArchiveSize)
+	maxArchiveEntrySize, maxArchiveSize = 2<<20, 3<<20
+	tempDir := t.TempDir()
+	// Zeros compress well: the archives are small, their files aren't.
+	mib := string(make([]byte, 1<<20))
+
+	small := filepath.Join(tempDir, "small.zip")
+	writeZip(t, small, map[string]string{"a.py": mib, "b.py": mib})
+	_, err := readArchive(small)
+	assert.Nil(t, err)
+
+	entry := filepath.Join(tempDir, "entry.tar.gz")
+	writeTarGz(t, entry, map[string]string{"bomb.py": mib + mib + "x"})
+	info, err := os.Stat(entry)
+	assert.Nil(t, err)
+	assert.Less(t, info.Size(), int64(1<<16))
+	_, err = readArchive(entry)
+	assert.ErrorContains(t, err, "bomb.py is over 2 MiB decompressed")
+
+	total := filepath.Join(tempDir, "total.zip")
+	writeZip(t, total, map[string]string{"a.py": mib, "b.py": mib, "c.py": mib, "d.py": "x"})
+	_, err = readArchive(total)
+	assert.ErrorContains(t, err, "its files are over 3 MiB decompressed")
+}
@@ -0,0 +1,477 @@
+// Package brio extracts the code snippets tagged in source files, as the brio command does, for
+// programs that embed it:
+//
+//	extractor := brio.New(brio.Options{Dir: "./src", Categories: brio.ParseCategories("messages:tests")})
+//	snippets, issues, err := extractor.Extract(ctx)
+//
+// Languages are handled by the plugins registered in the plugins package.
+package brio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// Marker styles accepted for snippet start/end tags.
+const (
+	MarkersArrows   = "arrows"   // # >: {...} / # <: {...}
+	MarkersKeywords = "keywords" // # start: {...} / # end: {...}
+	MarkersBoth     = "both"
+)
+
+// Tag payload formats.
+const (
+	PayloadJSON = "json" // # >: {"foundation": ["messages"]}
+	PayloadYAML = "yaml" // # >: foundation: [messages]
+)
+
+// Options configures an Extractor. The zero value scans the current directory for every tagged
+// snippet, with both marker styles and JSON payloads.
+type Options struct {
+	// Dir is the root directory scanned by Files and Extract; "" means the current directory. It may
+	// also be a .zip, .tar or .tar.gz archive, whose files are read without unpacking it.
+	Dir string
+	// Pattern is a glob matched against file names (e.g. "*.py"), or against paths relative to Dir
+	// when it names directories (e.g. "src/*.py", or `src\*.py` on Windows; see MatchPath), with
+	// brace sets matching any of their comma-separated alternatives (e.g. "*.{py,ts,tsx}"); "" and
+	// "*" match every file.
+	Pattern string
+	// Categories keeps only the snippets matching these categories and domains (see
+	// ParseCategories); an empty map keeps them all.
+	Categories map[string][]string
+	// ExcludeExpired drops snippets whose "_expires" date has passed instead of only reporting them.
+	ExcludeExpired bool
+	// Markers selects which start/end tag syntax is recognized: MarkersArrows, MarkersKeywords or
+	// MarkersBoth (the default).
+	Markers string
+	// Payload selects the tag payload syntax: PayloadJSON (the default) or PayloadYAML.
+	Payload string
+	// Regions turns IDE region markers (#region, // MARK:) into snippets.
+	Regions bool
+	// Virtual declares snippets from outside of their files (see VirtualSnippet).
+	Virtual []VirtualSnippet
+	// PathDefaults fill in the categories, domains and attributes tags leave out, by directory (see
+	// PathDefault).
+	PathDefaults []PathDefault
+	// CustomMarkers are the markers of other snippet conventions to extract too (see CustomMarker).
+	CustomMarkers []CustomMarker
+	// IncludeComments keeps the lines of multi-line comments without tags in snippets.
+	IncludeComments bool
+	// Fallback guesses the comment prefix of files no plugin handles from their tags.
+	Fallback bool
+	// Now returns the time expiry dates are checked against; nil means time.Now.
+	Now func() time.Time
+	// OnIssue, when set, receives the problems Each finds in annotations as it goes.
+	OnIssue func(Issue)
+	// Ignore names directories skipped wherever they are below Dir, e.g. "node_modules" or a glob
+	// such as "*.egg-info"; Dir itself is never skipped. See DefaultIgnores.
+	Ignore []string
+	// Jobs is how many files are read and parsed at once; 0 means one per CPU. Plugins are called
+	// concurrently when it isn't 1.
+	Jobs int
+	// Processors transform the matching snippets, in order, before Extract returns them or Each
+	// passes them on; see Chain. They are never called concurrently. A processor failing stops the
+	// extraction with its error.
+	Processors []Processor
+}
+
+// DefaultIgnores are the directories of dependencies, virtual environments, build output and version
+// control, for Options.Ignore: they hold no annotations of the project, and scanning them can take
+// longer than scanning the project itself.
+var DefaultIgnores = []string{"node_modules", "vendor", ".git", ".venv", "dist", "build", "target"}
+
+// Extractor finds tagged snippets in files according to its Options.
+type Extractor struct {
+	opts Options
+	// sidecars are the SidecarFile of the directories read so far.
+	sidecars sidecars
+}
+
+// New returns an Extractor using opts, with defaults filled in.
+func New(opts Options) *Extractor {
+	if opts.Markers == "" {
+		opts.Markers = MarkersBoth
+	}
+	if opts.Payload == "" {
+		opts.Payload = PayloadJSON
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Extractor{opts: opts}
+}
+
+// Extract returns the snippets of the files found by Files that match Options.Categories, along
+// with the problems found in their annotations (see ExtractFiles). When ctx is done before the
+// walk is over, the snippets and issues found so far are returned with ctx.Err().
+func (e *Extractor) Extract(ctx context.Context) ([]Snippet, []Issue, error) {
+	return e.collect(ctx, e.walk)
+}
+
+// ExtractFiles returns the snippets of files that match Options.Categories, along with the problems
+// found in their annotations; expired snippets are reported, and dropped when
+// Options.ExcludeExpired is set. Files that can't be scanned are skipped and reported in the
+// error as a *FileError each, next to the results of the others. When ctx is done before every
+// file is scanned, the snippets and issues found so far are returned with ctx.Err().
+func (e *Extractor) ExtractFiles(ctx context.Context, files []string) ([]Snippet, []Issue, error) {
+	return e.collect(ctx, sendFiles(files))
+}
+
+// collect returns the snippets and issues of the files produce sends, in order, along with the
+// files that couldn't be scanned, like ExtractFiles.
+func (e *Extractor) collect(ctx context.Context, produce func(ctx context.Context, send func(path string) error) error) ([]Snippet, []Issue, error) {
+	var results []Snippet
+	var issues []Issue
+	var errs []error
+
+	err := e.pipeline(ctx, produce, func(r fileResult) error {
+		issues = append(issues, r.issues...)
+		if r.err != nil {
+			errs = append(errs, r.err)
+			return nil
+		}
+		snips, err := Chain(e.opts.Processors).Apply(r.snips)
+		results = append(results, snips...)
+		return err
+	})
+	if err != nil {
+		return results, issues, err
+	}
+	return results, issues, errors.Join(errs...)
+}
+
+// ErrStop can be returned by the function given to Each to stop early without an error.
+var ErrStop = errors.New("stop")
+
+// Each walks Options.Dir like Extract, but calls fn with the matching snippets of each file as soon
+// as it and the files before it are scanned instead of collecting them. It stops at the first
+// error fn returns and returns it, unless it is ErrStop, and when ctx is done, returning ctx.Err().
+// Problems found in annotations are passed to Options.OnIssue. Files that can't be scanned are
+// skipped and reported in the error once the walk is over. fn and Options.OnIssue are never
+// called concurrently.
+func (e *Extractor) Each(ctx context.Context, fn func(Snippet) error) error {
+	return e.each(ctx, e.walk, fn)
+}
+
+// EachFiles calls fn with the matching snippets of files like Each does with those of Options.Dir.
+func (e *Extractor) EachFiles(ctx context.Context, files []string, fn func(Snippet) error) error {
+	return e.each(ctx, sendFiles(files), fn)
+}
+
+// each calls fn with the snippets of the files produce sends, like Each.
+func (e *Extractor) each(ctx context.Context, produce func(ctx context.Context, send func(path string) error) error, fn func(Snippet) error) error {
+	var errs []error
+	err := e.pipeline(ctx, produce, func(r fileResult) error {
+		if e.opts.OnIssue != nil {
+			for _, i := range r.issues {
+				e.opts.OnIssue(i)
+			}
+		}
+		if r.err != nil {
+			errs = append(errs, r.err)
+			return nil
+		}
+		snips, err := Chain(e.opts.Processors).Apply(r.snips)
+		if err != nil {
+			return err
+		}
+		for _, s := range snips {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// extractFile returns the snippets of filePath that match Options.Categories, passing the problems
+// found in its annotations to report when it isn't nil.
+func (e *Extractor) extractFile(ctx context.Context, filePath string, report func(Issue)) ([]Snippet, error) {
+	if report == nil {
+		report = func(Issue) {}
+	}
+	snips, issues, err := e.ScanFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	virtual, virtualIssues := e.VirtualSnippets(ctx, filePath)
+	sidecar, sidecarIssues := e.SidecarSnippets(filePath)
+	snips = mergeByLine(snips, append(virtual, sidecar...))
+	for _, i := range append(append(issues, virtualIssues...), sidecarIssues...) {
+		report(i)
+	}
+
+	var results []Snippet
+	for _, s := range snips {
+		if !s.Matches(e.opts.Categories) {
+			continue
+		}
+		if i, expired := s.ExpiryIssue(e.opts.Now()); expired {
+			report(i)
+			if e.opts.ExcludeExpired {
+				continue
+			}
+		}
+		if i, stale := s.HashIssue(); stale {
+			report(i)
+		}
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+// Files walks Options.Dir and returns the files a plugin handles whose name matches Options.Pattern.
+// When ctx is done before the walk is over, the files found so far are returned with ctx.Err().
+func (e *Extractor) Files(ctx context.Context) ([]string, error) {
+	var files []string
+	err := e.walk(ctx, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// walk calls visit with each file below Options.Dir, or inside it if it is an archive, that a plugin handles and whose name matches
+// Options.Pattern, stopping at the first error visit returns or when ctx is done.
+func (e *Extractor) walk(ctx context.Context, visit func(path string) error) error {
+	dir := e.opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	if a, name, ok := mountedTree(dir); ok && name == "." {
+		return e.walkTree(ctx, dir, a, visit)
+	}
+	if info, err := os.Stat(dir); err == nil && info.Mode().IsRegular() && isArchive(dir) {
+		a, err := openArchive(dir)
+		if err != nil {
+			return err
+		}
+		return e.walkTree(ctx, dir, a, visit)
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Skip directories, and what is below ignored ones
+		if info.IsDir() {
+			if path != dir && e.ignored(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Check if a plugin handles the file
+		if _, _, ok := e.Plugin(path); !ok {
+			return nil
+		}
+
+		// If pattern is provided, check if file matches pattern
+		if matched, err := e.matchesPattern(dir, path); err != nil || !matched {
+			return err
+		}
+
+		return visit(path)
+	})
+}
+
+// walkTree calls visit like walk with each file of a, named after root (e.g. vendor.zip/src/app.py).
+func (e *Extractor) walkTree(ctx context.Context, root string, a *archive, visit func(path string) error) error {
+	for _, name := range a.names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.ignoredPath(name) {
+			continue
+		}
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if _, _, ok := e.Plugin(path); !ok {
+			continue
+		}
+		if matched, err := e.matchesPattern(root, path); err != nil || !matched {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := visit(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignored reports whether the directory called name is skipped (see Options.Ignore).
+func (e *Extractor) ignored(name string) bool {
+	for _, pattern := range e.opts.Ignore {
+		if matched, _ := MatchPath(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredPath reports whether the file at the slash-separated path name, relative to the root of
+// a tree, is in a skipped directory.
+func (e *Extractor) ignoredPath(name string) bool {
+	dirs := strings.Split(name, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if e.ignored(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether path, below root, matches Options.Pattern (see MatchPath).
+func (e *Extractor) matchesPattern(root, path string) (bool, error) {
+	if e.opts.Pattern == "" || e.opts.Pattern == "*" {
+		return true, nil
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		rel = filepath.Base(path)
+	}
+	for _, pattern := range expandBraces(slashPattern(e.opts.Pattern)) {
+		if matched, err := MatchPath(pattern, rel); err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// expandBraces returns the patterns the brace sets of pattern stand for, in order: "*.{py,ts}" is
+// "*.py" and "*.ts". Sets nest, and braces without a comma or a closing brace, or escaped with a
+// backslash, are left as they are.
+func expandBraces(pattern string) []string {
+	for start := 0; start < len(pattern); start++ {
+		if pattern[start] == '\\' {
+			start++
+			continue
+		}
+		if pattern[start] != '{' {
+			continue
+		}
+		end, depth := -1, 0
+		var commas []int
+		for i := start; i < len(pattern) && end < 0; i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i
+				}
+			case ',':
+				if depth == 1 {
+					commas = append(commas, i)
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		if len(commas) == 0 {
+			continue
+		}
+		var expanded []string
+		bounds := append(append([]int{start}, commas...), end)
+		for k := 0; k+1 < len(bounds); k++ {
+			alternative := pattern[bounds[k]+1 : bounds[k+1]]
+			expanded = append(expanded, expandBraces(pattern[:start]+alternative+pattern[end+1:])...)
+		}
+		return expanded
+	}
+	return []string{pattern}
+}
+
+// Plugin returns the plugin handling filePath, chosen by its name (e.g. Dockerfile), its extension
+// or, for files without one, by the interpreter named in their shebang line, and describes how it
+// was chosen. With Options.Fallback, files no plugin handles get their comment prefix guessed from
+// their tags.
+func (e *Extractor) Plugin(filePath string) (plugins.Plugin, string, bool) {
+	if plugin, how, ok := pluginByName(filePath); ok {
+		return plugin, how, true
+	}
+	if filepath.Ext(filePath) == "" {
+		if plugin, shebang, ok := shebangPlugin(filePath); ok {
+			return plugin, fmt.Sprintf("shebang %s", shebang), true
+		}
+	}
+
+	if e.opts.Fallback {
+		if plugin, ok := guessPlugin(filePath); ok {
+			return plugin, fmt.Sprintf("guessing %s comments", plugin.Prefix), true
+		}
+	}
+	return nil, "", false
+}
+
+// pluginByName returns the plugin handling filePath by its name or its extension, without reading
+// it, and describes how it was chosen.
+func pluginByName(filePath string) (plugins.Plugin, string, bool) {
+	if plugin, ok := plugins.GetByFilename(filepath.Base(filePath)); ok {
+		return plugin, fmt.Sprintf("file name %s", filepath.Base(filePath)), true
+	}
+	if ext := filepath.Ext(filePath); ext != "" {
+		if plugin, ok := plugins.Get(ext); ok {
+			return plugin, fmt.Sprintf("extension %s", ext), true
+		}
+	}
+	return nil, "", false
+}
+
+// shebangPlugin returns the plugin for the interpreter named in the first line of filePath.
+func shebangPlugin(filePath string) (plugins.Plugin, string, bool) {
+	f, err := openFile(filePath)
+	if err != nil {
+		return nil, "", false
+	}
+	defer f.Close()
+	// Shebang lines are short; don't read all of a large file without newlines.
+	firstLine, _ := bufio.NewReader(io.LimitReader(f, 512)).ReadString('\n')
+	firstLine = strings.TrimSpace(firstLine)
+	plugin, ok := plugins.GetByShebang(firstLine)
+	return plugin, firstLine, ok
+}
+
+// maxGuessSize is the size above which the fallback doesn't read a file to guess its comments.
+const maxGuessSize = 1 << 20
+
+// guessPlugin returns a fallback plugin for filePath if its content holds tags behind a common
+// comment prefix.
+func guessPlugin(filePath string) (*plugins.HeuristicPlugin, bool) {
+	f, err := openFile(filePath)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	content, err := io.ReadAll(io.LimitReader(f, maxGuessSize+1))
+	if err != nil || len(content) > maxGuessSize {
+		return nil, false
+	}
+	return plugins.NewHeuristic(content, filepath.Ext(filePath))
+}
@@ -0,0 +1,148 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractorExtract(t *testing.T) {
+	tempDir := t.TempDir()
+
+	pyContent := `# >: {"foundation": ["messages"]}
+def send(message):
+    pass
+# <: {"foundation": ["messages"]}
+
+# >: {"tests": ["messages"], "_expires": "2025-06-01"}
+def test_send():
+    pass
+# <: {"tests": ["messages"]}`
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "messages.py"), []byte(pyContent), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("# >: {\"tests\": []}\n"), 0644))
+
+	extractor := New(Options{
+		Dir:        tempDir,
+		Categories: ParseCategories("messages:foundation,tests"),
+		Now:        func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local) },
+	})
+	snips, issues, err := extractor.Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 2)
+	assert.Equal(t, []string{"def send(message):", "    pass"}, snips[0].Content)
+	assert.Equal(t, "python", snips[0].Language())
+	assert.Len(t, issues, 1)
+	assert.Contains(t, issues[0].String(), "snippet expired on 2025-06-01")
+
+	extractor = New(Options{
+		Dir:            tempDir,
+		Pattern:        "*.py",
+		Categories:     ParseCategories("foundation"),
+		ExcludeExpired: true,
+	})
+	snips, _, err = extractor.Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, map[string][]string{"foundation": {"messages"}}, snips[0].Categories)
+}
+
+func TestExtractorExtractFilesReportsUnreadableFiles(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.py")
+
+	snips, issues, err := New(Options{}).ExtractFiles(context.Background(), []string{missing})
+	assert.Empty(t, snips)
+	assert.Empty(t, issues)
+	assert.ErrorContains(t, err, "missing.py")
+}
+
+func TestExtractorEach(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.py", "b.py", "c.py"} {
+		content := "# >: {\"tests\": [\"" + name + "\"]}\npass\n# <: {\"tests\": []}\n# >: {\"tests\": []}\n"
+		assert.Nil(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	var issues []Issue
+	extractor := New(Options{Dir: tempDir, OnIssue: func(i Issue) { issues = append(issues, i) }})
+
+	var seen []string
+	err := extractor.Each(context.Background(), func(s Snippet) error {
+		seen = append(seen, s.Categories["tests"][0])
+		if len(seen) == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.py", "b.py"}, seen)
+	assert.Len(t, issues, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = extractor.Each(ctx, func(s Snippet) error {
+		t.Fatal("no snippet expected once the context is canceled")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	snips, _, err := extractor.Extract(ctx)
+	assert.Empty(t, snips)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExtractorFilesIgnore(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "build")
+	for _, name := range []string{"app.py", "node_modules/pkg/index.py", "src/gen.egg-info/x.py", "src/vendor/lib.py", "src/main.py"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte("pass\n"), 0644))
+	}
+
+	// The root is scanned even though it is called like an ignored directory.
+	files, err := New(Options{Dir: root, Ignore: append(DefaultIgnores, "*.egg-info")}).Files(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "app.py"), filepath.Join(root, "src", "main.py")}, files)
+
+	files, err = New(Options{Dir: root}).Files(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, files, 5)
+
+	archivePath := filepath.Join(t.TempDir(), "src.zip")
+	writeZip(t, archivePath, map[string]string{"vendor/lib.py": "pass\n", "app/vendor.py": "pass\n"})
+	files, err = New(Options{Dir: archivePath, Ignore: DefaultIgnores}).Files(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(archivePath, "app", "vendor.py")}, files)
+}
+
+func TestExtractorFilesBracePattern(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"app.py", "app.ts", "view.tsx", "main.rs", "notes.md"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(root, name), []byte("pass\n"), 0644))
+	}
+
+	files, err := New(Options{Dir: root, Pattern: "*.{py,ts,tsx}"}).Files(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "app.py"), filepath.Join(root, "app.ts"), filepath.Join(root, "view.tsx")}, files)
+
+	_, err = New(Options{Dir: root, Pattern: "{app,[}.py"}).Files(context.Background())
+	assert.ErrorIs(t, err, filepath.ErrBadPattern)
+}
+
+func TestExpandBraces(t *testing.T) {
+	for pattern, expected := range map[string][]string{
+		"*.py":                {"*.py"},
+		"*.{py,ts,tsx}":       {"*.py", "*.ts", "*.tsx"},
+		"{app,lib}_*.{py,rb}": {"app_*.py", "app_*.rb", "lib_*.py", "lib_*.rb"},
+		"*.{j{s,sx},ts}":      {"*.js", "*.jsx", "*.ts"},
+		"*.{py,}":             {"*.py", "*."},
+		"{py}.*":              {"{py}.*"},
+		"*.{py,ts":            {"*.{py,ts"},
+		`\{a,b}.py`:           {`\{a,b}.py`},
+	} {
+		assert.Equal(t, expected, expandBraces(pattern), pattern)
+	}
+}
@@ -0,0 +1,234 @@
+package brio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// Definitions indexes the functions and types defined in a set of files by name, for Closure. Each
+// definition is a Snippet without categories whose lines run from its first line (decorators
+// included) to its last, and whose "definition" attribute is its name.
+//
+// Definitions are found line by line with the regular expressions of the plugins, not by parsing
+// the files: tree-sitter grammars would need cgo, which brio builds without. Languages whose plugin
+// has no patterns, such as the languages declared in the config without definitions, have none.
+type Definitions map[string][]Snippet
+
+// identifierPattern matches the identifiers of most languages.
+var identifierPattern = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// Definitions returns the definitions of files whose plugin is a plugins.DefinitionPlugin. Files
+// that can't be read are left out, as extracting their snippets reports them. When ctx is done, the
+// definitions found so far are returned with ctx.Err().
+func (e *Extractor) Definitions(ctx context.Context, files []string) (Definitions, error) {
+	defs := make(Definitions)
+	patterns := make(map[plugins.Plugin][]*regexp.Regexp)
+	for _, filePath := range files {
+		if err := ctx.Err(); err != nil {
+			return defs, err
+		}
+		plugin, _, ok := e.Plugin(filePath)
+		if !ok {
+			continue
+		}
+		dp, ok := plugin.(plugins.DefinitionPlugin)
+		if !ok || len(dp.GetDefinitionPatterns()) == 0 {
+			continue
+		}
+		if _, compiled := patterns[plugin]; !compiled {
+			for _, p := range dp.GetDefinitionPatterns() {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return defs, fmt.Errorf("definition pattern of %s: %w", plugin.GetName(), err)
+				}
+				patterns[plugin] = append(patterns[plugin], re)
+			}
+		}
+		content, err := ReadFile(filePath)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+		for i, line := range lines {
+			name := definedName(line, patterns[plugin])
+			if name == "" {
+				continue
+			}
+			start, end := definitionLines(lines, i, dp.IndentedBlocks())
+			defs[name] = append(defs[name], Snippet{
+				File:      filePath,
+				StartLine: start + 1,
+				EndLine:   end + 1,
+				Attrs:     map[string][]string{"definition": {name}},
+				Content:   lines[start : end+1],
+				Plugin:    plugin,
+			})
+		}
+	}
+	return defs, nil
+}
+
+// definedName returns the name line defines according to patterns, or "".
+func definedName(line string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(line); len(m) > 1 {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// definitionLines returns the indexes of the first and last lines of the definition starting on
+// line i of lines: with indented blocks, up to the last line indented deeper than it, decorators
+// (@name) above it included; otherwise, up to the line closing its braces, or its own line if it
+// opens none (e.g. a declaration ending with ";").
+func definitionLines(lines []string, i int, indented bool) (int, int) {
+	if indented {
+		start := i
+		for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "@") {
+			start--
+		}
+		indent := indentWidth(lines[i])
+		end := i
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+			if indentWidth(lines[j]) <= indent {
+				break
+			}
+			end = j
+		}
+		return start, end
+	}
+
+	depth, opened := 0, false
+	for j := i; j < len(lines); j++ {
+		line := strings.TrimSpace(lines[j])
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if strings.Contains(line, "{") {
+			opened = true
+		}
+		if opened && depth <= 0 {
+			return i, j
+		}
+		if !opened && !continuesSignature(line) && !nextOpensBrace(lines, j) {
+			return i, j
+		}
+	}
+	return i, len(lines) - 1
+}
+
+// continuesSignature reports whether the signature on line goes on on the next one.
+func continuesSignature(line string) bool {
+	for _, suffix := range []string{"(", ",", "=", "=>", "->", ":", "<"} {
+		if strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpensBrace reports whether the line after line j starts with the brace opening the body of a
+// definition, as when braces go on a line of their own.
+func nextOpensBrace(lines []string, j int) bool {
+	return j+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j+1]), "{")
+}
+
+// indentWidth returns the width of the indentation of line, tabs counting as one column.
+func indentWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// Closure returns the definitions snips refer to, then those these definitions refer to, and so on
+// up to depth levels, nearest first. A name refers to the definition of that name in the same file
+// or, failing that, to the only definition of that name in the same language; ambiguous names are
+// left out. Definitions overlapping snips or another definition returned are left out too.
+func (d Definitions) Closure(snips []Snippet, depth int) []Snippet {
+	var results []Snippet
+	overlaps := func(def Snippet, others []Snippet) bool {
+		for _, s := range others {
+			if s.File == def.File && s.Section == "" && def.StartLine <= s.EndLine && s.StartLine <= def.EndLine {
+				return true
+			}
+		}
+		return false
+	}
+	frontier := snips
+	for range depth {
+		var next []Snippet
+		for _, s := range frontier {
+			seen := make(map[string]bool)
+			for _, line := range s.Content {
+				for _, name := range identifierPattern.FindAllString(line, -1) {
+					if seen[name] {
+						continue
+					}
+					seen[name] = true
+					def, ok := d.resolve(name, s)
+					if !ok || overlaps(def, snips) || overlaps(def, results) {
+						continue
+					}
+					results = append(results, def)
+					next = append(next, def)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		frontier = next
+	}
+	return results
+}
+
+// resolve returns the definition name refers to in s.
+func (d Definitions) resolve(name string, s Snippet) (Snippet, bool) {
+	var candidates []Snippet
+	for _, def := range d[name] {
+		if def.File == s.File {
+			return def, true
+		}
+		if def.Language() == s.Language() {
+			candidates = append(candidates, def)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return Snippet{}, false
+}
+
+// Lookup returns the definitions of symbol, a name optionally qualified by the names of the
+// definitions enclosing it in the same file, e.g. "UserService.create_user".
+func (d Definitions) Lookup(symbol string) []Snippet {
+	names := strings.Split(symbol, ".")
+	var results []Snippet
+	for _, def := range d[names[len(names)-1]] {
+		if d.enclosed(def, names[:len(names)-1]) {
+			results = append(results, def)
+		}
+	}
+	return results
+}
+
+// enclosed reports whether def is within a definition of each of outer, in its file.
+func (d Definitions) enclosed(def Snippet, outer []string) bool {
+	for _, name := range outer {
+		found := false
+		for _, o := range d[name] {
+			if o.File == def.File && o.StartLine <= def.StartLine && def.EndLine <= o.EndLine && o.StartLine != def.StartLine {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionsClosure(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"billing.py": `from app.money import round_cents
+
+# >: {"foundation": ["billing"]}
+def total(lines):
+    return round_cents(sum(line_amount(l) for l in lines))
+# <: {"foundation": ["billing"]}
+
+@cached
+def line_amount(line):
+    return line.price * line.quantity
+
+def save(invoice):
+    pass
+`,
+		"money.py": `def round_cents(amount):
+    return round(amount, 2)
+
+def save(amount):
+    pass
+`,
+		"cart.ts": `export function cartTotal(items: Item[]): number {
+  return items.reduce((sum, item) => sum + price(item), 0);
+}
+
+export const price = (item: Item) =>
+  item.unitPrice * item.count;
+
+export interface Item {
+  unitPrice: number;
+  count: number;
+}
+`,
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		paths = append(paths, path)
+	}
+
+	defs, err := New(Options{}).Definitions(context.Background(), paths)
+	require.NoError(t, err)
+	require.Len(t, defs["save"], 2)
+	require.Len(t, defs["line_amount"], 1)
+	assert.Equal(t, 8, defs["line_amount"][0].StartLine, "decorators are part of the definition")
+	assert.Equal(t, []string{"@cached", "def line_amount(line):", "    return line.price * line.quantity"}, defs["line_amount"][0].Content)
+	assert.Equal(t, []string{"export const price = (item: Item) =>", "  item.unitPrice * item.count;"}, defs["price"][0].Content)
+	assert.Equal(t, 8, defs["Item"][0].StartLine)
+	assert.Equal(t, 11, defs["Item"][0].EndLine)
+
+	billing := filepath.Join(tempDir, "billing.py")
+	python, _ := plugins.Get(".py")
+	snips := []Snippet{{File: billing, StartLine: 3, EndLine: 6, Plugin: python, Content: []string{
+		"def total(lines):",
+		"    return round_cents(sum(line_amount(l) for l in lines))",
+	}}}
+	var names []string
+	for _, def := range defs.Closure(snips, 2) {
+		names = append(names, def.Attr("definition"))
+	}
+	assert.Equal(t, []string{"round_cents", "line_amount"}, names, "total is the snippet itself")
+
+	snips = []Snippet{defs["cartTotal"][0]}
+	names = nil
+	for _, def := range defs.Closure(snips, 1) {
+		names = append(names, def.Attr("definition"))
+	}
+	assert.Equal(t, []string{"Item", "price"}, names)
+	names = nil
+	for _, def := range defs.Closure(snips, 0) {
+		names = append(names, def.Attr("definition"))
+	}
+	assert.Empty(t, names)
+}
+
+func TestDefinitionsLookup(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `class UserService:
+    def create_user(self, name):
+        return User(name)
+
+class AdminService:
+    def create_user(self, name):
+        return Admin(name)
+
+def create_user(name):
+    pass
+`
+	filePath := filepath.Join(tempDir, "users.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	defs, err := New(Options{}).Definitions(context.Background(), []string{filePath})
+	require.NoError(t, err)
+
+	found := defs.Lookup("UserService.create_user")
+	require.Len(t, found, 1)
+	assert.Equal(t, 2, found[0].StartLine)
+	assert.Equal(t, 3, found[0].EndLine)
+	assert.Len(t, defs.Lookup("create_user"), 3)
+	assert.Len(t, defs.Lookup("UserService"), 1)
+	assert.Empty(t, defs.Lookup("BillingService.create_user"))
+}
@@ -0,0 +1,105 @@
+package brio
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// CustomMarker is a pair of regular expressions matching the start and end markers of an existing
+// snippet convention, such as the "# --8<-- [start:name]" and "# --8<-- [end:name]" sections of
+// mkdocs, so that they are extracted without rewriting them as tags. Start must have a named group
+// "name", which becomes the category of the snippet as for regions, or "payload", parsed as a tag
+// payload, or both. When End has a "name" group, it closes the snippet of that name, so snippets
+// may overlap; otherwise it closes the last one opened. Markers are searched anywhere in a line,
+// comment prefix included, and their lines are left out of snippets.
+type CustomMarker struct {
+	Start *regexp.Regexp
+	End   *regexp.Regexp
+}
+
+// Validate reports a marker whose start has neither a "name" nor a "payload" group.
+func (m CustomMarker) Validate() error {
+	if m.Start == nil || m.End == nil {
+		return fmt.Errorf("a start and an end pattern are required")
+	}
+	if m.Start.SubexpIndex("name") < 0 && m.Start.SubexpIndex("payload") < 0 {
+		return fmt.Errorf("start pattern %q needs a (?P<name>...) or (?P<payload>...) group", m.Start)
+	}
+	return nil
+}
+
+// customParser finds the snippets delimited by CustomMarkers in the lines fed to it.
+type customParser struct {
+	markers []CustomMarker
+	// parseTag parses the payload of a start marker.
+	parseTag func(payload string) (map[string][]string, error)
+	open     []*customSnippet // innermost last
+}
+
+// customSnippet is a snippet opened by the start of a custom marker.
+type customSnippet struct {
+	marker int
+	name   string
+	data   *snippetData
+}
+
+func newCustomParser(markers []CustomMarker, parseTag func(string) (map[string][]string, error)) *customParser {
+	return &customParser{markers: markers, parseTag: parseTag}
+}
+
+// feed processes one line and returns the snippets it closes. Problems with the markers are passed
+// to report. Lines that are not markers are added to every open snippet.
+func (c *customParser) feed(line string, lineNum int, report func(code string, line int, format string, args ...interface{})) []*snippetData {
+	for i, marker := range c.markers {
+		if m := marker.Start.FindStringSubmatch(line); m != nil {
+			categories := make(map[string][]string)
+			if j := marker.Start.SubexpIndex("payload"); j >= 0 && m[j] != "" {
+				data, err := c.parseTag(m[j])
+				if err != nil {
+					report(CodeInvalidTag, lineNum, "ignoring invalid tag: %v", err)
+					return nil
+				}
+				categories = data
+			}
+			name := ""
+			if j := marker.Start.SubexpIndex("name"); j >= 0 {
+				name = m[j]
+				if name != "" {
+					categories[name] = []string{}
+				}
+			}
+			c.open = append(c.open, &customSnippet{marker: i, name: name, data: &snippetData{
+				categories: categories,
+				startLine:  lineNum,
+				lines:      []string{},
+			}})
+			return nil
+		}
+		if m := marker.End.FindStringSubmatch(line); m != nil {
+			name, named := "", false
+			if j := marker.End.SubexpIndex("name"); j >= 0 {
+				name, named = m[j], true
+			}
+			for k := len(c.open) - 1; k >= 0; k-- {
+				if s := c.open[k]; s.marker == i && (!named || s.name == name) {
+					c.open = append(c.open[:k], c.open[k+1:]...)
+					return []*snippetData{s.data}
+				}
+			}
+			report(CodeUnmatchedTag, lineNum, "end marker without a matching start marker")
+			return nil
+		}
+	}
+	for _, s := range c.open {
+		s.data.lines = append(s.data.lines, line)
+	}
+	return nil
+}
+
+// finish reports the snippets still open at the end of the file, which are dropped.
+func (c *customParser) finish(report func(code string, line int, format string, args ...interface{})) {
+	for _, s := range c.open {
+		report(CodeUnmatchedTag, s.data.startLine, "start marker is never closed")
+	}
+	c.open = nil
+}
@@ -0,0 +1,62 @@
+package brio
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanFileCustomMarkers(t *testing.T) {
+	tempDir := t.TempDir()
+	content := `# --8<-- [start:setup]
+import os
+# --8<-- [start:config]
+CONFIG = os.environ["CONFIG"]
+# --8<-- [end:setup]
+DEBUG = False
+# --8<-- [end:config]
+# @snippet {"tests": ["config"]}
+def test_config():
+    pass
+# @end
+# --8<-- [end:nothing]
+# --8<-- [start:open]
+`
+	filePath := filepath.Join(tempDir, "settings.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	extractor := New(Options{CustomMarkers: []CustomMarker{
+		{Start: regexp.MustCompile(`--8<-- \[start:(?P<name>[\w.-]+)\]`), End: regexp.MustCompile(`--8<-- \[end:(?P<name>[\w.-]+)\]`)},
+		{Start: regexp.MustCompile(`@snippet (?P<payload>\{.*\})`), End: regexp.MustCompile(`@end\b`)},
+	}})
+	snips, issues, err := extractor.ScanFile(filePath)
+	require.NoError(t, err)
+	require.Len(t, snips, 3)
+	assert.Equal(t, map[string][]string{"setup": {}}, snips[0].Categories)
+	assert.Equal(t, []string{"import os", `CONFIG = os.environ["CONFIG"]`}, snips[0].Content, "overlapping sections close by name")
+	assert.Equal(t, 1, snips[0].StartLine)
+	assert.Equal(t, 5, snips[0].EndLine)
+	assert.Equal(t, []string{`CONFIG = os.environ["CONFIG"]`, "DEBUG = False"}, snips[1].Content)
+	assert.Equal(t, map[string][]string{"tests": {"config"}}, snips[2].Categories)
+	assert.Equal(t, []string{"def test_config():", "    pass"}, snips[2].Content)
+
+	var messages []string
+	for _, i := range issues {
+		messages = append(messages, i.String())
+	}
+	assert.Equal(t, []string{
+		filePath + ":12: BRIO001 end marker without a matching start marker",
+		filePath + ":13: BRIO001 start marker is never closed",
+	}, messages)
+}
+
+func TestCustomMarkerValidate(t *testing.T) {
+	assert.NoError(t, CustomMarker{Start: regexp.MustCompile(`(?P<name>\w+)`), End: regexp.MustCompile(`end`)}.Validate())
+	assert.EqualError(t, CustomMarker{Start: regexp.MustCompile(`start`), End: regexp.MustCompile(`end`)}.Validate(),
+		`start pattern "start" needs a (?P<name>...) or (?P<payload>...) group`)
+	assert.Error(t, CustomMarker{Start: regexp.MustCompile(`(?P<name>\w+)`)}.Validate())
+}
@@ -0,0 +1,103 @@
+package brio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsStore writes objects to a Google Cloud Storage bucket, or to the emulator at
+// STORAGE_EMULATOR_HOST.
+type gcsStore struct {
+	bucket string
+	client *http.Client
+	// tokens is set up on the first upload.
+	tokens oauth2.TokenSource
+}
+
+// gcsScope is the OAuth scope of the tokens gcsStore asks for.
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+func (s *gcsStore) put(ctx context.Context, key string, body []byte, contentType string) error {
+	endpoint := "https://storage.googleapis.com"
+	emulator := os.Getenv("STORAGE_EMULATOR_HOST")
+	if emulator != "" {
+		endpoint = emulator
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	} else if s.tokens == nil {
+		tokens, err := googleTokens(ctx, s.client)
+		if err != nil {
+			return err
+		}
+		s.tokens = tokens
+	}
+
+	target := strings.TrimSuffix(endpoint, "/") + "/upload/storage/v1/b/" + url.PathEscape(s.bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.tokens != nil {
+		// The token is refreshed once it is about to expire.
+		token, err := s.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get a Google access token: %v", err)
+		}
+		token.SetAuthHeader(req)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Cloud Storage answered %s%s", resp.Status, googleErrorMessage(data))
+	}
+	return nil
+}
+
+// googleErrorMessage returns the message of a Google API JSON error, as ": message", or "" if data
+// isn't one.
+func googleErrorMessage(data []byte) string {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &e) != nil || e.Error.Message == "" {
+		return ""
+	}
+	return ": " + e.Error.Message
+}
+
+// googleTokens returns the source of access tokens of the credentials Google's client libraries
+// find: GOOGLE_OAUTH_ACCESS_TOKEN, or else the Application Default Credentials, i.e. the file of
+// GOOGLE_APPLICATION_CREDENTIALS, those of gcloud auth application-default login, then the service
+// account of the metadata server on Google Cloud. Tokens are requested with client.
+func googleTokens(ctx context.Context, client *http.Client) (oauth2.TokenSource, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), nil
+	}
+	// The token source keeps ctx to refresh tokens after this upload is done.
+	ctx = context.WithValue(context.WithoutCancel(ctx), oauth2.HTTPClient, client)
+	creds, err := google.FindDefaultCredentials(ctx, gcsScope)
+	if err != nil {
+		return nil, fmt.Errorf("no usable Google credentials (%v): set GOOGLE_APPLICATION_CREDENTIALS, "+
+			"run gcloud auth application-default login, or run on Google Cloud with a service account", err)
+	}
+	return creds.TokenSource, nil
+}
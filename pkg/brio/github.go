@@ -0,0 +1,220 @@
+package brio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHubSource names a repository read through the GitHub REST API instead of a clone.
+type GitHubSource struct {
+	// Repo is the repository, as owner/name.
+	Repo string
+	// Ref is the branch, tag or commit to read; "" means the default branch.
+	Ref string
+	// Token authenticates the requests; without one, GitHub allows 60 requests an hour.
+	Token string
+	// APIURL is the root of the API; "" means https://api.github.com.
+	APIURL string
+	// Client sends the requests; nil means http.DefaultClient.
+	Client *http.Client
+	// Extra lists files to fetch as well, whatever their name, if the repository has them, such as
+	// the CODEOWNERS files.
+	Extra []string
+}
+
+// maxRateLimitRetries is how many times a request is sent again after hitting a rate limit.
+const maxRateLimitRetries = 3
+
+// FetchGitHub reads the files of src that a plugin handles and whose name matches Options.Pattern,
+// outside of the directories of Options.Ignore, and those of src.Extra, through the GitHub API,
+// without cloning it, and returns the root they are named under, such as github.com/org/repo@main,
+// to be used as Options.Dir. Only the matching files are downloaded, one request each; those whose
+// plugin can't be told by their name, such as scripts without an extension, are downloaded to be
+// told by their content, and dropped if no plugin handles them. When the rate limit is hit, it
+// waits for it to reset or for ctx to be done. The files stay in memory until the program exits.
+func (e *Extractor) FetchGitHub(ctx context.Context, src GitHubSource) (string, error) {
+	if src.APIURL == "" {
+		src.APIURL = "https://api.github.com"
+	}
+	if src.Client == nil {
+		src.Client = http.DefaultClient
+	}
+	owner, name, ok := strings.Cut(src.Repo, "/")
+	if !ok || owner == "" || name == "" {
+		return "", fmt.Errorf("invalid GitHub repository %q, expected owner/name", src.Repo)
+	}
+	repoURL := strings.TrimSuffix(src.APIURL, "/") + "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+
+	if src.Ref == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := src.getJSON(ctx, repoURL, &repo); err != nil {
+			return "", err
+		}
+		src.Ref = repo.DefaultBranch
+	}
+
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := src.getJSON(ctx, repoURL+"/git/trees/"+escapePath(src.Ref)+"?recursive=1", &tree); err != nil {
+		return "", err
+	}
+	if tree.Truncated {
+		return "", fmt.Errorf("%s@%s has too many files for the GitHub API, clone it instead", src.Repo, src.Ref)
+	}
+
+	root := filepath.Join("github.com", owner, name) + "@" + src.Ref
+	a := &archive{files: make(map[string][]byte)}
+	// unnamed are the files fetched to tell their plugin by their content.
+	var unnamed []string
+	for _, entry := range tree.Tree {
+		if entry.Type != "blob" {
+			continue
+		}
+		extra := slices.Contains(src.Extra, entry.Path)
+		if !extra {
+			if e.ignoredPath(entry.Path) {
+				continue
+			}
+			filePath := filepath.Join(root, filepath.FromSlash(entry.Path))
+			_, _, named := pluginByName(filePath)
+			if !named && filepath.Ext(filePath) != "" && !e.opts.Fallback {
+				continue
+			}
+			if matched, err := e.matchesPattern(root, filePath); err != nil || !matched {
+				if err != nil {
+					return "", err
+				}
+				continue
+			}
+			if !named {
+				unnamed = append(unnamed, entry.Path)
+			}
+		}
+		content, err := src.get(ctx, repoURL+"/git/blobs/"+entry.SHA, "application/vnd.github.raw+json")
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %v", path.Join(src.Repo, entry.Path), err)
+		}
+		a.add(entry.Path, content)
+	}
+	sort.Strings(a.names)
+	mount(root, a)
+
+	// Once mounted, the content of the files tells their plugin, if any.
+	for _, name := range unnamed {
+		if _, _, ok := e.Plugin(filepath.Join(root, filepath.FromSlash(name))); !ok {
+			a.remove(name)
+		}
+	}
+	return root, nil
+}
+
+// escapePath escapes each slash-separated segment of p, such as a branch named feature/x.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// getJSON decodes the JSON response to a GET request of apiURL into v.
+func (src GitHubSource) getJSON(ctx context.Context, apiURL string, v interface{}) error {
+	body, err := src.get(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// get returns the body of the response to a GET request of apiURL, waiting for the rate limit to
+// reset and trying again when it is hit.
+func (src GitHubSource) get(ctx context.Context, apiURL, accept string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		if src.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+src.Token)
+		}
+
+		resp, err := src.Client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return body, nil
+		}
+
+		wait, limited := rateLimitWait(resp, time.Now())
+		if !limited || attempt == maxRateLimitRetries {
+			return nil, fmt.Errorf("GitHub API %s: %s", resp.Status, apiMessage(body))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitWait returns how long to wait before sending a request again, if resp reports that a
+// primary or secondary rate limit was hit.
+func rateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Unix(reset, 0).Sub(now)
+			if wait < 0 {
+				wait = 0
+			}
+			return wait + time.Second, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+// apiMessage returns the message of a GitHub API error response, or the response itself.
+func apiMessage(body []byte) string {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Message != "" {
+		return apiErr.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,128 @@
+package brio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractorFetchGitHub(t *testing.T) {
+	var fetched []string
+	limited := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"default_branch": "main"}`))
+	})
+	mux.HandleFunc("/repos/org/repo/git/trees/main", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "1", r.URL.Query().Get("recursive"))
+		w.Write([]byte(`{"tree": [
+			{"path": "src", "type": "tree", "sha": "t1"},
+			{"path": "src/vendored.py", "type": "blob", "sha": "b1"},
+			{"path": "src/app.go", "type": "blob", "sha": "b2"},
+			{"path": "notes.txt", "type": "blob", "sha": "b3"}
+		]}`))
+	})
+	mux.HandleFunc("/repos/org/repo/git/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		// The first blob request hits the rate limit, which resets right away.
+		if !limited {
+			limited = true
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()-1, 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fetched = append(fetched, r.URL.Path)
+		assert.Equal(t, "application/vnd.github.raw+json", r.Header.Get("Accept"))
+		w.Write([]byte(archivedSource))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	extractor := New(Options{Pattern: "*.py"})
+	root, err := extractor.FetchGitHub(context.Background(), GitHubSource{Repo: "org/repo", Token: "secret", APIURL: server.URL})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join("github.com", "org", "repo@main"), root)
+	assert.Equal(t, []string{"/repos/org/repo/git/blobs/b1"}, fetched)
+
+	snips, _, err := New(Options{Dir: root}).Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 1)
+	assert.Equal(t, filepath.Join(root, "src", "vendored.py"), snips[0].File)
+
+	_, err = extractor.FetchGitHub(context.Background(), GitHubSource{Repo: "org/missing", Ref: "main", APIURL: server.URL})
+	assert.ErrorContains(t, err, "404")
+	_, err = extractor.FetchGitHub(context.Background(), GitHubSource{Repo: "repo"})
+	assert.ErrorContains(t, err, "expected owner/name")
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		wait    time.Duration
+		limited bool
+	}{
+		{"not found", http.StatusNotFound, nil, 0, false},
+		{"forbidden", http.StatusForbidden, nil, 0, false},
+		{"retry after", http.StatusForbidden, map[string]string{"Retry-After": "30"}, 30 * time.Second, true},
+		{"primary limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1060"}, 61 * time.Second, true},
+		{"too many requests", http.StatusTooManyRequests, nil, time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			wait, limited := rateLimitWait(resp, now)
+			assert.Equal(t, tt.limited, limited)
+			assert.Equal(t, tt.wait, wait)
+		})
+	}
+}
+
+func TestExtractorFetchGitHubByContent(t *testing.T) {
+	blobs := map[string]string{
+		"b1": "#!/bin/bash\n# >: {\"tests\": [\"deploy\"]}\nmake deploy\n# <: {\"tests\": []}\n",
+		"b2": "Plain notes, no plugin handles them.\n",
+		"b3": "* @org/platform\n",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/git/trees/main", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tree": [
+			{"path": "bin/deploy", "type": "blob", "sha": "b1"},
+			{"path": "NOTES", "type": "blob", "sha": "b2"},
+			{"path": ".github/CODEOWNERS", "type": "blob", "sha": "b3"}
+		]}`))
+	})
+	mux.HandleFunc("/repos/org/repo/git/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(blobs[path.Base(r.URL.Path)]))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	root, err := New(Options{Pattern: "*"}).FetchGitHub(context.Background(),
+		GitHubSource{Repo: "org/repo", Ref: "main", APIURL: server.URL, Extra: []string{".github/CODEOWNERS"}})
+	assert.Nil(t, err)
+
+	content, err := ReadFile(filepath.Join(root, ".github", "CODEOWNERS"))
+	assert.Nil(t, err)
+	assert.Equal(t, blobs["b3"], string(content))
+	_, err = ReadFile(filepath.Join(root, "NOTES"))
+	assert.Error(t, err, "files no plugin handles are dropped once fetched")
+
+	snips, _, err := New(Options{Dir: root}).Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 1, "the script is told by its shebang")
+	assert.Equal(t, filepath.Join(root, "bin", "deploy"), snips[0].File)
+}
@@ -0,0 +1,551 @@
+package brio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// History tells who changed the snippets of a git working tree and when, from the blame of their
+// files, and which changed recently: since a point in time, or since a commit.
+type History struct {
+	repo *git.Repository
+	root string // the root of the working tree
+	head *object.Commit
+	// recent tells whether a line last changed by commit hash on date counts as recent.
+	recent func(hash plumbing.Hash, date time.Time) bool
+	// origins caches the origin of each line of the files, by path relative to root.
+	origins map[string][]*Origin
+	// commits caches the origin of the lines each commit changed.
+	commits map[plumbing.Hash]*Origin
+}
+
+// Origin is the commit that last changed a line.
+type Origin struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"` // name <email>
+	Date    time.Time `json:"date"`   // when it was authored
+	Summary string    `json:"summary"`
+}
+
+// ageRe matches ages in days or weeks, such as 10d or 2w.
+var ageRe = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// OpenHistory opens the history of the git repository dir is in. since is either an age (e.g. 2w,
+// 10d or 36h), a date (YYYY-MM-DD) or a revision (e.g. a commit hash, a tag or HEAD~5): lines are
+// recent when they were last modified after now minus the age, after the date, or by a commit that
+// isn't an ancestor of the revision. Lines not committed yet are always recent, and the only ones
+// when since is empty.
+func OpenHistory(dir, since string, now time.Time) (*History, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the git repository of %s: %v", dir, err)
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the git repository of %s: %v", dir, err)
+	}
+	ref, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of %s: %v", dir, err)
+	}
+	head, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of %s: %v", dir, err)
+	}
+	root, err := filepath.EvalSymlinks(tree.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+
+	h := &History{
+		repo:    repo,
+		root:    root,
+		head:    head,
+		origins: make(map[string][]*Origin),
+		commits: make(map[plumbing.Hash]*Origin),
+	}
+	if since == "" {
+		h.recent = func(plumbing.Hash, time.Time) bool { return false }
+		return h, nil
+	}
+	if cutoff, ok := parseCutoff(since, now); ok {
+		h.recent = func(_ plumbing.Hash, date time.Time) bool { return date.After(cutoff) }
+		return h, nil
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(since))
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither an age such as 2w, a date nor a revision of %s", since, root)
+	}
+	commits, err := repo.Log(&git.LogOptions{From: *hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %v", since, err)
+	}
+	ancestors := make(map[plumbing.Hash]bool)
+	err = commits.ForEach(func(c *object.Commit) error {
+		ancestors[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %v", since, err)
+	}
+	h.recent = func(hash plumbing.Hash, _ time.Time) bool { return !ancestors[hash] }
+	return h, nil
+}
+
+// parseCutoff returns the time since, an age or a date, designates, relative to now.
+func parseCutoff(since string, now time.Time) (time.Time, bool) {
+	if m := ageRe.FindStringSubmatch(since); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		days := n
+		if m[2] == "w" {
+			days *= 7
+		}
+		return now.AddDate(0, 0, -days), true
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), true
+	}
+	if date, err := time.ParseInLocation("2006-01-02", since, now.Location()); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// Changed tells whether one of the lines of s is recent.
+func (h *History) Changed(s Snippet) (bool, error) {
+	origins, err := h.Origins(s)
+	if err != nil {
+		return false, err
+	}
+	for _, o := range origins {
+		if o == nil || h.recent(plumbing.NewHash(o.Hash), o.Date) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Origins returns the origin of each line between the tags of s, nil for the lines not committed
+// yet. Snippets of a section of their file, such as a notebook cell, whose lines can't be told
+// apart, get those of their whole file.
+func (h *History) Origins(s Snippet) ([]*Origin, error) {
+	origins, err := h.fileOrigins(s.File)
+	if err != nil {
+		return nil, err
+	}
+	if s.Section != "" {
+		return origins, nil
+	}
+	first, last := s.StartLine, s.EndLine
+	if last-first > 1 {
+		// Leave out the tags.
+		first, last = first+1, last-1
+	}
+	first, last = max(first, 1), min(last, len(origins))
+	if first > last {
+		return nil, nil
+	}
+	return origins[first-1 : last], nil
+}
+
+// fileOrigins returns the origin of each line of filePath, as it is in the working tree.
+func (h *History) fileOrigins(filePath string) ([]*Origin, error) {
+	abs, rel, err := h.path(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if origins, ok := h.origins[rel]; ok {
+		return origins, nil
+	}
+
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	current := string(content)
+	// Lines stay nil until matched with a committed one.
+	origins := make([]*Origin, countLines(current))
+
+	committed, err := h.head.File(rel)
+	if errors.Is(err, object.ErrFileNotFound) {
+		h.origins[rel] = origins
+		return origins, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at HEAD: %v", rel, err)
+	}
+	original, err := committed.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at HEAD: %v", rel, err)
+	}
+	blame, err := git.Blame(h.head, rel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s: %v", rel, err)
+	}
+
+	// Match the lines of the working tree with those of HEAD, whose blame is known; lines that
+	// were added or modified since aren't committed yet.
+	line, blamed := 0, 0
+	for _, d := range diff.Do(original, current) {
+		n := countLines(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			for i := 0; i < n && line < len(origins); i++ {
+				if blamed < len(blame.Lines) {
+					if origins[line], err = h.origin(blame.Lines[blamed]); err != nil {
+						return nil, err
+					}
+				}
+				line++
+				blamed++
+			}
+		case diffmatchpatch.DiffInsert:
+			line += n
+		case diffmatchpatch.DiffDelete:
+			blamed += n
+		}
+	}
+	h.origins[rel] = origins
+	return origins, nil
+}
+
+// path returns the absolute path of filePath, and its path relative to the root of the working
+// tree, with forward slashes.
+func (h *History) path(filePath string) (abs, rel string, err error) {
+	abs, err = filepath.Abs(filePath)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	rel, err = filepath.Rel(h.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("%s is outside the git repository %s", filePath, h.root)
+	}
+	return abs, filepath.ToSlash(rel), nil
+}
+
+// origin returns the origin of a line of a blame.
+func (h *History) origin(l *git.Line) (*Origin, error) {
+	if o, ok := h.commits[l.Hash]; ok {
+		return o, nil
+	}
+	c, err := h.repo.CommitObject(l.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %v", l.Hash, err)
+	}
+	o := commitOrigin(c)
+	h.commits[l.Hash] = o
+	return o, nil
+}
+
+// commitOrigin returns the origin of the lines commit c changed.
+func commitOrigin(c *object.Commit) *Origin {
+	summary, _, _ := strings.Cut(c.Message, "\n")
+	return &Origin{
+		Hash:    c.Hash.String(),
+		Author:  fmt.Sprintf("%s <%s>", c.Author.Name, c.Author.Email),
+		Date:    c.Author.When,
+		Summary: summary,
+	}
+}
+
+// Change is a commit that changed lines of a snippet.
+type Change struct {
+	Origin
+	// MovedFrom is the path of the file in the commit, relative to the root of the repository,
+	// when it was moved since; it is empty while the file was where it is now.
+	MovedFrom string `json:"moved_from,omitempty"`
+}
+
+// Log returns the commits of the first-parent history of HEAD that changed the lines between the
+// tags of s, newest first, like git log -L: the lines are followed as others are added or removed
+// around them, and as their file is moved. The commit that added the first of them ends the log.
+// Changes not committed yet aren't part of it; see Origins. Snippets of a section of their file,
+// such as a notebook cell, get the log of their whole file.
+func (h *History) Log(ctx context.Context, s Snippet) ([]Change, error) {
+	abs, path, err := h.path(s.File)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	current := string(content)
+	first, last := s.StartLine, s.EndLine
+	if last-first > 1 {
+		// Leave out the tags.
+		first, last = first+1, last-1
+	}
+	if s.Section != "" {
+		first, last = 1, countLines(current)
+	}
+
+	// Start from the lines of HEAD, if some of them were committed.
+	commit, currentPath := h.head, path
+	file, err := commit.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at HEAD: %v", path, err)
+	}
+	var committed bool
+	if first, last, _, committed, err = mapLines(file, current, first, last); err != nil || !committed {
+		return nil, err
+	}
+
+	var changes []Change
+	for {
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+		change := Change{Origin: *commitOrigin(commit)}
+		if path != currentPath {
+			change.MovedFrom = path
+		}
+
+		parent, parentPath, err := previousVersion(ctx, commit, path)
+		if err != nil {
+			return changes, err
+		}
+		if parent == nil {
+			// The file was added by commit, along with the lines.
+			return append(changes, change), nil
+		}
+		parentFile, err := parent.File(parentPath)
+		if err != nil {
+			return changes, fmt.Errorf("failed to read %s at %s: %v", parentPath, parent.Hash, err)
+		}
+		if parentFile.Hash != file.Hash {
+			contents, err := file.Contents()
+			if err != nil {
+				return changes, fmt.Errorf("failed to read %s at %s: %v", path, commit.Hash, err)
+			}
+			var touched, kept bool
+			if first, last, touched, kept, err = mapLines(parentFile, contents, first, last); err != nil {
+				return changes, err
+			}
+			if touched || !kept {
+				changes = append(changes, change)
+			}
+			if !kept {
+				// None of the lines were there before commit.
+				return changes, nil
+			}
+		}
+		commit, path, file = parent, parentPath, parentFile
+	}
+}
+
+// Diff returns the changes of the lines between the tags of s since revision rev (e.g. main or
+// HEAD~3), uncommitted changes included: every line of either version, starting with "-" if it was
+// removed, "+" if it was added, and " " otherwise. The lines of s at rev are found by following its
+// tags, or its lines when its tags changed too. Diff returns nil when the lines didn't change, and
+// only added lines when s wasn't there. Snippets of a section of their file, such as a notebook
+// cell, can't be diffed.
+func (h *History) Diff(s Snippet, rev string) ([]string, error) {
+	if s.Section != "" {
+		return nil, fmt.Errorf("%s is in a section of its file", SectionPath(s.File, s.Section))
+	}
+	abs, path, err := h.path(s.File)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	current := string(content)
+	lines := strings.Split(strings.TrimSuffix(current, "\n"), "\n")
+	if s.StartLine < 1 || s.EndLine > len(lines) || s.EndLine <= s.StartLine {
+		return nil, fmt.Errorf("%s:%d-%d is not between tags of the file", s.File, s.StartLine, s.EndLine)
+	}
+	after := lines[s.StartLine : s.EndLine-1]
+
+	hash, err := h.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a revision of %s", rev, h.root)
+	}
+	commit, err := h.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", rev, err)
+	}
+	var before []string
+	file, err := commit.File(path)
+	switch {
+	case errors.Is(err, object.ErrFileNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s at %s: %v", path, rev, err)
+	default:
+		if before, err = linesAt(file, current, s.StartLine, s.EndLine); err != nil {
+			return nil, err
+		}
+	}
+
+	return DiffLines(before, after), nil
+}
+
+// linesAt returns the lines of previous between the tags that are on lines start and end of
+// current, or, if they aren't both there, those matching the lines between them.
+func linesAt(previous *object.File, current string, start, end int) ([]string, error) {
+	contents, err := previous.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", previous.Name, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	prevStart, _, _, startKept, err := mapLines(previous, current, start, start)
+	if err != nil {
+		return nil, err
+	}
+	prevEnd, _, _, endKept, err := mapLines(previous, current, end, end)
+	if err != nil {
+		return nil, err
+	}
+	if startKept && endKept && prevStart < prevEnd {
+		return lines[prevStart : prevEnd-1], nil
+	}
+	if end-start < 2 {
+		return nil, nil
+	}
+	first, last, _, kept, err := mapLines(previous, current, start+1, end-1)
+	if err != nil || !kept {
+		return nil, err
+	}
+	return lines[first-1 : last], nil
+}
+
+// DiffLines returns every line of before and after, starting with "-" if after removed it, "+" if it
+// added it, and " " otherwise, or nil if they are the same.
+func DiffLines(before, after []string) []string {
+	var changes []string
+	changed := false
+	for _, d := range diff.Do(joinLines(before), joinLines(after)) {
+		prefix := " "
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix, changed = "+", true
+		case diffmatchpatch.DiffDelete:
+			prefix, changed = "-", true
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				changes = append(changes, prefix+strings.TrimSuffix(line, "\n"))
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return changes
+}
+
+// joinLines returns lines, each ended by a newline.
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// previousVersion returns the first parent of commit and the path under which it holds the file at
+// path in commit, which differs when commit moved it. It returns a nil commit when commit has no
+// parent or added the file.
+func previousVersion(ctx context.Context, commit *object.Commit, path string) (*object.Commit, string, error) {
+	if commit.NumParents() == 0 {
+		return nil, "", nil
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the parent of %s: %v", commit.Hash, err)
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %v", parent.Hash, err)
+	}
+	if _, err := parentTree.File(path); err == nil {
+		return parent, path, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %v", commit.Hash, err)
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to diff %s: %v", commit.Hash, err)
+	}
+	for _, c := range changes {
+		if c.To.Name == path && c.From.Name != "" {
+			return parent, c.From.Name, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// mapLines returns the lines of previous matching the lines first to last of current, and whether
+// some of those were added, modified or removed in between (touched), or were all added (!kept).
+func mapLines(previous *object.File, current string, first, last int) (prevFirst, prevLast int, touched, kept bool, err error) {
+	contents, err := previous.Contents()
+	if err != nil {
+		return 0, 0, false, false, fmt.Errorf("failed to read %s: %v", previous.Name, err)
+	}
+	prevLine, line := 1, 1 // the next line of each version
+	for _, d := range diff.Do(contents, current) {
+		n := countLines(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			for i := 0; i < n; i++ {
+				if line >= first && line <= last {
+					if prevFirst == 0 {
+						prevFirst = prevLine
+					}
+					prevLast = prevLine
+				}
+				prevLine++
+				line++
+			}
+		case diffmatchpatch.DiffInsert:
+			if line <= last && line+n-1 >= first {
+				touched = true
+			}
+			line += n
+		case diffmatchpatch.DiffDelete:
+			// Lines removed between two of the range.
+			if line > first && line <= last {
+				touched = true
+			}
+			prevLine += n
+		}
+	}
+	return prevFirst, prevLast, touched, prevFirst != 0, nil
+}
+
+// countLines returns how many lines s holds, the last one with or without a newline.
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
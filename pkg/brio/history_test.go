@@ -0,0 +1,202 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+const historySource = `# start: {"tests": []}
+def old():
+    pass
+# end: {"tests": []}
+
+# start: {"tests": []}
+def edited():
+    pass
+# end: {"tests": []}
+
+# start: {"tests": []}
+def uncommitted():
+    pass
+# end: {"tests": []}
+`
+
+func TestHistory(t *testing.T) {
+	dir := initRepo(t, map[string]string{"app.py": historySource})
+	repo, err := git.PlainOpen(dir)
+	assert.Nil(t, err)
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	app := filepath.Join(dir, "app.py")
+	base := time.Now()
+
+	// A month later, edited() changes; then uncommitted() does, without being committed.
+	edited := replaceLine(t, historySource, "    pass", "    return 1", 1)
+	assert.Nil(t, os.WriteFile(app, []byte(edited), 0644))
+	_, err = worktree.Add("app.py")
+	assert.Nil(t, err)
+	_, err = worktree.Commit("edit", &git.CommitOptions{
+		Author: &object.Signature{Name: "brio", Email: "brio@example.com", When: base.AddDate(0, 1, 0)},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(app, []byte(replaceLine(t, edited, "    pass", "    return 2", 1)), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "new.py"), []byte(historySource), 0644))
+
+	snips, _, err := New(Options{Dir: dir}).Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 6)
+
+	changed := func(since string, now time.Time) []string {
+		h, err := OpenHistory(dir, since, now)
+		assert.Nil(t, err)
+		var names []string
+		for _, s := range snips {
+			ok, err := h.Changed(s)
+			assert.Nil(t, err)
+			if ok {
+				names = append(names, filepath.Base(s.File)+":"+s.Content[0])
+			}
+		}
+		return names
+	}
+	now := base.AddDate(0, 1, 7)
+	assert.Equal(t, []string{"app.py:def edited():", "app.py:def uncommitted():",
+		"new.py:def old():", "new.py:def edited():", "new.py:def uncommitted():"}, changed("2w", now))
+	assert.Equal(t, []string{"app.py:def uncommitted():",
+		"new.py:def old():", "new.py:def edited():", "new.py:def uncommitted():"}, changed("3d", now))
+	assert.Equal(t, []string{"app.py:def uncommitted():",
+		"new.py:def old():", "new.py:def edited():", "new.py:def uncommitted():"}, changed("HEAD", now))
+	assert.Equal(t, []string{"app.py:def edited():", "app.py:def uncommitted():",
+		"new.py:def old():", "new.py:def edited():", "new.py:def uncommitted():"}, changed("v1.0.0", now))
+	assert.Len(t, changed(base.AddDate(0, 0, -1).Format("2006-01-02"), now), 6)
+
+	// Without a window, only what isn't committed yet changed.
+	assert.Equal(t, []string{"app.py:def uncommitted():",
+		"new.py:def old():", "new.py:def edited():", "new.py:def uncommitted():"}, changed("", now))
+
+	h, err := OpenHistory(dir, "", now)
+	assert.Nil(t, err)
+	origins, err := h.Origins(snips[1])
+	assert.Nil(t, err)
+	assert.Len(t, origins, 2)
+	assert.Equal(t, "initial", origins[0].Summary)
+	assert.Equal(t, "edit", origins[1].Summary)
+	assert.Equal(t, "brio <brio@example.com>", origins[1].Author)
+	assert.Equal(t, base.AddDate(0, 1, 0).Unix(), origins[1].Date.Unix())
+	origins, err = h.Origins(snips[2])
+	assert.Nil(t, err)
+	assert.Equal(t, "initial", origins[0].Summary)
+	assert.Nil(t, origins[1], "not committed yet")
+
+	_, err = OpenHistory(dir, "yesterday", now)
+	assert.ErrorContains(t, err, `"yesterday" is neither an age such as 2w, a date nor a revision`)
+	_, err = OpenHistory(t.TempDir(), "2w", now)
+	assert.ErrorContains(t, err, "failed to open the git repository")
+}
+
+// replaceLine replaces the nth line of s (counting from 0) that is old with new.
+func replaceLine(t *testing.T, s, old, new string, n int) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != old {
+			continue
+		}
+		if n == 0 {
+			lines[i] = new
+			return strings.Join(lines, "\n")
+		}
+		n--
+	}
+	t.Fatalf("no line %q to replace", old)
+	return ""
+}
+
+func TestHistoryLog(t *testing.T) {
+	source := "import os\n\n" + historySource
+	dir := initRepo(t, map[string]string{"app.py": source})
+	repo, err := git.PlainOpen(dir)
+	assert.Nil(t, err)
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	commit := func(message string, edit func()) {
+		edit()
+		_, err := worktree.Add(".")
+		assert.Nil(t, err)
+		_, err = worktree.Commit(message, &git.CommitOptions{
+			All:    true,
+			Author: &object.Signature{Name: "brio", Email: "brio@example.com", When: time.Now()},
+		})
+		assert.Nil(t, err)
+	}
+	write := func(name, content string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	// Lines added above the snippets move them without changing them.
+	source = "#!/usr/bin/env python\n" + source
+	commit("shebang", func() { write("app.py", source) })
+	source = replaceLine(t, source, "    pass", "    return 1", 1)
+	commit("edit", func() { write("app.py", source) })
+	commit("move", func() {
+		assert.Nil(t, os.Rename(filepath.Join(dir, "app.py"), filepath.Join(dir, "main.py")))
+		_, err := worktree.Remove("app.py")
+		assert.Nil(t, err)
+	})
+	write("main.py", replaceLine(t, source, "    pass", "    return 2", 1))
+
+	snips, _, err := New(Options{Dir: dir}).Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 3)
+	h, err := OpenHistory(dir, "", time.Now())
+	assert.Nil(t, err)
+
+	log := func(s Snippet) []string {
+		changes, err := h.Log(context.Background(), s)
+		assert.Nil(t, err)
+		var entries []string
+		for _, c := range changes {
+			entries = append(entries, c.Summary+" "+c.MovedFrom)
+		}
+		return entries
+	}
+	assert.Equal(t, "main.py", filepath.Base(snips[0].File))
+	assert.Equal(t, []string{"initial app.py"}, log(snips[0]))
+	assert.Equal(t, []string{"edit app.py", "initial app.py"}, log(snips[1]))
+	// Changes not committed yet are left out.
+	assert.Equal(t, []string{"initial app.py"}, log(snips[2]))
+}
+
+func TestHistoryDiff(t *testing.T) {
+	dir := initRepo(t, map[string]string{"app.py": historySource})
+	app := filepath.Join(dir, "app.py")
+	// Lines added above the snippets move them, edited() changes, and new.py isn't committed.
+	assert.Nil(t, os.WriteFile(app, []byte("import os\n\n"+replaceLine(t, historySource, "    pass", "    return 1", 1)), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "new.py"), []byte(historySource), 0644))
+
+	snips, _, err := New(Options{Dir: dir}).Extract(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, snips, 6)
+	h, err := OpenHistory(dir, "", time.Now())
+	assert.Nil(t, err)
+
+	diffs := make(map[string][]string)
+	for _, s := range snips {
+		changes, err := h.Diff(s, "HEAD")
+		assert.Nil(t, err)
+		diffs[filepath.Base(s.File)+":"+s.Content[0]] = changes
+	}
+	assert.Nil(t, diffs["app.py:def old():"], "moved, not changed")
+	assert.Equal(t, []string{" def edited():", "-    pass", "+    return 1"}, diffs["app.py:def edited():"])
+	assert.Equal(t, []string{"+def old():", "+    pass"}, diffs["new.py:def old():"], "not there at HEAD")
+
+	_, err = h.Diff(snips[0], "nope")
+	assert.ErrorContains(t, err, `"nope" is not a revision`)
+}
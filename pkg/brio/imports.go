@@ -0,0 +1,109 @@
+package brio
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// importBlock returns the import statements of a file of the given lines: the lines starting with
+// one of prefixes, unindented, along with the lines continuing them (e.g. the names of a Python
+// "from x import (...)" or of a multi-line TypeScript import). It also returns the index of the
+// line after the last statement.
+func importBlock(lines []string, prefixes []string) ([]string, int) {
+	var block []string
+	end := 0
+	for i := 0; i < len(lines); i++ {
+		if !hasAnyPrefix(lines[i], prefixes) {
+			continue
+		}
+		depth := 0
+		for ; i < len(lines); i++ {
+			line := strings.TrimRight(lines[i], "\r")
+			block = append(block, line)
+			depth += strings.Count(line, "(") + strings.Count(line, "{") + strings.Count(line, "[") -
+				strings.Count(line, ")") - strings.Count(line, "}") - strings.Count(line, "]")
+			if depth <= 0 && !strings.HasSuffix(line, "\\") {
+				break
+			}
+		}
+		end = i + 1
+	}
+	return block, end
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithImports returns a Processor prepending the import statements of their file to snippets, so
+// that the names they use can be told apart from those they define. The imports are headed by a
+// comment naming the file, and followed by a "..." comment. Snippets of languages whose plugin
+// isn't a plugins.ImportPlugin, of files without imports, from a section of their file (e.g. a
+// notebook cell) or tagging the imports themselves are left as they are. The last file read is
+// kept, as the snippets of a file come one after another.
+func WithImports() Processor {
+	var (
+		lastFile string
+		imports  []string
+		end      int
+	)
+	return ProcessorFunc(func(s Snippet) (Snippet, error) {
+		ip, ok := s.Plugin.(plugins.ImportPlugin)
+		if !ok || s.Section != "" {
+			return s, nil
+		}
+		if s.File != lastFile {
+			content, err := ReadFile(s.File)
+			if err != nil {
+				return s, err
+			}
+			lastFile = s.File
+			imports, end = importBlock(strings.Split(string(content), "\n"), ip.GetImportPrefixes())
+		}
+		// StartLine is the line of the start tag, counting from 1.
+		if len(imports) == 0 || s.StartLine-1 < end {
+			return s, nil
+		}
+		style := s.Plugin.GetCommentStyle()
+		content := make([]string, 0, len(imports)+len(s.Content)+2)
+		content = append(content, LineComment(style, "imports of "+filepath.Base(s.File)))
+		content = append(content, imports...)
+		content = append(content, LineComment(style, "..."))
+		s.Content = append(content, s.Content...)
+		return s, nil
+	})
+}
+
+// WithoutImports returns content, the content of s as WithImports printed it, perhaps edited since,
+// without the imports heading it, so that it can be written back to the file of s.
+func WithoutImports(s Snippet, content []string) []string {
+	if s.Plugin == nil || len(content) == 0 {
+		return content
+	}
+	style := s.Plugin.GetCommentStyle()
+	if strings.TrimSpace(content[0]) != LineComment(style, "imports of "+filepath.Base(s.File)) {
+		return content
+	}
+	for i, line := range content[1:] {
+		if strings.TrimSpace(line) == LineComment(style, "...") {
+			return content[i+2:]
+		}
+	}
+	return content
+}
+
+// LineComment returns text as a comment on a line of its own in style.
+func LineComment(style plugins.CommentStyle, text string) string {
+	if style.Single != "" {
+		return style.Single + " " + text
+	}
+	return style.Multi.Start + " " + text + " " + style.Multi.End
+}
@@ -0,0 +1,62 @@
+package brio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportBlock(t *testing.T) {
+	lines := []string{
+		`"""Messages."""`,
+		"import os",
+		"from typing import (",
+		"    Optional,",
+		")",
+		"",
+		"def send():",
+		"    import json",
+		"from app import \\",
+		"    models",
+		"x = 1",
+	}
+	block, end := importBlock(lines, []string{"import ", "from "})
+	assert.Equal(t, []string{"import os", "from typing import (", "    Optional,", ")", "from app import \\", "    models"}, block)
+	assert.Equal(t, 10, end)
+
+	block, end = importBlock([]string{"x = 1"}, []string{"import "})
+	assert.Empty(t, block)
+	assert.Equal(t, 0, end)
+}
+
+func TestWithImports(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "import os\n\n# >: {\"tests\": []}\ndef f():\n    pass\n# <: {\"tests\": []}\n"
+	filePath := filepath.Join(tempDir, "a.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	python, _ := plugins.Get(".py")
+
+	s := Snippet{File: filePath, StartLine: 3, Content: []string{"def f():", "    pass"}, Plugin: python}
+	got, err := WithImports().Process(s)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"# imports of a.py", "import os", "# ...", "def f():", "    pass"}, got.Content)
+	assert.Equal(t, s.Content, WithoutImports(s, got.Content))
+	edited := []string{"  # imports of a.py", "  import os", "  import sys", "  # ...", "  def f():", "      return 1"}
+	assert.Equal(t, []string{"  def f():", "      return 1"}, WithoutImports(s, edited))
+	assert.Equal(t, s.Content, WithoutImports(s, s.Content))
+
+	// Snippets tagging the imports, from a notebook cell or without plugin are left as they are.
+	for _, s := range []Snippet{
+		{File: filePath, StartLine: 1, Content: []string{"import os"}, Plugin: python},
+		{File: filePath, Section: "cell 2", StartLine: 3, Content: []string{"pass"}, Plugin: python},
+		{File: filePath, StartLine: 3, Content: []string{"pass"}},
+	} {
+		got, err := WithImports().Process(s)
+		require.NoError(t, err)
+		assert.Equal(t, s.Content, got.Content)
+	}
+}
@@ -0,0 +1,448 @@
+package brio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"gopkg.in/yaml.v3"
+)
+
+// parseTagJSON extracts JSON data from a line of text and parses it into a map of string slices.
+// Payloads that are not strict JSON are retried in relaxed JSON5 form (see relaxJSON).
+// Returns an error if JSON parsing fails or no JSON is found.
+func parseTagJSON(line string) (map[string][]string, error) {
+	startIdx := strings.Index(line, "{")
+	endIdx := strings.LastIndex(line, "}")
+	if startIdx == -1 || endIdx == -1 || endIdx < startIdx {
+		return nil, fmt.Errorf("no JSON found in line: %s", line)
+	}
+	jsonStr := line[startIdx : endIdx+1]
+
+	var raw map[string]interface{}
+	err := json.Unmarshal([]byte(jsonStr), &raw)
+	if err != nil {
+		// Report the error against what the user actually wrote.
+		if relaxedErr := json.Unmarshal([]byte(relaxJSON(jsonStr)), &raw); relaxedErr != nil {
+			return nil, err
+		}
+	}
+	return normalizeTag(raw)
+}
+
+// parseTagYAML parses a YAML tag payload such as "foundation: [messages]" or "{foundation: messages, tests: [a, b]}".
+func parseTagYAML(payload string) (map[string][]string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(payload), &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("empty tag: %s", payload)
+	}
+	return normalizeTag(raw)
+}
+
+// normalizeTag converts a decoded tag payload into a map of string slices.
+// Scalars become single-element slices, so both "tests": "messages" and "_expires": "2025-06-01" are accepted.
+func normalizeTag(raw map[string]interface{}) (map[string][]string, error) {
+	data := make(map[string][]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			data[key] = []string{}
+		case []interface{}:
+			domains := make([]string, 0, len(v))
+			for _, d := range v {
+				domains = append(domains, fmt.Sprint(d))
+			}
+			data[key] = domains
+		case map[string]interface{}:
+			return nil, fmt.Errorf("%s: expected a list of values, got a mapping", key)
+		default:
+			data[key] = []string{fmt.Sprint(v)}
+		}
+	}
+	return data, nil
+}
+
+// parseTagKV parses a key=value tag payload such as "foundation=messages,alerts tests=messages".
+// Repeated keys accumulate their domains; "category=" declares a category without domains.
+func parseTagKV(payload string) map[string][]string {
+	data := make(map[string][]string)
+	for _, field := range strings.Fields(payload) {
+		category, domains, _ := strings.Cut(field, "=")
+		if _, exists := data[category]; !exists {
+			data[category] = []string{}
+		}
+		for _, domain := range strings.Split(domains, ",") {
+			if domain != "" {
+				data[category] = append(data[category], domain)
+			}
+		}
+	}
+	return data
+}
+
+type commentParser struct {
+	*tagPatterns
+	plugin        plugins.Plugin
+	inMultiline   bool
+	depth         int // nesting level of multi-line comments
+	buffer        bytes.Buffer
+	foundStartTag bool // Add this to track if we've found a start tag
+	pending       *pendingTag
+}
+
+// tagPatterns holds what finds tags in the comments of a style, for a marker style and payload
+// format. It is built once and shared by the parsers of every file using them.
+type tagPatterns struct {
+	prefixes         []string // single line comment prefixes
+	multiStart       string
+	multiEnd         string
+	multiAtLineStart bool
+	markers          string
+	payload          string
+	// startPattern and endPattern find single line tags when the scanner can't (see findSingleTag).
+	startPattern  *regexp.Regexp
+	endPattern    *regexp.Regexp
+	multiStartTag *regexp.Regexp
+	multiEndTag   *regexp.Regexp
+}
+
+// patternCache holds the tagPatterns built so far, by comment style and options.
+var patternCache sync.Map
+
+// pendingTag accumulates a tag payload that spans several single-line comments:
+//
+//	# >: {
+//	#   "foundation": ["messages"],
+//	#   "model": ["messages"]
+//	# }
+type pendingTag struct {
+	isStart bool
+	line    int // line the payload was opened on
+	payload strings.Builder
+}
+
+// unclosedPayloadError is a tag payload abandoned before its braces balance, opened on line.
+type unclosedPayloadError struct {
+	line int
+}
+
+func (e unclosedPayloadError) Error() string {
+	return "tag payload is never closed"
+}
+
+// markerTokens returns the regular expressions matching the start and end
+// markers for the given marker style (see MarkersArrows and MarkersKeywords).
+func markerTokens(markers string) (start, end string) {
+	switch markers {
+	case MarkersArrows:
+		return `>:`, `<:`
+	case MarkersKeywords:
+		return `\bstart:`, `\bend:`
+	default:
+		return `(?:>|\bstart):`, `(?:<|\bend):`
+	}
+}
+
+// kvPayload matches the terse key=value tag syntax, e.g. "foundation=messages,alerts tests=messages".
+const kvPayload = `[\w.-]+=[^\s=]*(?:[ \t]+[\w.-]+=[^\s=]*)*`
+
+// kvTagPattern matches a whole payload written in key=value syntax.
+var kvTagPattern = regexp.MustCompile(`^\s*` + kvPayload + `\s*$`)
+
+// payloadPatterns returns the regular expressions capturing a tag payload of the given format,
+// on a single-line comment and inside a multi-line comment.
+// The key=value syntax is accepted in every format.
+func payloadPatterns(payload string) (single, multi string) {
+	if payload == PayloadYAML {
+		// Require a mapping so ordinary "# start: the server" comments aren't taken for tags.
+		return `(\{.*|` + kvPayload + `\s*$|[\w"'-][^:]*:.*)`, `[ \t]*([^\n]+)`
+	}
+	return `(\{.*|` + kvPayload + `\s*$)`, `(?s)\s*(\{.*}|` + kvPayload + `)`
+}
+
+func newCommentParser(p plugins.Plugin, opts Options) *commentParser {
+	return &commentParser{tagPatterns: patternsFor(p.GetCommentStyle(), opts), plugin: p}
+}
+
+// patternsFor returns the tagPatterns of style for the marker style and payload format of opts.
+func patternsFor(style plugins.CommentStyle, opts Options) *tagPatterns {
+	key := fmt.Sprintf("%q %q %q %v %s %s", style.SinglePrefixes(), style.Multi.Start, style.Multi.End,
+		style.MultiAtLineStart, opts.Markers, opts.Payload)
+	if cached, ok := patternCache.Load(key); ok {
+		return cached.(*tagPatterns)
+	}
+
+	startToken, endToken := markerTokens(opts.Markers)
+	singlePayload, multiPayload := payloadPatterns(opts.Payload)
+	patterns := &tagPatterns{
+		prefixes:         style.SinglePrefixes(),
+		multiStart:       style.Multi.Start,
+		multiEnd:         style.Multi.End,
+		multiAtLineStart: style.MultiAtLineStart,
+		markers:          opts.Markers,
+		payload:          opts.Payload,
+		startPattern: regexp.MustCompile(
+			`(?i)` + singlePrefixPattern(style) + `\s*` + startToken + `\s*` + singlePayload,
+		),
+		endPattern: regexp.MustCompile(
+			`(?i)` + singlePrefixPattern(style) + `\s*` + endToken + `\s*` + singlePayload,
+		),
+		// Tags inside a multi-line comment are searched in the whole comment body,
+		// and JSON payloads may span several lines.
+		multiStartTag: regexp.MustCompile(`(?i)` + startToken + multiPayload),
+		multiEndTag:   regexp.MustCompile(`(?i)` + endToken + multiPayload),
+	}
+	cached, _ := patternCache.LoadOrStore(key, patterns)
+	return cached.(*tagPatterns)
+}
+
+// singlePrefixPattern returns a regular expression matching any single line comment prefix of style.
+func singlePrefixPattern(style plugins.CommentStyle) string {
+	prefixes := style.SinglePrefixes()
+	if len(prefixes) == 0 {
+		// A language without single line comments has nothing to match.
+		return `[^\s\S]`
+	}
+	for i, prefix := range prefixes {
+		prefixes[i] = regexp.QuoteMeta(prefix)
+	}
+	return `(?:` + strings.Join(prefixes, "|") + `)`
+}
+
+// parseTag parses a tag payload in the configured format, or in key=value syntax.
+func (p *commentParser) parseTag(payload string) (map[string][]string, error) {
+	if kvTagPattern.MatchString(payload) {
+		return parseTagKV(payload), nil
+	}
+	if p.payload == PayloadYAML {
+		return parseTagYAML(payload)
+	}
+	return parseTagJSON(payload)
+}
+
+// parseLine feeds line lineNum to the parser and reports whether it completes a start or end tag.
+// err is set when a line looks like a tag but its payload cannot be parsed; the tag is then ignored.
+// A payload left unclosed is an unclosedPayloadError, for the line that opened it.
+func (p *commentParser) parseLine(line string, lineNum int) (isStart bool, isEnd bool, jsonData map[string][]string, err error) {
+	// Continue a tag payload opened on a previous line
+	var pendingErr error
+	if p.pending != nil {
+		var handled bool
+		isStart, isEnd, jsonData, handled, pendingErr = p.continuePending(line)
+		if handled {
+			return isStart, isEnd, jsonData, pendingErr
+		}
+	}
+
+	isStart, isEnd, jsonData, err = p.matchTag(line, lineNum)
+	if err == nil {
+		err = pendingErr
+	}
+	return isStart, isEnd, jsonData, err
+}
+
+// matchTag looks for a start or end tag on line, or in the multi-line comment it completes.
+func (p *commentParser) matchTag(line string, lineNum int) (isStart bool, isEnd bool, jsonData map[string][]string, err error) {
+	// Check for single-line comments first
+	if payload, ok := p.findSingleTag(line, true); ok {
+		if p.openPending(payload, true, lineNum) {
+			return false, false, nil, nil
+		}
+		data, err := p.parseTag(payload)
+		return err == nil, false, data, err
+	}
+	if payload, ok := p.findSingleTag(line, false); ok {
+		if p.openPending(payload, false, lineNum) {
+			return false, false, nil, nil
+		}
+		data, err := p.parseTag(payload)
+		return false, err == nil, data, err
+	}
+
+	// Handle multi-line comments, unless the language has none
+	if p.multiStart == "" {
+		return false, false, nil, nil
+	}
+	if !p.inMultiline {
+		if p.indexToken(line, p.multiStart) < 0 {
+			return false, false, nil, nil
+		}
+		p.buffer.Reset()
+	}
+	p.buffer.WriteString(line + "\n")
+
+	// A comment like /* ... */ can open and close on the same line
+	p.depth = p.commentDepth(line, p.depth)
+	p.inMultiline = p.depth > 0
+	if !p.inMultiline {
+		return p.finishMultiline()
+	}
+
+	return false, false, nil, nil
+}
+
+// commentDepth returns how deeply line leaves us inside multi-line comments, given the depth before it.
+// Only languages with MultiNested comments go deeper than one level.
+func (p *commentParser) commentDepth(line string, depth int) int {
+	nested := p.plugin.GetCommentStyle().MultiNested
+	for rest := line; ; {
+		start := p.indexToken(rest, p.multiStart)
+		end := p.indexToken(rest, p.multiEnd)
+
+		switch {
+		case depth == 0 || (nested && start >= 0 && (end < 0 || start < end)):
+			if start < 0 {
+				return depth
+			}
+			depth++
+			rest = rest[start+len(p.multiStart):]
+		case end >= 0 && nested:
+			depth--
+			rest = rest[end+len(p.multiEnd):]
+		case end >= 0:
+			depth = 0
+			rest = rest[end+len(p.multiEnd):]
+		default:
+			return depth
+		}
+
+		// Tokens anchored at the start of the line can't appear again on the same line.
+		if p.multiAtLineStart {
+			return depth
+		}
+	}
+}
+
+// indexToken returns the index of a multi-line comment token in s, or -1. Tokens of styles with
+// MultiAtLineStart only count at the start of s.
+func (p *tagPatterns) indexToken(s, token string) int {
+	if p.multiAtLineStart {
+		if strings.HasPrefix(s, token) {
+			return 0
+		}
+		return -1
+	}
+	return strings.Index(s, token)
+}
+
+// finishMultiline looks for a start or end tag in the multi-line comment collected in the buffer.
+func (p *commentParser) finishMultiline() (isStart bool, isEnd bool, jsonData map[string][]string, err error) {
+	// Process the entire multi-line comment, without its closing token since
+	// that may contain a brace (e.g. Haskell's -}).
+	fullComment := p.buffer.String()
+	if i := strings.LastIndex(fullComment, p.multiEnd); i >= 0 {
+		fullComment = fullComment[:i]
+	}
+	fullComment = stripCommentDecoration(fullComment)
+
+	// Look for >: {...} (or start: {...}) pattern in the full comment
+	if m := p.multiStartTag.FindStringSubmatch(fullComment); m != nil {
+		data, err := p.parseTag(m[1])
+		if err == nil {
+			p.foundStartTag = true
+		}
+		return err == nil, false, data, err
+	}
+
+	// Look for <: {...} (or end: {...}) pattern in the full comment
+	if m := p.multiEndTag.FindStringSubmatch(fullComment); m != nil {
+		data, err := p.parseTag(m[1])
+		return false, err == nil, data, err
+	}
+
+	return false, false, nil, nil
+}
+
+// openPending starts accumulating a tag payload of line lineNum when it opens braces without
+// closing them.
+func (p *commentParser) openPending(payload string, isStart bool, lineNum int) bool {
+	if !strings.HasPrefix(payload, "{") || braceDepth(payload) <= 0 {
+		return false
+	}
+	p.pending = &pendingTag{isStart: isStart, line: lineNum}
+	p.pending.payload.WriteString(payload)
+	return true
+}
+
+// continuePending adds line to the pending tag payload and parses it once its braces balance.
+// handled is false when line is not a comment or starts another tag, which abandons the pending tag
+// (reported through err, an unclosedPayloadError) so the line is parsed normally.
+func (p *commentParser) continuePending(line string) (isStart, isEnd bool, data map[string][]string, handled bool, err error) {
+	body, isComment := commentBody(line, p.plugin.GetCommentStyle())
+	if !isComment || p.hasSingleTag(line) {
+		opened := p.pending.line
+		p.pending = nil
+		return false, false, nil, false, unclosedPayloadError{line: opened}
+	}
+
+	pending := p.pending
+	pending.payload.WriteString("\n" + body)
+	if braceDepth(pending.payload.String()) > 0 {
+		return false, false, nil, true, nil
+	}
+
+	p.pending = nil
+	data, err = p.parseTag(pending.payload.String())
+	if err != nil {
+		return false, false, nil, true, err
+	}
+	return pending.isStart, !pending.isStart, data, true, nil
+}
+
+// commentBody returns the text of a single line comment without its prefix,
+// and whether line is such a comment at all.
+func commentBody(line string, style plugins.CommentStyle) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range style.SinglePrefixes() {
+		if strings.HasPrefix(trimmed, prefix) {
+			return strings.TrimPrefix(trimmed, prefix), true
+		}
+	}
+	return "", false
+}
+
+// braceDepth returns the number of unclosed '{' in s, ignoring braces inside quoted strings.
+func braceDepth(s string) int {
+	depth := 0
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripCommentDecoration removes the leading '*' that block comments commonly repeat on every line,
+// so tag payloads spread over such lines still parse.
+func stripCommentDecoration(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "*") && !strings.HasPrefix(trimmed, "*/") {
+			lines[i] = strings.TrimPrefix(trimmed, "*")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,86 @@
+package brio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagJSON(t *testing.T) {
+	line := `# start: {"foundation": ["messages"], "model": ["messages"]}`
+	data, err := parseTagJSON(line)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"messages"}, data["foundation"])
+	assert.Equal(t, []string{"messages"}, data["model"])
+
+	// Invalid JSON
+	invalid := `# start: foundation: [messages]`
+	data, err = parseTagJSON(invalid)
+	assert.Nil(t, data)
+	assert.NotNil(t, err)
+}
+
+func TestBraceDepth(t *testing.T) {
+	assert.Equal(t, 1, braceDepth(`{`))
+	assert.Equal(t, 0, braceDepth(`{"a": ["b"]}`))
+	assert.Equal(t, 1, braceDepth(`{"a": "}"`))
+	assert.Equal(t, 1, braceDepth(`{"a": "\"}"`))
+}
+
+func TestParseTagJSONRelaxed(t *testing.T) {
+	tests := []string{
+		`# >: {'foundation': ['messages'], 'model': ['messages']}`,
+		`# >: {foundation: [messages], model: [messages]}`,
+		`# >: {"foundation": ["messages",], "model": ["messages"],}`,
+	}
+
+	for _, line := range tests {
+		data, err := parseTagJSON(line)
+		assert.Nil(t, err, "Line: %s", line)
+		assert.Equal(t, map[string][]string{
+			"foundation": {"messages"},
+			"model":      {"messages"},
+		}, data, "Line: %s", line)
+	}
+
+	// Still invalid after relaxing
+	_, err := parseTagJSON(`# >: {"foundation": ["messages"}`)
+	assert.NotNil(t, err)
+}
+
+func TestParseTagKV(t *testing.T) {
+	assert.Equal(t, map[string][]string{
+		"foundation": {"messages", "alerts"},
+		"tests":      {"messages"},
+		"model":      {},
+	}, parseTagKV("foundation=messages,alerts tests=messages model="))
+}
+
+func TestScanFileUnclosedPayload(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "a.py")
+	require.NoError(t, os.WriteFile(filePath, []byte(`# >: {
+#   "tests": ["messages"],
+x = 1
+# >: {"tests": ["messages"]}
+y = 2
+# <: {"tests": ["messages"]}
+# >: {
+#   "model": ["messages"],
+`), 0644))
+
+	snips, issues, err := New(Options{}).ScanFile(filePath)
+	require.NoError(t, err)
+	require.Len(t, snips, 1)
+	var messages []string
+	for _, i := range issues {
+		messages = append(messages, i.String())
+	}
+	// Both are reported at the line opening the payload, not where it was given up.
+	assert.Equal(t, []string{
+		filePath + ":1: BRIO002 ignoring invalid tag: tag payload is never closed",
+		filePath + ":7: BRIO002 ignoring invalid tag: tag payload is never closed",
+	}, messages)
+}
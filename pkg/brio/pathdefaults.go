@@ -0,0 +1,155 @@
+package brio
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PathDefault gives the snippets of the files below a directory default categories, domains and
+// attributes, so that the tags of a service needn't all repeat its domain. Defaults only fill in
+// what tags leave out: a category a tag gives domains to keeps them.
+type PathDefault struct {
+	// Path is a directory or a file, relative to Base, with forward slashes or backslashes; each of
+	// its elements may be a glob, e.g. "services/*/api".
+	Path string
+	// Base is the directory Path is relative to, such as that of the config file declaring it; ""
+	// means Options.Dir. Files not below it, and those of a cloned repository or an archive, are
+	// matched relative to Options.Dir.
+	Base string
+	// Tags holds the categories and attributes ("_owner"...) the snippets get, as a tag payload
+	// does; those a snippet is tagged with keep their domains, unless they have none.
+	Tags map[string][]string
+	// Domains are given to the categories of the snippets that have none, after Tags.
+	Domains []string
+}
+
+// Validate reports a malformed glob in the path of d.
+func (d PathDefault) Validate() error {
+	for _, element := range pathElements(d.Path) {
+		if _, err := path.Match(element, ""); err != nil {
+			return filepath.ErrBadPattern
+		}
+	}
+	return nil
+}
+
+// matches reports whether the file whose path relative to d.Base has the elements rel is d.Path or
+// below it.
+func (d PathDefault) matches(rel []string) bool {
+	elements := pathElements(d.Path)
+	if len(elements) > len(rel) {
+		return false
+	}
+	for i, element := range elements {
+		name := rel[i]
+		if foldPathCase {
+			element, name = strings.ToLower(element), strings.ToLower(name)
+		}
+		if matched, _ := path.Match(element, name); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// pathElements returns the elements of a path written with either separator, without "." ones.
+func pathElements(p string) []string {
+	var elements []string
+	for _, element := range strings.Split(slashPattern(p), "/") {
+		if element != "" && element != "." {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// defaultPath returns the elements of the path of filePath relative to the directory d.Path is
+// relative to: d.Base, or Options.Dir when it is unset, or the file isn't below it or is read from
+// a cloned repository or an archive, whose paths only make sense from their root.
+func (e *Extractor) defaultPath(d PathDefault, filePath string) ([]string, bool) {
+	dir := e.opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	bases := []string{dir}
+	_, _, mounted := mountedTree(filePath)
+	_, _, archived := splitArchivePath(filePath)
+	if d.Base != "" && !mounted && !archived {
+		bases = []string{d.Base, dir}
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, false
+	}
+	for _, base := range bases {
+		root, err := filepath.Abs(base)
+		if err != nil || !within(abs, root) {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err == nil {
+			return pathElements(filepath.ToSlash(rel)), true
+		}
+	}
+	return nil, false
+}
+
+// applyPathDefaults returns snips, all of filePath, with the Options.PathDefaults matching the file
+// filled in, from the most specific path to the least, so that a subdirectory's defaults win over
+// its parent's.
+func (e *Extractor) applyPathDefaults(filePath string, snips []Snippet) []Snippet {
+	if len(e.opts.PathDefaults) == 0 || len(snips) == 0 {
+		return snips
+	}
+	var matching []PathDefault
+	for _, d := range e.opts.PathDefaults {
+		if rel, ok := e.defaultPath(d, filePath); ok && d.matches(rel) {
+			matching = append(matching, d)
+		}
+	}
+	if len(matching) == 0 {
+		return snips
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return len(pathElements(matching[i].Path)) > len(pathElements(matching[j].Path))
+	})
+
+	results := make([]Snippet, len(snips))
+	for i, s := range snips {
+		// The maps of s may be shared with other snippets: fill in copies.
+		categories := make(map[string][]string, len(s.Categories))
+		for category, domains := range s.Categories {
+			categories[category] = domains
+		}
+		attrs := make(map[string][]string, len(s.Attrs))
+		for name, values := range s.Attrs {
+			attrs[name] = values
+		}
+		for _, d := range matching {
+			defaultCategories, defaultAttrs := splitAttrs(d.Tags)
+			for category, domains := range defaultCategories {
+				if len(categories[category]) == 0 {
+					categories[category] = domains
+				}
+			}
+			for name, values := range defaultAttrs {
+				if _, ok := attrs[name]; !ok {
+					attrs[name] = values
+				}
+			}
+			for category, domains := range categories {
+				if len(domains) == 0 && len(d.Domains) > 0 {
+					categories[category] = d.Domains
+				}
+			}
+		}
+		s.Categories = categories
+		if len(attrs) > 0 {
+			s.Attrs = attrs
+		}
+		results[i] = s
+	}
+	return results
+}
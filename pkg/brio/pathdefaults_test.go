@@ -0,0 +1,51 @@
+package brio
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPathDefaults(t *testing.T) {
+	e := New(Options{PathDefaults: []PathDefault{
+		{Path: "services", Tags: map[string][]string{"_owner": {"platform"}}, Domains: []string{"core"}},
+		{Path: `services\messages\`, Tags: map[string][]string{"foundation": {}}, Domains: []string{"messages"}},
+		{Path: "services/*/api", Tags: map[string][]string{"api": {}}},
+	}})
+	tagged := map[string][]string{"tests": {}, "model": {"billing"}}
+	snips := []Snippet{{File: "queue.py", Categories: tagged, Attrs: map[string][]string{"owner": {"team-messages"}}}}
+
+	got := e.applyPathDefaults(filepath.Join("services", "messages", "queue.py"), snips)
+	require.Len(t, got, 1)
+	// The most specific path comes first: its domain fills in the categories without one.
+	assert.Equal(t, map[string][]string{"tests": {"messages"}, "model": {"billing"}, "foundation": {"messages"}}, got[0].Categories)
+	assert.Equal(t, map[string][]string{"owner": {"team-messages"}}, got[0].Attrs)
+	// The snippet given is left as it was.
+	assert.Equal(t, map[string][]string{"tests": {}, "model": {"billing"}}, tagged)
+
+	got = e.applyPathDefaults(filepath.Join("services", "billing", "api", "routes.py"), []Snippet{{File: "routes.py"}})
+	assert.Equal(t, map[string][]string{"api": {"core"}}, got[0].Categories)
+	assert.Equal(t, map[string][]string{"owner": {"platform"}}, got[0].Attrs)
+
+	got = e.applyPathDefaults(filepath.Join("web", "app.py"), snips)
+	assert.Equal(t, snips, got)
+
+	assert.NoError(t, PathDefault{Path: "services/*/api"}.Validate())
+	assert.ErrorIs(t, PathDefault{Path: "services/[api"}.Validate(), filepath.ErrBadPattern)
+
+	// Paths are relative to Base, wherever the files are named from, and to Options.Dir for the files
+	// of a mounted tree.
+	base := t.TempDir()
+	e = New(Options{Dir: "repo@main", PathDefaults: []PathDefault{
+		{Path: "services", Base: base, Domains: []string{"core"}},
+	}})
+	got = e.applyPathDefaults(filepath.Join(base, "services", "queue.py"), []Snippet{{Categories: map[string][]string{"tests": {}}}})
+	assert.Equal(t, map[string][]string{"tests": {"core"}}, got[0].Categories)
+	mount("repo@main", &archive{files: map[string][]byte{}})
+	got = e.applyPathDefaults(filepath.Join("repo@main", "services", "queue.py"), []Snippet{{Categories: map[string][]string{"tests": {}}}})
+	assert.Equal(t, map[string][]string{"tests": {"core"}}, got[0].Categories)
+	got = e.applyPathDefaults(filepath.Join(t.TempDir(), "services", "queue.py"), []Snippet{{Categories: map[string][]string{"tests": {}}}})
+	assert.Equal(t, map[string][]string{"tests": {}}, got[0].Categories)
+}
@@ -0,0 +1,142 @@
+package brio
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// foldPathCase makes file patterns and path comparisons ignore case, as the file systems of
+// Windows do. backslashEscapes makes a backslash in a pattern escape the glob metacharacters
+// following it, as on Unix; on Windows, it always separates directories.
+var (
+	foldPathCase     = runtime.GOOS == "windows"
+	backslashEscapes = runtime.GOOS != "windows"
+)
+
+// globMeta are the characters a backslash escapes in a pattern where backslashEscapes is set.
+const globMeta = `*?[]{},\`
+
+// NativePath returns path with the separators of the system, whether it was written with forward
+// slashes or backslashes: config files shared by Windows and Unix users name the same files
+// either way. Windows extended-length prefixes (\\?\) are dropped.
+func NativePath(path string) string {
+	path = trimLongPathPrefix(path)
+	return strings.NewReplacer(`\`, string(filepath.Separator), "/", string(filepath.Separator)).Replace(path)
+}
+
+// trimLongPathPrefix drops the \\?\ prefix of Windows extended-length paths, which os adds where
+// needed by itself: \\?\C:\src becomes C:\src and \\?\UNC\host\share becomes \\host\share.
+func trimLongPathPrefix(path string) string {
+	for _, prefix := range []string{`\\?\`, `//?/`} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			if share, ok := cutPrefixFold(rest, "UNC"+prefix[3:]); ok {
+				return prefix[:2] + share
+			}
+			return rest
+		}
+	}
+	return path
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring case.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// RelativePath returns path relative to the working directory when it is inside it, and path
+// otherwise, without extended-length prefix either way.
+func RelativePath(path string) string {
+	path = trimLongPathPrefix(path)
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
+}
+
+// samePath reports whether a and b name the same file, whichever their separators, and ignoring
+// case on Windows.
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(NativePath(a)), filepath.Clean(NativePath(b))
+	if equalPaths(a, b) {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && equalPaths(absA, absB)
+}
+
+func equalPaths(a, b string) bool {
+	if foldPathCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// slashPattern returns pattern with forward slashes separating directories. A backslash is a
+// separator unless backslashEscapes is set and it escapes one of globMeta, so that "src\main.py"
+// and "src/main.py" are the same pattern on every system, and "\*" matches a star on Unix.
+func slashPattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' {
+			if backslashEscapes && i+1 < len(pattern) && strings.IndexByte(globMeta, pattern[i+1]) >= 0 {
+				b.WriteByte(c)
+				b.WriteByte(pattern[i+1])
+				i++
+				continue
+			}
+			c = '/'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// MatchPath reports whether the file at rel, relative to the directory scanned, matches the glob
+// pattern: against rel if the pattern names directories ("src/*.py"), against the file name
+// otherwise ("*.py"). Either may use forward slashes or backslashes (see slashPattern), and case
+// is ignored on Windows.
+func MatchPath(pattern, rel string) (bool, error) {
+	pattern = slashPattern(pattern)
+	name := strings.TrimPrefix(filepath.ToSlash(rel), "./")
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	} else {
+		pattern = strings.TrimPrefix(pattern, "./")
+	}
+	if foldPathCase {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	matched, err := path.Match(pattern, name)
+	if err != nil {
+		// As filepath.Match reports it.
+		err = filepath.ErrBadPattern
+	}
+	return matched, err
+}
+
+// ValidatePattern reports whether pattern is a well-formed glob for MatchPath, e.g. without
+// unclosed brackets.
+func ValidatePattern(pattern string) error {
+	for _, p := range expandBraces(slashPattern(pattern)) {
+		if _, err := MatchPath(p, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
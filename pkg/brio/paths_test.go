@@ -0,0 +1,95 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPath(t *testing.T) {
+	for _, c := range []struct {
+		pattern, rel string
+		matched      bool
+	}{
+		{"*.py", "app.py", true},
+		{"*.py", filepath.Join("src", "app.py"), true},
+		{"src/*.py", filepath.Join("src", "app.py"), true},
+		{`src\a*.py`, filepath.Join("src", "app.py"), true},
+		{`./src\a*.py`, filepath.Join("src", "app.py"), true},
+		{`src\*.py`, filepath.Join("src", "app.py"), false},
+		{"src/*.py", "app.py", false},
+		{"src/*.py", filepath.Join("src", "lib", "app.py"), false},
+		{`\*.py`, "*.py", true},
+		{`\*.py`, "app.py", false},
+		{"*.PY", "app.py", false},
+	} {
+		matched, err := MatchPath(c.pattern, c.rel)
+		assert.Nil(t, err)
+		assert.Equal(t, c.matched, matched, "%s against %s", c.pattern, c.rel)
+	}
+
+	_, err := MatchPath("src/[.py", "src/app.py")
+	assert.ErrorIs(t, err, filepath.ErrBadPattern)
+	assert.ErrorIs(t, ValidatePattern("{app,[}.py"), filepath.ErrBadPattern)
+	assert.Nil(t, ValidatePattern(`src\a*.{py,ts}`))
+}
+
+func TestMatchPathWindows(t *testing.T) {
+	foldPathCase, backslashEscapes = true, false
+	defer func() { foldPathCase, backslashEscapes = false, true }()
+
+	for _, c := range []struct {
+		pattern, rel string
+	}{
+		{`SRC\*.PY`, filepath.Join("src", "App.py")},
+		{`src\*.py`, "src/app.py"},
+	} {
+		matched, err := MatchPath(c.pattern, c.rel)
+		assert.Nil(t, err)
+		assert.True(t, matched, "%s against %s", c.pattern, c.rel)
+	}
+	assert.Equal(t, "src/{lib,app}/*.py", slashPattern(`src\{lib,app}\*.py`))
+	assert.True(t, samePath("SRC/App.py", filepath.Join("src", "app.py")))
+}
+
+func TestNativePath(t *testing.T) {
+	sep := string(filepath.Separator)
+	assert.Equal(t, "tools"+sep+"plugins", NativePath(`tools\plugins`))
+	assert.Equal(t, "tools"+sep+"plugins", NativePath("tools/plugins"))
+	assert.Equal(t, `C:\src\app.py`, trimLongPathPrefix(`\\?\C:\src\app.py`))
+	assert.Equal(t, `\\host\share\app.py`, trimLongPathPrefix(`\\?\UNC\host\share\app.py`))
+	assert.Equal(t, "/src/app.py", trimLongPathPrefix("/src/app.py"))
+	assert.True(t, samePath(`src\app.py`, "src/app.py"))
+}
+
+func TestRelativePath(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join("src", "app.py"), RelativePath(filepath.Join(wd, "src", "app.py")))
+	outside := filepath.Join(filepath.Dir(wd), "..app", "app.py")
+	assert.Equal(t, outside, RelativePath(outside))
+}
+
+func TestExtractorFilesPathPattern(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"app.py", "src/main.py", "src/lib/util.py"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte("pass\n"), 0644))
+	}
+
+	for _, pattern := range []string{"src/*.py", `src\m*.py`, "{src,lib}/*.py"} {
+		files, err := New(Options{Dir: root, Pattern: pattern}).Files(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, []string{filepath.Join(root, "src", "main.py")}, files, pattern)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "src.zip")
+	writeZip(t, archivePath, map[string]string{"src/main.py": "pass\n", "app.py": "pass\n"})
+	files, err := New(Options{Dir: archivePath, Pattern: `src\m*.py`}).Files(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(archivePath, "src", "main.py")}, files)
+}
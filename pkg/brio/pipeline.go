@@ -0,0 +1,147 @@
+package brio
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Extractions run as a pipeline: a producer lists the files to scan, Options.Jobs workers read and
+// parse them, so that waiting on the disk for a file overlaps with parsing others, and a consumer
+// takes the results in the order the files were listed in. Results are the same whatever the
+// number of workers. At most window() files are in flight, sent but not consumed yet, so that a file
+// slow to scan doesn't have the results of every file after it held in memory.
+
+// fileJob is a file to scan, and its place in the order files were listed in.
+type fileJob struct {
+	index int
+	path  string
+}
+
+// fileResult is what extracting a file yields.
+type fileResult struct {
+	index  int
+	snips  []Snippet
+	issues []Issue
+	err    error
+}
+
+// jobs returns how many files are scanned at once.
+func (e *Extractor) jobs() int {
+	if e.opts.Jobs > 0 {
+		return e.opts.Jobs
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// window returns how many files may be sent to the workers before the results of the first of them
+// are consumed.
+func (e *Extractor) window() int {
+	return 4 * e.jobs()
+}
+
+// pipeline extracts the files produce passes to send, and passes the results to consume one file
+// at a time, in the order they were sent. It stops at the first error produce or consume returns
+// and returns it; consume isn't called again once it returned an error. When ctx is done before
+// every file is consumed, it returns ctx.Err().
+func (e *Extractor) pipeline(ctx context.Context, produce func(ctx context.Context, send func(path string) error) error,
+	consume func(fileResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan fileJob)
+	results := make(chan fileResult)
+	// inFlight holds a token for each file sent whose result isn't consumed yet.
+	inFlight := make(chan struct{}, e.window())
+
+	sent := 0
+	var produceErr error
+	go func() {
+		defer close(jobs)
+		produceErr = produce(ctx, func(path string) error {
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- fileJob{index: sent, path: path}:
+				sent++
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < e.jobs(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				// Once the pipeline is stopped, the files left are only drained.
+				if ctx.Err() != nil {
+					continue
+				}
+				r := fileResult{index: job.index}
+				r.snips, r.err = e.extractFile(ctx, job.path, func(i Issue) { r.issues = append(r.issues, i) })
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// Results arrive in any order; each is held until those of the files sent before it are in, and
+	// no more than the window are.
+	held := make(map[int]fileResult)
+	next := 0
+	var err error
+	for r := range results {
+		if err != nil {
+			continue
+		}
+		held[r.index] = r
+		for r, ok := held[next]; ok; r, ok = held[next] {
+			delete(held, next)
+			next++
+			err = consume(r)
+			<-inFlight
+			if err != nil {
+				cancel()
+				break
+			}
+		}
+	}
+
+	// results is closed once the workers are done, which they are once jobs is closed: the producer
+	// is done as well.
+	if err != nil {
+		return err
+	}
+	if produceErr != nil {
+		return produceErr
+	}
+	if next < sent {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// sendFiles returns a producer for pipeline sending files one after another.
+func sendFiles(files []string) func(ctx context.Context, send func(path string) error) error {
+	return func(ctx context.Context, send func(path string) error) error {
+		for _, path := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := send(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+package brio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineKeepsOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for i := 0; i < 40; i++ {
+		// Files of very different sizes finish out of order.
+		content := fmt.Sprintf("# >: {\"tests\": [\"f%02d\"]}\n%s# <: {\"tests\": []}\n# <: {\"tests\": []}\n",
+			i, strings.Repeat("pass\n", (i%7)*2000))
+		path := filepath.Join(tempDir, fmt.Sprintf("f%02d.py", i))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		files = append(files, path)
+	}
+	files = append(files[:20], append([]string{filepath.Join(tempDir, "missing.py")}, files[20:]...)...)
+
+	want, wantIssues, wantErr := New(Options{Jobs: 1}).ExtractFiles(context.Background(), files)
+	require.Len(t, want, 40)
+	require.Len(t, wantIssues, 40)
+	for i, s := range want {
+		assert.Equal(t, fmt.Sprintf("f%02d", i), s.Categories["tests"][0])
+		assert.Equal(t, fmt.Sprintf("f%02d.py", i), filepath.Base(wantIssues[i].File))
+	}
+
+	for _, jobs := range []int{0, 4, 16} {
+		got, issues, err := New(Options{Jobs: jobs}).ExtractFiles(context.Background(), files)
+		assert.Equal(t, want, got, "jobs=%d", jobs)
+		assert.Equal(t, wantIssues, issues, "jobs=%d", jobs)
+		assert.Equal(t, wantErr.Error(), err.Error(), "jobs=%d", jobs)
+	}
+}
+
+func TestPipelineStopsAtConsumerError(t *testing.T) {
+	tempDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		content := fmt.Sprintf("# >: {\"tests\": [\"f%02d\"]}\npass\n# <: {\"tests\": []}\n", i)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, fmt.Sprintf("f%02d.py", i)), []byte(content), 0644))
+	}
+
+	failure := errors.New("full")
+	var seen []string
+	err := New(Options{Dir: tempDir, Jobs: 4}).Each(context.Background(), func(s Snippet) error {
+		seen = append(seen, s.Categories["tests"][0])
+		if len(seen) == 3 {
+			return failure
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"f00", "f01", "f02"}, seen)
+}
+
+func TestPipelineBoundsFilesInFlight(t *testing.T) {
+	tempDir := t.TempDir()
+	var files []string
+	for i := 0; i < 60; i++ {
+		// The first file is by far the slowest to scan, so the others finish before it.
+		lines := 1
+		if i == 0 {
+			lines = 500000
+		}
+		content := fmt.Sprintf("# >: {\"tests\": [\"f%02d\"]}\n%s# <: {\"tests\": []}\n", i, strings.Repeat("pass\n", lines))
+		path := filepath.Join(tempDir, fmt.Sprintf("f%02d.py", i))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		files = append(files, path)
+	}
+
+	e := New(Options{Jobs: 2})
+	var sent atomic.Int64
+	consumed := 0
+	produce := func(ctx context.Context, send func(path string) error) error {
+		return sendFiles(files)(ctx, func(path string) error {
+			err := send(path)
+			sent.Add(1)
+			return err
+		})
+	}
+	err := e.pipeline(context.Background(), produce, func(r fileResult) error {
+		assert.LessOrEqual(t, int(sent.Load())-consumed, e.window())
+		consumed++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, len(files), consumed)
+}
@@ -0,0 +1,124 @@
+package brio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Most files hold no tag at all. Rather than parsing them line by line, ScanFile first reads them
+// in large chunks looking for anything that could be a marker, and skips the files where there is
+// none.
+
+// prescanChunkSize is how much of a file mayHoldTags reads at once.
+const prescanChunkSize = 1 << 20
+
+// longLineBlock is the size of the blocks of a chunk mayHoldTags looks for long lines in.
+const longLineBlock = bufio.MaxScanTokenSize / 2
+
+// markerLookback is how many bytes before a colon a marker may begin at: "start" is 5 bytes long,
+// and a case-insensitive match may fold a 2-byte letter (ſ) onto one of them.
+const markerLookback = 6
+
+// prescanBuffers recycles the chunks read by mayHoldTags.
+var prescanBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, markerLookback+prescanChunkSize)
+		return &buf
+	},
+}
+
+var (
+	startKeyword = []byte("start")
+	endKeyword   = []byte("end")
+)
+
+// mayHoldTags reports whether the source read from r may hold a tag for p: it holds a start or end
+// marker, inside a comment or not. Sources with a line too long to be scanned are also reported,
+// for scanSource to fail on them.
+func (p *tagPatterns) mayHoldTags(r io.Reader) (bool, error) {
+	bufp := prescanBuffers.Get().(*[]byte)
+	defer prescanBuffers.Put(bufp)
+	buf := *bufp
+
+	carry := 0 // bytes kept from the end of the previous chunk, for markers split across chunks
+	tail := 0  // length of the last line read so far
+	for {
+		n, err := io.ReadFull(r, buf[carry:])
+		chunk := buf[:carry+n]
+
+		for i := carry; ; {
+			colon := bytes.IndexByte(chunk[i:], ':')
+			if colon < 0 {
+				break
+			}
+			i += colon
+			if p.markerBefore(chunk, i) {
+				return true, nil
+			}
+			i++
+		}
+
+		// A line too long to scan either spans chunks, or a whole block of half its length, which
+		// then holds no newline. The check is loose, but finding the first newline of each block is
+		// cheap.
+		data := chunk[carry:]
+		head := bytes.IndexByte(data, '\n')
+		if head < 0 {
+			head = len(data)
+		}
+		if tail+head >= bufio.MaxScanTokenSize {
+			return true, nil
+		}
+		for block := data; len(block) >= longLineBlock; block = block[longLineBlock:] {
+			if bytes.IndexByte(block[:longLineBlock], '\n') < 0 {
+				return true, nil
+			}
+		}
+		if head == len(data) {
+			tail += len(data)
+		} else {
+			tail = len(data) - 1 - bytes.LastIndexByte(data, '\n')
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		carry = copy(buf, chunk[max(0, len(chunk)-markerLookback):])
+	}
+}
+
+// markerBefore reports whether the colon at b[i] may end a start or end marker of p. Any non-ASCII
+// byte before it is taken for a folded letter of a keyword.
+func (p *tagPatterns) markerBefore(b []byte, i int) bool {
+	if i == 0 {
+		return false
+	}
+	if p.markers != MarkersKeywords && (b[i-1] == '>' || b[i-1] == '<') {
+		return true
+	}
+	// Both keywords end with an ASCII letter no other letter folds onto.
+	if last := b[i-1] | 0x20; p.markers == MarkersArrows || last != 't' && last != 'd' {
+		return false
+	}
+	before := b[max(0, i-markerLookback):i]
+	if hasSuffixFold(before, startKeyword) || hasSuffixFold(before, endKeyword) {
+		return true
+	}
+	for _, c := range before {
+		if c >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuffixFold reports whether b ends with suffix, ignoring ASCII case.
+func hasSuffixFold(b, suffix []byte) bool {
+	return len(b) >= len(suffix) && bytes.EqualFold(b[len(b)-len(suffix):], suffix)
+}
@@ -0,0 +1,109 @@
+package brio
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMayHoldTagsKeepsEveryTag(t *testing.T) {
+	style := plugins.NewCommentStyle([]string{"#"}, []string{"/*", "*/"}, false)
+	lines := append(scannerLines, `/* >: {"a": []} */`, `/* end: a=b */`)
+	for _, markers := range []string{MarkersArrows, MarkersKeywords, MarkersBoth} {
+		patterns := patternsFor(style, Options{Markers: markers, Payload: PayloadJSON})
+		for _, line := range lines {
+			tagged := patterns.multiStartTag.MatchString(line) || patterns.multiEndTag.MatchString(line)
+			if !tagged {
+				continue
+			}
+			ok, err := patterns.mayHoldTags(strings.NewReader(line))
+			require.NoError(t, err)
+			assert.True(t, ok, "%s: %q", markers, line)
+		}
+	}
+}
+
+func TestMayHoldTags(t *testing.T) {
+	padding := strings.Repeat("x = 1\n", prescanChunkSize/6+1)
+	tests := []struct {
+		name    string
+		markers string
+		src     string
+		want    bool
+	}{
+		{"untagged", MarkersBoth, "def f(x: int) -> int:\n    return x\n", false},
+		{"arrow", MarkersBoth, "# >: {\"a\": []}\n", true},
+		{"keyword", MarkersBoth, "# END: a=b\n", true},
+		{"keyword with arrows only", MarkersArrows, "# start: {\"a\": []}\n", false},
+		{"arrow with keywords only", MarkersKeywords, "# >: {\"a\": []}\n", false},
+		{"folded keyword", MarkersKeywords, "# ſtart: {\"a\": []}\n", true},
+		{"marker split across chunks", MarkersBoth, padding[:prescanChunkSize-3] + "# start: a=b\n", true},
+		{"marker in last chunk", MarkersBoth, padding + padding + "# <: a=b\n", true},
+		{"long line", MarkersBoth, strings.Repeat("x", bufio.MaxScanTokenSize) + "\n", true},
+		{"long line across chunks", MarkersBoth, padding[:prescanChunkSize-10] + strings.Repeat("x", bufio.MaxScanTokenSize) + "\n" + padding, true},
+		{"long line at the end of a chunk", MarkersBoth, padding[:prescanChunkSize-40000] + strings.Repeat("x", 40000+30000) + "\n", true},
+		{"long untagged file", MarkersBoth, padding + padding, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := patternsFor(plugins.NewCommentStyle([]string{"#"}, nil, false), Options{Markers: tt.markers, Payload: PayloadJSON})
+			got, err := patterns.mayHoldTags(strings.NewReader(tt.src))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanFileSkipsUntaggedFiles(t *testing.T) {
+	dir := t.TempDir()
+	untagged := filepath.Join(dir, "plain.py")
+	require.NoError(t, os.WriteFile(untagged, []byte("def f():\n    return 1\n"), 0644))
+	tagged := filepath.Join(dir, "tagged.py")
+	require.NoError(t, os.WriteFile(tagged, []byte("# >: {\"a\": []}\nx = 1\n# <: {\"a\": []}\n"), 0644))
+
+	extractor := New(Options{})
+	snips, issues, err := extractor.ScanFile(untagged)
+	require.NoError(t, err)
+	assert.Empty(t, snips)
+	assert.Empty(t, issues)
+
+	snips, _, err = extractor.ScanFile(tagged)
+	require.NoError(t, err)
+	require.Len(t, snips, 1)
+	assert.Equal(t, []string{"x = 1"}, snips[0].Content)
+}
+
+// BenchmarkMayHoldTags reads a file without tags, which scanSource would parse line by line.
+func BenchmarkMayHoldTags(b *testing.B) {
+	var src bytes.Buffer
+	for src.Len() < 8<<20 {
+		src.WriteString("def handler(request: Request) -> Response:  # see the docs: https://example.com\n")
+		src.WriteString("    return respond(request, status=200)\n")
+	}
+	python, _ := plugins.Get(".py")
+	extractor := New(Options{})
+	patterns := patternsFor(python.GetCommentStyle(), extractor.opts)
+	b.SetBytes(int64(src.Len()))
+
+	b.Run("prescan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if ok, err := patterns.mayHoldTags(bytes.NewReader(src.Bytes())); err != nil || ok {
+				b.Fatal(ok, err)
+			}
+		}
+	})
+	b.Run("scanSource", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := extractor.scanSource("bench.py", "", python, bytes.NewReader(src.Bytes())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package brio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Processor transforms the snippets an Extractor finds before they are returned, e.g. to redact
+// secrets, truncate long snippets or add attributes. Process gets a copy of the snippet, but its
+// slices and maps are shared with it: replace them rather than modifying them in place.
+type Processor interface {
+	Process(Snippet) (Snippet, error)
+}
+
+// ProcessorFunc adapts a function to Processor.
+type ProcessorFunc func(Snippet) (Snippet, error)
+
+// Process calls f(s).
+func (f ProcessorFunc) Process(s Snippet) (Snippet, error) {
+	return f(s)
+}
+
+// ErrDrop can be returned by a Processor to leave the snippet out without an error.
+var ErrDrop = errors.New("drop")
+
+// Chain is a Processor running its processors one after another, each on what the one before it
+// returned. It stops at the first one returning an error, ErrDrop included.
+type Chain []Processor
+
+// Process passes s through the processors of c in order. The hash of s stays that of its content
+// before them.
+func (c Chain) Process(s Snippet) (Snippet, error) {
+	if s.source == nil {
+		s.source = s.Content
+	}
+	for _, p := range c {
+		var err error
+		if s, err = p.Process(s); err != nil {
+			return Snippet{}, err
+		}
+	}
+	return s, nil
+}
+
+// Apply passes each of snips through c and returns the results, leaving out those dropped. It stops
+// at the first error, returned with the snippet it happened on.
+func (c Chain) Apply(snips []Snippet) ([]Snippet, error) {
+	if len(c) == 0 {
+		return snips, nil
+	}
+	results := make([]Snippet, 0, len(snips))
+	for _, s := range snips {
+		processed, err := c.Process(s)
+		if errors.Is(err, ErrDrop) {
+			continue
+		}
+		if err != nil {
+			return results, fmt.Errorf("processing %s:%d: %w", SectionPath(s.File, s.Section), s.StartLine, err)
+		}
+		results = append(results, processed)
+	}
+	return results, nil
+}
+
+// NormalizeWhitespace returns a Processor cleaning up the whitespace of snippet content, for
+// codebases mixing tabs and spaces: unless tabWidth is 0, tabs are expanded to spaces up to the
+// next multiple of tabWidth columns, as expand(1) does, and with trimTrailing the whitespace ending
+// lines is stripped.
+func NormalizeWhitespace(tabWidth int, trimTrailing bool) Processor {
+	return ProcessorFunc(func(s Snippet) (Snippet, error) {
+		content := make([]string, len(s.Content))
+		for i, line := range s.Content {
+			if tabWidth > 0 {
+				line = expandTabs(line, tabWidth)
+			}
+			if trimTrailing {
+				line = strings.TrimRightFunc(line, unicode.IsSpace)
+			}
+			content[i] = line
+		}
+		s.Content = content
+		return s, nil
+	})
+}
+
+// expandTabs replaces the tabs of line by spaces up to the next multiple of width columns.
+func expandTabs(line string, width int) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	column := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := width - column%width
+			b.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		}
+		b.WriteRune(r)
+		column++
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package brio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	redact := ProcessorFunc(func(s Snippet) (Snippet, error) {
+		content := make([]string, len(s.Content))
+		for i, line := range s.Content {
+			content[i] = strings.ReplaceAll(line, "hunter2", "[REDACTED]")
+		}
+		s.Content = content
+		return s, nil
+	})
+	truncate := ProcessorFunc(func(s Snippet) (Snippet, error) {
+		s.Content = s.Content[:min(len(s.Content), 1)]
+		return s, nil
+	})
+	skip := ProcessorFunc(func(s Snippet) (Snippet, error) {
+		if s.Attr("skip") != "" {
+			return s, ErrDrop
+		}
+		return s, nil
+	})
+
+	original := []string{`password = "hunter2"`, "login(password)"}
+	snips := []Snippet{
+		{File: "a.py", StartLine: 2, Content: original},
+		{File: "b.py", StartLine: 5, Content: []string{"pass"}, Attrs: map[string][]string{"skip": {"yes"}}},
+	}
+	got, err := Chain{redact, truncate, skip}.Apply(snips)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, []string{`password = "[REDACTED]"`}, got[0].Content)
+	assert.Equal(t, `password = "hunter2"`, original[0], "the snippet given is left as it was")
+	assert.Equal(t, snips[0].Hash(), got[0].Hash(), "the hash is that of the content in the file")
+
+	failing := ProcessorFunc(func(s Snippet) (Snippet, error) {
+		return s, errors.New("boom")
+	})
+	_, err = Chain{failing}.Apply(snips)
+	assert.EqualError(t, err, "processing a.py:2: boom")
+
+	got, err = Chain(nil).Apply(snips)
+	require.NoError(t, err)
+	assert.Equal(t, snips, got)
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	original := []string{"\tif ok {  ", "\t\treturn x\t// done", "  \tpass\t", "\t"}
+	s := Snippet{Content: original}
+
+	got, err := NormalizeWhitespace(4, true).Process(s)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"    if ok {", "        return x    // done", "    pass", ""}, got.Content)
+	assert.Equal(t, "\tif ok {  ", original[0], "the snippet given is left as it was")
+
+	got, err = NormalizeWhitespace(2, false).Process(s)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"  if ok {  ", "    return x  // done", "    pass  ", "  "}, got.Content)
+
+	got, err = NormalizeWhitespace(0, true).Process(s)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"\tif ok {", "\t\treturn x\t// done", "  \tpass", ""}, got.Content)
+}
+
+func TestExtractorProcessors(t *testing.T) {
+	tempDir := t.TempDir()
+	content := "# >: {\"tests\": []}\ntoken = \"abc\"\n# <: {\"tests\": []}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.py"), []byte(content), 0644))
+
+	annotate := ProcessorFunc(func(s Snippet) (Snippet, error) {
+		s.Attrs = map[string][]string{"owner": {"security"}}
+		return s, nil
+	})
+	extractor := New(Options{Dir: tempDir, Processors: []Processor{annotate}})
+	snips, _, err := extractor.Extract(context.Background())
+	require.NoError(t, err)
+	require.Len(t, snips, 1)
+	assert.Equal(t, "security", snips[0].Attr("owner"))
+
+	var seen []Snippet
+	require.NoError(t, extractor.Each(context.Background(), func(s Snippet) error {
+		seen = append(seen, s)
+		return nil
+	}))
+	assert.Equal(t, snips, seen)
+
+	failing := New(Options{Dir: tempDir, Processors: []Processor{ProcessorFunc(func(s Snippet) (Snippet, error) {
+		return s, errors.New("boom")
+	})}})
+	_, _, err = failing.Extract(context.Background())
+	assert.ErrorContains(t, err, "boom")
+	err = failing.Each(context.Background(), func(Snippet) error { return nil })
+	assert.ErrorContains(t, err, "boom")
+}
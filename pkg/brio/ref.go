@@ -0,0 +1,65 @@
+package brio
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// OpenRef reads the files of the local git repository holding dir as they are at ref (a branch, a
+// tag, a commit or any revision git understands, such as HEAD~2), without checking it out. It returns
+// the path dir has in them, under a root named after the repository followed by @ref, such as
+// brio@main/src, to be used as Options.Dir. The files stay in memory until the program exits.
+func OpenRef(dir, ref string) (string, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to open the git repository of %s: %v", dir, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to open the git repository of %s: %v", dir, err)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %s: %v", ref, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+	tree, err := readCommit(commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+
+	top := worktree.Filesystem.Root()
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	sub, err := filepath.Rel(top, abs)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(filepath.Base(top)+"@"+ref, sub)
+	mount(root, subtree(tree, filepath.ToSlash(sub)))
+	return root, nil
+}
+
+// subtree returns the files of a below the directory dir, named relative to it.
+func subtree(a *archive, dir string) *archive {
+	if dir == "." {
+		return a
+	}
+	sub := &archive{files: make(map[string][]byte)}
+	for _, name := range a.names {
+		if rel, ok := strings.CutPrefix(name, dir+"/"); ok {
+			sub.names = append(sub.names, rel)
+			sub.files[rel] = a.files[name]
+		}
+	}
+	return sub
+}
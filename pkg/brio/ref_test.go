@@ -0,0 +1,36 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenRef(t *testing.T) {
+	dir := initRepo(t, map[string]string{"src/vendored.py": archivedSource})
+	// The working tree changing after the commit doesn't change what the ref holds.
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "src", "vendored.py"), []byte("# edited\n"), 0644))
+
+	for _, ref := range []string{"main", "v1.0.0", "HEAD"} {
+		t.Run(ref, func(t *testing.T) {
+			root, err := OpenRef(filepath.Join(dir, "src"), ref)
+			assert.Nil(t, err)
+			assert.Equal(t, filepath.Join(filepath.Base(dir)+"@"+ref, "src"), root)
+
+			snips, issues, err := New(Options{Dir: root}).Extract(context.Background())
+			assert.Nil(t, err)
+			assert.Empty(t, issues)
+			assert.Len(t, snips, 1)
+			assert.Equal(t, filepath.Join(root, "vendored.py"), snips[0].File)
+			assert.Equal(t, []string{"def vendored():", "    pass"}, snips[0].Content)
+		})
+	}
+
+	_, err := OpenRef(dir, "no-such-branch")
+	assert.ErrorContains(t, err, "unknown revision no-such-branch")
+	_, err = OpenRef(t.TempDir(), "main")
+	assert.ErrorContains(t, err, "failed to open the git repository")
+}
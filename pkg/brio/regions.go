@@ -0,0 +1,102 @@
+package brio
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// regionParser recognizes IDE folding markers that already structure a lot of code:
+// "#region Name" / "#endregion" (C#, also "//#region" in TypeScript and "# region" in Python)
+// and Xcode-style "// MARK: - Name" sections, which run until the next MARK or the end of the file.
+// Each region becomes a snippet whose only category is the region name.
+type regionParser struct {
+	openPattern  *regexp.Regexp
+	closePattern *regexp.Regexp
+	markPattern  *regexp.Regexp
+	open         []*snippetData // nested #region blocks, innermost last
+	mark         *snippetData   // current MARK section, if any
+}
+
+// newRegionParser returns a regionParser for a language commented in style. Markers must follow
+// its comment prefix, or be "#region" directives, so that code such as "region = get()" is left alone.
+func newRegionParser(style plugins.CommentStyle) *regionParser {
+	prefix := `^\s*(?:` + singlePrefixPattern(style) + `\s*#?|#)`
+	return &regionParser{
+		openPattern:  regexp.MustCompile(prefix + `region\b[ \t]*(.*)$`),
+		closePattern: regexp.MustCompile(prefix + `endregion\b`),
+		markPattern:  regexp.MustCompile(`^\s*` + singlePrefixPattern(style) + `\s*MARK:\s*(?:-\s*)?(.*)$`),
+	}
+}
+
+// feed processes one line and returns the regions it closes.
+// Lines that are not markers of a region are added to the content of that region.
+func (r *regionParser) feed(line string, lineNum int) []*snippetData {
+	var closed []*snippetData
+
+	switch {
+	case r.closePattern.MatchString(line):
+		if len(r.open) > 0 {
+			region := r.open[len(r.open)-1]
+			r.open = r.open[:len(r.open)-1]
+			// Unnamed regions only keep the nesting balanced.
+			if _, unnamed := region.categories[""]; !unnamed {
+				closed = append(closed, region)
+			}
+		}
+		r.appendLine(line, true)
+
+	case r.openPattern.MatchString(line):
+		r.appendLine(line, true)
+		name := r.openPattern.FindStringSubmatch(line)[1]
+		r.open = append(r.open, newRegionData(name, lineNum))
+
+	case r.markPattern.MatchString(line):
+		if r.mark != nil {
+			closed = append(closed, r.mark)
+		}
+		r.appendLine(line, false)
+		r.mark = nil
+		// A bare "MARK: -" is only a separator and ends the previous section.
+		if name := r.markPattern.FindStringSubmatch(line)[1]; strings.TrimSpace(name) != "" {
+			r.mark = newRegionData(name, lineNum)
+		}
+
+	default:
+		r.appendLine(line, true)
+	}
+
+	return closed
+}
+
+// finish returns the MARK section still open at the end of the file.
+// Unterminated #region blocks are dropped, just like unterminated tags.
+func (r *regionParser) finish() []*snippetData {
+	if r.mark == nil {
+		return nil
+	}
+	closed := []*snippetData{r.mark}
+	r.mark = nil
+	r.open = nil
+	return closed
+}
+
+// appendLine adds line to every open #region block and, if withMark is set, to the current MARK section.
+func (r *regionParser) appendLine(line string, withMark bool) {
+	for _, region := range r.open {
+		region.lines = append(region.lines, line)
+	}
+	if withMark && r.mark != nil {
+		r.mark.lines = append(r.mark.lines, line)
+	}
+}
+
+// newRegionData starts a region snippet named after the marker text.
+func newRegionData(name string, lineNum int) *snippetData {
+	return &snippetData{
+		categories: map[string][]string{strings.TrimSpace(name): {}},
+		startLine:  lineNum,
+		lines:      []string{},
+	}
+}
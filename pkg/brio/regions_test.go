@@ -0,0 +1,28 @@
+package brio
+
+import (
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionMarkers(t *testing.T) {
+	python := newRegionParser(plugins.CommentStyle{Single: "#"})
+	for _, line := range []string{"# region Models", "#region Models", "  # endregion"} {
+		assert.True(t, python.openPattern.MatchString(line) || python.closePattern.MatchString(line), line)
+	}
+
+	typescript := newRegionParser(plugins.CommentStyle{Single: "//"})
+	for _, line := range []string{"//#region Models", "// #region Models", "// region Models", "#region Models", "//#endregion"} {
+		assert.True(t, typescript.openPattern.MatchString(line) || typescript.closePattern.MatchString(line), line)
+	}
+
+	// Code that merely names regions isn't a marker.
+	for _, line := range []string{"region = get()", "endregion()", "  region.close()", "let x = region // note"} {
+		assert.False(t, typescript.openPattern.MatchString(line), line)
+		assert.False(t, typescript.closePattern.MatchString(line), line)
+		assert.False(t, python.openPattern.MatchString(line), line)
+		assert.False(t, python.closePattern.MatchString(line), line)
+	}
+}
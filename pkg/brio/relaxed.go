@@ -0,0 +1,111 @@
+package brio
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// relaxJSON rewrites a JSON5/HJSON-style tag payload into strict JSON so hand-written tags
+// don't have to be perfect. It accepts:
+//   - single-quoted strings: {'foundation': ['messages']}
+//   - unquoted keys and bare-word values: {foundation: [messages]}
+//   - trailing commas: {"foundation": ["messages",],}
+//
+// Strict JSON passes through unchanged. Anything else is left for json.Unmarshal to report.
+func relaxJSON(s string) string {
+	var out strings.Builder
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			end := closingQuote(runes, i)
+			out.WriteString(quoteJSON(unquoteRelaxed(runes[i+1:end], r)))
+			i = end
+
+		case r == ',':
+			// Drop the comma if only whitespace separates it from a closing bracket.
+			j := i + 1
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+			if j < len(runes) && (runes[j] == '}' || runes[j] == ']') {
+				continue
+			}
+			out.WriteRune(r)
+
+		case isBareWordRune(r):
+			j := i
+			for j < len(runes) && isBareWordRune(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			if isJSONLiteral(word) {
+				out.WriteString(word)
+			} else {
+				out.WriteString(quoteJSON(word))
+			}
+			i = j - 1
+
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	return out.String()
+}
+
+// closingQuote returns the index of the quote closing the string opened at runes[start],
+// or len(runes) if the string is unterminated.
+func closingQuote(runes []rune, start int) int {
+	quote := runes[start]
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(runes)
+}
+
+// unquoteRelaxed decodes the body of a single- or double-quoted string.
+func unquoteRelaxed(body []rune, quote rune) string {
+	if quote == '"' {
+		if s, err := strconv.Unquote(`"` + string(body) + `"`); err == nil {
+			return s
+		}
+	}
+	var out strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' && i+1 < len(body) {
+			i++
+		}
+		out.WriteRune(body[i])
+	}
+	return out.String()
+}
+
+// isBareWordRune reports whether r can appear in an unquoted key or value.
+func isBareWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_$-./@", r)
+}
+
+// isJSONLiteral reports whether word must stay unquoted: true, false, null or a number.
+func isJSONLiteral(word string) bool {
+	switch word {
+	case "true", "false", "null":
+		return true
+	}
+	return (unicode.IsDigit(rune(word[0])) || word[0] == '-') && json.Valid([]byte(word))
+}
+
+// quoteJSON encodes s as a JSON string.
+func quoteJSON(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,92 @@
+package brio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// RenderMarkdown renders snippets as Markdown: each one is headed by its path, relative to the
+// current directory when possible and with forward slashes, and fenced with its plugin's language
+// identifier.
+func RenderMarkdown(snips []Snippet) string {
+	return renderMarkdown(snips, false)
+}
+
+// RenderAnchoredMarkdown renders snippets like RenderMarkdown, numbered and with an anchor each
+// (see Snippet.Anchor), so that documents can link to them: "1. <a id="src-models-py-l57"></a>src/models.py:".
+// Snippets sharing an anchor get -2, -3... appended to the later ones.
+func RenderAnchoredMarkdown(snips []Snippet) string {
+	return renderMarkdown(snips, true)
+}
+
+func renderMarkdown(snips []Snippet, anchored bool) string {
+	wd, wdErr := os.Getwd()
+	var output strings.Builder
+	anchors := make(map[string]int)
+
+	for i, s := range snips {
+		relativePath := trimLongPathPrefix(s.File)
+		// If we successfully retrieved the current directory,
+		// try converting the snippet’s path into a relative path
+		if wdErr == nil {
+			if rp, err := filepath.Rel(wd, relativePath); err == nil {
+				relativePath = rp
+			}
+		}
+		// Headers read the same whichever the system.
+		relativePath = filepath.ToSlash(relativePath)
+
+		if anchored {
+			anchor := s.Anchor()
+			if anchors[anchor]++; anchors[anchor] > 1 {
+				anchor = fmt.Sprintf("%s-%d", anchor, anchors[anchor])
+			}
+			output.WriteString(fmt.Sprintf("%d. <a id=\"%s\"></a>", i+1, anchor))
+		}
+		output.WriteString(fmt.Sprintf("%s:\n", SectionPath(relativePath, s.Section)))
+		output.WriteString(fmt.Sprintf("```%s\n", s.Language()))
+		for _, line := range s.Content {
+			output.WriteString(line + "\n")
+		}
+		output.WriteString("```\n\n")
+	}
+
+	return output.String()
+}
+
+// Anchor returns the identifier documents link to the snippet by: its "_id" when it has one,
+// otherwise its path relative to the current directory and its start line, slugified, e.g.
+// "src-models-py-l57" for line 57 of src/models.py.
+func (s Snippet) Anchor() string {
+	if id := s.Attr("id"); id != "" {
+		return Slugify(id)
+	}
+	return Slugify(fmt.Sprintf("%s L%d", SectionPath(filepath.ToSlash(RelativePath(s.File)), s.Section), s.StartLine))
+}
+
+// Slugify turns a name into a lowercase, dash-separated identifier usable in file names and anchors.
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(slug.String(), "-")
+}
+
+// Language returns the Markdown fence language of the snippet, or "" if it has no plugin.
+func (s Snippet) Language() string {
+	if s.Plugin == nil {
+		return ""
+	}
+	return s.Plugin.GetMarkdownIdentifier()
+}
@@ -0,0 +1,37 @@
+package brio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "messages", Slugify("Messages"))
+	assert.Equal(t, "user-service-create", Slugify("User Service: create()"))
+	assert.Equal(t, "v2-3", Slugify("--v2.3--"))
+}
+
+func TestRenderAnchoredMarkdown(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	snips := []Snippet{
+		{File: filepath.Join(wd, "src", "models.py"), StartLine: 57, Content: []string{"class User:"}},
+		{File: filepath.Join(wd, "src", "queue.py"), StartLine: 3, Attrs: map[string][]string{"id": {"Queue Push"}}, Content: []string{"def push():"}},
+		{File: filepath.Join(wd, "src", "other.py"), StartLine: 1, Attrs: map[string][]string{"id": {"queue-push"}}, Content: []string{"pass"}},
+		{File: filepath.Join(wd, "book.ipynb"), Section: "cell 2", StartLine: 4, Content: []string{"x = 1"}},
+	}
+
+	assert.Equal(t, "src-models-py-l57", snips[0].Anchor())
+	assert.Equal(t, "queue-push", snips[1].Anchor())
+	assert.Equal(t, "book-ipynb-cell-2-l4", snips[3].Anchor())
+
+	assert.Equal(t, `1. <a id="src-models-py-l57"></a>src/models.py:
+`+"```\nclass User:\n```\n\n"+`2. <a id="queue-push"></a>src/queue.py:
+`+"```\ndef push():\n```\n\n"+`3. <a id="queue-push-2"></a>src/other.py:
+`+"```\npass\n```\n\n"+`4. <a id="book-ipynb-cell-2-l4"></a>book.ipynb (cell 2):
+`+"```\nx = 1\n```\n\n", RenderAnchoredMarkdown(snips))
+	assert.Equal(t, "src/models.py:\n```\nclass User:\n```\n\n", RenderMarkdown(snips[:1]))
+}
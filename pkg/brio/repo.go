@@ -0,0 +1,115 @@
+package brio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// Clone makes a shallow clone of the git repository at repoURL, checked out at ref (a branch or a
+// tag; "" means the default branch), without writing anything to disk. It returns the root its files
+// are named under, such as github.com/org/repo@main, to be used as Options.Dir. The files stay in
+// memory until the program exits.
+func Clone(ctx context.Context, repoURL, ref string) (string, error) {
+	repo, err := cloneRef(ctx, repoURL, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %v", repoURL, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %v", repoURL, err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %v", repoURL, err)
+	}
+	tree, err := readCommit(commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", repoURL, err)
+	}
+
+	if ref == "" {
+		ref = head.Name().Short()
+	}
+	root := repoRoot(repoURL, ref)
+	mount(root, tree)
+	return root, nil
+}
+
+// cloneRef clones ref of repoURL, trying it as a branch first and then as a tag.
+func cloneRef(ctx context.Context, repoURL, ref string) (*git.Repository, error) {
+	clone := func(name plumbing.ReferenceName) (*git.Repository, error) {
+		return git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+			URL:           repoURL,
+			ReferenceName: name,
+			SingleBranch:  true,
+			Depth:         1,
+			Tags:          git.NoTags,
+		})
+	}
+
+	if ref == "" || strings.HasPrefix(ref, "refs/") {
+		return clone(plumbing.ReferenceName(ref))
+	}
+	repo, err := clone(plumbing.NewBranchReferenceName(ref))
+	var noMatch git.NoMatchingRefSpecError
+	if errors.As(err, &noMatch) || errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return clone(plumbing.NewTagReferenceName(ref))
+	}
+	return repo, err
+}
+
+// readCommit reads the regular files of the tree of commit.
+func readCommit(commit *object.Commit) (*archive, error) {
+	files, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	a := &archive{files: make(map[string][]byte)}
+	err = files.ForEach(func(f *object.File) error {
+		if f.Mode != filemode.Regular && f.Mode != filemode.Executable {
+			return nil
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		a.add(f.Name, content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(a.names)
+	return a, nil
+}
+
+// repoRoot returns the root the files of ref of repoURL are named under: its host and path without
+// the scheme or the .git suffix, followed by @ref.
+func repoRoot(repoURL, ref string) string {
+	name := repoURL
+	if u, err := url.Parse(repoURL); err == nil && u.Scheme != "" {
+		name = u.Host + u.Path
+	} else if i := strings.Index(name, "@"); i >= 0 {
+		// scp-like syntax: git@github.com:org/repo.git
+		name = strings.Replace(name[i+1:], ":", "/", 1)
+	}
+	name = strings.TrimSuffix(strings.Trim(name, "/"), ".git")
+	return filepath.FromSlash(name) + "@" + ref
+}
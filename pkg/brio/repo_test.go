@@ -0,0 +1,80 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// initRepo creates a git repository on branch main with one commit holding files.
+func initRepo(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	assert.Nil(t, err)
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+		_, err = worktree.Add(name)
+		assert.Nil(t, err)
+	}
+	commit, err := worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "brio", Email: "brio@example.com", When: time.Now()},
+	})
+	assert.Nil(t, err)
+	_, err = repo.CreateTag("v1.0.0", commit, nil)
+	assert.Nil(t, err)
+	return dir
+}
+
+func TestClone(t *testing.T) {
+	dir := initRepo(t, map[string]string{"src/vendored.py": archivedSource})
+
+	for _, ref := range []string{"main", "v1.0.0", ""} {
+		t.Run(ref, func(t *testing.T) {
+			root, err := Clone(context.Background(), "file://"+dir, ref)
+			assert.Nil(t, err)
+			want := ref
+			if ref == "" {
+				want = "main"
+			}
+			assert.Equal(t, strings.TrimPrefix(dir, "/")+"@"+want, root)
+
+			snips, issues, err := New(Options{Dir: root}).Extract(context.Background())
+			assert.Nil(t, err)
+			assert.Empty(t, issues)
+			assert.Len(t, snips, 1)
+			assert.Equal(t, filepath.Join(root, "src", "vendored.py"), snips[0].File)
+			assert.Equal(t, []string{"def vendored():", "    pass"}, snips[0].Content)
+		})
+	}
+
+	_, err := Clone(context.Background(), "file://"+dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestRepoRoot(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/org/repo", "github.com/org/repo@main"},
+		{"https://github.com/org/repo.git", "github.com/org/repo@main"},
+		{"git@github.com:org/repo.git", "github.com/org/repo@main"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, filepath.FromSlash(tt.want), repoRoot(tt.url, "main"))
+	}
+}
@@ -0,0 +1,94 @@
+package brio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// s3Store writes objects to an Amazon S3 bucket, or to an S3-compatible service at
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL.
+type s3Store struct {
+	bucket string
+	client *http.Client
+	// s3 is set up on the first upload.
+	s3 *s3.Client
+}
+
+// setup loads the configuration of the AWS SDK, as the AWS CLI does: the region and credentials of
+// the environment, of the profile AWS_PROFILE of ~/.aws/config and ~/.aws/credentials (static keys,
+// credential_process, role_arn, SSO), of a web identity token, or of the task or instance role.
+// Credentials that expire are refreshed before they do, so long --watch runs keep uploading.
+func (s *s3Store) setup(ctx context.Context) error {
+	// The SDK's own client is kept unless the transport is a custom one, so that AWS_CA_BUNDLE applies.
+	var client aws.HTTPClient = s.client
+	if s.client.Transport == nil {
+		client = awshttp.NewBuildableClient().WithTimeout(s.client.Timeout)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithHTTPClient(client))
+	if err != nil {
+		return fmt.Errorf("failed to load the AWS configuration: %v", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	s.s3 = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// Custom endpoints, and buckets with dots, which the certificate of virtual-hosted buckets
+		// doesn't cover, are addressed path-style.
+		o.UsePathStyle = o.BaseEndpoint != nil || strings.Contains(s.bucket, ".")
+		// S3-compatible services don't all take the checksums the SDK adds by default.
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+	})
+	return nil
+}
+
+func (s *s3Store) put(ctx context.Context, key string, body []byte, contentType string) error {
+	if s.s3 == nil {
+		if err := s.setup(ctx); err != nil {
+			return err
+		}
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	}
+	_, err := s.s3.PutObject(ctx, input)
+	// A bucket of another region answers where it is.
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		if region := respErr.Response.Header.Get("X-Amz-Bucket-Region"); region != "" && region != s.s3.Options().Region {
+			input.Body = bytes.NewReader(body)
+			_, err = s.s3.PutObject(ctx, input, func(o *s3.Options) { o.Region = region })
+		}
+	}
+	if err != nil {
+		return s3Error(err)
+	}
+	return nil
+}
+
+// s3Error returns err as "S3 answered 403 Forbidden: Code: message" when S3 answered with an error.
+func s3Error(err error) error {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return err
+	}
+	status := respErr.HTTPStatusCode()
+	msg := fmt.Sprintf("S3 answered %d %s", status, http.StatusText(status))
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() != "" {
+		msg += fmt.Sprintf(": %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,350 @@
+package brio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// ScanFile returns every snippet tagged in filePath, whatever its categories, along with the problems
+// found in its annotations. The Options.PathDefaults of the file are filled in.
+func (e *Extractor) ScanFile(filePath string) ([]Snippet, []Issue, error) {
+	snips, issues, err := e.scanFile(filePath)
+	return e.applyPathDefaults(filePath, snips), issues, err
+}
+
+// scanFile returns the snippets tagged in filePath and the problems of its annotations, as tagged.
+func (e *Extractor) scanFile(filePath string) ([]Snippet, []Issue, error) {
+	plugin, _, ok := e.Plugin(filePath)
+	if !ok {
+		return nil, nil, fileError(filePath, FailureUnsupported, fmt.Errorf("no plugin found for file type: %s", filePath))
+	}
+	if sp, ok := plugin.(plugins.SnippetParser); ok {
+		return scanWithParser(filePath, plugin, sp)
+	}
+	if dp, ok := plugin.(plugins.DocumentPlugin); ok {
+		return e.scanDocument(filePath, dp)
+	}
+
+	if e.skippable(plugin) {
+		tagged, err := e.mayHoldTags(filePath, plugin)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !tagged {
+			return nil, nil, nil
+		}
+	}
+
+	f, err := openFile(filePath)
+	if err != nil {
+		return nil, nil, fileError(filePath, FailureUnreadable, fmt.Errorf("failed to open file %s: %v", filePath, err))
+	}
+	defer f.Close()
+	snips, issues, err := e.scanSource(filePath, "", plugin, f)
+	if err != nil {
+		return nil, nil, readError(filePath, err)
+	}
+	return snips, issues, nil
+}
+
+// skippable reports whether files of plugin hold no snippet unless they hold a tag marker, so that
+// mayHoldTags can rule them out. Regions, custom markers and the tagged blocks of composite files need
+// no marker.
+func (e *Extractor) skippable(plugin plugins.Plugin) bool {
+	_, composite := plugin.(plugins.CompositePlugin)
+	return !e.opts.Regions && len(e.opts.CustomMarkers) == 0 && !composite
+}
+
+// mayHoldTags reports whether filePath, written in the language of plugin, may hold a tag.
+func (e *Extractor) mayHoldTags(filePath string, plugin plugins.Plugin) (bool, error) {
+	f, err := openFile(filePath)
+	if err != nil {
+		return false, fileError(filePath, FailureUnreadable, fmt.Errorf("failed to open file %s: %v", filePath, err))
+	}
+	defer f.Close()
+	tagged, err := patternsFor(plugin.GetCommentStyle(), e.opts).mayHoldTags(f)
+	if err != nil {
+		return false, readError(filePath, err)
+	}
+	return tagged, nil
+}
+
+// scanDocument scans each section of a document (e.g. the code cells of a notebook) on its own.
+func (e *Extractor) scanDocument(filePath string, document plugins.DocumentPlugin) ([]Snippet, []Issue, error) {
+	content, err := ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fileError(filePath, FailureUnreadable, fmt.Errorf("failed to open file %s: %v", filePath, err))
+	}
+	sections, err := document.Sections(content)
+	if err != nil {
+		return nil, nil, fileError(filePath, FailureUndecodable, fmt.Errorf("failed to parse file %s: %v", filePath, err))
+	}
+
+	var snips []Snippet
+	var issues []Issue
+	for _, section := range sections {
+		s, i, err := e.scanSource(filePath, section.Name, section.Plugin, bytes.NewReader(section.Content))
+		if err != nil {
+			return nil, nil, readError(filePath, err)
+		}
+		snips = append(snips, s...)
+		issues = append(issues, i...)
+	}
+	return snips, issues, nil
+}
+
+// scanSource returns the snippets tagged in the source read from r, written in the language of
+// plugin. section names the part of filePath the source comes from, if it isn't the whole file.
+func (e *Extractor) scanSource(filePath, section string, plugin plugins.Plugin, r io.Reader) ([]Snippet, []Issue, error) {
+	// Composite files (e.g. Vue components) switch plugins between their blocks.
+	composite, isComposite := plugin.(plugins.CompositePlugin)
+	block := plugin
+	if isComposite {
+		block = composite.OuterBlock()
+	}
+	parser := newCommentParser(block, e.opts)
+	scanner := bufio.NewScanner(r)
+
+	var regions *regionParser
+	if e.opts.Regions {
+		regions = newRegionParser(plugin.GetCommentStyle())
+	}
+
+	var snips []Snippet
+	var issues []Issue
+	report := func(code string, line int, format string, args ...interface{}) {
+		issues = append(issues, Issue{File: filePath, Section: section, Line: line, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var custom *customParser
+	if len(e.opts.CustomMarkers) > 0 {
+		custom = newCustomParser(e.opts.CustomMarkers, parser.parseTag)
+	}
+
+	// collect turns finished snippet data into a snippet.
+	collect := func(data *snippetData, endLine int) {
+		categories, attrs := splitAttrs(data.categories)
+		snippetPlugin := plugin
+		if data.plugin != nil {
+			snippetPlugin = data.plugin
+		}
+		snips = append(snips, Snippet{
+			File:       filePath,
+			Section:    section,
+			StartLine:  data.startLine,
+			EndLine:    endLine,
+			Categories: categories,
+			Attrs:      attrs,
+			Content:    data.lines,
+			Plugin:     snippetPlugin,
+		})
+	}
+
+	var activeSnippet *snippetData
+	var taggedBlock *snippetData // snippet made of a whole block tagged on its opening line
+	var held []string            // lines of the open multi-line comment, while a snippet is active
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		switched := false
+		if isComposite {
+			var next plugins.Plugin
+			if next, switched = composite.SwitchBlock(block, line); switched {
+				if taggedBlock != nil {
+					collect(taggedBlock, lineNum)
+					taggedBlock = nil
+				}
+				block = next
+				parser = newCommentParser(block, e.opts)
+				// A snippet spanning several blocks is fenced in the language of the whole file.
+				if activeSnippet != nil {
+					activeSnippet.plugin = plugin
+				}
+				if tagged, ok := block.(plugins.TaggedBlock); ok && tagged.BlockTag() != "" {
+					data, err := parser.parseTag(tagged.BlockTag())
+					if err != nil {
+						report(CodeInvalidTag, lineNum, "ignoring invalid tag: %v", err)
+					} else {
+						taggedBlock = &snippetData{categories: data, startLine: lineNum, lines: []string{}, plugin: block}
+					}
+				}
+			}
+		}
+		if taggedBlock != nil && !switched {
+			taggedBlock.lines = append(taggedBlock.lines, line)
+		}
+
+		if regions != nil {
+			for _, region := range regions.feed(line, lineNum) {
+				collect(region, lineNum)
+			}
+		}
+
+		if custom != nil {
+			for _, data := range custom.feed(line, lineNum, report) {
+				collect(data, lineNum)
+			}
+		}
+
+		inComment := parser.inMultiline
+		isStart, isEnd, data, err := parser.parseLine(line, lineNum)
+		if err != nil {
+			at := lineNum
+			var unclosed unclosedPayloadError
+			if errors.As(err, &unclosed) {
+				at = unclosed.line
+			}
+			report(CodeInvalidTag, at, "ignoring invalid tag: %v", err)
+		}
+
+		if isStart || isEnd {
+			held = nil
+		}
+		if isStart {
+			if activeSnippet != nil {
+				report(CodeUnmatchedTag, activeSnippet.startLine, "start tag is never closed")
+			}
+			activeSnippet = &snippetData{
+				categories: data,
+				startLine:  lineNum,
+				lines:      []string{},
+				plugin:     block,
+			}
+			continue
+		}
+
+		if isEnd {
+			if activeSnippet == nil {
+				report(CodeUnmatchedTag, lineNum, "end tag without a matching start tag")
+				continue
+			}
+			collect(activeSnippet, lineNum)
+			activeSnippet = nil
+			continue
+		}
+
+		// Only collect lines if we have an active snippet and the line is not part of a comment or tag.
+		// Lines of a multi-line comment are held until it closes without a tag, and kept if they are
+		// code or comments are included.
+		if activeSnippet != nil && parser.pending == nil {
+			held = append(held, line)
+			if parser.inMultiline {
+				continue
+			}
+			if !inComment || e.opts.IncludeComments || block.GetCommentStyle().MultiIsCode {
+				activeSnippet.lines = append(activeSnippet.lines, held...)
+			}
+			held = nil
+		}
+	}
+	if parser.pending != nil {
+		report(CodeInvalidTag, parser.pending.line, "ignoring invalid tag: %v", unclosedPayloadError{line: parser.pending.line})
+	}
+	if activeSnippet != nil {
+		report(CodeUnmatchedTag, activeSnippet.startLine, "start tag is never closed")
+	}
+	if taggedBlock != nil {
+		report(CodeUnmatchedTag, taggedBlock.startLine, "tagged block is never closed")
+	}
+	if regions != nil {
+		for _, region := range regions.finish() {
+			collect(region, lineNum)
+		}
+	}
+	if custom != nil {
+		custom.finish(report)
+	}
+
+	return snips, issues, scanner.Err()
+}
+
+// scanWithParser returns the snippets a SnippetParser plugin finds in filePath.
+func scanWithParser(filePath string, plugin plugins.Plugin, parser plugins.SnippetParser) ([]Snippet, []Issue, error) {
+	content, err := ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fileError(filePath, FailureUnreadable, fmt.Errorf("failed to open file %s: %v", filePath, err))
+	}
+
+	found, problems, err := parser.ParseSnippets(filePath, content)
+	if err != nil {
+		return nil, nil, fileError(filePath, FailurePlugin, fmt.Errorf("failed to parse file %s: %v", filePath, err))
+	}
+
+	var snips []Snippet
+	for _, s := range found {
+		categories, attrs := splitAttrs(s.Tags)
+		snips = append(snips, Snippet{
+			File:       filePath,
+			StartLine:  s.StartLine,
+			EndLine:    s.EndLine,
+			Categories: categories,
+			Attrs:      attrs,
+			Content:    s.Content,
+			Plugin:     plugin,
+		})
+	}
+	var issues []Issue
+	for _, p := range problems {
+		issues = append(issues, Issue{File: filePath, Line: p.Line, Code: CodePluginIssue, Message: p.Message})
+	}
+	return snips, issues, nil
+}
+
+// Kinds of FileError.
+const (
+	FailureUnsupported = "unsupported" // no plugin handles the file
+	FailureUnreadable  = "unreadable"  // the file couldn't be opened or read
+	FailureOversized   = "oversized"   // a line of the file is too long to scan
+	FailureUndecodable = "undecodable" // the file's container format (e.g. notebook JSON) is invalid
+	FailurePlugin      = "plugin"      // the plugin parsing the file failed
+)
+
+// failureCodes maps the kinds of FileError to their diagnostic code.
+var failureCodes = map[string]string{
+	FailureUnsupported: CodeUnsupportedFile,
+	FailureUnreadable:  CodeUnreadableFile,
+	FailureOversized:   CodeOversizedFile,
+	FailureUndecodable: CodeUndecodableFile,
+	FailurePlugin:      CodePluginFailure,
+}
+
+// FileError reports a file that couldn't be scanned, and why.
+type FileError struct {
+	File string
+	Kind string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the diagnostic code of the kind of failure.
+func (e *FileError) Code() string {
+	return failureCodes[e.Kind]
+}
+
+// fileError returns a FileError of the given kind for filePath.
+func fileError(filePath, kind string, err error) *FileError {
+	return &FileError{File: filePath, Kind: kind, Err: err}
+}
+
+// readError returns the FileError of a failure while reading filePath line by line.
+func readError(filePath string, err error) *FileError {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fileError(filePath, FailureOversized, fmt.Errorf("failed to read file %s: a line is longer than %d bytes", filePath, bufio.MaxScanTokenSize))
+	}
+	return fileError(filePath, FailureUnreadable, fmt.Errorf("failed to read file %s: %v", filePath, err))
+}
@@ -0,0 +1,176 @@
+package brio
+
+import "strings"
+
+// The scanner below finds single line tags without regular expressions. Most lines of a file hold
+// no tag, and running startPattern and endPattern over each of them dominated the time of a scan;
+// the scanner rejects them with a few byte comparisons. It matches exactly what the patterns
+// match, and falls back to them for lines it can't handle (see findSingleTag).
+
+// hasSingleTag reports whether line holds a single line start or end tag.
+func (p *tagPatterns) hasSingleTag(line string) bool {
+	if _, ok := p.findSingleTag(line, true); ok {
+		return true
+	}
+	_, ok := p.findSingleTag(line, false)
+	return ok
+}
+
+// findSingleTag returns the payload of the single line start tag (or end tag, if start is false)
+// on line, as captured by startPattern (or endPattern).
+func (p *tagPatterns) findSingleTag(line string, start bool) (string, bool) {
+	if !p.hasMarker(line, start) {
+		return "", false
+	}
+	// Case-insensitive matching folds some non-ASCII letters onto ASCII ones (ſ is s), which the
+	// scanner doesn't do.
+	if !isASCII(line) {
+		pattern := p.endPattern
+		if start {
+			pattern = p.startPattern
+		}
+		if m := pattern.FindStringSubmatch(line); m != nil {
+			return m[1], true
+		}
+		return "", false
+	}
+
+	for i := 0; i < len(line); i++ {
+		for _, prefix := range p.prefixes {
+			if !hasPrefixFold(line[i:], prefix) {
+				continue
+			}
+			j, ok := p.matchMarker(line, skipSpace(line, i+len(prefix)), start)
+			if !ok {
+				continue
+			}
+			payload := line[skipSpace(line, j):]
+			if p.isPayload(payload) {
+				return payload, true
+			}
+		}
+	}
+	return "", false
+}
+
+// hasMarker reports whether a start (or end) marker appears anywhere on line. Every marker ends
+// with a colon, so only the bytes before each colon are looked at.
+func (p *tagPatterns) hasMarker(line string, start bool) bool {
+	arrow, keyword := byte('<'), "end:"
+	if start {
+		arrow, keyword = '>', "start:"
+	}
+	for i := strings.IndexByte(line, ':'); i >= 0; {
+		if p.markers != MarkersKeywords && i > 0 && line[i-1] == arrow {
+			return true
+		}
+		if p.markers != MarkersArrows && i+1 >= len(keyword) && strings.EqualFold(line[i+1-len(keyword):i+1], keyword) {
+			return true
+		}
+		next := strings.IndexByte(line[i+1:], ':')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+// matchMarker reports whether a start (or end) marker begins at line[i:], and returns the index
+// following it.
+func (p *tagPatterns) matchMarker(line string, i int, start bool) (int, bool) {
+	arrow, keyword := byte('<'), "end:"
+	if start {
+		arrow, keyword = '>', "start:"
+	}
+	if p.markers != MarkersKeywords && i+1 < len(line) && line[i] == arrow && line[i+1] == ':' {
+		return i + 2, true
+	}
+	if p.markers != MarkersArrows && hasPrefixFold(line[i:], keyword) && (i == 0 || !isWordByte(line[i-1])) {
+		return i + len(keyword), true
+	}
+	return 0, false
+}
+
+// isPayload reports whether s, the rest of a line following a marker, is a tag payload in the
+// format of p: a JSON object, key=value pairs or, in YAML, a mapping.
+func (p *tagPatterns) isPayload(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s[0] == '{' || isKVPayload(s) {
+		return true
+	}
+	if p.payload == PayloadYAML {
+		c := s[0]
+		return (isWordByte(c) || c == '"' || c == '\'' || c == '-') && strings.IndexByte(s[1:], ':') >= 0
+	}
+	return false
+}
+
+// isKVPayload reports whether s is made of key=value pairs only, as matched by kvPayload followed
+// by trailing spaces.
+func isKVPayload(s string) bool {
+	i := 0
+	for {
+		// key
+		keyStart := i
+		for i < len(s) && (isWordByte(s[i]) || s[i] == '.' || s[i] == '-') {
+			i++
+		}
+		if i == keyStart || i == len(s) || s[i] != '=' {
+			return false
+		}
+		i++
+		// value
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' {
+			i++
+		}
+		// Pairs are separated by spaces and tabs; anything else may only trail.
+		blanks := i
+		for blanks < len(s) && (s[blanks] == ' ' || s[blanks] == '\t') {
+			blanks++
+		}
+		if rest := skipSpace(s, blanks); rest == len(s) {
+			return true
+		}
+		if blanks == i || blanks < len(s) && isSpace(s[blanks]) {
+			return false
+		}
+		i = blanks
+	}
+}
+
+// skipSpace returns the index of the first byte of s from i on that isn't a space, as matched
+// by \s.
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+// isSpace reports whether c is matched by \s.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// isWordByte reports whether c is matched by \w.
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// hasPrefixFold reports whether s begins with prefix, ignoring ASCII case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// isASCII reports whether s holds ASCII characters only.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
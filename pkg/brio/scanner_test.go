@@ -0,0 +1,145 @@
+package brio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+)
+
+// scannerLines are lines the scanner must treat exactly like the regular expressions do.
+var scannerLines = []string{
+	``,
+	`def send(message):`,
+	`    return {"key": value}  # not a tag: {}`,
+	`# >: {"foundation": ["messages"]}`,
+	`# <: {"foundation": ["messages"]}`,
+	`#>:{"a": []}`,
+	`x = 1  # >: {"a": []}`,
+	`# start: {"a": []}`,
+	`# END: {"a": []}`,
+	`# Start:foundation=messages`,
+	`# restart: {"a": []}`,
+	`#start: {"a": []}`,
+	`# >: foundation=messages,alerts tests=messages`,
+	`# >: foundation=messages  `,
+	`# >: foundation=messages tests`,
+	`# >: foundation=a=b`,
+	"# >: foundation=messages\ttests=x",
+	"# >: foundation=messages\r",
+	"# >: a=b\rc=d",
+	`# >: foundation: [messages]`,
+	`# >: "foundation": messages`,
+	`# >: -x: y`,
+	`# start: the server`,
+	`# start: nothing to see`,
+	`# >:`,
+	`# >: `,
+	`# >: }`,
+	`# note: >: {"a": []}`,
+	`# # >: {"a": []}`,
+	`// >: {"a": []}`,
+	`// end: {"a": []}`,
+	`-- >: {"a": []}`,
+	`REM start: {"a": []}`,
+	`rem >: {"a": []}`,
+	`url = "http://x" // >: {"a": []}`,
+	`# >: {"café": ["crème"]}`,
+	`# ſtart: {"a": []}`,
+	`# >: a=b :`,
+}
+
+func TestFindSingleTagMatchesPatterns(t *testing.T) {
+	styles := []plugins.CommentStyle{
+		plugins.NewCommentStyle([]string{"#"}, nil, false),
+		plugins.NewCommentStyle([]string{"//"}, []string{"/*", "*/"}, false),
+		plugins.NewCommentStyle([]string{"--", "#"}, nil, false),
+		plugins.NewCommentStyle([]string{"REM", "::"}, nil, false),
+		plugins.NewCommentStyle(nil, []string{"<!--", "-->"}, false),
+	}
+
+	for _, style := range styles {
+		for _, markers := range []string{MarkersArrows, MarkersKeywords, MarkersBoth} {
+			for _, payload := range []string{PayloadJSON, PayloadYAML} {
+				patterns := patternsFor(style, Options{Markers: markers, Payload: payload})
+				for _, line := range scannerLines {
+					for _, start := range []bool{true, false} {
+						pattern := patterns.endPattern
+						if start {
+							pattern = patterns.startPattern
+						}
+						want, wantOK := "", false
+						if m := pattern.FindStringSubmatch(line); m != nil {
+							want, wantOK = m[1], true
+						}
+						got, ok := patterns.findSingleTag(line, start)
+						name := fmt.Sprintf("%q %s %s start=%v: %q", style.SinglePrefixes(), markers, payload, start, line)
+						assert.Equal(t, wantOK, ok, name)
+						assert.Equal(t, want, got, name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPatternsForIsCached(t *testing.T) {
+	style := plugins.NewCommentStyle([]string{"#"}, nil, false)
+	opts := Options{Markers: MarkersBoth, Payload: PayloadJSON}
+	assert.Same(t, patternsFor(style, opts), patternsFor(style, opts))
+	assert.NotSame(t, patternsFor(style, opts), patternsFor(style, Options{Markers: MarkersArrows, Payload: PayloadJSON}))
+}
+
+// benchmarkSource returns a Python file of about 10,000 lines, one in a hundred of them a tag.
+func benchmarkSource() []byte {
+	var src bytes.Buffer
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&src, "# >: {\"tests\": [\"case%d\"]}\n", i)
+		for j := 0; j < 48; j++ {
+			fmt.Fprintf(&src, "def handler_%d_%d(request: Request) -> Response:  # see the docs: https://example.com\n", i, j)
+			src.WriteString("    return respond(request, status=200)\n")
+		}
+		fmt.Fprintf(&src, "# <: {\"tests\": [\"case%d\"]}\n", i)
+	}
+	return src.Bytes()
+}
+
+func BenchmarkFindSingleTag(b *testing.B) {
+	lines := strings.Split(string(benchmarkSource()), "\n")
+	patterns := patternsFor(plugins.NewCommentStyle([]string{"#"}, nil, false), Options{Markers: MarkersBoth, Payload: PayloadJSON})
+
+	b.Run("scanner", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				if _, ok := patterns.findSingleTag(line, true); !ok {
+					patterns.findSingleTag(line, false)
+				}
+			}
+		}
+	})
+	b.Run("regexp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, line := range lines {
+				if !patterns.startPattern.MatchString(line) {
+					patterns.endPattern.MatchString(line)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkScanSource(b *testing.B) {
+	src := benchmarkSource()
+	python, _ := plugins.Get(".py")
+	extractor := New(Options{})
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := extractor.scanSource("bench.py", "", python, bytes.NewReader(src)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
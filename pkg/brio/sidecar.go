@@ -0,0 +1,201 @@
+package brio
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SidecarFile is the name of the files tagging whole files of their directory, so that files
+// without room for tags, such as SQL schemas or generated code, take part in extraction. Each maps
+// globs, relative to its directory as for Options.Pattern, to the tag payload the files they match
+// get, as YAML or JSON:
+//
+//	"*.sql": {foundation: [billing]}
+//	"legacy/*.sql": {foundation: [billing], _owner: [team-db]}
+const SidecarFile = ".briotags"
+
+// sidecarRule tags the files matching pattern with tags.
+type sidecarRule struct {
+	pattern string
+	tags    map[string][]string
+}
+
+// sidecar is a parsed SidecarFile, with the problems found in it.
+type sidecar struct {
+	rules  []sidecarRule
+	issues []Issue
+}
+
+// sidecars caches the SidecarFile of each directory an Extractor reads.
+type sidecars struct {
+	mu    sync.Mutex
+	byDir map[string]*sidecar
+}
+
+// sidecarLine finds the line yaml.v3 errors are at.
+var sidecarLine = regexp.MustCompile(`line (\d+)`)
+
+// SidecarSnippets returns the snippets the SidecarFile of the directory of filePath, or of a
+// directory above it, up to Options.Dir or the working directory, tag filePath with: the whole
+// file, with the categories and attributes of every rule matching it, the nearest files' attributes
+// first. The problems of the sidecar files read for the first time are returned too.
+func (e *Extractor) SidecarSnippets(filePath string) ([]Snippet, []Issue) {
+	var issues []Issue
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, nil
+	}
+	tags := make(map[string][]string)
+	matched := false
+	for _, dir := range e.sidecarDirs(abs) {
+		sc, fresh := e.loadSidecar(dir)
+		if fresh {
+			issues = append(issues, sc.issues...)
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			continue
+		}
+		for _, rule := range sc.rules {
+			for _, pattern := range expandBraces(slashPattern(rule.pattern)) {
+				if ok, _ := MatchPath(pattern, rel); ok {
+					mergeTags(tags, rule.tags)
+					matched = true
+					break
+				}
+			}
+		}
+	}
+	if !matched {
+		return nil, issues
+	}
+
+	content, err := ReadFile(filePath)
+	if err != nil {
+		// Scanning the file reports it.
+		return nil, issues
+	}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, issues
+	}
+	lines := strings.Split(text, "\n")
+	plugin, _, _ := e.Plugin(filePath)
+	categories, attrs := splitAttrs(tags)
+	s := Snippet{File: filePath, StartLine: 1, EndLine: len(lines), Categories: categories, Attrs: attrs, Content: lines, Plugin: plugin}
+	return e.applyPathDefaults(filePath, []Snippet{s}), issues
+}
+
+// mergeTags adds the categories of from to tags, with the domains of both, and the attributes tags
+// doesn't have yet.
+func mergeTags(tags, from map[string][]string) {
+	for key, values := range from {
+		existing, ok := tags[key]
+		if strings.HasPrefix(key, "_") {
+			if !ok {
+				tags[key] = values
+			}
+			continue
+		}
+		merged := append([]string{}, existing...)
+		for _, v := range values {
+			if !slices.Contains(merged, v) {
+				merged = append(merged, v)
+			}
+		}
+		tags[key] = merged
+	}
+}
+
+// sidecarDirs returns the directories whose SidecarFile may tag the file at the absolute path abs,
+// nearest first: its own, then those above it up to Options.Dir, or the working directory, when
+// abs is below it.
+func (e *Extractor) sidecarDirs(abs string) []string {
+	dir := filepath.Dir(abs)
+	root, err := filepath.Abs(e.opts.Dir)
+	if err != nil || !within(dir, root) {
+		if root, err = filepath.Abs("."); err != nil || !within(dir, root) {
+			return []string{dir}
+		}
+	}
+	dirs := []string{dir}
+	for !equalPaths(dir, root) {
+		dir = filepath.Dir(dir)
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// within reports whether the absolute path dir is root or below it.
+func within(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// loadSidecar returns the SidecarFile of dir, parsed once per Extractor; fresh is set the first
+// time, so that its problems are reported once.
+func (e *Extractor) loadSidecar(dir string) (sc *sidecar, fresh bool) {
+	e.sidecars.mu.Lock()
+	defer e.sidecars.mu.Unlock()
+	key := filepath.Clean(dir)
+	if sc, ok := e.sidecars.byDir[key]; ok {
+		return sc, false
+	}
+	if e.sidecars.byDir == nil {
+		e.sidecars.byDir = make(map[string]*sidecar)
+	}
+	sc = parseSidecar(filepath.Join(dir, SidecarFile))
+	e.sidecars.byDir[key] = sc
+	return sc, true
+}
+
+// parseSidecar reads the SidecarFile at path; a missing file has no rules.
+func parseSidecar(path string) *sidecar {
+	sc := &sidecar{}
+	content, err := ReadFile(path)
+	if err != nil {
+		return sc
+	}
+	report := func(line int, format string, args ...interface{}) {
+		sc.issues = append(sc.issues, Issue{File: RelativePath(path), Line: line, Code: CodeInvalidTag, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		line := 1
+		if m := sidecarLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		report(line, "invalid %s: %v", SidecarFile, err)
+		return sc
+	}
+	if len(doc.Content) == 0 {
+		return sc
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		report(root.Line, "%s must map globs to tag payloads", SidecarFile)
+		return sc
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if err := ValidatePattern(key.Value); err != nil {
+			report(key.Line, "invalid pattern %q in %s: %v", key.Value, SidecarFile, err)
+			continue
+		}
+		var tags map[string][]string
+		if err := value.Decode(&tags); err != nil {
+			report(value.Line, "invalid tags for %q in %s: %v", key.Value, SidecarFile, err)
+			continue
+		}
+		sc.rules = append(sc.rules, sidecarRule{pattern: key.Value, tags: tags})
+	}
+	return sc
+}
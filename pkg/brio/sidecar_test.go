@@ -0,0 +1,50 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidecarSnippets(t *testing.T) {
+	tempDir := t.TempDir()
+	schemas := filepath.Join(tempDir, "schemas")
+	require.NoError(t, os.MkdirAll(filepath.Join(schemas, "legacy"), 0755))
+	write := func(path, content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write(filepath.Join(tempDir, SidecarFile), `"schemas/legacy/*.py": {foundation: [legacy], _owner: [team-db]}`+"\n")
+	write(filepath.Join(schemas, SidecarFile), `{"*.py": {"foundation": ["billing"], "_owner": ["team-billing"]}, "[oops": {"tests": []}}`+"\n")
+	write(filepath.Join(schemas, "invoice.py"), "class Invoice:\n    pass\n")
+	write(filepath.Join(schemas, "legacy", "ledger.py"), "class Ledger:\n    pass\n")
+	write(filepath.Join(tempDir, "app.py"), "print(1)\n")
+
+	e := New(Options{Dir: tempDir})
+	snips, issues := e.SidecarSnippets(filepath.Join(schemas, "legacy", "ledger.py"))
+	require.Len(t, snips, 1)
+	assert.Equal(t, 1, snips[0].StartLine)
+	assert.Equal(t, 2, snips[0].EndLine)
+	assert.Equal(t, []string{"class Ledger:", "    pass"}, snips[0].Content)
+	// The nearest file's attributes win; categories add up.
+	assert.Equal(t, map[string][]string{"foundation": {"billing", "legacy"}}, snips[0].Categories)
+	assert.Equal(t, "team-billing", snips[0].Attr("owner"))
+	require.Len(t, issues, 1)
+	assert.Equal(t, CodeInvalidTag, issues[0].Code)
+	assert.Contains(t, issues[0].Message, `invalid pattern "[oops"`)
+
+	// Issues are reported by the first file reading the sidecar file only.
+	_, issues = e.SidecarSnippets(filepath.Join(schemas, "invoice.py"))
+	assert.Empty(t, issues)
+
+	snips, _, err := New(Options{Dir: tempDir, Categories: ParseCategories("billing:foundation")}).Extract(context.Background())
+	require.NoError(t, err)
+	var files []string
+	for _, s := range snips {
+		files = append(files, filepath.Base(s.File))
+	}
+	assert.Equal(t, []string{"invoice.py", "ledger.py"}, files)
+}
@@ -0,0 +1,265 @@
+package brio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rechati/brio/cmd/plugins"
+)
+
+// Snippet is a tagged section of code with its file, line range, categories and content.
+// Attrs holds the tag fields starting with an underscore (e.g. "_expires"), keyed without the underscore.
+type Snippet struct {
+	File       string
+	Section    string // part of File the line numbers are relative to (e.g. a notebook cell), if any
+	StartLine  int
+	EndLine    int
+	Categories map[string][]string
+	Attrs      map[string][]string
+	Content    []string
+	// Plugin is the language plugin of the snippet, which gives its Markdown fence
+	Plugin plugins.Plugin
+	// source is the content of the snippet in its file, kept for Hash once Content is changed.
+	source []string
+}
+
+// Attr returns the first value of the named attribute, or "" if the snippet doesn't set it.
+func (s Snippet) Attr(name string) string {
+	if values := s.Attrs[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// snippetData represents a snippet of code extracted from a file, including its associated metadata and content lines.
+type snippetData struct {
+	categories map[string][]string
+	startLine  int
+	lines      []string
+	plugin     plugins.Plugin // block the snippet is in, for composite files
+}
+
+// Diagnostic codes of issues and file errors. They never change meaning, so tools can filter on them.
+const (
+	CodeUnmatchedTag       = "BRIO001" // start tag never closed, end tag without a start
+	CodeInvalidTag         = "BRIO002" // tag payload that can't be parsed
+	CodeUnsupportedFile    = "BRIO003" // no plugin handles the file
+	CodeUnreadableFile     = "BRIO004" // the file couldn't be opened or read
+	CodeOversizedFile      = "BRIO005" // a line of the file is too long to scan
+	CodeUndecodableFile    = "BRIO006" // the file's container format is invalid
+	CodePluginFailure      = "BRIO007" // the plugin parsing the file failed
+	CodeExpired            = "BRIO008" // the snippet's _expires date has passed
+	CodeInvalidExpiry      = "BRIO009" // the snippet's _expires date is not YYYY-MM-DD
+	CodePluginIssue        = "BRIO010" // problem reported by a plugin that parses files itself
+	CodeDanglingDependency = "BRIO011" // _depends_on names an id no snippet declares
+	CodeDependencyCycle    = "BRIO012" // snippets depend on each other through _depends_on
+	CodeDuplicateID        = "BRIO013" // two snippets declare the same _id
+	CodeStaleHash          = "BRIO014" // the snippet's content no longer matches its _hash
+	CodeDanglingInjection  = "BRIO015" // an inject block of a document matches no snippet
+	CodeStaleVirtual       = "BRIO016" // a virtual snippet names a symbol or lines its file doesn't have
+)
+
+// Issue describes a problem with the annotations of a file, such as a start tag that is never closed.
+type Issue struct {
+	File    string `json:"file"`
+	Section string `json:"section,omitempty"`
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d: %s %s", SectionPath(i.File, i.Section), i.Line, i.Code, i.Message)
+}
+
+// SectionPath returns path followed by the section of the file, if any (e.g. "nb.ipynb (cell 3)").
+func SectionPath(path, section string) string {
+	if section == "" {
+		return path
+	}
+	return fmt.Sprintf("%s (%s)", path, section)
+}
+
+// splitAttrs separates the underscore-prefixed attribute fields of a tag from its categories.
+func splitAttrs(data map[string][]string) (categories, attrs map[string][]string) {
+	categories = make(map[string][]string, len(data))
+	for key, values := range data {
+		if name, isAttr := strings.CutPrefix(key, "_"); isAttr {
+			if attrs == nil {
+				attrs = make(map[string][]string)
+			}
+			attrs[name] = values
+			continue
+		}
+		categories[key] = values
+	}
+	return categories, attrs
+}
+
+// Priority returns the "_priority" of the snippet, which decides which snippets are kept first when
+// output is trimmed to a budget: higher ones first, 0 when unset or not an integer.
+func (s Snippet) Priority() int {
+	priority, err := strconv.Atoi(strings.TrimSpace(s.Attr("priority")))
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// ExpiryIssue reports a snippet whose "_expires" date (YYYY-MM-DD) has passed at now, or is not a valid date.
+func (s Snippet) ExpiryIssue(now time.Time) (Issue, bool) {
+	expires := s.Attr("expires")
+	if expires == "" {
+		return Issue{}, false
+	}
+
+	expiresAt, err := time.ParseInLocation(time.DateOnly, expires, time.Local)
+	if err != nil {
+		return Issue{File: s.File, Section: s.Section, Line: s.StartLine, Code: CodeInvalidExpiry, Message: fmt.Sprintf("invalid _expires date %q, want YYYY-MM-DD", expires)}, true
+	}
+	if now.Before(expiresAt) {
+		return Issue{}, false
+	}
+
+	message := fmt.Sprintf("snippet expired on %s", expires)
+	if since := s.Attr("since"); since != "" {
+		message += fmt.Sprintf(" (in place since %s)", since)
+	}
+	return Issue{File: s.File, Section: s.Section, Line: s.StartLine, Code: CodeExpired, Message: message}, true
+}
+
+// hashLength is how many hex digits of the SHA-256 of its content a snippet's hash keeps.
+const hashLength = 12
+
+// Hash returns a short hash of the content of the snippet, as recorded by "_hash". Trailing spaces
+// and line endings don't change it. It is the hash of the content in the file, before processors or
+// WithContent changed it, so that what is printed can be checked against the file.
+func (s Snippet) Hash() string {
+	content := s.Content
+	if s.source != nil {
+		content = s.source
+	}
+	h := sha256.New()
+	for _, line := range content {
+		h.Write([]byte(strings.TrimRight(line, " \t\r")))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:hashLength]
+}
+
+// WithContent returns s with content in place of its content, keeping the content it has in its
+// file for Hash.
+func (s Snippet) WithContent(content []string) Snippet {
+	if s.source == nil {
+		s.source = s.Content
+	}
+	s.Content = content
+	return s
+}
+
+// HashIssue reports a snippet whose content changed since its "_hash" was recorded, so that what
+// documents it may be out of date.
+func (s Snippet) HashIssue() (Issue, bool) {
+	recorded := s.Attr("hash")
+	if recorded == "" {
+		return Issue{}, false
+	}
+	current := s.Hash()
+	if strings.EqualFold(recorded, current) {
+		return Issue{}, false
+	}
+	message := fmt.Sprintf("snippet content changed since _hash %q was recorded (now %q)", recorded, current)
+	return Issue{File: s.File, Section: s.Section, Line: s.StartLine, Code: CodeStaleHash, Message: message}, true
+}
+
+// Matches checks if a snippet matches the requested category-domain mapping specified in catMap.
+// If catMap is empty, the function returns true, indicating all snippets should match.
+// The function iterates through the snippet's categories and checks for intersections with the requested domains in catMap.
+func (s Snippet) Matches(catMap map[string][]string) bool {
+	// If user specified no categories, everything matches.
+	if len(catMap) == 0 {
+		return true
+	}
+
+	// e.g. snippet categories: {"foundation": ["messages"], "model": ["messages"]}
+	// catMap might be: {"foundation": ["messages"], "tests": ["messages"]}
+	for snippetCat, snippetDomains := range s.Categories {
+		if requestedDomains, found := catMap[snippetCat]; found {
+			// If category is requested with no domain => matches any domain for that category.
+			if len(requestedDomains) == 0 {
+				return true
+			}
+			// Otherwise, check domain intersection. An empty requested domain
+			// (e.g. "-c foundation") also matches any domain, including none at all.
+			for _, rd := range requestedDomains {
+				if rd == "" {
+					return true
+				}
+			}
+			for _, sd := range snippetDomains {
+				for _, rd := range requestedDomains {
+					if sd == rd {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ParseCategories parses a category argument such as "messages:foundation,tests" into a map of
+// categories to their associated domains, as accepted by Snippet.Matches and Options.Categories.
+func ParseCategories(categoryArg string) map[string][]string {
+	result := make(map[string][]string)
+	if categoryArg == "" {
+		return result
+	}
+
+	parts := strings.Split(categoryArg, ",")
+	var currentDomain string
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if strings.Contains(part, ":") {
+			// e.g., "messages:foundation"
+			splitPart := strings.SplitN(part, ":", 2)
+			currentDomain = strings.TrimSpace(splitPart[0])
+			category := strings.TrimSpace(splitPart[1])
+			addToCategoryMap(result, category, currentDomain)
+		} else {
+			// e.g., "tests" with inherited domain
+			addToCategoryMap(result, part, currentDomain)
+		}
+	}
+
+	return result
+}
+
+// addToCategoryMap adds a domain to the specified category in the map.
+// If the category does not exist, it initializes it with an empty slice.
+// Prevents duplicate domains within a category.
+// Ensures the slice contains an empty string if the domain is an empty string and the category is new.
+func addToCategoryMap(catMap map[string][]string, category, domain string) {
+	if _, exists := catMap[category]; !exists {
+		catMap[category] = []string{}
+	}
+	if domain != "" {
+		// Avoid duplicates
+		for _, d := range catMap[category] {
+			if d == domain {
+				return
+			}
+		}
+		catMap[category] = append(catMap[category], domain)
+	} else {
+		// If domain is empty string, ensure the slice contains an empty string
+		if len(catMap[category]) == 0 {
+			catMap[category] = append(catMap[category], "")
+		}
+	}
+}
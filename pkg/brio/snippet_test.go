@@ -0,0 +1,94 @@
+package brio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCategories(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string][]string
+	}{
+		{
+			input: "messages:foundation,tests",
+			expected: map[string][]string{
+				"foundation": {"messages"},
+				"tests":      {"messages"},
+			},
+		},
+		{
+			input:    "",
+			expected: map[string][]string{},
+		},
+		{
+			input: "foundation",
+			expected: map[string][]string{
+				"foundation": {""},
+			},
+		},
+		{
+			input: "messages:foundation,tests,additional",
+			expected: map[string][]string{
+				"foundation": {"messages"},
+				"tests":      {"messages"},
+				"additional": {"messages"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		result := ParseCategories(tc.input)
+		assert.Equal(t, tc.expected, result, "Input: %s", tc.input)
+	}
+}
+
+func TestSnippetMatches(t *testing.T) {
+	snip := Snippet{
+		Categories: map[string][]string{
+			"foundation": {"messages"},
+			"model":      {"messages"},
+		},
+	}
+
+	// Empty catMap => matches everything
+	assert.True(t, snip.Matches(map[string][]string{}))
+
+	// Matching category + domain
+	assert.True(t, snip.Matches(map[string][]string{"foundation": {"messages"}}))
+
+	// Matching category, but domain mismatch
+	assert.False(t, snip.Matches(map[string][]string{"foundation": {"alert"}}))
+
+	// Category matches, but catMap has no domain => matches
+	assert.True(t, snip.Matches(map[string][]string{"foundation": {}}))
+
+	// Category given without a domain on the command line => matches
+	assert.True(t, snip.Matches(ParseCategories("foundation")))
+}
+
+func TestSnippetHash(t *testing.T) {
+	s := Snippet{File: "app.py", StartLine: 4, Content: []string{"def send():", "    pass"}}
+	hash := s.Hash()
+	assert.Len(t, hash, 12)
+	assert.Equal(t, hash, Snippet{Content: []string{"def send():  ", "    pass\r"}}.Hash())
+	assert.NotEqual(t, hash, Snippet{Content: []string{"def send():", "    return"}}.Hash())
+	rewritten := s.WithContent([]string{"def send():", "\tpass"})
+	assert.Equal(t, []string{"def send():", "\tpass"}, rewritten.Content)
+	assert.Equal(t, hash, rewritten.Hash())
+	assert.Equal(t, hash, rewritten.WithContent(nil).Hash())
+
+	_, stale := s.HashIssue()
+	assert.False(t, stale, "no _hash")
+	s.Attrs = map[string][]string{"hash": {strings.ToUpper(hash)}}
+	_, stale = s.HashIssue()
+	assert.False(t, stale)
+
+	s.Attrs["hash"] = []string{"abc123"}
+	i, stale := s.HashIssue()
+	assert.True(t, stale)
+	assert.Equal(t, Issue{File: "app.py", Line: 4, Code: CodeStaleHash,
+		Message: `snippet content changed since _hash "abc123" was recorded (now "` + hash + `")`}, i)
+}
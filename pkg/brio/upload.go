@@ -0,0 +1,134 @@
+package brio
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Bucket is a place in object storage to upload files to, named by a URL such as
+// s3://bucket/prefix/ (Amazon S3) or gs://bucket/prefix/ (Google Cloud Storage). Requests are
+// authenticated with the credentials the environment provides, as the cloud CLIs find them (see
+// s3Store.setup and googleTokens).
+type Bucket struct {
+	url    string
+	store  objectStore
+	prefix string
+}
+
+// objectStore writes objects to a bucket of a storage service.
+type objectStore interface {
+	put(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// OpenBucket returns the bucket of rawURL, s3://bucket/prefix or gs://bucket/prefix. Requests are
+// sent with client; nil means http.DefaultClient.
+func OpenBucket(rawURL string, client *http.Client) (*Bucket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q names no bucket, expected s3://bucket/prefix/ or gs://bucket/prefix/", rawURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b := &Bucket{url: rawURL, prefix: strings.TrimPrefix(u.Path, "/")}
+	switch u.Scheme {
+	case "s3":
+		b.store = &s3Store{bucket: u.Host, client: client}
+	case "gs":
+		b.store = &gcsStore{bucket: u.Host, client: client}
+	default:
+		return nil, fmt.Errorf("unsupported storage %q, expected s3://bucket/prefix/ or gs://bucket/prefix/", rawURL)
+	}
+	return b, nil
+}
+
+// Key returns the key the file name is uploaded under: name below the prefix of the bucket URL
+// when it ends with a slash or is empty, or else the prefix itself, which names the object.
+func (b *Bucket) Key(name string) string {
+	if b.prefix == "" || strings.HasSuffix(b.prefix, "/") {
+		return b.prefix + name
+	}
+	return b.prefix
+}
+
+// String returns the URL the bucket was opened with.
+func (b *Bucket) String() string {
+	return b.url
+}
+
+// URL returns the URL of the object key, e.g. s3://bucket/prefix/snippets.md.
+func (b *Bucket) URL(key string) string {
+	u, _ := url.Parse(b.url)
+	return u.Scheme + "://" + u.Host + "/" + key
+}
+
+// Put uploads body as the file name (see Key), with the content type of its extension, and returns
+// its key.
+func (b *Bucket) Put(ctx context.Context, name string, body []byte) (string, error) {
+	key := b.Key(name)
+	if err := b.store.put(ctx, key, body, contentType(key)); err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", b.URL(key), err)
+	}
+	return key, nil
+}
+
+// PutDir uploads the files below dir, keyed by their path relative to it below the prefix of the
+// bucket URL, and returns how many it uploaded. It stops at the first failure.
+func (b *Bucket) PutDir(ctx context.Context, dir string) (int, error) {
+	prefix := b.prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	uploaded := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		body, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		key := prefix + filepath.ToSlash(rel)
+		if err := b.store.put(ctx, key, body, contentType(key)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", b.URL(key), err)
+		}
+		uploaded++
+		return nil
+	})
+	return uploaded, err
+}
+
+// contentType returns the media type of the object key from its extension.
+func contentType(key string) string {
+	switch ext := path.Ext(key); ext {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".gz":
+		return "application/gzip"
+	case "":
+		return "application/octet-stream"
+	default:
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,174 @@
+package brio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenBucket(t *testing.T) {
+	b, err := OpenBucket("s3://packs/nightly/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly/snippets.md", b.Key("snippets.md"))
+	assert.Equal(t, "s3://packs/nightly/snippets.md", b.URL(b.Key("snippets.md")))
+
+	b, err = OpenBucket("gs://packs/nightly/context.md", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly/context.md", b.Key("snippets.md"))
+
+	b, err = OpenBucket("gs://packs", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "snippets.md", b.Key("snippets.md"))
+
+	for _, raw := range []string{"https://packs/nightly/", "s3:///nightly/", "packs/nightly"} {
+		_, err := OpenBucket(raw, nil)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestBucketPutS3(t *testing.T) {
+	type upload struct {
+		path, auth, contentType, body string
+	}
+	var uploads []upload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		auth := r.Header.Get("Authorization")
+		// The bucket lives in eu-west-1: requests signed for another region are redirected.
+		if !strings.Contains(auth, "/eu-west-1/s3/") {
+			w.Header().Set("X-Amz-Bucket-Region", "eu-west-1")
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+		uploads = append(uploads, upload{r.URL.EscapedPath(), auth, r.Header.Get("Content-Type"), string(body)})
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_CA_BUNDLE", "")
+	// The region and the credentials come from the profile of the config file, as for the AWS CLI.
+	configPath := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(configPath, []byte(`[profile ci]
+region = us-east-1
+credential_process = echo '{"Version": 1, "AccessKeyId": "AKIDPROCESS", "SecretAccessKey": "secret"}'
+`), 0600))
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_PROFILE", "ci")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	b, err := OpenBucket("s3://packs/nightly/", server.Client())
+	require.NoError(t, err)
+	key, err := b.Put(context.Background(), "snippets.md", []byte("# Snippets\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "nightly/snippets.md", key)
+
+	require.Len(t, uploads, 1)
+	assert.Equal(t, "/packs/nightly/snippets.md", uploads[0].path)
+	assert.True(t, strings.HasPrefix(uploads[0].auth, "AWS4-HMAC-SHA256 Credential=AKIDPROCESS/"), uploads[0].auth)
+	assert.Equal(t, "text/markdown; charset=utf-8", uploads[0].contentType)
+	assert.Equal(t, "# Snippets\n", uploads[0].body)
+}
+
+func TestBucketPutS3Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	b, err := OpenBucket("s3://packs/snippets.md", server.Client())
+	require.NoError(t, err)
+	_, err = b.Put(context.Background(), "ignored.md", []byte("x"))
+	require.Error(t, err)
+	assert.Equal(t, "failed to upload s3://packs/snippets.md: S3 answered 403 Forbidden: AccessDenied: Access Denied", err.Error())
+}
+
+func TestBucketPutDirGCS(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/upload/storage/v1/b/packs/o" || r.URL.Query().Get("uploadType") != "media" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		names = append(names, r.URL.Query().Get("name")+" "+r.Header.Get("Content-Type"))
+		mu.Unlock()
+		io.WriteString(w, `{"kind": "storage#object"}`)
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "auth"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.md"), []byte("# Index\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "auth", "login.html"), []byte("<p>login</p>\n"), 0644))
+
+	b, err := OpenBucket("gs://packs/docs", server.Client())
+	require.NoError(t, err)
+	n, err := b.PutDir(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"docs/auth/login.html text/html; charset=utf-8",
+		"docs/index.md text/markdown; charset=utf-8",
+		"docs/manifest.json application/json",
+	}, names)
+}
+
+func TestBucketPutGCSRefreshesTokens(t *testing.T) {
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "refresh", r.Form.Get("refresh_token"))
+			tokens++
+			// Tokens about to expire are refreshed before they are used.
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "Bearer", "expires_in": 5}`, tokens)
+		case "/upload/storage/v1/b/packs/o":
+			assert.Equal(t, fmt.Sprintf("Bearer token%d", tokens), r.Header.Get("Authorization"))
+			io.WriteString(w, `{"kind": "storage#object"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "authorized_user", "client_id": "id", "client_secret": "secret", `+
+		`"refresh_token": "refresh", "token_uri": "`+server.URL+`/token"}`), 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+
+	store := &gcsStore{bucket: "packs", client: server.Client()}
+	source, err := googleTokens(context.Background(), server.Client())
+	require.NoError(t, err)
+	store.tokens = source
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, store.put(context.Background(), "snippets.md", []byte("x"), "text/markdown"))
+	}
+	assert.Equal(t, 2, tokens)
+}
@@ -0,0 +1,100 @@
+package brio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VirtualSnippet declares a snippet from outside of its file, for code whose owners would rather
+// not have tags in it: a symbol (see Definitions.Lookup) or a range of lines of File, with the
+// categories and attributes ("_owner", "_expires"...) a tag would give it. Virtual snippets are
+// extracted along with the tagged snippets of their file.
+type VirtualSnippet struct {
+	// File is the path of the file, relative to the working directory.
+	File string
+	// Symbol names the function or class the snippet is, e.g. "UserService.create_user".
+	Symbol string
+	// StartLine and EndLine are the first and last lines of the snippet, from 1, when Symbol isn't
+	// set.
+	StartLine int
+	EndLine   int
+	// Tags holds the categories and attributes of the snippet, as a tag payload does.
+	Tags map[string][]string
+}
+
+// String names v in issues.
+func (v VirtualSnippet) String() string {
+	if v.Symbol != "" {
+		return fmt.Sprintf("virtual snippet %s", v.Symbol)
+	}
+	return fmt.Sprintf("virtual snippet of lines %d-%d", v.StartLine, v.EndLine)
+}
+
+// VirtualSnippets returns the snippets Options.Virtual declares in filePath, whatever their
+// categories and with the Options.PathDefaults of the file filled in, along with issues for those
+// whose symbol or lines the file doesn't have.
+func (e *Extractor) VirtualSnippets(ctx context.Context, filePath string) ([]Snippet, []Issue) {
+	var declared []VirtualSnippet
+	for _, v := range e.opts.Virtual {
+		if samePath(v.File, filePath) {
+			declared = append(declared, v)
+		}
+	}
+	if len(declared) == 0 {
+		return nil, nil
+	}
+
+	var snips []Snippet
+	var issues []Issue
+	report := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{File: filePath, Line: 1, Code: CodeStaleVirtual, Message: fmt.Sprintf(format, args...)})
+	}
+	content, err := ReadFile(filePath)
+	if err != nil {
+		// Scanning the file reports it.
+		return nil, nil
+	}
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+	plugin, _, _ := e.Plugin(filePath)
+	var defs Definitions
+	var defsErr error
+	for _, v := range declared {
+		categories, attrs := splitAttrs(v.Tags)
+		s := Snippet{File: filePath, Categories: categories, Attrs: attrs, Plugin: plugin}
+		if v.Symbol != "" {
+			if defs == nil && defsErr == nil {
+				defs, defsErr = e.Definitions(ctx, []string{filePath})
+			}
+			if defsErr != nil {
+				report("%s can't be looked up: %v", v, defsErr)
+				continue
+			}
+			found := defs.Lookup(v.Symbol)
+			if len(found) != 1 {
+				report("%s matches %d definitions of the file instead of one", v, len(found))
+				continue
+			}
+			s.StartLine, s.EndLine, s.Content = found[0].StartLine, found[0].EndLine, found[0].Content
+		} else {
+			if v.StartLine < 1 || v.EndLine < v.StartLine || v.EndLine > len(lines) {
+				report("%s is out of the %d lines of the file", v, len(lines))
+				continue
+			}
+			s.StartLine, s.EndLine, s.Content = v.StartLine, v.EndLine, lines[v.StartLine-1:v.EndLine]
+		}
+		snips = append(snips, s)
+	}
+	return e.applyPathDefaults(filePath, snips), issues
+}
+
+// mergeByLine returns the snippets of a and b, both of the same file, sorted by start line.
+func mergeByLine(a, b []Snippet) []Snippet {
+	if len(b) == 0 {
+		return a
+	}
+	merged := append(append([]Snippet(nil), a...), b...)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].StartLine < merged[j].StartLine })
+	return merged
+}
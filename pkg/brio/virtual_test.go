@@ -0,0 +1,62 @@
+package brio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rechati/brio/cmd/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualSnippets(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "service.ts")
+	content := `import { db } from "./db"
+
+export function createUser() {
+  return db.insert()
+}
+`
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	extractor := New(Options{Virtual: []VirtualSnippet{
+		{File: filePath, Symbol: "createUser", Tags: map[string][]string{"services": {}}},
+		{File: filePath, StartLine: 1, EndLine: 1, Tags: map[string][]string{"services": {"header"}, "_expires": {"2000-01-01"}}},
+		{File: filePath, Symbol: "missing"},
+		{File: filePath, StartLine: 5, EndLine: 9},
+		{File: filepath.Join(tempDir, "other.ts"), StartLine: 1, EndLine: 1},
+	}})
+	snips, issues := extractor.VirtualSnippets(context.Background(), filePath)
+	require.Len(t, snips, 2)
+	assert.Equal(t, []string{"export function createUser() {", "  return db.insert()", "}"}, snips[0].Content)
+	assert.Equal(t, 3, snips[0].StartLine)
+	assert.Equal(t, "typescript", snips[0].Language())
+	assert.Equal(t, map[string][]string{"services": {"header"}}, snips[1].Categories)
+	assert.Equal(t, "2000-01-01", snips[1].Attr("expires"))
+
+	var messages []string
+	for _, i := range issues {
+		messages = append(messages, i.String())
+	}
+	assert.Equal(t, []string{
+		filePath + ":1: BRIO016 virtual snippet missing matches 0 definitions of the file instead of one",
+		filePath + ":1: BRIO016 virtual snippet of lines 5-9 is out of the 5 lines of the file",
+	}, messages)
+}
+
+func TestVirtualSnippetsDefinitionError(t *testing.T) {
+	plugins.Register(&plugins.ConfigPlugin{Name: "Broken", Extensions: []string{".brokendefs"},
+		Style: plugins.CommentStyle{Single: "#"}, Definitions: []string{`^def (\w+`}})
+	filePath := filepath.Join(t.TempDir(), "app.brokendefs")
+	require.NoError(t, os.WriteFile(filePath, []byte("def run\n"), 0644))
+
+	extractor := New(Options{Virtual: []VirtualSnippet{{File: filePath, Symbol: "run"}}})
+	snips, issues := extractor.VirtualSnippets(context.Background(), filePath)
+	assert.Empty(t, snips)
+	require.Len(t, issues, 1)
+	assert.Equal(t, CodeStaleVirtual, issues[0].Code)
+	assert.Contains(t, issues[0].Message, "virtual snippet run can't be looked up: definition pattern of Broken: ")
+}